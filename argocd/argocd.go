@@ -4,7 +4,7 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"regexp"
 	"strconv"
 	"strings"
@@ -179,7 +179,7 @@ func ParseLogChanges(pipelineName, message string) (*model.PipelineChanges, erro
 	if matches == nil {
 		return nil, nil
 	}
-	log.Printf("Pipeline %s: %s", pipelineName, message)
+	slog.Info(fmt.Sprintf("Pipeline %s: %s", pipelineName, message))
 	changed := matches[1]
 	destroyed := matches[2]
 	argoChanges := model.PipelineChanges{}
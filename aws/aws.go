@@ -3,7 +3,6 @@ package aws
 import (
 	"context"
 	"fmt"
-	"log"
 	"log/slog"
 	"os"
 	"time"
@@ -55,7 +54,7 @@ func NewAWS(ctx context.Context, cloudPrefix string, awsFlags common.AWS, pipeli
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("AWS account id: %s\n", accountId)
+	common.Logger(ctx).Info(fmt.Sprintf("AWS account id: %s", accountId))
 	return &awsService{
 		ctx:         ctx,
 		awsConfig:   awsConfig,
@@ -73,7 +72,7 @@ func GetAWSConfig(ctx context.Context, roleArn string) (aws.Config, error) {
 	if err != nil {
 		return cfg, fmt.Errorf("failed to initialize AWS session: %v", err)
 	}
-	log.Printf("AWS session initialized with region: %s\n", cfg.Region)
+	common.Logger(ctx).Info(fmt.Sprintf("AWS session initialized with region: %s", cfg.Region))
 	if roleArn != "" {
 		return GetAssumedConfig(cfg, roleArn), nil
 	}
@@ -199,33 +198,33 @@ func (a *awsService) DeleteResources(deleteBucket, deleteServiceAccount bool) er
 	scheduler := NewScheduler(a.ctx, a.awsConfig, a.cloudPrefix)
 	err := scheduler.deleteUpdateSchedule()
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete EventBridge schedule %s: %s",
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete EventBridge schedule %s: %s",
 			getScheduleName(a.cloudPrefix, common.UpdateCommand), err)))
 	}
 	agentPrefix := model.GetAgentPrefix(a.cloudPrefix)
 	agentProjectName := model.GetAgentProjectName(agentPrefix, common.RunCommand)
 	err = a.resources.GetPipeline().(*Pipeline).deletePipeline(agentProjectName)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent run pipeline: %s", err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent run pipeline: %s", err)))
 	}
 	err = a.resources.GetBuilder().DeleteProject(agentProjectName, model.Step{})
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent run project: %s", err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent run project: %s", err)))
 	}
 
 	agentProjectName = model.GetAgentProjectName(agentPrefix, common.UpdateCommand)
 	err = a.resources.GetPipeline().(*Pipeline).deletePipeline(agentProjectName)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent update pipeline: %s", err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent update pipeline: %s", err)))
 	}
 	err = a.resources.GetBuilder().DeleteProject(agentProjectName, model.Step{})
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent update project: %s", err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent update project: %s", err)))
 	}
 
 	err = DeleteDynamoDBTable(a.ctx, a.awsConfig, fmt.Sprintf("%s-%s", a.cloudPrefix, a.accountId))
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete DynamoDB table: %s", err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete DynamoDB table: %s", err)))
 	}
 	a.deleteCloudWatchLogs()
 	a.deleteIAMRoles()
@@ -233,12 +232,12 @@ func (a *awsService) DeleteResources(deleteBucket, deleteServiceAccount bool) er
 		a.DeleteServiceAccount()
 	}
 	if !deleteBucket {
-		log.Printf("Terraform state bucket %s will not be deleted, delete it manually if needed\n", a.resources.GetBucketName())
+		common.Logger(a.ctx).Info(fmt.Sprintf("Terraform state bucket %s will not be deleted, delete it manually if needed", a.resources.GetBucketName()))
 		return nil
 	}
-	err = a.resources.GetBucket().Delete()
+	err = a.resources.GetBucket().Delete(a.ctx)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete S3 bucket: %s", err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete S3 bucket: %s", err)))
 	}
 	return nil
 }
@@ -262,7 +261,7 @@ func getBucketName(cloudPrefix, accountId, region string) string {
 
 func (a *awsService) createBucket(bucket string) (*S3, string, error) {
 	s3 := NewS3(a.ctx, a.awsConfig, bucket)
-	exists, err := s3.BucketExists()
+	exists, err := s3.BucketExists(a.ctx)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to check if S3 Bucket %s exists: %s", bucket, err)
 	}
@@ -340,7 +339,7 @@ func (a *awsService) createIAMRoles(logGroupArn string, s3Arn string, dynamoDBTa
 	}
 
 	if buildRoleCreated || pipelineRoleCreated {
-		log.Println("Waiting for roles to be available...")
+		common.Logger(a.ctx).Info("Waiting for roles to be available...")
 		time.Sleep(15 * time.Second)
 	}
 
@@ -414,7 +413,7 @@ func (a *awsService) createSchedule(schedule model.Schedule, iam IAM, manager mo
 	updateCron := schedule.UpdateCron
 	if err != nil {
 		if updateCron == "" {
-			slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to get EventBridge schedule %s: %s",
+			common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to get EventBridge schedule %s: %s",
 				getScheduleName(a.cloudPrefix, common.UpdateCommand), err)))
 			return nil
 		}
@@ -473,7 +472,7 @@ func (a *awsService) createScheduleRole(iam IAM, runArn, updateArn string) (stri
 	if err != nil {
 		return "", fmt.Errorf("failed to attach schedule policy to role %s: %s", *role.RoleName, err)
 	}
-	log.Println("Waiting for schedule role to be available...")
+	common.Logger(a.ctx).Info("Waiting for schedule role to be available...")
 	time.Sleep(15 * time.Second)
 	return *role.Arn, nil
 }
@@ -488,11 +487,11 @@ func (a *awsService) deleteCloudWatchLogs() {
 	logStream := a.getLogGroup()
 	err := cloudwatch.DeleteLogStream(logGroup, logStream)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete CloudWatch log stream: %s", err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete CloudWatch log stream: %s", err)))
 	}
 	err = cloudwatch.DeleteLogGroup(logGroup)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete CloudWatch log group: %s", err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete CloudWatch log group: %s", err)))
 	}
 }
 
@@ -500,41 +499,41 @@ func (a *awsService) deleteIAMRoles() {
 	buildRole := a.getBuildRoleName()
 	err := a.resources.IAM.DeleteRolePolicyAttachments(buildRole)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to detach IAM policies %s: %s", buildRole, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to detach IAM policies %s: %s", buildRole, err)))
 	}
 	err = a.resources.IAM.DeleteRole(buildRole)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM role %s: %s", buildRole, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM role %s: %s", buildRole, err)))
 	}
 	err = a.resources.IAM.DeletePolicy(buildRole, a.accountId)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM policy %s: %s", buildRole, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM policy %s: %s", buildRole, err)))
 	}
 	pipelineRole := a.getPipelineRoleName()
 	err = a.resources.IAM.DeleteRolePolicyAttachments(pipelineRole)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to detach IAM policy %s: %s", pipelineRole, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to detach IAM policy %s: %s", pipelineRole, err)))
 	}
 	err = a.resources.IAM.DeleteRole(pipelineRole)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM role %s: %s", pipelineRole, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM role %s: %s", pipelineRole, err)))
 	}
 	err = a.resources.IAM.DeletePolicy(pipelineRole, a.accountId)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM policy %s: %s", pipelineRole, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM policy %s: %s", pipelineRole, err)))
 	}
 	scheduleRole := a.getScheduleRoleName()
 	err = a.resources.IAM.DeleteRolePolicyAttachments(scheduleRole)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to detach IAM policy %s: %s", scheduleRole, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to detach IAM policy %s: %s", scheduleRole, err)))
 	}
 	err = a.resources.IAM.DeleteRole(scheduleRole)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM role %s: %s", scheduleRole, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM role %s: %s", scheduleRole, err)))
 	}
 	err = a.resources.IAM.DeletePolicy(scheduleRole, a.accountId)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM policy %s: %s", scheduleRole, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM policy %s: %s", scheduleRole, err)))
 	}
 }
 
@@ -573,7 +572,7 @@ func (a *awsService) createServiceRole(SAFlags common.ServiceAccount, iam IAM, u
 		if SAFlags.RemoveUser {
 			deleteServiceUser(iam, policyArn, username)
 		} else {
-			slog.Warn(common.PrefixWarning(fmt.Sprintf("Service account user %s already exists, use remove-user flag to delete it", username)))
+			common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Service account user %s already exists, use remove-user flag to delete it", username)))
 		}
 	}
 
@@ -588,7 +587,7 @@ func (a *awsService) createServiceRole(SAFlags common.ServiceAccount, iam IAM, u
 		}
 		return iam.AttachRolePolicy(policyArn, *role.RoleName)
 	}
-	log.Printf("Service account role %s", *role.Arn)
+	common.Logger(a.ctx).Info(fmt.Sprintf("Service account role %s", *role.Arn))
 	if role.AssumeRolePolicyDocument == nil {
 		return iam.UpdateTrustedRole(*role.RoleName, SAFlags.TrustRole)
 	}
@@ -599,12 +598,12 @@ func (a *awsService) createServiceRole(SAFlags common.ServiceAccount, iam IAM, u
 	if trusted {
 		return nil
 	} else if !SAFlags.RotateCredentials {
-		slog.Error(common.PrefixError(fmt.Errorf("service account role %s has different trust relationship, use rotate-credentials flag to update it", *role.RoleName)))
+		common.Logger(a.ctx).Error(common.PrefixError(fmt.Errorf("service account role %s has different trust relationship, use rotate-credentials flag to update it", *role.RoleName)))
 		return nil
 	}
 	err = iam.UpdateTrustedRole(*role.RoleName, SAFlags.TrustRole)
 	if err == nil {
-		log.Printf("Updated trust relationship for service account role %s\n", *role.RoleName)
+		common.Logger(a.ctx).Info(fmt.Sprintf("Updated trust relationship for service account role %s", *role.RoleName))
 	}
 	return err
 }
@@ -622,14 +621,14 @@ func (a *awsService) createServiceUser(SAFlags common.ServiceAccount, iam IAM, u
 		}
 	} else {
 		if !SAFlags.RotateCredentials {
-			log.Printf("Service account %s already exists, use rotate-credentials flag to generate new credentials\n", username)
+			common.Logger(a.ctx).Info(fmt.Sprintf("Service account %s already exists, use rotate-credentials flag to generate new credentials", username))
 			return nil
 		}
 		err := iam.DeleteAccessKeys(*user.UserName)
 		if err != nil {
 			return err
 		}
-		log.Printf("Deleted previous keys for service account %s\n", username)
+		common.Logger(a.ctx).Info(fmt.Sprintf("Deleted previous keys for service account %s", username))
 	}
 	accessKey, err := iam.CreateAccessKey(*user.UserName)
 	if err != nil {
@@ -645,26 +644,26 @@ func (a *awsService) DeleteServiceAccount() {
 	policyArn := fmt.Sprintf("arn:aws:iam::%s:policy/%s", a.accountId, username)
 	err := a.resources.IAM.DeleteRolePolicyAttachments(username)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to detach IAM role policies %s: %s", username, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to detach IAM role policies %s: %s", username, err)))
 	}
 	err = a.resources.IAM.DeleteRole(username)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM role %s: %s", username, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM role %s: %s", username, err)))
 	}
 	deleteServiceUser(a.resources.IAM, policyArn, username)
 	err = a.resources.IAM.DeletePolicy(username, a.accountId)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM policy %s: %s", username, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete IAM policy %s: %s", username, err)))
 	}
 	accessKeyIdParam := fmt.Sprintf("/entigo-infralib/%s/access_key_id", username)
-	err = a.resources.SSM.DeleteParameter(accessKeyIdParam)
+	err = a.resources.SSM.DeleteParameter(a.ctx, accessKeyIdParam)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete SSM parameter %s: %s", accessKeyIdParam, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete SSM parameter %s: %s", accessKeyIdParam, err)))
 	}
 	secretAccessKeyParam := fmt.Sprintf("/entigo-infralib/%s/secret_access_key", username)
-	err = a.resources.SSM.DeleteParameter(secretAccessKeyParam)
+	err = a.resources.SSM.DeleteParameter(a.ctx, secretAccessKeyParam)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete SSM parameter %s: %s", secretAccessKeyParam, err)))
+		common.Logger(a.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete SSM parameter %s: %s", secretAccessKeyParam, err)))
 	}
 }
 
@@ -707,7 +706,7 @@ func (a *awsService) setupConfigEncryption(moduleName string, outputs map[string
 		return nil
 	}
 	a.resources.GetSSM().(*ssm).AddEncryptionKeyId(arn)
-	err = a.resources.GetBucket().(*S3).addEncryption(arn)
+	err = a.resources.GetBucket().(*S3).addEncryption(a.ctx, arn)
 	if err != nil {
 		return fmt.Errorf("failed to add encryption to bucket: %v", err)
 	}
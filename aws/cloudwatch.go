@@ -4,8 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"log/slog"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
@@ -68,7 +66,7 @@ func (c *cloudWatch) CreateLogGroup(logGroupName string) (string, error) {
 		}
 		return "", err
 	}
-	log.Printf("Created log group %s\n", logGroupName)
+	common.Logger(c.ctx).Info(fmt.Sprintf("Created log group %s", logGroupName))
 	return c.getLogGroup(logGroupName)
 }
 
@@ -96,7 +94,7 @@ func (c *cloudWatch) addRetentionPolicy(logGroup types.LogGroup) {
 		RetentionInDays: aws.Int32(180),
 	})
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to set retention policy for log group %s: %v",
+		common.Logger(c.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to set retention policy for log group %s: %v",
 			*logGroup.LogGroupName, err)))
 	}
 }
@@ -128,7 +126,7 @@ func (c *cloudWatch) CreateLogStream(logGroupName string, logStreamName string)
 		}
 		return err
 	}
-	log.Printf("Created log stream %s\n", logStreamName)
+	common.Logger(c.ctx).Info(fmt.Sprintf("Created log stream %s", logStreamName))
 	return nil
 }
 
@@ -166,7 +164,7 @@ func (c *cloudWatch) DeleteLogGroup(logGroupName string) error {
 		}
 		return err
 	}
-	log.Printf("Deleted log group %s\n", logGroupName)
+	common.Logger(c.ctx).Info(fmt.Sprintf("Deleted log group %s", logGroupName))
 	return nil
 }
 
@@ -182,7 +180,7 @@ func (c *cloudWatch) DeleteLogStream(logGroupName, logStreamName string) error {
 		}
 		return err
 	}
-	log.Printf("Deleted log stream %s\n", logStreamName)
+	common.Logger(c.ctx).Info(fmt.Sprintf("Deleted log stream %s", logStreamName))
 	return nil
 }
 
@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -107,7 +107,7 @@ func (b *builder) CreateProject(projectName string, bucket string, stepName stri
 	if err != nil && errors.As(err, &awsError) {
 		return b.UpdateProject(projectName, "", "", step, imageVersion, imageSource, vpcConfig, authSources)
 	}
-	log.Printf("Created CodeBuild project %s\n", projectName)
+	common.Logger(b.ctx).Info(fmt.Sprintf("Created CodeBuild project %s", projectName))
 	return err
 }
 
@@ -179,7 +179,7 @@ func (b *builder) CreateAgentProject(projectName string, awsPrefix string, image
 		}},
 	})
 	if err == nil {
-		log.Printf("Created CodeBuild project %s\n", projectName)
+		common.Logger(b.ctx).Info(fmt.Sprintf("Created CodeBuild project %s", projectName))
 	}
 	return err
 }
@@ -308,7 +308,7 @@ func logProjectChanges(projectName string, imageChanged, vpcChanged bool, awsVpc
 			sb.WriteString(" removed vpc")
 		}
 	}
-	log.Println(sb.String())
+	slog.Info(sb.String())
 }
 
 func (b *builder) DeleteProject(projectName string, _ model.Step) error {
@@ -322,7 +322,7 @@ func (b *builder) DeleteProject(projectName string, _ model.Step) error {
 		}
 		return err
 	}
-	log.Printf("Deleted CodeBuild project %s\n", projectName)
+	common.Logger(b.ctx).Info(fmt.Sprintf("Deleted CodeBuild project %s", projectName))
 	return nil
 }
 
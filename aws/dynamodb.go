@@ -3,12 +3,13 @@ package aws
 import (
 	"context"
 	"errors"
-	"log"
+	"fmt"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
 )
 
@@ -39,7 +40,7 @@ func CreateDynamoDBTable(ctx context.Context, awsConfig aws.Config, tableName st
 			return nil, err
 		}
 	}
-	log.Printf("Created DynamoDB table %s\n", tableName)
+	common.Logger(ctx).Info(fmt.Sprintf("Created DynamoDB table %s", tableName))
 	err = pollUntilTableActive(ctx, dynamodbClient, tableName)
 	if err != nil {
 		return nil, err
@@ -61,7 +62,7 @@ func pollUntilTableActive(ctx context.Context, client *dynamodb.Client, name str
 			if table.TableStatus == types.TableStatusActive {
 				return nil
 			}
-			log.Printf("Waiting for DynamoDB table %s to become active\n", name)
+			common.Logger(ctx).Info(fmt.Sprintf("Waiting for DynamoDB table %s to become active", name))
 		}
 	}
 }
@@ -78,7 +79,7 @@ func DeleteDynamoDBTable(ctx context.Context, awsConfig aws.Config, tableName st
 		}
 		return err
 	}
-	log.Printf("Deleted DynamoDB table %s\n", tableName)
+	common.Logger(ctx).Info(fmt.Sprintf("Deleted DynamoDB table %s", tableName))
 	return nil
 }
 
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/url"
 	"strings"
 
@@ -93,7 +92,7 @@ func (i *identity) CreateRole(roleName string, statement []PolicyStatement) (*ty
 		}
 		return nil, fmt.Errorf("failed to create role %s: %s", roleName, err)
 	}
-	log.Printf("Created IAM role: %s\n", *result.Role.Arn)
+	common.Logger(i.ctx).Info(fmt.Sprintf("Created IAM role: %s", *result.Role.Arn))
 	return result.Role, nil
 }
 
@@ -129,7 +128,7 @@ func (i *identity) CreatePolicy(policyName string, statement []PolicyStatement)
 		}
 		return &types.Policy{Arn: aws.String(fmt.Sprintf(policyArnFormat, i.accountId, policyName))}, nil
 	}
-	log.Printf("Created IAM policy: %s\n", policyName)
+	common.Logger(i.ctx).Info(fmt.Sprintf("Created IAM policy: %s", policyName))
 	return result.Policy, nil
 }
 
@@ -159,7 +158,7 @@ func (i *identity) UpdatePolicy(policyArn string, statement []PolicyStatement) e
 		return err
 	}
 	if notChanged {
-		log.Printf("Policy %s is already up to date\n", policyArn)
+		common.Logger(i.ctx).Info(fmt.Sprintf("Policy %s is already up to date", policyArn))
 		return nil
 	}
 	if len(versionsOutput.Versions) >= 5 {
@@ -179,7 +178,7 @@ func (i *identity) UpdatePolicy(policyArn string, statement []PolicyStatement) e
 	if err != nil {
 		return fmt.Errorf("failed to update policy %s: %s", policyArn, err)
 	}
-	log.Printf("Updated IAM policy: %s\n", policyArn)
+	common.Logger(i.ctx).Info(fmt.Sprintf("Updated IAM policy: %s", policyArn))
 	return nil
 }
 
@@ -343,7 +342,7 @@ func (i *identity) DeletePolicy(policyName string, accountId string) error {
 		}
 		return err
 	}
-	log.Printf("Deleted IAM policy: %s\n", policyName)
+	common.Logger(i.ctx).Info(fmt.Sprintf("Deleted IAM policy: %s", policyName))
 	return nil
 }
 
@@ -356,7 +355,7 @@ func (i *identity) DeleteRole(roleName string) error {
 		}
 		return err
 	}
-	log.Printf("Deleted IAM role: %s\n", roleName)
+	common.Logger(i.ctx).Info(fmt.Sprintf("Deleted IAM role: %s", roleName))
 	return nil
 }
 
@@ -406,7 +405,7 @@ func (i *identity) CreateUser(username string) (*types.User, error) {
 		}
 		return nil, fmt.Errorf("failed to create user %s: %v", username, err)
 	}
-	log.Printf("Created IAM user: %s\n", username)
+	common.Logger(i.ctx).Info(fmt.Sprintf("Created IAM user: %s", username))
 	return user.User, nil
 }
 
@@ -419,7 +418,7 @@ func (i *identity) DeleteUser(userName string) error {
 		}
 		return err
 	}
-	log.Printf("Deleted IAM user: %s\n", userName)
+	common.Logger(i.ctx).Info(fmt.Sprintf("Deleted IAM user: %s", userName))
 	return nil
 }
 
@@ -454,7 +453,7 @@ func (i *identity) CreateAccessKey(userName string) (*types.AccessKey, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create access key for user %s: %s", userName, err)
 	}
-	log.Printf("Created access key for user: %s\n", userName)
+	common.Logger(i.ctx).Info(fmt.Sprintf("Created access key for user: %s", userName))
 	return accessKey.AccessKey, nil
 }
 
@@ -502,7 +501,7 @@ func (i *identity) CreateServiceLinkedRole(service string) error {
 		AWSServiceName: aws.String(service),
 	})
 	if err == nil {
-		log.Printf("Created service linked role for %s\n", service)
+		common.Logger(i.ctx).Info(fmt.Sprintf("Created service linked role for %s", service))
 		return nil
 	}
 	var awsError *types.InvalidInputException
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 	"maps"
 	"slices"
@@ -18,10 +17,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/codepipeline/types"
 	"github.com/entigolabs/entigo-infralib-agent/argocd"
 	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/metrics"
 	"github.com/entigolabs/entigo-infralib-agent/model"
 	"github.com/entigolabs/entigo-infralib-agent/terraform"
+	"github.com/entigolabs/entigo-infralib-agent/tracing"
 	"github.com/entigolabs/entigo-infralib-agent/util"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -71,12 +73,22 @@ type Pipeline struct {
 	manager        model.NotificationManager
 	campaignId     string
 	pipelineIndex  string
+	sopsAgeKey     bool
+	redactValues   bool
 }
 
 func (p *Pipeline) SetCampaignId(id string) {
 	p.campaignId = id
 }
 
+func (p *Pipeline) SetSopsAgeKey(enabled bool) {
+	p.sopsAgeKey = enabled
+}
+
+func (p *Pipeline) SetRedactValues(enabled bool) {
+	p.redactValues = enabled
+}
+
 func (p *Pipeline) SetPipelineIndex(index int) {
 	p.pipelineIndex = strconv.Itoa(index)
 }
@@ -97,12 +109,12 @@ func NewPipeline(ctx context.Context, awsConfig aws.Config, roleArn string, clou
 	}
 }
 
-func (p *Pipeline) CreatePipeline(projectName string, stepName string, step model.Step, bucket model.Bucket, authSources map[string]model.SourceAuth) (*string, error) {
-	metadata, err := bucket.GetRepoMetadata()
+func (p *Pipeline) CreatePipeline(ctx context.Context, projectName string, stepName string, step model.Step, bucket model.Bucket, authSources map[string]model.SourceAuth) (*string, error) {
+	metadata, err := bucket.GetRepoMetadata(ctx)
 	if err != nil {
 		return nil, err
 	}
-	execution, err := p.CreateApplyPipeline(projectName, projectName, stepName, step, metadata.Name, authSources)
+	execution, err := p.CreateApplyPipeline(ctx, projectName, projectName, stepName, step, metadata.Name, authSources)
 	if err != nil {
 		return nil, err
 	}
@@ -129,17 +141,17 @@ func (p *Pipeline) deletePipeline(projectName string) error {
 		}
 		return err
 	}
-	log.Printf("Deleted CodePipeline %s\n", projectName)
+	common.Logger(p.ctx).Info(fmt.Sprintf("Deleted CodePipeline %s", projectName))
 	return nil
 }
 
-func (p *Pipeline) CreateApplyPipeline(pipelineName string, projectName string, stepName string, step model.Step, bucket string, authSources map[string]model.SourceAuth) (*string, error) {
+func (p *Pipeline) CreateApplyPipeline(ctx context.Context, pipelineName string, projectName string, stepName string, step model.Step, bucket string, authSources map[string]model.SourceAuth) (*string, error) {
 	pipe, err := p.getPipeline(pipelineName)
 	if err != nil {
 		return nil, err
 	}
 	if pipe != nil {
-		return p.startUpdatedPipeline(pipe, stepName, step, bucket, authSources)
+		return p.startUpdatedPipeline(ctx, pipe, stepName, step, bucket, authSources)
 	}
 	planCommand, applyCommand := model.GetCommands(step.Type)
 	planEnvars, err := p.getEnvironmentVariablesByType(planCommand, stepName, step, bucket, authSources)
@@ -161,6 +173,9 @@ func (p *Pipeline) CreateApplyPipeline(pipelineName string, projectName string,
 			}, {
 				Name:         aws.String("PipelineIndex"),
 				DefaultValue: aws.String("0"),
+			}, {
+				Name:         aws.String("TraceParent"),
+				DefaultValue: aws.String(model.CampaignSentinelNone),
 			}},
 			ArtifactStore: &types.ArtifactStore{
 				Location: aws.String(bucket),
@@ -248,7 +263,7 @@ func (p *Pipeline) CreateApplyPipeline(pipelineName string, projectName string,
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("Created CodePipeline %s\n", pipelineName)
+	p.stepLogger(step).Info(fmt.Sprintf("Created CodePipeline %s", pipelineName))
 	err = p.disableStageTransition(pipelineName, planName)
 	if err != nil {
 		return nil, err
@@ -261,7 +276,7 @@ func (p *Pipeline) CreateApplyPipeline(pipelineName string, projectName string,
 	if err != nil {
 		return nil, err
 	}
-	return p.StartPipelineExecution(pipelineName, "", model.Step{}, "")
+	return p.StartPipelineExecution(ctx, pipelineName, "", model.Step{}, "")
 }
 
 func (p *Pipeline) CreateDestroyPipeline(pipelineName string, projectName string, stepName string, step model.Step, bucket string, authSources map[string]model.SourceAuth) error {
@@ -300,6 +315,9 @@ func (p *Pipeline) CreateDestroyPipeline(pipelineName string, projectName string
 			}, {
 				Name:         aws.String("PipelineIndex"),
 				DefaultValue: aws.String("0"),
+			}, {
+				Name:         aws.String("TraceParent"),
+				DefaultValue: aws.String(model.CampaignSentinelNone),
 			}},
 			ArtifactStore: &types.ArtifactStore{
 				Location: aws.String(bucket),
@@ -386,7 +404,7 @@ func (p *Pipeline) CreateDestroyPipeline(pipelineName string, projectName string
 	if err != nil {
 		return err
 	}
-	log.Printf("Created destroy CodePipeline %s\n", pipelineName)
+	p.stepLogger(step).Info(fmt.Sprintf("Created destroy CodePipeline %s", pipelineName))
 	err = p.disableStageTransition(pipelineName, destroyName)
 	if err != nil {
 		return err
@@ -428,7 +446,7 @@ func (p *Pipeline) CreateAgentPipelines(prefix string, projectName string, bucke
 		if !run {
 			return nil
 		}
-		_, err = p.StartPipelineExecution(runPipeline, "", model.Step{}, "")
+		_, err = p.StartPipelineExecution(p.ctx, runPipeline, "", model.Step{}, "")
 		return err
 	}
 	err = p.createAgentPipeline(prefix, runPipeline, bucket)
@@ -493,13 +511,13 @@ func (p *Pipeline) createAgentPipeline(prefix string, projectName string, bucket
 		}},
 	})
 	if err == nil {
-		log.Printf("Created CodePipeline %s\n", projectName)
+		common.Logger(p.ctx).Info(fmt.Sprintf("Created CodePipeline %s", projectName))
 	}
 	return err
 }
 
-func (p *Pipeline) StartPipelineExecution(pipelineName string, _ string, _ model.Step, _ string) (*string, error) {
-	log.Printf("Starting pipeline %s\n", pipelineName)
+func (p *Pipeline) StartPipelineExecution(ctx context.Context, pipelineName string, _ string, _ model.Step, _ string) (*string, error) {
+	common.Logger(ctx).Info(fmt.Sprintf("Starting pipeline %s", pipelineName))
 	input := &codepipeline.StartPipelineExecutionInput{
 		Name:               aws.String(pipelineName),
 		ClientRequestToken: aws.String(uuid.NewString()),
@@ -516,7 +534,13 @@ func (p *Pipeline) StartPipelineExecution(pipelineName string, _ string, _ model
 			Value: aws.String(p.pipelineIndex),
 		})
 	}
-	execution, err := p.codePipeline.StartPipelineExecution(p.ctx, input)
+	if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+		input.Variables = append(input.Variables, types.PipelineVariable{
+			Name:  aws.String("TraceParent"),
+			Value: aws.String(traceParent),
+		})
+	}
+	execution, err := p.codePipeline.StartPipelineExecution(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -524,16 +548,16 @@ func (p *Pipeline) StartPipelineExecution(pipelineName string, _ string, _ model
 }
 
 func (p *Pipeline) StartAgentExecution(pipelineName string) error {
-	_, err := p.StartPipelineExecution(pipelineName, "", model.Step{}, "")
+	_, err := p.StartPipelineExecution(p.ctx, pipelineName, "", model.Step{}, "")
 	return err
 }
 
-func (p *Pipeline) startUpdatedPipeline(pipeline *types.PipelineDeclaration, stepName string, step model.Step, bucket string, authSources map[string]model.SourceAuth) (*string, error) {
+func (p *Pipeline) startUpdatedPipeline(ctx context.Context, pipeline *types.PipelineDeclaration, stepName string, step model.Step, bucket string, authSources map[string]model.SourceAuth) (*string, error) {
 	err := p.updatePipeline(pipeline, stepName, step, bucket, authSources)
 	if err != nil {
 		return nil, err
 	}
-	return p.StartPipelineExecution(*pipeline.Name, stepName, step, "")
+	return p.StartPipelineExecution(ctx, *pipeline.Name, stepName, step, "")
 }
 
 func (p *Pipeline) UpdatePipeline(pipelineName string, stepName string, step model.Step, bucket string, authSources map[string]model.SourceAuth) error {
@@ -590,6 +614,13 @@ func (p *Pipeline) updatePipeline(pipeline *types.PipelineDeclaration, stepName
 		})
 		changed = true
 	}
+	if !hasPipelineVariable(pipeline.Variables, "TraceParent") {
+		pipeline.Variables = append(pipeline.Variables, types.PipelineVariableDeclaration{
+			Name:         aws.String("TraceParent"),
+			DefaultValue: aws.String(model.CampaignSentinelNone),
+		})
+		changed = true
+	}
 	for _, stage := range pipeline.Stages {
 		if *stage.Name == sourceName || *stage.Name == approveStageName {
 			continue
@@ -614,7 +645,7 @@ func (p *Pipeline) updatePipeline(pipeline *types.PipelineDeclaration, stepName
 		Pipeline: pipeline,
 	})
 	if err == nil {
-		log.Printf("Updated CodePipeline %s\n", *pipeline.Name)
+		p.stepLogger(step).Info(fmt.Sprintf("Updated CodePipeline %s", *pipeline.Name))
 	}
 	return err
 }
@@ -653,12 +684,16 @@ func getCommand(actionName string, stepType model.StepType) model.ActionCommand
 	return ""
 }
 
-func (p *Pipeline) WaitPipelineExecution(pipelineName string, _ string, executionId *string, autoApprove bool, step model.Step, approve model.ManualApprove) error {
+func (p *Pipeline) WaitPipelineExecution(ctx context.Context, pipelineName string, _ string, executionId *string, autoApprove bool, step model.Step, approve model.ManualApprove) (err error) {
+	ctx, span := tracing.Start(ctx, "CodePipeline.WaitPipelineExecution", attribute.String("pipeline", pipelineName),
+		attribute.String("step", step.Name))
+	defer tracing.End(span, &err)
 	if executionId == nil {
 		return fmt.Errorf("execution id is nil")
 	}
-	log.Printf("Waiting for pipeline %s to complete, polling delay %s\n", pipelineName, pollingDelay)
-	err := p.waitPipelineExecutionStart(pipelineName, executionId)
+	defer metrics.ApprovalPending(step.Name, false)
+	p.stepLogger(step).Info(fmt.Sprintf("Waiting for pipeline %s to complete, polling delay %s", pipelineName, pollingDelay))
+	err = p.waitPipelineExecutionStart(pipelineName, executionId)
 	if err != nil {
 		return err
 	}
@@ -667,10 +702,10 @@ func (p *Pipeline) WaitPipelineExecution(pipelineName string, _ string, executio
 	var status approvalStatus
 	for {
 		select {
-		case <-p.ctx.Done():
-			return p.ctx.Err()
+		case <-ctx.Done():
+			return ctx.Err()
 		case <-ticker.C:
-			execution, err := p.codePipeline.GetPipelineExecution(p.ctx, &codepipeline.GetPipelineExecutionInput{
+			execution, err := p.codePipeline.GetPipelineExecution(ctx, &codepipeline.GetPipelineExecutionInput{
 				PipelineName:        aws.String(pipelineName),
 				PipelineExecutionId: executionId,
 			})
@@ -683,7 +718,7 @@ func (p *Pipeline) WaitPipelineExecution(pipelineName string, _ string, executio
 			if status == approvalStatusApproved {
 				continue
 			}
-			executionsList, err := p.codePipeline.ListActionExecutions(p.ctx, &codepipeline.ListActionExecutionsInput{
+			executionsList, err := p.codePipeline.ListActionExecutions(ctx, &codepipeline.ListActionExecutionsInput{
 				PipelineName: aws.String(pipelineName),
 				Filter:       &types.ActionExecutionFilter{PipelineExecutionId: executionId},
 			})
@@ -757,7 +792,7 @@ func (p *Pipeline) stopPreviousExecution(pipelineName, executionId string, actio
 		Name: aws.String(pipelineName),
 	})
 	if err != nil {
-		slog.Debug(fmt.Sprintf("Couldn't get pipeline state for %s: %s", pipelineName, err.Error()))
+		common.Logger(p.ctx).Debug(fmt.Sprintf("Couldn't get pipeline state for %s: %s", pipelineName, err.Error()))
 		return
 	}
 	for _, stage := range state.StageStates {
@@ -769,10 +804,10 @@ func (p *Pipeline) stopPreviousExecution(pipelineName, executionId string, actio
 			return
 		}
 		previousId := *stage.LatestExecution.PipelineExecutionId
-		log.Printf("Stopping previous pipeline %s execution\n", pipelineName)
+		common.Logger(p.ctx).Info(fmt.Sprintf("Stopping previous pipeline %s execution", pipelineName))
 		err = p.stopPipelineExecution(pipelineName, previousId, "New pipeline execution started")
 		if err != nil {
-			slog.Warn(common.PrefixWarning(fmt.Sprintf("Couldn't stop previous pipeline %s execution %s, "+
+			common.Logger(p.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Couldn't stop previous pipeline %s execution %s, "+
 				"please stop it manually, error: %s", pipelineName, previousId, err.Error())))
 		}
 		return
@@ -812,7 +847,7 @@ func (p *Pipeline) processStateStages(pipelineName, executionId string, actions
 		}
 		switch status {
 		case approvalStatusWaiting:
-			log.Printf("Waiting for manual approval of pipeline %s\n", pipelineName)
+			p.stepLogger(step).Info(fmt.Sprintf("Waiting for manual approval of pipeline %s", pipelineName))
 			return status, nil
 		case approvalStatusApprove:
 			return p.approveStage(pipelineName)
@@ -831,13 +866,18 @@ func (p *Pipeline) processChanges(pipelineName string, executionId string, actio
 	if pipeChanges == nil {
 		return approvalStatusStop, fmt.Errorf("couldn't get pipeline changes for %s", pipelineName)
 	}
+	metrics.PlanChanges(step.Name, *pipeChanges)
+	if p.manager != nil {
+		p.manager.PlanChanges(pipelineName, step.Name, *pipeChanges)
+	}
 	if util.ShouldStopPipeline(*pipeChanges, step.Approve, approve) {
 		return p.stopPipeline(pipelineName, executionId, step.Approve, approve)
 	}
 	if util.ShouldApprovePipeline(*pipeChanges, step.Approve, autoApprove, approve) {
 		return p.approveStage(pipelineName)
 	}
-	log.Printf("Waiting for manual approval of pipeline %s\n", pipelineName)
+	p.stepLogger(step).Info(fmt.Sprintf("Waiting for manual approval of pipeline %s", pipelineName))
+	metrics.ApprovalPending(step.Name, true)
 	if p.manager != nil {
 		p.manager.ManualApproval(pipelineName, step.Name, *pipeChanges, p.getLink(pipelineName))
 	}
@@ -851,7 +891,7 @@ func (p *Pipeline) getLink(pipelineName string) string {
 func (p *Pipeline) getChanges(pipelineName string, actions []types.ActionExecutionDetail, stepType model.StepType) (*model.PipelineChanges, error) {
 	switch stepType {
 	case model.StepTypeTerraform:
-		return p.getPipelineChanges(pipelineName, actions, terraform.ParseLogChanges)
+		return p.getPipelineChanges(pipelineName, actions, terraform.NewLogChangesParser())
 	case model.StepTypeArgoCD:
 		return p.getPipelineChanges(pipelineName, actions, argocd.ParseLogChanges)
 	}
@@ -888,14 +928,14 @@ func (p *Pipeline) getPipelineChanges(pipelineName string, actions []types.Actio
 }
 
 func (p *Pipeline) stopPipeline(pipelineName, executionId string, approve model.Approve, manualApprove model.ManualApprove) (approvalStatus, error) {
-	log.Printf("Stopping pipeline %s\n", pipelineName)
+	common.Logger(p.ctx).Info(fmt.Sprintf("Stopping pipeline %s", pipelineName))
 	reason := "No changes detected"
 	if approve == model.ApproveReject || manualApprove == model.ManualApproveReject {
 		reason = "Rejected"
 	}
 	err := p.stopPipelineExecution(pipelineName, executionId, reason)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Couldn't stop pipeline %s, please stop manually: %s", pipelineName, err.Error())))
+		common.Logger(p.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Couldn't stop pipeline %s, please stop manually: %s", pipelineName, err.Error())))
 	}
 	if approve == model.ApproveReject || manualApprove == model.ManualApproveReject {
 		return approvalStatusStop, fmt.Errorf("stopped because step approve type is 'reject'")
@@ -924,7 +964,7 @@ func getCodeBuildRunId(actions []types.ActionExecutionDetail) (string, error) {
 func (p *Pipeline) approveStage(pipelineName string) (approvalStatus, error) {
 	token := p.getApprovalToken(pipelineName)
 	if token == nil {
-		log.Printf("No approval token found yet for %s, please wait or approve manually\n", pipelineName)
+		common.Logger(p.ctx).Info(fmt.Sprintf("No approval token found yet for %s, please wait or approve manually", pipelineName))
 		return approvalStatusApprove, nil
 	}
 	_, err := p.codePipeline.PutApprovalResult(p.ctx, &codepipeline.PutApprovalResultInput{
@@ -938,11 +978,11 @@ func (p *Pipeline) approveStage(pipelineName string) (approvalStatus, error) {
 		},
 	})
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Couldn't approve pipeline %s, approve manually: %s",
+		common.Logger(p.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Couldn't approve pipeline %s, approve manually: %s",
 			pipelineName, err.Error())))
 		return approvalStatusApprove, nil
 	}
-	log.Printf("Approved stage %s for %s\n", approveStageName, pipelineName)
+	common.Logger(p.ctx).Info(fmt.Sprintf("Approved stage %s for %s", approveStageName, pipelineName))
 	return approvalStatusApproved, nil
 }
 
@@ -972,11 +1012,11 @@ func (p *Pipeline) waitAndStopAutoExecution(pipelineName string, timeout time.Du
 			if execution.Status != types.PipelineExecutionStatusInProgress {
 				continue
 			}
-			slog.Debug("intercepting auto-execution", "pipeline", pipelineName, "execution", *execution.PipelineExecutionId)
+			common.Logger(p.ctx).Debug("intercepting auto-execution", "pipeline", pipelineName, "execution", *execution.PipelineExecutionId)
 			return p.stopPipelineExecution(pipelineName, *execution.PipelineExecutionId, "Superseded by agent-orchestrated execution")
 		}
 		if time.Now().After(deadline) {
-			slog.Warn("no auto-execution appeared to intercept", "pipeline", pipelineName, "timeout", timeout)
+			common.Logger(p.ctx).Warn("no auto-execution appeared to intercept", "pipeline", pipelineName, "timeout", timeout)
 			return nil
 		}
 		select {
@@ -1064,11 +1104,20 @@ func (p *Pipeline) buildEnvVars(command model.ActionCommand, stepName string, st
 		{Name: "INFRALIB_STEP", Value: step.Name},
 		{Name: "CAMPAIGN_ID", Value: "#{variables.CampaignId}"},
 		{Name: "PIPELINE_INDEX", Value: "#{variables.PipelineIndex}"},
+		{Name: model.TraceParentEnv, Value: "#{variables.TraceParent}"},
 	}
 	if p.campaignId != "" {
 		vars = append(vars, envVar{Name: model.WrapperConfigEnv, Value: model.WrapperConfigSecretName(p.cloudPrefix),
 			Type: "SECRETS_MANAGER"})
 	}
+	if p.sopsAgeKey {
+		vars = append(vars, envVar{Name: model.SopsAgeKeyEnv, Value: model.SopsAgeKeySecretName(p.cloudPrefix),
+			Type: "SECRETS_MANAGER"})
+	}
+	if p.redactValues {
+		vars = append(vars, envVar{Name: model.RedactValuesEnv, Value: model.RedactValuesSecretName(p.cloudPrefix),
+			Type: "SECRETS_MANAGER"})
+	}
 	if step.Type == model.StepTypeArgoCD {
 		if step.KubernetesClusterName != "" {
 			vars = append(vars, envVar{Name: "KUBERNETES_CLUSTER_NAME", Value: step.KubernetesClusterName})
@@ -1130,11 +1179,11 @@ func (p *Pipeline) StartDestroyExecution(projectName string, step model.Step) er
 	if err != nil {
 		return err
 	}
-	executionId, err := p.StartPipelineExecution(pipelineName, "", step, "")
+	executionId, err := p.StartPipelineExecution(p.ctx, pipelineName, "", step, "")
 	if err != nil {
 		return err
 	}
-	return p.WaitPipelineExecution(pipelineName, pipelineName, executionId, true, step, "")
+	return p.WaitPipelineExecution(p.ctx, pipelineName, pipelineName, executionId, true, step, "")
 }
 
 func (p *Pipeline) enableAllStageTransitions(pipelineName string) error {
@@ -1153,7 +1202,7 @@ func (p *Pipeline) enableAllStageTransitions(pipelineName string) error {
 			return err
 		}
 	}
-	log.Printf("Enabled all stage transitions for pipeline %s", pipelineName)
+	common.Logger(p.ctx).Info(fmt.Sprintf("Enabled all stage transitions for pipeline %s", pipelineName))
 	return nil
 }
 
@@ -1168,3 +1217,7 @@ func (p *Pipeline) enableStageTransition(pipelineName string, stageName string)
 	}
 	return nil
 }
+
+func (p *Pipeline) stepLogger(step model.Step) *slog.Logger {
+	return common.Logger(p.ctx).With(common.StepAttr, step.Name)
+}
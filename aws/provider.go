@@ -2,10 +2,10 @@ package aws
 
 import (
 	"context"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
-	"log"
 )
 
 type awsProvider struct {
@@ -16,15 +16,27 @@ type awsProvider struct {
 }
 
 func NewAWSProvider(ctx context.Context, awsFlags common.AWS) (model.ResourceProvider, error) {
-	awsConfig, err := GetAWSConfig(ctx, awsFlags.RoleArn)
+	return newAWSProvider(ctx, awsFlags.RoleArn, "")
+}
+
+// NewAWSRemoteProvider creates a provider for resources of another prefix, optionally in another account or region.
+func NewAWSRemoteProvider(ctx context.Context, roleArn, region string) (model.ResourceProvider, error) {
+	return newAWSProvider(ctx, roleArn, region)
+}
+
+func newAWSProvider(ctx context.Context, roleArn, region string) (*awsProvider, error) {
+	awsConfig, err := GetAWSConfig(ctx, roleArn)
 	if err != nil {
 		return nil, err
 	}
+	if region != "" {
+		awsConfig.Region = region
+	}
 	accountId, err := getAccountId(ctx, awsConfig)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("AWS account id: %s\n", accountId)
+	common.Logger(ctx).Info(fmt.Sprintf("AWS account id: %s", accountId))
 	return &awsProvider{
 		ctx:          ctx,
 		awsConfig:    awsConfig,
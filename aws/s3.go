@@ -8,15 +8,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsS3 "github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/tracing"
 	"github.com/entigolabs/entigo-infralib-agent/util"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -57,11 +59,11 @@ func (s *S3) CreateBucket() (string, bool, error) {
 		var existsError *types.BucketAlreadyExists
 		var ownedError *types.BucketAlreadyOwnedByYou
 		if errors.As(err, &existsError) || errors.As(err, &ownedError) {
-			err = s.checkLifecycle()
+			err = s.checkLifecycle(s.ctx)
 			if err != nil {
 				return "", false, err
 			}
-			err = s.ensureAllowSSLRequestsOnlyPolicy()
+			err = s.ensureAllowSSLRequestsOnlyPolicy(s.ctx)
 			if err != nil {
 				return "", false, err
 			}
@@ -69,46 +71,50 @@ func (s *S3) CreateBucket() (string, bool, error) {
 		}
 		return "", false, err
 	}
-	log.Printf("Created S3 Bucket %s\n", s.bucket)
-	err = s.putBucketTags()
+	common.Logger(s.ctx).Info(fmt.Sprintf("Created S3 Bucket %s", s.bucket))
+	err = s.putBucketTags(s.ctx)
 	if err != nil {
 		return "", false, err
 	}
-	err = s.putBucketVersioning()
+	err = s.putBucketVersioning(s.ctx)
 	if err != nil {
 		return "", false, err
 	}
-	err = s.putBucketLifecycle()
+	err = s.putBucketLifecycle(s.ctx)
 	if err != nil {
 		return "", false, err
 	}
-	err = s.putAllowSSLRequestsOnlyPolicy()
+	err = s.putAllowSSLRequestsOnlyPolicy(s.ctx)
 	if err != nil {
 		return "", false, err
 	}
 	return fmt.Sprintf(bucketArnFormat, s.bucket), true, nil
 }
 
-func (s *S3) Delete() error {
-	err := s.truncateBucket()
+func (s *S3) Delete(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "S3.Delete")
+	defer tracing.End(span, &err)
+	err = s.truncateBucket(ctx)
 	if err != nil {
 		return checkNotFoundError(err)
 	}
-	_, err = s.awsS3.DeleteBucket(s.ctx, &awsS3.DeleteBucketInput{
+	_, err = s.awsS3.DeleteBucket(ctx, &awsS3.DeleteBucketInput{
 		Bucket: aws.String(s.bucket),
 	})
 	if err != nil {
 		return checkNotFoundError(err)
 	}
-	log.Printf("Deleted S3 Bucket %s\n", s.bucket)
+	common.Logger(ctx).Info(fmt.Sprintf("Deleted S3 Bucket %s", s.bucket))
 	return nil
 }
 
-func (s *S3) GetRepoMetadata() (*model.RepositoryMetadata, error) {
+func (s *S3) GetRepoMetadata(ctx context.Context) (_ *model.RepositoryMetadata, err error) {
+	ctx, span := tracing.Start(ctx, "S3.GetRepoMetadata")
+	defer tracing.End(span, &err)
 	if s.repoMetadata != nil {
 		return s.repoMetadata, nil
 	}
-	exists, err := s.BucketExists()
+	exists, err := s.BucketExists(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -122,8 +128,8 @@ func (s *S3) GetRepoMetadata() (*model.RepositoryMetadata, error) {
 	return s.repoMetadata, nil
 }
 
-func (s *S3) addEncryption(kmsArn string) error {
-	encryption, err := s.awsS3.GetBucketEncryption(s.ctx, &awsS3.GetBucketEncryptionInput{
+func (s *S3) addEncryption(ctx context.Context, kmsArn string) error {
+	encryption, err := s.awsS3.GetBucketEncryption(ctx, &awsS3.GetBucketEncryptionInput{
 		Bucket: aws.String(s.bucket),
 	})
 	if err != nil {
@@ -132,7 +138,7 @@ func (s *S3) addEncryption(kmsArn string) error {
 	if hasKMSEncryption(encryption.ServerSideEncryptionConfiguration) {
 		return nil
 	}
-	_, err = s.awsS3.PutBucketEncryption(s.ctx, &awsS3.PutBucketEncryptionInput{
+	_, err = s.awsS3.PutBucketEncryption(ctx, &awsS3.PutBucketEncryptionInput{
 		Bucket: aws.String(s.bucket),
 		ServerSideEncryptionConfiguration: &types.ServerSideEncryptionConfiguration{
 			Rules: []types.ServerSideEncryptionRule{
@@ -163,8 +169,10 @@ func hasKMSEncryption(config *types.ServerSideEncryptionConfiguration) bool {
 	return false
 }
 
-func (s *S3) PutFile(file string, content []byte) error {
-	_, err := s.awsS3.PutObject(s.ctx, &awsS3.PutObjectInput{
+func (s *S3) PutFile(ctx context.Context, file string, content []byte) (err error) {
+	ctx, span := tracing.Start(ctx, "S3.PutFile", attribute.String("file", file))
+	defer tracing.End(span, &err)
+	_, err = s.awsS3.PutObject(ctx, &awsS3.PutObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(file),
 		Body:   bytes.NewReader(content),
@@ -172,8 +180,10 @@ func (s *S3) PutFile(file string, content []byte) error {
 	return err
 }
 
-func (s *S3) GetFile(file string) ([]byte, error) {
-	output, err := s.awsS3.GetObject(s.ctx, &awsS3.GetObjectInput{
+func (s *S3) GetFile(ctx context.Context, file string) (_ []byte, err error) {
+	ctx, span := tracing.Start(ctx, "S3.GetFile", attribute.String("file", file))
+	defer tracing.End(span, &err)
+	output, err := s.awsS3.GetObject(ctx, &awsS3.GetObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(file),
 	})
@@ -191,7 +201,9 @@ func (s *S3) GetFile(file string) ([]byte, error) {
 	return io.ReadAll(output.Body)
 }
 
-func (s *S3) DeleteFiles(files []string) error {
+func (s *S3) DeleteFiles(ctx context.Context, files []string) (err error) {
+	ctx, span := tracing.Start(ctx, "S3.DeleteFiles")
+	defer tracing.End(span, &err)
 	if len(files) == 0 {
 		return nil
 	}
@@ -203,7 +215,7 @@ func (s *S3) DeleteFiles(files []string) error {
 	}
 	for len(objects) > 0 {
 		limit := util.MinInt(len(objects), 1000) // Max 1000 objects per request
-		_, err := s.awsS3.DeleteObjects(s.ctx, &awsS3.DeleteObjectsInput{
+		_, err := s.awsS3.DeleteObjects(ctx, &awsS3.DeleteObjectsInput{
 			Bucket: aws.String(s.bucket),
 			Delete: &types.Delete{Objects: objects[:limit]},
 		})
@@ -216,19 +228,23 @@ func (s *S3) DeleteFiles(files []string) error {
 	return nil
 }
 
-func (s *S3) DeleteFile(file string) error {
-	_, err := s.awsS3.DeleteObject(s.ctx, &awsS3.DeleteObjectInput{
+func (s *S3) DeleteFile(ctx context.Context, file string) (err error) {
+	ctx, span := tracing.Start(ctx, "S3.DeleteFile", attribute.String("file", file))
+	defer tracing.End(span, &err)
+	_, err = s.awsS3.DeleteObject(ctx, &awsS3.DeleteObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(file),
 	})
 	return checkNotFoundError(err)
 }
 
-func (s *S3) CheckFolderExists(folder string) (bool, error) {
+func (s *S3) CheckFolderExists(ctx context.Context, folder string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "S3.CheckFolderExists", attribute.String("folder", folder))
+	defer tracing.End(span, &err)
 	if !strings.HasSuffix(folder, "/") {
 		folder += "/"
 	}
-	output, err := s.awsS3.ListObjectsV2(s.ctx, &awsS3.ListObjectsV2Input{
+	output, err := s.awsS3.ListObjectsV2(ctx, &awsS3.ListObjectsV2Input{
 		Bucket:  aws.String(s.bucket),
 		Prefix:  aws.String(folder),
 		MaxKeys: aws.Int32(1),
@@ -240,7 +256,9 @@ func (s *S3) CheckFolderExists(folder string) (bool, error) {
 	return output.Contents != nil, nil
 }
 
-func (s *S3) ListFolderFiles(folder string) ([]string, error) {
+func (s *S3) ListFolderFiles(ctx context.Context, folder string) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "S3.ListFolderFiles", attribute.String("folder", folder))
+	defer tracing.End(span, &err)
 	if !strings.HasSuffix(folder, "/") {
 		folder += "/"
 	}
@@ -250,7 +268,7 @@ func (s *S3) ListFolderFiles(folder string) ([]string, error) {
 		Prefix: aws.String(folder),
 	})
 	for paginator.HasMorePages() {
-		output, err := paginator.NextPage(s.ctx)
+		output, err := paginator.NextPage(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -261,11 +279,13 @@ func (s *S3) ListFolderFiles(folder string) ([]string, error) {
 	return files, nil
 }
 
-func (s *S3) ListFolderFilesWithExclude(folder string, excludeFolders model.Set[string]) ([]string, error) {
+func (s *S3) ListFolderFilesWithExclude(ctx context.Context, folder string, excludeFolders model.Set[string]) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "S3.ListFolderFilesWithExclude", attribute.String("folder", folder))
+	defer tracing.End(span, &err)
 	if !strings.HasSuffix(folder, "/") {
 		folder += "/"
 	}
-	output, err := s.awsS3.ListObjectsV2(s.ctx, &awsS3.ListObjectsV2Input{
+	output, err := s.awsS3.ListObjectsV2(ctx, &awsS3.ListObjectsV2Input{
 		Bucket:    aws.String(s.bucket),
 		Prefix:    aws.String(folder),
 		Delimiter: aws.String("/"),
@@ -284,7 +304,7 @@ func (s *S3) ListFolderFilesWithExclude(folder string, excludeFolders model.Set[
 		if excludeFolders.Contains(strings.TrimSuffix(strings.TrimPrefix(*prefix.Prefix, folder), "/")) {
 			continue
 		}
-		subFiles, err := s.ListFolderFiles(*prefix.Prefix)
+		subFiles, err := s.ListFolderFiles(ctx, *prefix.Prefix)
 		if err != nil {
 			return nil, err
 		}
@@ -293,16 +313,18 @@ func (s *S3) ListFolderFilesWithExclude(folder string, excludeFolders model.Set[
 	return files, nil
 }
 
-func (s *S3) BucketExists() (bool, error) {
-	_, err := s.awsS3.HeadBucket(s.ctx, &awsS3.HeadBucketInput{
+func (s *S3) BucketExists(ctx context.Context) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "S3.BucketExists")
+	defer tracing.End(span, &err)
+	_, err = s.awsS3.HeadBucket(ctx, &awsS3.HeadBucketInput{
 		Bucket: aws.String(s.bucket),
 	})
 	if err == nil {
-		err = s.checkLifecycle()
+		err = s.checkLifecycle(ctx)
 		if err != nil {
 			return false, err
 		}
-		return true, s.ensureAllowSSLRequestsOnlyPolicy()
+		return true, s.ensureAllowSSLRequestsOnlyPolicy(ctx)
 	}
 	return false, checkNotFoundError(err)
 }
@@ -317,21 +339,21 @@ func checkNotFoundError(err error) error {
 	return err
 }
 
-func (s *S3) truncateBucket() error {
-	err := s.truncateObjectVersions()
+func (s *S3) truncateBucket(ctx context.Context) error {
+	err := s.truncateObjectVersions(ctx)
 	if err != nil {
 		return err
 	}
-	return s.truncateDeleteMarkers()
+	return s.truncateDeleteMarkers(ctx)
 }
 
-func (s *S3) truncateObjectVersions() error {
+func (s *S3) truncateObjectVersions(ctx context.Context) error {
 	paginator := awsS3.NewListObjectVersionsPaginator(s.awsS3, &awsS3.ListObjectVersionsInput{
 		Bucket: aws.String(s.bucket),
 	})
 	first := true
 	for paginator.HasMorePages() {
-		output, err := paginator.NextPage(s.ctx)
+		output, err := paginator.NextPage(ctx)
 		if err != nil {
 			return err
 		}
@@ -339,7 +361,7 @@ func (s *S3) truncateObjectVersions() error {
 			return nil
 		}
 		if first {
-			log.Printf("Emptying bucket %s...\n", s.bucket)
+			common.Logger(ctx).Info(fmt.Sprintf("Emptying bucket %s...", s.bucket))
 			first = false
 		}
 		var objects []types.ObjectIdentifier
@@ -349,7 +371,7 @@ func (s *S3) truncateObjectVersions() error {
 				VersionId: version.VersionId,
 			})
 		}
-		_, err = s.awsS3.DeleteObjects(s.ctx, &awsS3.DeleteObjectsInput{
+		_, err = s.awsS3.DeleteObjects(ctx, &awsS3.DeleteObjectsInput{
 			Bucket: aws.String(s.bucket),
 			Delete: &types.Delete{Objects: objects},
 		})
@@ -360,12 +382,12 @@ func (s *S3) truncateObjectVersions() error {
 	return nil
 }
 
-func (s *S3) truncateDeleteMarkers() error {
+func (s *S3) truncateDeleteMarkers(ctx context.Context) error {
 	paginator := awsS3.NewListObjectVersionsPaginator(s.awsS3, &awsS3.ListObjectVersionsInput{
 		Bucket: aws.String(s.bucket),
 	})
 	for paginator.HasMorePages() {
-		output, err := paginator.NextPage(s.ctx)
+		output, err := paginator.NextPage(ctx)
 		if err != nil {
 			return err
 		}
@@ -379,7 +401,7 @@ func (s *S3) truncateDeleteMarkers() error {
 				VersionId: marker.VersionId,
 			})
 		}
-		_, err = s.awsS3.DeleteObjects(s.ctx, &awsS3.DeleteObjectsInput{
+		_, err = s.awsS3.DeleteObjects(ctx, &awsS3.DeleteObjectsInput{
 			Bucket: aws.String(s.bucket),
 			Delete: &types.Delete{Objects: objects},
 		})
@@ -390,8 +412,8 @@ func (s *S3) truncateDeleteMarkers() error {
 	return nil
 }
 
-func (s *S3) putBucketTags() error {
-	_, err := s.awsS3.PutBucketTagging(s.ctx, &awsS3.PutBucketTaggingInput{
+func (s *S3) putBucketTags(ctx context.Context) error {
+	_, err := s.awsS3.PutBucketTagging(ctx, &awsS3.PutBucketTaggingInput{
 		Bucket: aws.String(s.bucket),
 		Tagging: &types.Tagging{
 			TagSet: []types.Tag{{
@@ -403,8 +425,8 @@ func (s *S3) putBucketTags() error {
 	return err
 }
 
-func (s *S3) putBucketVersioning() error {
-	_, err := s.awsS3.PutBucketVersioning(s.ctx, &awsS3.PutBucketVersioningInput{
+func (s *S3) putBucketVersioning(ctx context.Context) error {
+	_, err := s.awsS3.PutBucketVersioning(ctx, &awsS3.PutBucketVersioningInput{
 		Bucket: aws.String(s.bucket),
 		VersioningConfiguration: &types.VersioningConfiguration{
 			Status: types.BucketVersioningStatusEnabled,
@@ -413,15 +435,15 @@ func (s *S3) putBucketVersioning() error {
 	return err
 }
 
-func (s *S3) checkLifecycle() error {
-	resp, err := s.awsS3.GetBucketLifecycleConfiguration(s.ctx, &awsS3.GetBucketLifecycleConfigurationInput{
+func (s *S3) checkLifecycle(ctx context.Context) error {
+	resp, err := s.awsS3.GetBucketLifecycleConfiguration(ctx, &awsS3.GetBucketLifecycleConfigurationInput{
 		Bucket: aws.String(s.bucket),
 	})
 	if err != nil {
 		var notFound *types.NotFound
 		var apiErr smithy.APIError
 		if errors.As(err, &notFound) || (errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchLifecycleConfiguration") {
-			return s.putBucketLifecycle()
+			return s.putBucketLifecycle(ctx)
 		}
 		return err
 	}
@@ -430,11 +452,11 @@ func (s *S3) checkLifecycle() error {
 			return nil
 		}
 	}
-	return s.putBucketLifecycle()
+	return s.putBucketLifecycle(ctx)
 }
 
-func (s *S3) putBucketLifecycle() error {
-	_, err := s.awsS3.PutBucketLifecycleConfiguration(s.ctx, &awsS3.PutBucketLifecycleConfigurationInput{
+func (s *S3) putBucketLifecycle(ctx context.Context) error {
+	_, err := s.awsS3.PutBucketLifecycleConfiguration(ctx, &awsS3.PutBucketLifecycleConfigurationInput{
 		Bucket: aws.String(s.bucket),
 		LifecycleConfiguration: &types.BucketLifecycleConfiguration{
 			Rules: []types.LifecycleRule{
@@ -451,7 +473,7 @@ func (s *S3) putBucketLifecycle() error {
 		},
 	})
 	if err == nil {
-		log.Printf("Enabled lifecycle rule DeleteOlderVersions for bucket %s\n", s.bucket)
+		common.Logger(ctx).Info(fmt.Sprintf("Enabled lifecycle rule DeleteOlderVersions for bucket %s", s.bucket))
 	}
 	return err
 }
@@ -475,7 +497,7 @@ func (s *S3) newAllowSSLStatement() PolicyStatement {
 	}
 }
 
-func (s *S3) putAllowSSLRequestsOnlyPolicy() error {
+func (s *S3) putAllowSSLRequestsOnlyPolicy(ctx context.Context) error {
 	policy := PolicyDocument{
 		Version: "2012-10-17",
 		Id:      allowSSLPolicyId,
@@ -483,17 +505,17 @@ func (s *S3) putAllowSSLRequestsOnlyPolicy() error {
 			s.newAllowSSLStatement(),
 		},
 	}
-	return s.putBucketPolicy(policy)
+	return s.putBucketPolicy(ctx, policy)
 }
 
-func (s *S3) ensureAllowSSLRequestsOnlyPolicy() error {
-	output, err := s.awsS3.GetBucketPolicy(s.ctx, &awsS3.GetBucketPolicyInput{
+func (s *S3) ensureAllowSSLRequestsOnlyPolicy(ctx context.Context) error {
+	output, err := s.awsS3.GetBucketPolicy(ctx, &awsS3.GetBucketPolicyInput{
 		Bucket: aws.String(s.bucket),
 	})
 	if err != nil {
 		var apiErr smithy.APIError
 		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchBucketPolicy" {
-			return s.putAllowSSLRequestsOnlyPolicy()
+			return s.putAllowSSLRequestsOnlyPolicy(ctx)
 		}
 		return err
 	}
@@ -510,10 +532,10 @@ func (s *S3) ensureAllowSSLRequestsOnlyPolicy() error {
 		}
 	}
 	currentPolicy.Statement = append(currentPolicy.Statement, s.newAllowSSLStatement())
-	return s.putBucketPolicy(currentPolicy)
+	return s.putBucketPolicy(ctx, currentPolicy)
 }
 
-func (s *S3) putBucketPolicy(policy PolicyDocument) error {
+func (s *S3) putBucketPolicy(ctx context.Context, policy PolicyDocument) error {
 	policyBytes, err := json.MarshalIndent(policy, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal bucket policy: %w", err)
@@ -521,7 +543,7 @@ func (s *S3) putBucketPolicy(policy PolicyDocument) error {
 
 	policyStr := string(policyBytes)
 
-	_, err = s.awsS3.PutBucketPolicy(s.ctx, &awsS3.PutBucketPolicyInput{
+	_, err = s.awsS3.PutBucketPolicy(ctx, &awsS3.PutBucketPolicyInput{
 		Bucket: aws.String(s.bucket),
 		Policy: aws.String(policyStr),
 	})
@@ -529,7 +551,7 @@ func (s *S3) putBucketPolicy(policy PolicyDocument) error {
 		return fmt.Errorf("failed to put bucket policy: %w", err)
 	}
 
-	log.Printf("Successfully updated S3 Bucket Policy for %s with AllowSSLRequestsOnly rule.\n", s.bucket)
+	common.Logger(ctx).Info(fmt.Sprintf("Successfully updated S3 Bucket Policy for %s with AllowSSLRequestsOnly rule.", s.bucket))
 	return nil
 }
 
@@ -540,5 +562,5 @@ func (s *S3) addDummyZip() error {
 	if err != nil {
 		return err
 	}
-	return s.PutFile(model.AgentSource, buffer.Bytes())
+	return s.PutFile(s.ctx, model.AgentSource, buffer.Bytes())
 }
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/scheduler"
@@ -62,7 +61,7 @@ func (s Scheduler) createUpdateSchedule(cron, pipelineArn, roleArn string) error
 		},
 	})
 	if err == nil {
-		log.Printf("Created EventBridge schedule: %s\n", s.updateSchedule)
+		common.Logger(s.ctx).Info(fmt.Sprintf("Created EventBridge schedule: %s", s.updateSchedule))
 	}
 	return err
 }
@@ -76,7 +75,7 @@ func (s Scheduler) deleteUpdateSchedule() error {
 		}
 	}
 	if err == nil {
-		log.Printf("Deleted EventBridge schedule: %s\n", s.updateSchedule)
+		common.Logger(s.ctx).Info(fmt.Sprintf("Deleted EventBridge schedule: %s", s.updateSchedule))
 	}
 	return err
 }
@@ -96,7 +95,7 @@ func (s Scheduler) updateUpdateSchedule(cron, pipelineArn, roleArn string) error
 		},
 	})
 	if err == nil {
-		log.Printf("Updated EventBridge schedule: %s\n", s.updateSchedule)
+		common.Logger(s.ctx).Info(fmt.Sprintf("Updated EventBridge schedule: %s", s.updateSchedule))
 	}
 	return err
 }
@@ -11,9 +11,11 @@ import (
 	smTypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
 	awsSSM "github.com/aws/aws-sdk-go-v2/service/ssm"
 	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/tracing"
 	"github.com/entigolabs/entigo-infralib-agent/util"
-	"log"
+	"go.opentelemetry.io/otel/attribute"
 	"strings"
 	"time"
 )
@@ -35,8 +37,10 @@ func NewSSM(ctx context.Context, awsConfig aws.Config) model.SSM {
 	}
 }
 
-func (s *ssm) GetParameter(name string) (*model.Parameter, error) {
-	result, err := s.ssmClient.GetParameter(s.ctx, &awsSSM.GetParameterInput{
+func (s *ssm) GetParameter(ctx context.Context, name string) (_ *model.Parameter, err error) {
+	ctx, span := tracing.Start(ctx, "SSM.GetParameter", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	result, err := s.ssmClient.GetParameter(ctx, &awsSSM.GetParameterInput{
 		Name:           aws.String(name),
 		WithDecryption: aws.Bool(true),
 	})
@@ -53,8 +57,10 @@ func (s *ssm) GetParameter(name string) (*model.Parameter, error) {
 	}, nil
 }
 
-func (s *ssm) ParameterExists(name string) (bool, error) {
-	_, err := s.GetParameter(name)
+func (s *ssm) ParameterExists(ctx context.Context, name string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "SSM.ParameterExists", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	_, err = s.GetParameter(ctx, name)
 	if err != nil {
 		var notFoundErr *model.ParameterNotFoundError
 		if errors.As(err, &notFoundErr) {
@@ -65,8 +71,10 @@ func (s *ssm) ParameterExists(name string) (bool, error) {
 	return true, nil
 }
 
-func (s *ssm) PutParameter(name string, value string) error {
-	output, err := s.ssmClient.GetParameter(s.ctx, &awsSSM.GetParameterInput{
+func (s *ssm) PutParameter(ctx context.Context, name string, value string) (err error) {
+	ctx, span := tracing.Start(ctx, "SSM.PutParameter", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	output, err := s.ssmClient.GetParameter(ctx, &awsSSM.GetParameterInput{
 		Name:           aws.String(name),
 		WithDecryption: aws.Bool(true),
 	})
@@ -96,12 +104,14 @@ func (s *ssm) PutParameter(name string, value string) error {
 			Value: aws.String(model.ResourceTagValue),
 		}}
 	}
-	_, err = s.ssmClient.PutParameter(s.ctx, input)
+	_, err = s.ssmClient.PutParameter(ctx, input)
 	return err
 }
 
-func (s *ssm) DeleteParameter(name string) error {
-	_, err := s.ssmClient.DeleteParameter(s.ctx, &awsSSM.DeleteParameterInput{
+func (s *ssm) DeleteParameter(ctx context.Context, name string) (err error) {
+	ctx, span := tracing.Start(ctx, "SSM.DeleteParameter", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	_, err = s.ssmClient.DeleteParameter(ctx, &awsSSM.DeleteParameterInput{
 		Name: aws.String(name),
 	})
 	if err != nil {
@@ -114,7 +124,9 @@ func (s *ssm) DeleteParameter(name string) error {
 	return nil
 }
 
-func (s *ssm) ListParameters() ([]string, error) {
+func (s *ssm) ListParameters(ctx context.Context) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "SSM.ListParameters")
+	defer tracing.End(span, &err)
 	var keys []string
 	input := &resourcegroupstaggingapi.GetResourcesInput{
 		ResourceTypeFilters: []string{"ssm:parameter"},
@@ -127,7 +139,7 @@ func (s *ssm) ListParameters() ([]string, error) {
 	}
 	paginator := resourcegroupstaggingapi.NewGetResourcesPaginator(s.tagClient, input)
 	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(s.ctx)
+		page, err := paginator.NextPage(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -148,25 +160,27 @@ func (s *ssm) AddEncryptionKeyId(keyId string) {
 	s.kmsKeyId = keyId
 }
 
-func (s *ssm) PutSecret(name string, value string) error {
-	secret, kmsKeyId, err := s.getSecret(name)
+func (s *ssm) PutSecret(ctx context.Context, name string, value string) (err error) {
+	ctx, span := tracing.Start(ctx, "SSM.PutSecret", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	secret, kmsKeyId, err := s.getSecret(ctx, name)
 	if err != nil {
 		return err
 	}
 	if secret == nil {
-		return s.createSecret(name, value)
+		return s.createSecret(ctx, name, value)
 	}
 	if (kmsKeyId == nil && s.kmsKeyId != "") || (kmsKeyId != nil && s.kmsKeyId != "" && *kmsKeyId != s.kmsKeyId) {
-		return s.updateKmsKey(name, value)
+		return s.updateKmsKey(ctx, name, value)
 	}
 	if *secret == value {
 		return nil
 	}
-	return s.updateSecret(name, value)
+	return s.updateSecret(ctx, name, value)
 }
 
-func (s *ssm) getSecret(name string) (*string, *string, error) {
-	described, err := s.smClient.DescribeSecret(s.ctx, &secretsmanager.DescribeSecretInput{
+func (s *ssm) getSecret(ctx context.Context, name string) (*string, *string, error) {
+	described, err := s.smClient.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
 		SecretId: aws.String(name),
 	})
 	if err != nil {
@@ -177,14 +191,14 @@ func (s *ssm) getSecret(name string) (*string, *string, error) {
 		return nil, nil, err
 	}
 	if described.DeletedDate != nil {
-		_, err = s.smClient.RestoreSecret(s.ctx, &secretsmanager.RestoreSecretInput{
+		_, err = s.smClient.RestoreSecret(ctx, &secretsmanager.RestoreSecretInput{
 			SecretId: aws.String(name),
 		})
 		if err != nil {
 			return nil, nil, err
 		}
 	}
-	secret, err := s.smClient.GetSecretValue(s.ctx, &secretsmanager.GetSecretValueInput{
+	secret, err := s.smClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
 		SecretId: aws.String(name),
 	})
 	if err == nil {
@@ -197,7 +211,7 @@ func (s *ssm) getSecret(name string) (*string, *string, error) {
 	return nil, nil, err
 }
 
-func (s *ssm) createSecret(name, value string) error {
+func (s *ssm) createSecret(ctx context.Context, name, value string) error {
 	input := secretsmanager.CreateSecretInput{
 		Name:         aws.String(name),
 		SecretString: aws.String(value),
@@ -211,27 +225,27 @@ func (s *ssm) createSecret(name, value string) error {
 	if s.kmsKeyId != "" {
 		input.KmsKeyId = aws.String(s.kmsKeyId)
 	}
-	_, err := s.smClient.CreateSecret(s.ctx, &input)
+	_, err := s.smClient.CreateSecret(ctx, &input)
 	return err
 }
 
-func (s *ssm) updateSecret(name, value string) error {
-	_, err := s.smClient.PutSecretValue(s.ctx, &secretsmanager.PutSecretValueInput{
+func (s *ssm) updateSecret(ctx context.Context, name, value string) error {
+	_, err := s.smClient.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
 		SecretId:     aws.String(name),
 		SecretString: aws.String(value),
 	})
 	return err
 }
 
-func (s *ssm) updateKmsKey(name string, value string) error {
-	err := s.deleteSecret(name, true)
+func (s *ssm) updateKmsKey(ctx context.Context, name string, value string) error {
+	err := s.deleteSecret(ctx, name, true)
 	if err != nil {
 		return err
 	}
-	ctx, cancel := context.WithTimeout(s.ctx, 2*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 	delay := 1
-	log.Printf("updating kms key for secret %s, timeout %s\n", name, 2*time.Minute)
+	common.Logger(ctx).Info(fmt.Sprintf("updating kms key for secret %s, timeout %s", name, 2*time.Minute))
 	for {
 		select {
 		case <-ctx.Done():
@@ -240,7 +254,7 @@ func (s *ssm) updateKmsKey(name string, value string) error {
 			}
 			return ctx.Err()
 		default:
-			err = s.createSecret(name, value)
+			err = s.createSecret(ctx, name, value)
 			if err == nil {
 				return nil
 			}
@@ -254,18 +268,20 @@ func (s *ssm) updateKmsKey(name string, value string) error {
 	}
 }
 
-func (s *ssm) DeleteSecret(name string) error {
-	return s.deleteSecret(name, false)
+func (s *ssm) DeleteSecret(ctx context.Context, name string) (err error) {
+	ctx, span := tracing.Start(ctx, "SSM.DeleteSecret", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	return s.deleteSecret(ctx, name, false)
 }
 
-func (s *ssm) deleteSecret(name string, force bool) error {
+func (s *ssm) deleteSecret(ctx context.Context, name string, force bool) error {
 	input := secretsmanager.DeleteSecretInput{
 		SecretId:                   aws.String(name),
 		ForceDeleteWithoutRecovery: &force,
 	}
-	_, err := s.smClient.DeleteSecret(s.ctx, &input)
+	_, err := s.smClient.DeleteSecret(ctx, &input)
 	if err == nil {
-		log.Printf("Deleted secret: %s\n", name)
+		common.Logger(ctx).Info(fmt.Sprintf("Deleted secret: %s", name))
 		return nil
 	}
 	var notFoundError *smTypes.ResourceNotFoundException
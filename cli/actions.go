@@ -3,30 +3,69 @@ package cli
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/entigolabs/entigo-infralib-agent/commands/bootstrap"
+	"github.com/entigolabs/entigo-infralib-agent/commands/cache"
 	"github.com/entigolabs/entigo-infralib-agent/commands/delete"
 	"github.com/entigolabs/entigo-infralib-agent/commands/destroy"
+	"github.com/entigolabs/entigo-infralib-agent/commands/diff"
 	"github.com/entigolabs/entigo-infralib-agent/commands/migrate"
+	"github.com/entigolabs/entigo-infralib-agent/commands/notifications"
+	"github.com/entigolabs/entigo-infralib-agent/commands/outputs"
 	"github.com/entigolabs/entigo-infralib-agent/commands/params"
 	"github.com/entigolabs/entigo-infralib-agent/commands/provision"
 	"github.com/entigolabs/entigo-infralib-agent/commands/pull"
+	"github.com/entigolabs/entigo-infralib-agent/commands/push"
 	agentRun "github.com/entigolabs/entigo-infralib-agent/commands/run"
 	"github.com/entigolabs/entigo-infralib-agent/commands/sa"
+	"github.com/entigolabs/entigo-infralib-agent/commands/serve"
 	"github.com/entigolabs/entigo-infralib-agent/commands/update"
 	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/metrics"
+	"github.com/entigolabs/entigo-infralib-agent/tracing"
 	"github.com/urfave/cli/v3"
 )
 
+const (
+	traceFlushTimeout    = 5 * time.Second
+	metricsExportTimeout = 10 * time.Second
+)
+
 func action(cmd common.Command) cli.ActionFunc {
-	return func(ctx context.Context, _ *cli.Command) error {
+	return func(ctx context.Context, _ *cli.Command) (err error) {
 		if err := flags.Setup(cmd); err != nil {
 			return err
 		}
 		if err := common.ChooseLogger(flags.LogLevel); err != nil {
 			return err
 		}
-		return run(ctx, cmd)
+		if err := common.ChooseLogFormat(flags.LogFormat); err != nil {
+			return err
+		}
+		shutdown, err := tracing.Init(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			// Spans are flushed also when the agent was terminated.
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), traceFlushTimeout)
+			defer cancel()
+			shutdown(flushCtx)
+		}()
+		stopMetrics, err := metrics.Start(flags.Metrics, flags.Prefix)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), metricsExportTimeout)
+			defer cancel()
+			stopMetrics(stopCtx)
+		}()
+		ctx, span := tracing.Start(tracing.FromEnv(ctx), string(cmd))
+		defer tracing.End(span, &err)
+		err = run(ctx, cmd)
+		return err
 	}
 }
 
@@ -47,6 +86,10 @@ func run(ctx context.Context, cmd common.Command) error {
 		return sa.Run(ctx, flags)
 	case common.PullCommand:
 		return pull.Run(ctx, flags)
+	case common.DiffCommand:
+		return diff.Run(ctx, flags)
+	case common.PushCommand:
+		return push.Run(ctx, flags)
 	case common.AddCustomCommand, common.DeleteCustomCommand, common.GetCustomCommand, common.ListCustomCommand:
 		return params.Custom(ctx, flags, cmd)
 	case common.MigrateConfigCommand:
@@ -57,6 +100,14 @@ func run(ctx context.Context, cmd common.Command) error {
 		return migrate.Validate(ctx, flags)
 	case common.ProvisionCommand:
 		return provision.Run(ctx, flags)
+	case common.CachePruneCommand:
+		return cache.Prune(flags)
+	case common.OutputsCommand:
+		return outputs.Run(ctx, flags)
+	case common.NotificationsFlushCommand:
+		return notifications.Flush(ctx, flags)
+	case common.ServeWrapperCommand:
+		return serve.Wrapper(ctx, flags)
 	default:
 		return errors.New("unsupported command")
 	}
@@ -5,7 +5,7 @@ import (
 	"errors"
 	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/urfave/cli/v3"
-	"log"
+	"log/slog"
 	"os"
 )
 
@@ -19,7 +19,8 @@ func Run(ctx context.Context) {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
 		}
-		log.Fatal(&common.PrefixedError{Reason: err})
+		slog.Error(common.PrefixError(err))
+		os.Exit(1)
 	}
 }
 
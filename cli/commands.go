@@ -14,6 +14,8 @@ func cliCommands() []*cli.Command {
 		&deleteCommand,
 		&SACommand,
 		&pullCommand,
+		&diffCommand,
+		&pushCommand,
 		&addCustomCommand,
 		&deleteCustomCommand,
 		&getCustomCommand,
@@ -22,6 +24,10 @@ func cliCommands() []*cli.Command {
 		&migratePlanCommand,
 		&migrateValidateCommand,
 		&provisionCommand,
+		&cacheCommand,
+		&notificationsCommand,
+		&outputsCommand,
+		&serveWrapperCommand,
 	}
 }
 
@@ -81,6 +87,22 @@ var pullCommand = cli.Command{
 	Flags:   cliFlags(common.PullCommand),
 }
 
+var diffCommand = cli.Command{
+	Name:    string(common.DiffCommand),
+	Aliases: []string{"df"},
+	Usage:   "show differences between local config files and the bucket",
+	Action:  action(common.DiffCommand),
+	Flags:   cliFlags(common.DiffCommand),
+}
+
+var pushCommand = cli.Command{
+	Name:    string(common.PushCommand),
+	Aliases: []string{"ps"},
+	Usage:   "push agent config yaml and included files to the bucket",
+	Action:  action(common.PushCommand),
+	Flags:   cliFlags(common.PushCommand),
+}
+
 var addCustomCommand = cli.Command{
 	Name:    string(common.AddCustomCommand),
 	Aliases: []string{"ac"},
@@ -144,3 +166,48 @@ var provisionCommand = cli.Command{
 	Action:  action(common.ProvisionCommand),
 	Flags:   cliFlags(common.ProvisionCommand),
 }
+
+var outputsCommand = cli.Command{
+	Name:    string(common.OutputsCommand),
+	Aliases: []string{"out"},
+	Usage:   "list or get terraform outputs of steps",
+	Action:  action(common.OutputsCommand),
+	Flags:   cliFlags(common.OutputsCommand),
+}
+
+var serveWrapperCommand = cli.Command{
+	Name:   string(common.ServeWrapperCommand),
+	Usage:  "run the wrapper backend server for receiving provision logs and plan summaries",
+	Action: action(common.ServeWrapperCommand),
+	Flags:  cliFlags(common.ServeWrapperCommand),
+}
+
+var cacheCommand = cli.Command{
+	Name:  "cache",
+	Usage: "manage the source cache",
+	Commands: []*cli.Command{
+		&cachePruneCommand,
+	},
+}
+
+var notificationsCommand = cli.Command{
+	Name:  "notifications",
+	Usage: "manage notifications",
+	Commands: []*cli.Command{
+		&notificationsFlushCommand,
+	},
+}
+
+var notificationsFlushCommand = cli.Command{
+	Name:   "flush",
+	Usage:  "re-send undelivered notifications from the outbox",
+	Action: action(common.NotificationsFlushCommand),
+	Flags:  cliFlags(common.NotificationsFlushCommand),
+}
+
+var cachePruneCommand = cli.Command{
+	Name:   "prune",
+	Usage:  "remove unused entries from the source cache",
+	Action: action(common.CachePruneCommand),
+	Flags:  cliFlags(common.CachePruneCommand),
+}
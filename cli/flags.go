@@ -14,7 +14,7 @@ func cliFlags(cmd common.Command) []cli.Flag {
 }
 
 func appendBaseFlags(flags []cli.Flag) []cli.Flag {
-	return append(flags, &loggingFlag)
+	return append(flags, &loggingFlag, &logFormatFlag)
 }
 
 func appendCmdSpecificFlags(baseFlags []cli.Flag, cmd common.Command) []cli.Flag {
@@ -23,12 +23,20 @@ func appendCmdSpecificFlags(baseFlags []cli.Flag, cmd common.Command) []cli.Flag
 		return append(append(baseFlags, getProviderFlags()...), &yesFlag, &deleteBucketFlag, &deleteSAFlag)
 	case common.UpdateCommand:
 		return append(append(baseFlags, getProviderFlags()...), &stepsFlag, &pipelineTypeFlag,
-			&logsPathFlag, &printLogsFlag, &terraformCacheFlag, &skipBucketDelayFlag)
+			&logsPathFlag, &printLogsFlag, &terraformCacheFlag, &skipBucketDelayFlag, &cacheDirFlag, &metricsAddressFlag,
+			&pushgatewayFlag, &metricsTextfileFlag, &junitReportFlag, &markdownReportFlag)
 	case common.RunCommand:
 		return append(append(baseFlags, getProviderFlags()...), &allowParallelFlag, &stepsFlag,
-			&pipelineTypeFlag, &logsPathFlag, &printLogsFlag, &terraformCacheFlag, &skipBucketDelayFlag)
+			&pipelineTypeFlag, &logsPathFlag, &printLogsFlag, &terraformCacheFlag, &skipBucketDelayFlag, &cacheDirFlag,
+			&metricsAddressFlag, &pushgatewayFlag, &metricsTextfileFlag, &junitReportFlag, &markdownReportFlag)
 	case common.PullCommand:
 		return append(append(baseFlags, getProviderFlags()...), &forceFlag)
+	case common.DiffCommand:
+		return append(baseFlags, getProviderFlags()...)
+	case common.PushCommand:
+		return append(append(baseFlags, getProviderFlags()...), &dryRunFlag, &pushYesFlag)
+	case common.NotificationsFlushCommand:
+		return append(baseFlags, getProviderFlags()...)
 	case common.SACommand:
 		return append(append(baseFlags, getProviderFlags()...), &rotateCredentialsFlag, &removeUserFlag, &trustRoleFlag)
 	case common.ListCustomCommand:
@@ -50,7 +58,15 @@ func appendCmdSpecificFlags(baseFlags []cli.Flag, cmd common.Command) []cli.Flag
 		return append(baseFlags, &stateFileFlag, importFileFlag(false))
 	case common.ProvisionCommand:
 		return append(baseFlags, &wrapperConfigFlag, &stepFlag, &commandFlag, &entrypointFlag, &prefixStepFlag,
-			&campaignIdFlag, &pipelineIndexFlag, &insecureFlag)
+			&campaignIdFlag, &pipelineIndexFlag, &insecureFlag, &spoolDirFlag, &wrapperBucketFlag, &wrapperProjectFlag)
+	case common.CachePruneCommand:
+		return append(baseFlags, &cacheDirFlag, &maxAgeFlag)
+	case common.ServeWrapperCommand:
+		return append(append(baseFlags, getProviderFlags()...), &grpcAddressFlag, &httpAddressFlag, &storageFlag,
+			&storageDirFlag, &serverTokenFlag, &insecureNoAuthFlag, &tlsCertFlag, &tlsKeyFlag)
+	case common.OutputsCommand:
+		return append(append(baseFlags, getProviderFlags()...), &outputStepFlag, &outputModuleFlag, &outputKeyFlag,
+			&outputFormatFlag, &showSensitiveFlag)
 	default:
 		return baseFlags
 	}
@@ -78,6 +94,15 @@ var loggingFlag = cli.StringFlag{
 	Destination: &flags.LogLevel,
 }
 
+var logFormatFlag = cli.StringFlag{
+	Name:        "log-format",
+	Sources:     cli.EnvVars("LOG_FORMAT"),
+	DefaultText: "text",
+	Value:       "text",
+	Usage:       "set log format (text | json)",
+	Destination: &flags.LogFormat,
+}
+
 var configFlag = cli.StringFlag{
 	Name:        "config",
 	Aliases:     []string{"c"},
@@ -170,6 +195,25 @@ var yesFlag = cli.BoolFlag{
 	Destination: &flags.Delete.SkipConfirmation,
 }
 
+var pushYesFlag = cli.BoolFlag{
+	Name:        "yes",
+	Aliases:     []string{"y"},
+	Sources:     cli.EnvVars("YES"),
+	Usage:       "skip confirmation prompt",
+	DefaultText: "false",
+	Value:       false,
+	Destination: &flags.Push.SkipConfirmation,
+}
+
+var dryRunFlag = cli.BoolFlag{
+	Name:        "dry-run",
+	Sources:     cli.EnvVars("DRY_RUN"),
+	Usage:       "list the changed files without uploading them",
+	DefaultText: "false",
+	Value:       false,
+	Destination: &flags.Push.DryRun,
+}
+
 var skipBucketDelayFlag = cli.BoolFlag{
 	Name:        "skip-bucket-creation-delay",
 	Aliases:     []string{"sb"},
@@ -287,6 +331,56 @@ var valueFlag = cli.StringFlag{
 	Required:    true,
 }
 
+var outputStepFlag = cli.StringFlag{
+	Name:        "step",
+	Sources:     cli.EnvVars("OUTPUT_STEP"),
+	Usage:       "step name for listing outputs",
+	Value:       "",
+	Destination: &flags.Outputs.Step,
+	Required:    false,
+}
+
+var outputModuleFlag = cli.StringFlag{
+	Name:        "module",
+	Aliases:     []string{"m"},
+	Sources:     cli.EnvVars("OUTPUT_MODULE"),
+	Usage:       "module name for filtering step outputs",
+	Value:       "",
+	Destination: &flags.Outputs.Module,
+	Required:    false,
+}
+
+var outputKeyFlag = cli.StringFlag{
+	Name:        "key",
+	Aliases:     []string{"k"},
+	Sources:     cli.EnvVars("OUTPUT_KEY"),
+	Usage:       "output key in the stepName.moduleName.key format, supports list indexes",
+	Value:       "",
+	Destination: &flags.Outputs.Key,
+	Required:    false,
+}
+
+var outputFormatFlag = cli.StringFlag{
+	Name:        "format",
+	Aliases:     []string{"o"},
+	Sources:     cli.EnvVars("OUTPUT_FORMAT"),
+	DefaultText: string(common.OutputFormatRaw),
+	Value:       string(common.OutputFormatRaw),
+	Usage:       "output format (raw | json | dotenv)",
+	Destination: &flags.Outputs.Format,
+	Required:    false,
+}
+
+var showSensitiveFlag = cli.BoolFlag{
+	Name:        "show-sensitive",
+	Aliases:     []string{"ss"},
+	Sources:     cli.EnvVars("SHOW_SENSITIVE"),
+	Usage:       "show values of sensitive outputs",
+	Value:       false,
+	Destination: &flags.Outputs.ShowSensitive,
+	Required:    false,
+}
+
 var overwriteFlag = cli.BoolFlag{
 	Name:        "overwrite",
 	Aliases:     []string{"o"},
@@ -447,3 +541,171 @@ var insecureFlag = cli.BoolFlag{
 	Destination: &flags.Wrapper.Insecure,
 	Required:    false,
 }
+
+var spoolDirFlag = cli.StringFlag{
+	Name:        "spool-dir",
+	Sources:     cli.EnvVars("WRAPPER_SPOOL_DIR"),
+	DefaultText: "",
+	Value:       "",
+	Usage:       "directory for spooling log lines while the wrapper backend is unavailable, defaults to the temp dir",
+	Destination: &flags.Wrapper.SpoolDir,
+	Required:    false,
+}
+
+var wrapperBucketFlag = cli.StringFlag{
+	Name:        "bucket",
+	Sources:     cli.EnvVars("INFRALIB_BUCKET"),
+	Value:       "",
+	Usage:       "bucket for storing the timing reports of the applied resources",
+	Destination: &flags.Wrapper.Bucket,
+	Required:    false,
+}
+
+var wrapperProjectFlag = cli.StringFlag{
+	Name:        "gcloud-project",
+	Sources:     cli.EnvVars("GOOGLE_PROJECT"),
+	Value:       "",
+	Usage:       "GCloud project id of the bucket, empty for an AWS bucket",
+	Destination: &flags.Wrapper.GCloudProject,
+	Required:    false,
+}
+
+var cacheDirFlag = cli.StringFlag{
+	Name:        "cache-dir",
+	Aliases:     []string{"cd"},
+	Sources:     cli.EnvVars("CACHE_DIR"),
+	DefaultText: "",
+	Value:       "",
+	Usage:       "directory for caching source repositories and checksums between runs",
+	Destination: &flags.Cache.Dir,
+	Required:    false,
+}
+
+var maxAgeFlag = cli.DurationFlag{
+	Name:        "max-age",
+	Aliases:     []string{"ma"},
+	Sources:     cli.EnvVars("MAX_AGE"),
+	DefaultText: "0s",
+	Value:       0,
+	Usage:       "remove cache entries not used within the duration, 0 removes all entries",
+	Destination: &flags.Cache.MaxAge,
+	Required:    false,
+}
+
+var metricsAddressFlag = cli.StringFlag{
+	Name:        "metrics-address",
+	Sources:     cli.EnvVars("METRICS_ADDRESS"),
+	Value:       "",
+	Usage:       "listen address for serving prometheus metrics on /metrics, empty disables the listener",
+	Destination: &flags.Metrics.Address,
+	Required:    false,
+}
+
+var pushgatewayFlag = cli.StringFlag{
+	Name:        "metrics-pushgateway",
+	Sources:     cli.EnvVars("METRICS_PUSHGATEWAY"),
+	Value:       "",
+	Usage:       "prometheus pushgateway url for pushing metrics at exit",
+	Destination: &flags.Metrics.Pushgateway,
+	Required:    false,
+}
+
+var metricsTextfileFlag = cli.StringFlag{
+	Name:        "metrics-textfile",
+	Sources:     cli.EnvVars("METRICS_TEXTFILE"),
+	Value:       "",
+	Usage:       "file for writing metrics at exit in the node exporter textfile format",
+	Destination: &flags.Metrics.Textfile,
+	Required:    false,
+}
+
+var junitReportFlag = cli.StringFlag{
+	Name:        "junit-report",
+	Sources:     cli.EnvVars("JUNIT_REPORT"),
+	Value:       "",
+	Usage:       "file for writing a junit xml report of the step results",
+	Destination: &flags.Report.JUnit,
+	Required:    false,
+}
+
+var markdownReportFlag = cli.StringFlag{
+	Name:        "markdown-report",
+	Sources:     cli.EnvVars("MARKDOWN_REPORT"),
+	Value:       "",
+	Usage:       "file for writing a markdown summary of the module changes, plans, approvals and durations",
+	Destination: &flags.Report.Markdown,
+	Required:    false,
+}
+
+var grpcAddressFlag = cli.StringFlag{
+	Name:        "grpc-address",
+	Sources:     cli.EnvVars("GRPC_ADDRESS"),
+	Value:       ":50051",
+	Usage:       "listen address for the wrapper grpc server",
+	Destination: &flags.WrapperServer.GRPCAddress,
+	Required:    false,
+}
+
+var httpAddressFlag = cli.StringFlag{
+	Name:        "http-address",
+	Sources:     cli.EnvVars("HTTP_ADDRESS"),
+	Value:       ":8080",
+	Usage:       "listen address for reading stored logs and plans over http, empty disables the http server",
+	Destination: &flags.WrapperServer.HTTPAddress,
+	Required:    false,
+}
+
+var storageFlag = cli.StringFlag{
+	Name:        "storage",
+	Sources:     cli.EnvVars("STORAGE"),
+	Value:       string(common.WrapperStorageDisk),
+	Usage:       "storage for received logs and plans, disk or bucket",
+	Destination: &flags.WrapperServer.Storage,
+	Required:    false,
+}
+
+var storageDirFlag = cli.StringFlag{
+	Name:        "storage-dir",
+	Sources:     cli.EnvVars("STORAGE_DIR"),
+	Value:       "wrapper",
+	Usage:       "directory for received logs and plans when using disk storage",
+	Destination: &flags.WrapperServer.StorageDir,
+	Required:    false,
+}
+
+var serverTokenFlag = cli.StringFlag{
+	Name:        "token",
+	Sources:     cli.EnvVars("WRAPPER_TOKEN"),
+	Value:       "",
+	Usage:       "bearer token required from grpc and http clients",
+	Destination: &flags.WrapperServer.Token,
+	Required:    false,
+}
+
+var insecureNoAuthFlag = cli.BoolFlag{
+	Name:        "insecure-no-auth",
+	Sources:     cli.EnvVars("WRAPPER_INSECURE_NO_AUTH"),
+	DefaultText: "false",
+	Value:       false,
+	Usage:       "run the grpc and http servers without authentication, allowed only when token is not set",
+	Destination: &flags.WrapperServer.InsecureNoAuth,
+	Required:    false,
+}
+
+var tlsCertFlag = cli.StringFlag{
+	Name:        "tls-cert",
+	Sources:     cli.EnvVars("TLS_CERT"),
+	Value:       "",
+	Usage:       "tls certificate file for the grpc and http servers",
+	Destination: &flags.WrapperServer.TLSCert,
+	Required:    false,
+}
+
+var tlsKeyFlag = cli.StringFlag{
+	Name:        "tls-key",
+	Sources:     cli.EnvVars("TLS_KEY"),
+	Value:       "",
+	Usage:       "tls key file for the grpc and http servers",
+	Destination: &flags.WrapperServer.TLSKey,
+	Required:    false,
+}
@@ -3,7 +3,7 @@ package bootstrap
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
@@ -21,11 +21,11 @@ func Bootstrap(ctx context.Context, flags *common.Flags) error {
 	if err != nil {
 		return err
 	}
-	config, err := service.GetBaseConfig(resources.GetCloudPrefix(), flags.Config, resources.GetBucket())
+	config, err := service.GetBaseConfig(ctx, resources.GetCloudPrefix(), flags.Config, resources.GetBucket())
 	if err != nil {
 		return err
 	}
-	manager, err := notify.NewNotificationManager(ctx, config.Notifications, uuid.New())
+	manager, err := notify.NewNotificationManager(ctx, config.Notifications, uuid.New(), resources.GetBucket())
 	if err != nil {
 		return err
 	}
@@ -38,7 +38,7 @@ func Bootstrap(ctx context.Context, flags *common.Flags) error {
 		agentVersion = config.AgentVersion
 	}
 
-	log.Printf("Agent version: %s\n", agentVersion)
+	slog.Info(fmt.Sprintf("Agent version: %s", agentVersion))
 	agent := service.NewAgent(resources, getTerraformCache(flags.Pipeline))
 	return agent.CreatePipeline(agentVersion, flags.Start)
 }
@@ -0,0 +1,10 @@
+package cache
+
+import (
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/git"
+)
+
+func Prune(flags *common.Flags) error {
+	return git.PruneCache(flags.Cache.Dir, flags.Cache.MaxAge)
+}
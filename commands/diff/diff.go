@@ -0,0 +1,38 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/service"
+	"github.com/entigolabs/entigo-infralib-agent/util"
+)
+
+func Run(ctx context.Context, flags *common.Flags) error {
+	provider, err := service.GetCloudProvider(ctx, flags)
+	if err != nil {
+		return err
+	}
+	resources, err := provider.GetResources()
+	if err != nil {
+		return fmt.Errorf("failed to get resources: %v", err)
+	}
+	_, changes, err := service.GetLocalConfigChanges(ctx, resources.GetBucket(), flags.Config)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		slog.Info(fmt.Sprintf("Local config files match bucket %s", resources.GetBucketName()))
+		return nil
+	}
+	for _, change := range changes {
+		fileDiff, err := util.UnifiedDiff(change.Name, change.Remote, change.Local)
+		if err != nil {
+			return fmt.Errorf("failed to create diff for %s: %v", change.Name, err)
+		}
+		fmt.Print(fileDiff)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/notify"
+	"github.com/entigolabs/entigo-infralib-agent/service"
+	"github.com/google/uuid"
+)
+
+func Flush(ctx context.Context, flags *common.Flags) error {
+	provider, err := service.GetCloudProvider(ctx, flags)
+	if err != nil {
+		return err
+	}
+	resources, err := provider.GetResources()
+	if err != nil {
+		return fmt.Errorf("failed to get resources: %v", err)
+	}
+	config, err := service.GetRootConfig(ctx, resources.GetSSM(), resources.GetCloudPrefix(), flags.Config,
+		resources.GetBucket())
+	if err != nil {
+		return err
+	}
+	manager, err := notify.NewNotificationManager(ctx, config.Notifications, uuid.New(), resources.GetBucket())
+	if err != nil {
+		return err
+	}
+	return manager.Flush(ctx)
+}
@@ -0,0 +1,175 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/service"
+)
+
+const sensitiveValue = "<sensitive>"
+
+var dotenvKeyRegex = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+type output struct {
+	key       string
+	sensitive bool
+	value     interface{}
+}
+
+func Run(ctx context.Context, flags *common.Flags) error {
+	provider, err := service.GetResourceProvider(ctx, flags)
+	if err != nil {
+		return err
+	}
+	prefix, err := service.GetProviderPrefix(flags)
+	if err != nil {
+		return err
+	}
+	prefix = strings.ToLower(prefix)
+	bucket, err := provider.GetBucket(prefix)
+	if err != nil {
+		return err
+	}
+	var outputs []output
+	if flags.Outputs.Key != "" {
+		outputs, err = getOutput(ctx, bucket, prefix, flags.Outputs.Key)
+	} else {
+		outputs, err = getStepOutputs(ctx, bucket, prefix, flags.Outputs.Step, flags.Outputs.Module)
+	}
+	if err != nil {
+		return err
+	}
+	return printOutputs(outputs, flags.Outputs)
+}
+
+func getOutput(ctx context.Context, bucket model.Bucket, prefix, key string) ([]output, error) {
+	tfOutput, value, err := service.GetStepOutput(ctx, bucket, prefix, key)
+	if err != nil {
+		return nil, err
+	}
+	var outputValue interface{} = value
+	if !strings.Contains(key, "[") {
+		outputValue = tfOutput.Value
+	}
+	return []output{{key: key, sensitive: tfOutput.Sensitive, value: outputValue}}, nil
+}
+
+func getStepOutputs(ctx context.Context, bucket model.Bucket, prefix, step, module string) ([]output, error) {
+	tfOutputs, err := service.GetStepOutputs(ctx, bucket, prefix, step)
+	if err != nil {
+		return nil, err
+	}
+	if len(tfOutputs) == 0 {
+		return nil, fmt.Errorf("no outputs found for step %s", step)
+	}
+	outputs := make([]output, 0)
+	for _, name := range slices.Sorted(maps.Keys(tfOutputs)) {
+		moduleName, outputName, found := strings.Cut(name, "__")
+		if !found || (module != "" && moduleName != module) {
+			continue
+		}
+		tfOutput := tfOutputs[name]
+		outputs = append(outputs, output{
+			key:       fmt.Sprintf("%s.%s.%s", step, moduleName, outputName),
+			sensitive: tfOutput.Sensitive,
+			value:     tfOutput.Value,
+		})
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("no outputs found for module %s in step %s", module, step)
+	}
+	return outputs, nil
+}
+
+func printOutputs(outputs []output, flags common.Outputs) error {
+	for i, out := range outputs {
+		if out.sensitive && !flags.ShowSensitive {
+			outputs[i].value = sensitiveValue
+		}
+	}
+	switch common.OutputFormat(flags.Format) {
+	case common.OutputFormatJSON:
+		return printJSON(outputs, flags.Key != "")
+	case common.OutputFormatDotenv:
+		for _, out := range outputs {
+			value, err := formatValue(out.value)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s=%s\n", getDotenvKey(out.key), strconv.Quote(value))
+		}
+	default:
+		for _, out := range outputs {
+			value, err := formatValue(out.value)
+			if err != nil {
+				return err
+			}
+			if flags.Key != "" {
+				fmt.Println(value)
+			} else {
+				fmt.Printf("%s = %s\n", out.key, value)
+			}
+		}
+	}
+	return nil
+}
+
+func printJSON(outputs []output, single bool) error {
+	var value interface{}
+	if single {
+		value = outputs[0].value
+	} else {
+		values := make(map[string]interface{}, len(outputs))
+		for _, out := range outputs {
+			values[out.key] = out.value
+		}
+		value = values
+	}
+	// Values like URLs and the sensitive placeholder are printed without HTML escaping.
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("failed to marshal outputs: %w", err)
+	}
+	return nil
+}
+
+// formatValue returns strings, numbers and bools as is, lists and maps are returned as json.
+func formatValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []interface{}, map[string]interface{}:
+		var buffer bytes.Buffer
+		encoder := json.NewEncoder(&buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(v); err != nil {
+			return "", fmt.Errorf("failed to marshal output value: %w", err)
+		}
+		return strings.TrimSuffix(buffer.String(), "\n"), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case nil:
+		return "", nil
+	default:
+		return fmt.Sprintf("%v", v), nil
+	}
+}
+
+// getDotenvKey removes the step name from the key, e.g. net.vpc.private_subnets[0] becomes VPC_PRIVATE_SUBNETS_0.
+func getDotenvKey(key string) string {
+	_, key, _ = strings.Cut(key, ".")
+	return strings.ToUpper(strings.Trim(dotenvKeyRegex.ReplaceAllString(key, "_"), "_"))
+}
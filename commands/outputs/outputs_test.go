@@ -0,0 +1,165 @@
+package outputs
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+type memoryBucket struct {
+	model.Bucket
+	files map[string][]byte
+}
+
+func (b *memoryBucket) GetFile(_ context.Context, file string) ([]byte, error) {
+	return b.files[file], nil
+}
+
+func captureStdout(t *testing.T, print func() error) string {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	err = print()
+	os.Stdout = stdout
+	_ = writer.Close()
+	if err != nil {
+		t.Fatalf("failed to print outputs: %v", err)
+	}
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read stdout: %v", err)
+	}
+	return string(output)
+}
+
+func TestPrintOutputs(t *testing.T) {
+	getOutputs := func() []output {
+		return []output{
+			{key: "net.vpc.vpc_id", value: "vpc-1"},
+			{key: "net.vpc.private_subnets", value: []interface{}{"subnet-a", "subnet-b"}},
+			{key: "net.vpc.azs", value: float64(3)},
+			{key: "net.vpc.db_password", sensitive: true, value: "db-password"},
+		}
+	}
+	tests := []struct {
+		name     string
+		flags    common.Outputs
+		outputs  []output
+		expected string
+	}{
+		{
+			name:  "raw",
+			flags: common.Outputs{Format: string(common.OutputFormatRaw)},
+			expected: "net.vpc.vpc_id = vpc-1\nnet.vpc.private_subnets = [\"subnet-a\",\"subnet-b\"]\n" +
+				"net.vpc.azs = 3\nnet.vpc.db_password = <sensitive>\n",
+		},
+		{
+			name:     "raw key",
+			flags:    common.Outputs{Format: string(common.OutputFormatRaw), Key: "net.vpc.private_subnets[0]"},
+			outputs:  []output{{key: "net.vpc.private_subnets[0]", value: "subnet-a"}},
+			expected: "subnet-a\n",
+		},
+		{
+			name:  "show sensitive",
+			flags: common.Outputs{Format: string(common.OutputFormatRaw), ShowSensitive: true},
+			expected: "net.vpc.vpc_id = vpc-1\nnet.vpc.private_subnets = [\"subnet-a\",\"subnet-b\"]\n" +
+				"net.vpc.azs = 3\nnet.vpc.db_password = db-password\n",
+		},
+		{
+			name:  "json",
+			flags: common.Outputs{Format: string(common.OutputFormatJSON)},
+			expected: "{\n  \"net.vpc.azs\": 3,\n  \"net.vpc.db_password\": \"<sensitive>\",\n" +
+				"  \"net.vpc.private_subnets\": [\n    \"subnet-a\",\n    \"subnet-b\"\n  ],\n" +
+				"  \"net.vpc.vpc_id\": \"vpc-1\"\n}\n",
+		},
+		{
+			name:     "raw urls",
+			flags:    common.Outputs{Format: string(common.OutputFormatRaw), Key: "net.vpc.endpoints"},
+			outputs:  []output{{key: "net.vpc.endpoints", value: []interface{}{"https://host/?a=1&b=2"}}},
+			expected: "[\"https://host/?a=1&b=2\"]\n",
+		},
+		{
+			name:     "json key",
+			flags:    common.Outputs{Format: string(common.OutputFormatJSON), Key: "net.vpc.vpc_id"},
+			outputs:  []output{{key: "net.vpc.vpc_id", value: "vpc-1"}},
+			expected: "\"vpc-1\"\n",
+		},
+		{
+			name:  "dotenv",
+			flags: common.Outputs{Format: string(common.OutputFormatDotenv)},
+			expected: "VPC_VPC_ID=\"vpc-1\"\nVPC_PRIVATE_SUBNETS=\"[\\\"subnet-a\\\",\\\"subnet-b\\\"]\"\n" +
+				"VPC_AZS=\"3\"\nVPC_DB_PASSWORD=\"<sensitive>\"\n",
+		},
+		{
+			name:     "dotenv index",
+			flags:    common.Outputs{Format: string(common.OutputFormatDotenv), Key: "net.vpc.private_subnets[0]"},
+			outputs:  []output{{key: "net.vpc.private_subnets[0]", value: "subnet-a"}},
+			expected: "VPC_PRIVATE_SUBNETS_0=\"subnet-a\"\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outputs := test.outputs
+			if outputs == nil {
+				outputs = getOutputs()
+			}
+			printed := captureStdout(t, func() error {
+				return printOutputs(outputs, test.flags)
+			})
+			if printed != test.expected {
+				t.Fatalf("expected output:\n%s\ngot:\n%s", test.expected, printed)
+			}
+		})
+	}
+}
+
+func TestGetStepOutputs(t *testing.T) {
+	bucket := &memoryBucket{files: map[string][]byte{
+		"prefix-net/terraform-output.json": []byte(`{
+			"vpc__vpc_id": {"sensitive": false, "type": "string", "value": "vpc-1"},
+			"route53__zone_id": {"sensitive": false, "type": "string", "value": "zone-1"},
+			"other": {"sensitive": false, "type": "string", "value": "other"}
+		}`),
+	}}
+	tests := []struct {
+		name     string
+		step     string
+		module   string
+		expected []string
+		err      string
+	}{
+		{name: "step", step: "net", expected: []string{"net.route53.zone_id", "net.vpc.vpc_id"}},
+		{name: "module", step: "net", module: "vpc", expected: []string{"net.vpc.vpc_id"}},
+		{name: "missing module", step: "net", module: "eks", err: "no outputs found for module eks in step net"},
+		{name: "missing step", step: "apps", err: "no outputs found for step apps"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			outputs, err := getStepOutputs(t.Context(), bucket, "prefix", test.step, test.module)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get outputs: %v", err)
+			}
+			var keys []string
+			for _, out := range outputs {
+				keys = append(keys, out.key)
+			}
+			if strings.Join(keys, ",") != strings.Join(test.expected, ",") {
+				t.Fatalf("expected outputs %v, got %v", test.expected, keys)
+			}
+		})
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/entigolabs/entigo-infralib-agent/model"
 	"github.com/entigolabs/entigo-infralib-agent/service"
 	"github.com/entigolabs/entigo-infralib-agent/util"
-	"log"
+	"log/slog"
 )
 
 func Custom(ctx context.Context, flags *common.Flags, command common.Command) error {
@@ -15,24 +15,24 @@ func Custom(ctx context.Context, flags *common.Flags, command common.Command) er
 	if err != nil {
 		return err
 	}
-	ssm, err := getSSM(provider, flags)
+	ssm, err := getSSM(ctx, provider, flags)
 	if err != nil {
 		return err
 	}
 	switch command {
 	case common.AddCustomCommand:
-		err = addParam(ssm, flags.Params)
+		err = addParam(ctx, ssm, flags.Params)
 	case common.DeleteCustomCommand:
-		err = deleteParam(ssm, flags.Params)
+		err = deleteParam(ctx, ssm, flags.Params)
 	case common.GetCustomCommand:
-		err = getParam(ssm, flags.Params)
+		err = getParam(ctx, ssm, flags.Params)
 	case common.ListCustomCommand:
-		err = listParams(ssm)
+		err = listParams(ctx, ssm)
 	}
 	return err
 }
 
-func getSSM(provider model.ResourceProvider, flags *common.Flags) (model.SSM, error) {
+func getSSM(ctx context.Context, provider model.ResourceProvider, flags *common.Flags) (model.SSM, error) {
 	ssm, err := provider.GetSSM()
 	if err != nil {
 		return nil, err
@@ -48,7 +48,7 @@ func getSSM(provider model.ResourceProvider, flags *common.Flags) (model.SSM, er
 	if err != nil {
 		return nil, err
 	}
-	keyId, err := service.GetEncryptionKey(provider.GetProviderType(), prefix, flags.Config, bucket)
+	keyId, err := service.GetEncryptionKey(ctx, provider.GetProviderType(), prefix, flags.Config, bucket)
 	if err != nil {
 		return nil, err
 	}
@@ -59,8 +59,8 @@ func getSSM(provider model.ResourceProvider, flags *common.Flags) (model.SSM, er
 	return ssm, nil
 }
 
-func addParam(ssm model.SSM, params common.Params) error {
-	exists, err := ssm.ParameterExists(params.Key)
+func addParam(ctx context.Context, ssm model.SSM, params common.Params) error {
+	exists, err := ssm.ParameterExists(ctx, params.Key)
 	if err != nil {
 		return err
 	}
@@ -71,7 +71,7 @@ func addParam(ssm model.SSM, params common.Params) error {
 			return err
 		}
 	}
-	err = ssm.PutParameter(params.Key, params.Value)
+	err = ssm.PutParameter(ctx, params.Key, params.Value)
 	if err == nil {
 		fmt.Printf("To use the parameter in your configuration use {{ .%s.%s }}\n", model.ReplaceTypeOutputCustom,
 			params.Key)
@@ -79,16 +79,16 @@ func addParam(ssm model.SSM, params common.Params) error {
 	return err
 }
 
-func deleteParam(ssm model.SSM, params common.Params) error {
-	err := ssm.DeleteParameter(params.Key)
+func deleteParam(ctx context.Context, ssm model.SSM, params common.Params) error {
+	err := ssm.DeleteParameter(ctx, params.Key)
 	if err == nil {
-		log.Printf("Successfully deleted %s", params.Key)
+		slog.Info(fmt.Sprintf("Successfully deleted %s", params.Key))
 	}
 	return err
 }
 
-func getParam(ssm model.SSM, params common.Params) error {
-	param, err := ssm.GetParameter(params.Key)
+func getParam(ctx context.Context, ssm model.SSM, params common.Params) error {
+	param, err := ssm.GetParameter(ctx, params.Key)
 	if err != nil {
 		return err
 	}
@@ -102,8 +102,8 @@ func getParam(ssm model.SSM, params common.Params) error {
 	return nil
 }
 
-func listParams(ssm model.SSM) error {
-	keys, err := ssm.ListParameters()
+func listParams(ctx context.Context, ssm model.SSM) error {
+	keys, err := ssm.ListParameters(ctx)
 	if err != nil {
 		return err
 	}
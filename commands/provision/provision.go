@@ -8,26 +8,54 @@ import (
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/service"
 	"github.com/entigolabs/entigo-infralib-agent/wrapper"
 	"gopkg.in/yaml.v3"
 )
 
-// Run executes the Infralib Tool entrypoint.sh. When a wrapper config is
-// supplied via the WRAPPER_CONFIG env var (resolved from secret manager by the
-// pipeline), raw log lines and the plan summary are forwarded to the portal
-// backend over gRPC; without a config, the wrapper is transparent.
+// Run executes the Infralib Tool entrypoint.sh. Encrypted step files in the
+// workspace are decrypted first. When a wrapper config is supplied via the
+// WRAPPER_CONFIG env var (resolved from secret manager by the pipeline), raw
+// log lines and the plan summary are forwarded to the portal backend over gRPC;
+// without a config, the wrapper is transparent.
 func Run(ctx context.Context, flags *common.Flags) error {
 	config, err := parseConfig(flags.Wrapper.Config)
 	if err != nil {
 		return err
 	}
+	ctx = logContext(ctx, flags.Wrapper)
 	wrap, err := wrapper.NewWrapper(ctx, flags.Wrapper, config, os.Environ(), os.Stdout)
 	if err != nil {
 		return fmt.Errorf("failed to initialize wrapper: %w", err)
 	}
+	wrap.SetDecrypt(func(folder string) error {
+		return service.DecryptStepFiles(ctx, folder)
+	})
+	wrap.SetTimingReport(func(report model.TimingReport) error {
+		bucket, err := service.GetPipelineBucket(ctx, flags.Wrapper)
+		if err != nil || bucket == nil {
+			return err
+		}
+		return service.PutTimingReport(ctx, bucket, flags.Wrapper.PrefixStep, report)
+	})
 	return wrap.Provision()
 }
 
+// logContext adds the campaign attributes of the wrapper flags to the log records.
+func logContext(ctx context.Context, flags common.Wrapper) context.Context {
+	attrs := [][2]string{
+		{common.CampaignIdAttr, flags.CampaignId},
+		{common.StepAttr, flags.Step},
+		{common.PipelineIndexAttr, flags.PipelineIndex},
+	}
+	for _, attr := range attrs {
+		if attr[1] != "" {
+			ctx = common.WithLogAttrs(ctx, attr[0], attr[1])
+		}
+	}
+	return ctx
+}
+
 func parseConfig(raw string) (*model.NotificationApi, error) {
 	if strings.TrimSpace(raw) == "" {
 		return nil, nil
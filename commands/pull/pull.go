@@ -8,7 +8,6 @@ import (
 	"github.com/entigolabs/entigo-infralib-agent/service"
 	"github.com/entigolabs/entigo-infralib-agent/util"
 	"gopkg.in/yaml.v3"
-	"log"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -24,7 +23,7 @@ func Run(ctx context.Context, flags *common.Flags) error {
 	if err != nil {
 		return fmt.Errorf("failed to get resources: %v", err)
 	}
-	conf, err := service.GetRemoteConfig(nil, resources.GetCloudPrefix(), resources.GetBucket(), false)
+	conf, err := service.GetRemoteConfig(ctx, nil, resources.GetCloudPrefix(), resources.GetBucket(), true)
 	if err != nil {
 		return err
 	}
@@ -37,7 +36,7 @@ func Run(ctx context.Context, flags *common.Flags) error {
 		if !flags.Force {
 			return fmt.Errorf("files already exist in config folder. Use force to overwrite. Files: %s", strings.Join(existingFiles, ", "))
 		} else {
-			log.Printf("Force flag set. Overwriting existing files: %s", strings.Join(existingFiles, ", "))
+			slog.Info(fmt.Sprintf("Force flag set. Overwriting existing files: %s", strings.Join(existingFiles, ", ")))
 		}
 	}
 	if flags.Force {
@@ -129,7 +128,7 @@ func writeConfigFiles(conf model.Config, config string, basePath string) error {
 			}
 		}
 	}
-	log.Println("Steps files written successfully")
+	slog.Info("Steps files written successfully")
 	return nil
 }
 
@@ -141,6 +140,13 @@ func writeModuleFiles(step model.Step, basePath string) error {
 		if module.InputsFile == "" {
 			continue
 		}
+		if module.FileEncrypted {
+			// Encrypted input files must stay encrypted
+			if err := writeFile(basePath+module.InputsFile, module.FileContent); err != nil {
+				return err
+			}
+			continue
+		}
 		bytes, err := yaml.Marshal(module.Inputs)
 		if err != nil {
 			return fmt.Errorf("failed to marshal module inputs: %s", err)
@@ -0,0 +1,64 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/service"
+	"github.com/entigolabs/entigo-infralib-agent/util"
+)
+
+func Run(ctx context.Context, flags *common.Flags) error {
+	provider, err := service.GetCloudProvider(ctx, flags)
+	if err != nil {
+		return err
+	}
+	resources, err := provider.GetResources()
+	if err != nil {
+		return fmt.Errorf("failed to get resources: %v", err)
+	}
+	bucket := resources.GetBucket()
+	config, changes, err := service.GetLocalConfigChanges(ctx, bucket, flags.Config)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		slog.Info(fmt.Sprintf("Local config files match bucket %s, nothing to push", resources.GetBucketName()))
+		return nil
+	}
+	fmt.Printf("Changed files in bucket %s:\n", resources.GetBucketName())
+	for _, change := range changes {
+		fmt.Printf("  %-8s %s\n", getChangeType(change), change.Name)
+	}
+	if flags.Push.DryRun {
+		slog.Info("Dry run, files were not pushed")
+		return nil
+	}
+	if !flags.Push.SkipConfirmation {
+		fmt.Print("Push the files? (Y/N): ")
+		if err = util.AskForConfirmationContext(ctx); err != nil {
+			return err
+		}
+	}
+	if err = service.PutConfig(ctx, bucket, config); err != nil {
+		return err
+	}
+	if err = service.PutAdditionalFiles(ctx, bucket, config); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Pushed %d changed files to bucket %s", len(changes), resources.GetBucketName()))
+	return nil
+}
+
+func getChangeType(change service.ConfigFileChange) string {
+	switch {
+	case change.Remote == nil:
+		return "added"
+	case change.Local == nil:
+		return "deleted"
+	default:
+		return "modified"
+	}
+}
@@ -0,0 +1,44 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/service"
+	"github.com/entigolabs/entigo-infralib-agent/wrapper"
+)
+
+// Wrapper runs the reference WrapperService server, storing received streams on disk or in the agent bucket.
+func Wrapper(ctx context.Context, flags *common.Flags) error {
+	store, err := getStore(ctx, flags)
+	if err != nil {
+		return err
+	}
+	if flags.WrapperServer.InsecureNoAuth {
+		slog.Warn(common.PrefixWarning("wrapper server is running without authentication"))
+	}
+	server := wrapper.NewServer(store, flags.WrapperServer.Token)
+	return server.Serve(ctx, wrapper.ServerConfig{
+		GRPCAddress: flags.WrapperServer.GRPCAddress,
+		HTTPAddress: flags.WrapperServer.HTTPAddress,
+		TLSCert:     flags.WrapperServer.TLSCert,
+		TLSKey:      flags.WrapperServer.TLSKey,
+	})
+}
+
+func getStore(ctx context.Context, flags *common.Flags) (wrapper.Store, error) {
+	if common.WrapperStorage(flags.WrapperServer.Storage) == common.WrapperStorageDisk {
+		return wrapper.NewDiskStore(flags.WrapperServer.StorageDir)
+	}
+	provider, err := service.GetCloudProvider(ctx, flags)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := provider.GetResources()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resources: %v", err)
+	}
+	return wrapper.NewBucketStore(resources.GetBucket()), nil
+}
@@ -3,21 +3,27 @@ package common
 type Command string
 
 const (
-	RunCommand             Command = "run"
-	BootstrapCommand       Command = "bootstrap"
-	DestroyCommand         Command = "destroy"
-	DeleteCommand          Command = "delete"
-	UpdateCommand          Command = "update"
-	SACommand              Command = "service-account"
-	PullCommand            Command = "pull"
-	AddCustomCommand       Command = "add-custom"
-	DeleteCustomCommand    Command = "delete-custom"
-	GetCustomCommand       Command = "get-custom"
-	ListCustomCommand      Command = "list-custom"
-	MigrateConfigCommand   Command = "migrate-config"
-	MigratePlanCommand     Command = "migrate-plan"
-	MigrateValidateCommand Command = "migrate-validate"
-	ProvisionCommand       Command = "provision"
+	RunCommand                Command = "run"
+	BootstrapCommand          Command = "bootstrap"
+	DestroyCommand            Command = "destroy"
+	DeleteCommand             Command = "delete"
+	UpdateCommand             Command = "update"
+	SACommand                 Command = "service-account"
+	PullCommand               Command = "pull"
+	DiffCommand               Command = "diff"
+	PushCommand               Command = "push"
+	AddCustomCommand          Command = "add-custom"
+	DeleteCustomCommand       Command = "delete-custom"
+	GetCustomCommand          Command = "get-custom"
+	ListCustomCommand         Command = "list-custom"
+	MigrateConfigCommand      Command = "migrate-config"
+	MigratePlanCommand        Command = "migrate-plan"
+	MigrateValidateCommand    Command = "migrate-validate"
+	ProvisionCommand          Command = "provision"
+	CachePruneCommand         Command = "cache-prune"
+	OutputsCommand            Command = "outputs"
+	NotificationsFlushCommand Command = "notifications-flush"
+	ServeWrapperCommand       Command = "serve-wrapper"
 )
 
 type LogLevel string
@@ -28,3 +34,10 @@ const (
 	WarnLogLevel  LogLevel = "warn"
 	ErrorLogLevel LogLevel = "error"
 )
+
+type LogFormat string
+
+const (
+	TextLogFormat LogFormat = "text"
+	JSONLogFormat LogFormat = "json"
+)
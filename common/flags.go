@@ -2,6 +2,7 @@ package common
 
 import (
 	"strconv"
+	"time"
 )
 
 const (
@@ -14,6 +15,7 @@ const (
 
 type Flags struct {
 	LogLevel                string
+	LogFormat               string
 	Config                  string
 	Prefix                  string
 	Force                   bool
@@ -29,6 +31,12 @@ type Flags struct {
 	Params                  Params
 	Migrate                 Migrate
 	Wrapper                 Wrapper
+	WrapperServer           WrapperServer
+	Cache                   Cache
+	Outputs                 Outputs
+	Metrics                 Metrics
+	Report                  Report
+	Push                    Push
 }
 
 func (f *Flags) Setup(cmd Command) error {
@@ -83,6 +91,49 @@ type Wrapper struct {
 	CampaignId    string
 	PipelineIndex string
 	Insecure      bool
+	SpoolDir      string
+	Bucket        string
+	GCloudProject string
+}
+
+type WrapperServer struct {
+	GRPCAddress    string
+	HTTPAddress    string
+	Storage        string
+	StorageDir     string
+	Token          string
+	InsecureNoAuth bool
+	TLSCert        string
+	TLSKey         string
+}
+
+type Cache struct {
+	Dir    string
+	MaxAge time.Duration
+}
+
+type Metrics struct {
+	Address     string
+	Pushgateway string
+	Textfile    string
+}
+
+type Push struct {
+	DryRun           bool
+	SkipConfirmation bool
+}
+
+type Report struct {
+	JUnit    string
+	Markdown string
+}
+
+type Outputs struct {
+	Step          string
+	Module        string
+	Key           string
+	Format        string
+	ShowSensitive bool
 }
 
 type PipelineType string
@@ -92,6 +143,21 @@ const (
 	PipelineTypeCloud PipelineType = "cloud"
 )
 
+type WrapperStorage string
+
+const (
+	WrapperStorageDisk   WrapperStorage = "disk"
+	WrapperStorageBucket WrapperStorage = "bucket"
+)
+
+type OutputFormat string
+
+const (
+	OutputFormatRaw    OutputFormat = "raw"
+	OutputFormatJSON   OutputFormat = "json"
+	OutputFormatDotenv OutputFormat = "dotenv"
+)
+
 type Params struct {
 	Key       string
 	Value     string
@@ -15,23 +15,91 @@ func (f *Flags) validate(cmd Command) error {
 		fallthrough
 	case BootstrapCommand:
 		fallthrough
-	case PullCommand:
+	case PullCommand, OutputsCommand, NotificationsFlushCommand:
 		if f.Config == "" && f.Prefix == "" {
 			return fmt.Errorf("config or prefix must be set")
 		}
+		if cmd == OutputsCommand {
+			if err := f.validateOutputs(); err != nil {
+				return err
+			}
+		}
 		fallthrough
 	case SACommand, AddCustomCommand, DeleteCustomCommand, GetCustomCommand, ListCustomCommand:
-		if f.GCloud.ProjectId != "" {
-			if f.GCloud.Location == "" || f.GCloud.Zone == "" {
-				return fmt.Errorf("gcloud location and zone must be set")
-			}
-		} else {
-			if f.GCloud.CredentialsJson != "" {
-				return fmt.Errorf("gcloud project ID must be set when credentials JSON is provided")
-			}
+		return f.validateGCloud()
+	case DiffCommand, PushCommand:
+		if f.Config == "" {
+			return fmt.Errorf("config must be set")
+		}
+		return f.validateGCloud()
+	case ServeWrapperCommand:
+		return f.validateWrapperServer()
+	case CachePruneCommand:
+		if f.Cache.Dir == "" {
+			return fmt.Errorf("cache dir must be set")
+		}
+		if f.Cache.MaxAge < 0 {
+			return fmt.Errorf("max age must not be negative")
 		}
 		return nil
 	default:
 		return nil
 	}
 }
+
+func (f *Flags) validateOutputs() error {
+	if f.Outputs.Key == "" && f.Outputs.Step == "" {
+		return fmt.Errorf("key or step must be set")
+	}
+	if f.Outputs.Key != "" && (f.Outputs.Step != "" || f.Outputs.Module != "") {
+		return fmt.Errorf("key can't be used together with step or module")
+	}
+	switch OutputFormat(f.Outputs.Format) {
+	case OutputFormatRaw, OutputFormatJSON, OutputFormatDotenv:
+		return nil
+	default:
+		return fmt.Errorf("output format must be one of 'raw', 'json' or 'dotenv'")
+	}
+}
+
+func (f *Flags) validateWrapperServer() error {
+	if f.WrapperServer.GRPCAddress == "" {
+		return fmt.Errorf("grpc address must be set")
+	}
+	if f.WrapperServer.Token == "" && !f.WrapperServer.InsecureNoAuth {
+		return fmt.Errorf("token must be set, use insecure-no-auth to run without authentication")
+	}
+	if f.WrapperServer.Token != "" && f.WrapperServer.InsecureNoAuth {
+		return fmt.Errorf("token can't be used together with insecure-no-auth")
+	}
+	if (f.WrapperServer.TLSCert == "") != (f.WrapperServer.TLSKey == "") {
+		return fmt.Errorf("tls cert and key must be set together")
+	}
+	switch WrapperStorage(f.WrapperServer.Storage) {
+	case WrapperStorageDisk:
+		if f.WrapperServer.StorageDir == "" {
+			return fmt.Errorf("storage dir must be set")
+		}
+		return nil
+	case WrapperStorageBucket:
+		if f.Config == "" && f.Prefix == "" {
+			return fmt.Errorf("config or prefix must be set")
+		}
+		return f.validateGCloud()
+	default:
+		return fmt.Errorf("storage must be either 'disk' or 'bucket'")
+	}
+}
+
+func (f *Flags) validateGCloud() error {
+	if f.GCloud.ProjectId != "" {
+		if f.GCloud.Location == "" || f.GCloud.Zone == "" {
+			return fmt.Errorf("gcloud location and zone must be set")
+		}
+	} else {
+		if f.GCloud.CredentialsJson != "" {
+			return fmt.Errorf("gcloud project ID must be set when credentials JSON is provided")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package common
+
+import "testing"
+
+func TestValidateWrapperServer(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   WrapperServer
+		expected string
+	}{
+		{name: "token", server: WrapperServer{Token: "changeme"}},
+		{name: "insecure no auth", server: WrapperServer{InsecureNoAuth: true}},
+		{name: "no token", expected: "token must be set, use insecure-no-auth to run without authentication"},
+		{
+			name:     "token and insecure no auth",
+			server:   WrapperServer{Token: "changeme", InsecureNoAuth: true},
+			expected: "token can't be used together with insecure-no-auth",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.server.GRPCAddress = ":50051"
+			test.server.Storage = string(WrapperStorageDisk)
+			test.server.StorageDir = "wrapper"
+			flags := &Flags{WrapperServer: test.server}
+			err := flags.validateWrapperServer()
+			if test.expected == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.expected != "" && (err == nil || err.Error() != test.expected) {
+				t.Fatalf("expected error %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateOutputs(t *testing.T) {
+	tests := []struct {
+		name     string
+		outputs  Outputs
+		expected string
+	}{
+		{name: "step", outputs: Outputs{Step: "net", Format: string(OutputFormatRaw)}},
+		{name: "module", outputs: Outputs{Step: "net", Module: "vpc", Format: string(OutputFormatDotenv)}},
+		{name: "key", outputs: Outputs{Key: "net.vpc.private_subnets[0]", Format: string(OutputFormatJSON)}},
+		{name: "no key or step", outputs: Outputs{Format: string(OutputFormatRaw)}, expected: "key or step must be set"},
+		{
+			name:     "key and module",
+			outputs:  Outputs{Key: "net.vpc.vpc_id", Module: "vpc", Format: string(OutputFormatRaw)},
+			expected: "key can't be used together with step or module",
+		},
+		{
+			name:     "unknown format",
+			outputs:  Outputs{Step: "net", Format: "yaml"},
+			expected: "output format must be one of 'raw', 'json' or 'dotenv'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := &Flags{Outputs: test.outputs}
+			err := flags.validateOutputs()
+			if test.expected == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.expected != "" && (err == nil || err.Error() != test.expected) {
+				t.Fatalf("expected error %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -1,17 +1,43 @@
 package common
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
 )
 
+const (
+	CampaignIdAttr    = "campaign_id"
+	PrefixAttr        = "prefix"
+	StepAttr          = "step"
+	ModuleAttr        = "module"
+	PipelineIndexAttr = "pipeline_index"
+)
+
+var logLevel = new(slog.LevelVar)
+
+// logOutput is the stderr writer of the default logger, paused while the user is asked for input.
+var logOutput = &pausableWriter{writer: os.Stderr}
+
+// uncolored drops the terminal colors from warnings and errors, set with the json log format.
+var uncolored atomic.Bool
+
 type Warning struct {
 	Reason error
 }
 
 func (w *Warning) Error() string {
+	if uncolored.Load() {
+		return w.Reason.Error()
+	}
 	return fmt.Sprintf("\x1b[36;1m%s\x1b[0m", w.Reason)
 }
 
@@ -25,6 +51,9 @@ type PrefixedError struct {
 }
 
 func (pe *PrefixedError) Error() string {
+	if uncolored.Load() {
+		return pe.Reason.Error()
+	}
 	return fmt.Sprintf("\x1b[31;1m%s\x1b[0m", pe.Reason)
 }
 
@@ -33,22 +62,104 @@ func PrefixError(err error) string {
 	return prefixed.Error()
 }
 
+// ChooseLogger sets the log level, keeping the chosen log format.
 func ChooseLogger(loggingLvl string) error {
+	var level slog.Level
 	switch loggingLvl {
 	case string(DebugLogLevel):
-		slog.SetLogLoggerLevel(slog.LevelDebug)
-		log.SetFlags(log.LstdFlags | log.Lshortfile)
+		level = slog.LevelDebug
+	case string(ProdLogLevel):
+		level = slog.LevelInfo
 	case string(WarnLogLevel):
-		slog.SetLogLoggerLevel(slog.LevelWarn)
-		fallthrough
+		level = slog.LevelWarn
 	case string(ErrorLogLevel):
-		slog.SetLogLoggerLevel(slog.LevelError)
-		fallthrough
-	case string(ProdLogLevel):
-		log.SetFlags(0)
+		level = slog.LevelError
 	default:
 		msg := fmt.Sprintf("unsupported logging level: %v", loggingLvl)
 		return &PrefixedError{errors.New(msg)}
 	}
+	logLevel.Set(level)
+	slog.SetLogLoggerLevel(level)
+	if level == slog.LevelDebug {
+		log.SetFlags(log.LstdFlags | log.Lshortfile)
+	} else {
+		log.SetFlags(0)
+	}
+	return nil
+}
+
+// ChooseLogFormat sets the format of the default logger. The json format writes one json object per line to stderr
+// and omits the terminal colors from warnings and errors.
+func ChooseLogFormat(format string) error {
+	switch LogFormat(format) {
+	case TextLogFormat:
+		uncolored.Store(false)
+		log.SetOutput(logOutput)
+	case JSONLogFormat:
+		uncolored.Store(true)
+		slog.SetDefault(slog.New(slog.NewJSONHandler(logOutput, &slog.HandlerOptions{Level: logLevel})))
+	default:
+		msg := fmt.Sprintf("unsupported log format: %v", format)
+		return &PrefixedError{errors.New(msg)}
+	}
 	return nil
 }
+
+type logAttrsKey struct{}
+
+// WithLogAttrs returns a context whose logger adds the given key value pairs to every record.
+func WithLogAttrs(ctx context.Context, args ...any) context.Context {
+	attrs, _ := ctx.Value(logAttrsKey{}).([]any)
+	return context.WithValue(ctx, logAttrsKey{}, append(slices.Clip(attrs), args...))
+}
+
+// Logger returns the default logger with the attributes of the context.
+func Logger(ctx context.Context) *slog.Logger {
+	if ctx == nil {
+		return slog.Default()
+	}
+	attrs, _ := ctx.Value(logAttrsKey{}).([]any)
+	if len(attrs) == 0 {
+		return slog.Default()
+	}
+	return slog.Default().With(attrs...)
+}
+
+// LogOutput returns the stderr writer of the default logger for output that must pause together with the logs.
+func LogOutput() io.Writer {
+	return logOutput
+}
+
+// PauseLogs holds back the log output, so it doesn't mix with a prompt. The returned function writes the held back
+// output and resumes logging.
+func PauseLogs() func() {
+	return logOutput.pause()
+}
+
+type pausableWriter struct {
+	lock   sync.Mutex
+	writer io.Writer
+	paused bool
+	buffer bytes.Buffer
+}
+
+func (w *pausableWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.paused {
+		return w.buffer.Write(p)
+	}
+	return w.writer.Write(p)
+}
+
+func (w *pausableWriter) pause() func() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.paused = true
+	return func() {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+		w.paused = false
+		_, _ = w.buffer.WriteTo(w.writer)
+	}
+}
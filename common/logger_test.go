@@ -0,0 +1,22 @@
+package common
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPausableWriter(t *testing.T) {
+	var output bytes.Buffer
+	writer := &pausableWriter{writer: &output}
+	_, _ = writer.Write([]byte("before\n"))
+	resume := writer.pause()
+	_, _ = writer.Write([]byte("during\n"))
+	if output.String() != "before\n" {
+		t.Fatalf("output must be held back while paused, got %q", output.String())
+	}
+	resume()
+	_, _ = writer.Write([]byte("after\n"))
+	if expected := "before\nduring\nafter\n"; output.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, output.String())
+	}
+}
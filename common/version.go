@@ -1,7 +1,8 @@
 package common
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 )
 
 // Version information set by link flags during build. We fall back to these sane
@@ -29,7 +30,7 @@ func (v Version) String() string {
 }
 
 // GetVersion returns the version information
-func getVersion() Version {
+func GetVersion() Version {
 	return Version{
 		Version:   version,
 		BuildDate: buildDate,
@@ -39,11 +40,11 @@ func getVersion() Version {
 }
 
 func PrintVersion() {
-	version := getVersion()
-	log.Printf("agent: %s\n", version)
-	log.Printf("  BuildDate: %s\n", version.BuildDate)
-	log.Printf("  GitCommit: %s\n", version.GitCommit)
+	version := GetVersion()
+	slog.Info(fmt.Sprintf("agent: %s", version))
+	slog.Info(fmt.Sprintf("  BuildDate: %s", version.BuildDate))
+	slog.Info(fmt.Sprintf("  GitCommit: %s", version.GitCommit))
 	if version.GitTag != "" {
-		log.Printf("  GitTag: %s\n", version.GitTag)
+		slog.Info(fmt.Sprintf("  GitTag: %s", version.GitTag))
 	}
 }
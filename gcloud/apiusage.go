@@ -3,9 +3,9 @@ package gcloud
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"google.golang.org/api/option"
 	"google.golang.org/api/serviceusage/v1"
 )
@@ -35,7 +35,7 @@ func (a *ApiUsage) EnableServices(services []string) error {
 	if err != nil {
 		return err
 	}
-	log.Printf("APIs enabled successfully: %s\n", strings.Join(services, ", "))
+	common.Logger(a.ctx).Info(fmt.Sprintf("APIs enabled successfully: %s", strings.Join(services, ", ")))
 	return nil
 }
 
@@ -45,6 +45,6 @@ func (a *ApiUsage) EnableService(service string) error {
 	if err != nil {
 		return fmt.Errorf("error enabling API %s: %v", service, err)
 	}
-	log.Printf("API %s enabled successfully\n", service)
+	common.Logger(a.ctx).Info(fmt.Sprintf("API %s enabled successfully", service))
 	return nil
 }
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 	"os"
 	"strings"
@@ -14,11 +13,14 @@ import (
 	"cloud.google.com/go/deploy/apiv1/deploypb"
 	"github.com/entigolabs/entigo-infralib-agent/argocd"
 	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/metrics"
 	"github.com/entigolabs/entigo-infralib-agent/model"
 	"github.com/entigolabs/entigo-infralib-agent/terraform"
+	"github.com/entigolabs/entigo-infralib-agent/tracing"
 	"github.com/entigolabs/entigo-infralib-agent/util"
 	"github.com/google/uuid"
 	"github.com/googleapis/gax-go/v2/apierror"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc/codes"
@@ -79,6 +81,18 @@ func (p *Pipeline) SetCampaignId(id string) {
 	}
 }
 
+func (p *Pipeline) SetSopsAgeKey(enabled bool) {
+	if p.builder != nil {
+		p.builder.SetSopsAgeKey(enabled)
+	}
+}
+
+func (p *Pipeline) SetRedactValues(enabled bool) {
+	if p.builder != nil {
+		p.builder.SetRedactValues(enabled)
+	}
+}
+
 func (p *Pipeline) SetPipelineIndex(index int) {
 	if p.builder != nil {
 		p.builder.SetPipelineIndex(index)
@@ -185,7 +199,7 @@ func (p *Pipeline) deleteTargets() error {
 	if err != nil {
 		return err
 	}
-	log.Printf("Deleted target %s-plan\n", p.cloudPrefix)
+	common.Logger(p.ctx).Info(fmt.Sprintf("Deleted target %s-plan", p.cloudPrefix))
 	targetOp, err = p.client.DeleteTarget(p.ctx, &deploypb.DeleteTargetRequest{
 		Name:         fmt.Sprintf("projects/%s/locations/%s/targets/%s-apply", p.projectId, p.location, p.cloudPrefix),
 		AllowMissing: true,
@@ -197,13 +211,13 @@ func (p *Pipeline) deleteTargets() error {
 	if err != nil {
 		return err
 	}
-	log.Printf("Deleted target %s-apply\n", p.cloudPrefix)
+	common.Logger(p.ctx).Info(fmt.Sprintf("Deleted target %s-apply", p.cloudPrefix))
 	return nil
 }
 
-func (p *Pipeline) CreatePipeline(projectName, stepName string, step model.Step, bucket model.Bucket, _ map[string]model.SourceAuth) (*string, error) {
+func (p *Pipeline) CreatePipeline(ctx context.Context, projectName, stepName string, step model.Step, bucket model.Bucket, _ map[string]model.SourceAuth) (*string, error) {
 	planCommand, applyCommand := model.GetCommands(step.Type)
-	bucketMeta, err := bucket.GetRepoMetadata()
+	bucketMeta, err := bucket.GetRepoMetadata(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -216,7 +230,7 @@ func (p *Pipeline) CreatePipeline(projectName, stepName string, step model.Step,
 	if err != nil {
 		return nil, err
 	}
-	err = bucket.PutFile(fmt.Sprintf(bucketFileFormat, stepName), tarContent)
+	err = bucket.PutFile(ctx, fmt.Sprintf(bucketFileFormat, stepName), tarContent)
 	if err != nil {
 		return nil, err
 	}
@@ -224,7 +238,7 @@ func (p *Pipeline) CreatePipeline(projectName, stepName string, step model.Step,
 	if err != nil {
 		return nil, err
 	}
-	return p.StartPipelineExecution(projectName, stepName, step, bucketMeta.Name)
+	return p.StartPipelineExecution(ctx, projectName, stepName, step, bucketMeta.Name)
 }
 
 func (p *Pipeline) DeletePipeline(projectName string) error {
@@ -252,7 +266,7 @@ func (p *Pipeline) DeletePipeline(projectName string) error {
 	if err != nil {
 		return err
 	}
-	log.Printf("Deleted delivery pipeline %s\n", projectName)
+	common.Logger(p.ctx).Info(fmt.Sprintf("Deleted delivery pipeline %s", projectName))
 	return nil
 }
 
@@ -307,7 +321,7 @@ func (p *Pipeline) createDeliveryPipeline(pipelineName string, firstCommand, sec
 	if err != nil {
 		return err
 	}
-	log.Printf("Created delivery pipeline %s\n", pipelineName)
+	common.Logger(p.ctx).Info(fmt.Sprintf("Created delivery pipeline %s", pipelineName))
 	return nil
 }
 
@@ -350,7 +364,7 @@ func (p *Pipeline) getChanges(pipelineName string, pipeChanges *model.PipelineCh
 	}
 	switch stepType {
 	case model.StepTypeTerraform:
-		return p.getPipelineChanges(pipelineName, jobName, executionName, terraform.ParseLogChanges)
+		return p.getPipelineChanges(pipelineName, jobName, executionName, terraform.NewLogChangesParser())
 	case model.StepTypeArgoCD:
 		return p.getPipelineChanges(pipelineName, jobName, executionName, argocd.ParseLogChanges)
 	}
@@ -384,7 +398,7 @@ func (p *Pipeline) CreateAgentPipelines(_ string, pipelineName string, _ string,
 		return nil
 	}
 	job := fmt.Sprintf("%s-%s", pipelineName, common.RunCommand)
-	_, err := p.builder.executeJob(job, false)
+	_, err := p.builder.executeJob(p.ctx, job, false)
 	return err
 }
 
@@ -399,21 +413,21 @@ func (p *Pipeline) UpdatePipeline(projectName string, stepName string, step mode
 	if err != nil {
 		return err
 	}
-	err = p.storage.PutFile(fmt.Sprintf(bucketFileFormat, stepName), tarContent)
+	err = p.storage.PutFile(p.ctx, fmt.Sprintf(bucketFileFormat, stepName), tarContent)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (p *Pipeline) StartPipelineExecution(pipelineName string, stepName string, _ model.Step, bucket string) (*string, error) {
-	log.Printf("Starting pipeline %s\n", pipelineName)
+func (p *Pipeline) StartPipelineExecution(ctx context.Context, pipelineName string, stepName string, _ model.Step, bucket string) (*string, error) {
+	common.Logger(ctx).Info(fmt.Sprintf("Starting pipeline %s", pipelineName))
 	prefix := pipelineName
 	if len(prefix) > 26 { // Max length for id is 63, uuid v4 is 36 chars plus hyphen, 63 - 37 = 26
 		prefix = prefix[:26]
 	}
 	releaseId := fmt.Sprintf("%s-%s", prefix, uuid.New().String())
-	releaseOp, err := p.client.CreateRelease(p.ctx, &deploypb.CreateReleaseRequest{
+	releaseOp, err := p.client.CreateRelease(ctx, &deploypb.CreateReleaseRequest{
 		Parent:    fmt.Sprintf("projects/%s/locations/%s/deliveryPipelines/%s", p.projectId, p.location, pipelineName),
 		ReleaseId: releaseId,
 		Release: &deploypb.Release{
@@ -424,26 +438,30 @@ func (p *Pipeline) StartPipelineExecution(pipelineName string, stepName string,
 	if err != nil {
 		return nil, err
 	}
-	_, err = releaseOp.Wait(p.ctx) // Wait for release creation, otherwise wait loop will fail
+	_, err = releaseOp.Wait(ctx) // Wait for release creation, otherwise wait loop will fail
 	return &releaseId, err
 }
 
 func (p *Pipeline) StartAgentExecution(pipelineName string) error {
-	_, err := p.builder.executeJob(pipelineName, false)
+	_, err := p.builder.executeJob(p.ctx, pipelineName, false)
 	return err
 }
 
-func (p *Pipeline) WaitPipelineExecution(pipelineName string, projectName string, releaseId *string, autoApprove bool, step model.Step, approve model.ManualApprove) error {
+func (p *Pipeline) WaitPipelineExecution(ctx context.Context, pipelineName string, projectName string, releaseId *string, autoApprove bool, step model.Step, approve model.ManualApprove) (err error) {
+	ctx, span := tracing.Start(ctx, "CloudDeploy.WaitPipelineExecution", attribute.String("pipeline", pipelineName),
+		attribute.String("step", step.Name))
+	defer tracing.End(span, &err)
 	if releaseId == nil {
 		return fmt.Errorf("release id is nil")
 	}
-	log.Printf("Waiting for pipeline %s to complete\n", pipelineName)
-	err := p.waitForReleaseRender(pipelineName, *releaseId)
+	defer metrics.ApprovalPending(step.Name, false)
+	p.stepLogger(step).Info(fmt.Sprintf("Waiting for pipeline %s to complete", pipelineName))
+	err = p.waitForReleaseRender(pipelineName, *releaseId)
 	if err != nil {
 		return err
 	}
 	rolloutId := fmt.Sprintf("%s-rollout-plan", pipelineName)
-	rollout, err := p.client.CreateRollout(p.ctx, &deploypb.CreateRolloutRequest{
+	rollout, err := p.client.CreateRollout(ctx, &deploypb.CreateRolloutRequest{
 		Parent:    fmt.Sprintf("projects/%s/locations/%s/deliveryPipelines/%s/releases/%s", p.projectId, p.location, pipelineName, *releaseId),
 		RolloutId: rolloutId,
 		Rollout: &deploypb.Rollout{
@@ -453,14 +471,14 @@ func (p *Pipeline) WaitPipelineExecution(pipelineName string, projectName string
 	if err != nil {
 		return err
 	}
-	slog.Debug(fmt.Sprintf("Waiting for pipeline %s rollout %s to finish\n", pipelineName, rolloutId))
+	p.stepLogger(step).Debug(fmt.Sprintf("Waiting for pipeline %s rollout %s to finish\n", pipelineName, rolloutId))
 	err = p.waitForPlanRollout(rollout)
 	if err != nil {
 		return err
 	}
 	planCommand, applyCommand := model.GetCommands(step.Type)
 	planJob := fmt.Sprintf("%s-%s", projectName, planCommand)
-	executionName, err := p.builder.executeJob(planJob, true)
+	executionName, err := p.builder.executeJob(ctx, planJob, true)
 	if err != nil {
 		return err
 	}
@@ -468,14 +486,20 @@ func (p *Pipeline) WaitPipelineExecution(pipelineName string, projectName string
 	if err != nil {
 		return err
 	}
+	if pipeChanges != nil {
+		metrics.PlanChanges(step.Name, *pipeChanges)
+		if p.manager != nil {
+			p.manager.PlanChanges(pipelineName, step.Name, *pipeChanges)
+		}
+	}
 	if pipeChanges != nil && util.ShouldStopPipeline(*pipeChanges, step.Approve, approve) {
-		log.Printf("Stopping pipeline %s\n", pipelineName)
-		_, err = p.client.AbandonRelease(p.ctx, &deploypb.AbandonReleaseRequest{
+		p.stepLogger(step).Info(fmt.Sprintf("Stopping pipeline %s", pipelineName))
+		_, err = p.client.AbandonRelease(ctx, &deploypb.AbandonReleaseRequest{
 			Name: fmt.Sprintf("projects/%s/locations/%s/deliveryPipelines/%s/releases/%s", p.projectId,
 				p.location, pipelineName, *releaseId),
 		})
 		if err != nil {
-			slog.Warn(common.PrefixWarning(fmt.Sprintf("Couldn't stop pipeline %s, please stop manually: %s", pipelineName, err.Error())))
+			p.stepLogger(step).Warn(common.PrefixWarning(fmt.Sprintf("Couldn't stop pipeline %s, please stop manually: %s", pipelineName, err.Error())))
 		}
 		if step.Approve == model.ApproveReject || approve == model.ManualApproveReject {
 			return fmt.Errorf("stopped because step approve type is 'reject'")
@@ -483,7 +507,7 @@ func (p *Pipeline) WaitPipelineExecution(pipelineName string, projectName string
 		return nil
 	}
 	rolloutId = fmt.Sprintf("%s-rollout-apply", pipelineName)
-	rollout, err = p.client.CreateRollout(p.ctx, &deploypb.CreateRolloutRequest{
+	rollout, err = p.client.CreateRollout(ctx, &deploypb.CreateRolloutRequest{
 		Parent:    fmt.Sprintf("projects/%s/locations/%s/deliveryPipelines/%s/releases/%s", p.projectId, p.location, pipelineName, *releaseId),
 		RolloutId: rolloutId,
 		Rollout: &deploypb.Rollout{
@@ -493,21 +517,21 @@ func (p *Pipeline) WaitPipelineExecution(pipelineName string, projectName string
 	if err != nil {
 		return err
 	}
-	slog.Debug(fmt.Sprintf("Waiting for pipeline %s rollout %s to finish\n", pipelineName, rolloutId))
+	p.stepLogger(step).Debug(fmt.Sprintf("Waiting for pipeline %s rollout %s to finish\n", pipelineName, rolloutId))
 	err = p.waitForApplyRollout(rollout, pipelineName, step, executionName, autoApprove, pipeChanges, approve)
 	if err != nil {
 		return err
 	}
-	_, err = p.builder.executeJob(fmt.Sprintf("%s-%s", projectName, applyCommand), true)
+	_, err = p.builder.executeJob(ctx, fmt.Sprintf("%s-%s", projectName, applyCommand), true)
 	return err
 }
 
 func (p *Pipeline) StartDestroyExecution(projectName string, _ model.Step) error {
-	_, err := p.builder.executeJob(fmt.Sprintf("%s-plan-destroy", projectName), true)
+	_, err := p.builder.executeJob(p.ctx, fmt.Sprintf("%s-plan-destroy", projectName), true)
 	if err != nil {
 		return err
 	}
-	_, err = p.builder.executeJob(fmt.Sprintf("%s-apply-destroy", projectName), true)
+	_, err = p.builder.executeJob(p.ctx, fmt.Sprintf("%s-apply-destroy", projectName), true)
 	return err
 }
 
@@ -579,20 +603,21 @@ func (p *Pipeline) waitForApplyRollout(rolloutOp *deploy.CreateRolloutOperation,
 					continue
 				}
 				if executionName == "" {
-					log.Println("Execution name not found, please approve manually")
+					p.stepLogger(step).Info("Execution name not found, please approve manually")
 				} else if util.ShouldApprovePipeline(*pipeChanges, step.Approve, autoApprove, approve) {
 					_, err = p.client.ApproveRollout(p.ctx, &deploypb.ApproveRolloutRequest{
 						Name:     rollout.GetName(),
 						Approved: true,
 					})
 					if err != nil {
-						log.Printf("Failed to approve rollout, please approve manually: %s", err)
+						p.stepLogger(step).Info(fmt.Sprintf("Failed to approve rollout, please approve manually: %s", err))
 					} else {
-						log.Printf("Approved %s\n", pipelineName)
+						p.stepLogger(step).Info(fmt.Sprintf("Approved %s", pipelineName))
 						approved = true
 					}
 				} else {
-					log.Printf("Waiting for manual approval of pipeline %s\n", pipelineName)
+					p.stepLogger(step).Info(fmt.Sprintf("Waiting for manual approval of pipeline %s", pipelineName))
+					metrics.ApprovalPending(step.Name, true)
 					if !notified && p.manager != nil {
 						p.manager.ManualApproval(pipelineName, step.Name, *pipeChanges, p.getLink(pipelineName))
 						notified = true
@@ -616,3 +641,7 @@ func (p *Pipeline) waitForApplyRollout(rolloutOp *deploy.CreateRolloutOperation,
 		}
 	}
 }
+
+func (p *Pipeline) stepLogger(step model.Step) *slog.Logger {
+	return common.Logger(p.ctx).With(common.StepAttr, step.Name)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"log/slog"
 	"os"
 	"strings"
@@ -217,27 +216,27 @@ func (g *gcloudService) GetResources() (model.Resources, error) {
 func (g *gcloudService) DeleteResources(deleteBucket, deleteServiceAccount bool) error {
 	scheduler, err := NewScheduler(g.ctx, g.options, g.projectId, g.location, g.cloudPrefix)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to create scheduler service: %s", err)))
+		common.Logger(g.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to create scheduler service: %s", err)))
 	} else {
 		err = scheduler.deleteUpdateSchedule()
 		if err != nil {
-			slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete update schedule: %s", err)))
+			common.Logger(g.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete update schedule: %s", err)))
 		}
 	}
 	agentPrefix := model.GetAgentPrefix(g.cloudPrefix)
 	agentJob := model.GetAgentProjectName(agentPrefix, common.RunCommand)
 	err = g.resources.GetBuilder().(*Builder).deleteJob(agentJob)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent job %s: %s", agentJob, err)))
+		common.Logger(g.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent job %s: %s", agentJob, err)))
 	}
 	agentJob = model.GetAgentProjectName(agentPrefix, common.UpdateCommand)
 	err = g.resources.GetBuilder().(*Builder).deleteJob(agentJob)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent job %s: %s", agentJob, err)))
+		common.Logger(g.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete agent job %s: %s", agentJob, err)))
 	}
 	err = g.resources.GetPipeline().(*Pipeline).deleteTargets()
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete pipeline targets: %s", err)))
+		common.Logger(g.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete pipeline targets: %s", err)))
 	}
 	iam, err := NewIAM(g.ctx, g.options, g.projectId)
 	if err != nil {
@@ -249,19 +248,19 @@ func (g *gcloudService) DeleteResources(deleteBucket, deleteServiceAccount bool)
 	}
 	err = iam.DeleteServiceAccount(accountName)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete service account %s: %s", accountName, err)))
+		common.Logger(g.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete service account %s: %s", accountName, err)))
 	}
 	if deleteServiceAccount {
 		g.DeleteServiceAccount(iam)
 	}
 	if !deleteBucket {
-		log.Printf("Terraform state bucket %s will not be deleted, delete it manually if needed\n", g.resources.GetBucketName())
+		common.Logger(g.ctx).Info(fmt.Sprintf("Terraform state bucket %s will not be deleted, delete it manually if needed", g.resources.GetBucketName()))
 		return nil
 	}
-	err = g.resources.GetBucket().Delete()
+	err = g.resources.GetBucket().Delete(g.ctx)
 	if err != nil {
 		bucket := fmt.Sprintf("%s-%s", g.cloudPrefix, g.projectId)
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete storage bucket %s: %s", bucket, err)))
+		common.Logger(g.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete storage bucket %s: %s", bucket, err)))
 	}
 	return nil
 }
@@ -303,7 +302,7 @@ func (g *gcloudService) createServiceAccount(iam *IAM) (string, error) {
 		return "", fmt.Errorf("failed to add roles to project: %s", err)
 	}
 	if created {
-		log.Println("Waiting 60 seconds for service account permissions to be applied...")
+		common.Logger(g.ctx).Info("Waiting 60 seconds for service account permissions to be applied...")
 		time.Sleep(60 * time.Second)
 	}
 	nameParts := strings.Split(account.Name, "/")
@@ -318,7 +317,7 @@ func (g *gcloudService) createSchedule(schedule model.Schedule, serviceAccount s
 	updateSchedule, err := scheduler.getUpdateSchedule()
 	if err != nil {
 		if schedule.UpdateCron == "" {
-			slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to get schedule %s: %s",
+			common.Logger(g.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to get schedule %s: %s",
 				getScheduleName(g.cloudPrefix, common.UpdateCommand), err)))
 			return nil
 		}
@@ -387,14 +386,14 @@ func (g *gcloudService) CreateServiceAccount(SAFlags common.ServiceAccount) erro
 	}
 	if !created {
 		if !SAFlags.RotateCredentials {
-			log.Printf("Service account %s already exists, use rotate-credentials flag to generate new credentials\n", username)
+			common.Logger(g.ctx).Info(fmt.Sprintf("Service account %s already exists, use rotate-credentials flag to generate new credentials", username))
 			return nil
 		}
 		deleted, err := iam.DeleteServiceAccountKeys(account.Name)
 		if err != nil {
 			return fmt.Errorf("failed to remove existing service account keys: %s", err)
 		}
-		log.Printf("Deleted %d previous keys for service account %s\n", deleted, username)
+		common.Logger(g.ctx).Info(fmt.Sprintf("Deleted %d previous keys for service account %s", deleted, username))
 	}
 	key, err := iam.CreateServiceAccountKey(account.Name)
 	if err != nil {
@@ -420,7 +419,7 @@ func (g *gcloudService) createServiceAccountImpersonation(SAFlags common.Service
 	if containsPrincipal(principals, SAFlags.TrustRole) {
 		return nil
 	} else if len(principals) > 0 && !SAFlags.RotateCredentials {
-		slog.Error(common.PrefixError(fmt.Errorf("service account %s has different trust principal, use rotate-credentials flag to update it", accountName)))
+		common.Logger(g.ctx).Error(common.PrefixError(fmt.Errorf("service account %s has different trust principal, use rotate-credentials flag to update it", accountName)))
 		return nil
 	}
 	return grantImpersonation(iam, accountName, SAFlags.TrustRole)
@@ -430,7 +429,7 @@ func grantImpersonation(iam *IAM, accountName string, principal string) error {
 	if err := iam.GrantImpersonation(accountName, principal); err != nil {
 		return fmt.Errorf("failed to grant impersonation on service account %s: %s", accountName, err)
 	}
-	log.Printf("Granted impersonation on service account %s to %s\n", accountName, principal)
+	slog.Info(fmt.Sprintf("Granted impersonation on service account %s to %s", accountName, principal))
 	return nil
 }
 
@@ -451,9 +450,9 @@ func removeKeysIfNeeded(iam *IAM, accountName string, remove bool) error {
 		return fmt.Errorf("failed to delete service account keys: %s", err)
 	}
 	if deleted == 0 {
-		log.Printf("Service account %s has no keys", accountName)
+		slog.Info(fmt.Sprintf("Service account %s has no keys", accountName))
 	} else {
-		log.Printf("Deleted %d previous keys for service account %s\n", deleted, accountName)
+		slog.Info(fmt.Sprintf("Deleted %d previous keys for service account %s", deleted, accountName))
 	}
 	return nil
 }
@@ -471,16 +470,16 @@ func (g *gcloudService) DeleteServiceAccount(iam *IAM) {
 	username := fmt.Sprintf("%s-sa-%s", g.cloudPrefix, g.location)
 	err := iam.DeleteServiceAccount(username)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete service account %s: %s", username, err)))
+		common.Logger(g.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete service account %s: %s", username, err)))
 	}
 	keyParam := fmt.Sprintf("entigo-infralib-%s-key", username)
-	err = g.resources.SSM.DeleteParameter(keyParam)
+	err = g.resources.SSM.DeleteParameter(g.ctx, keyParam)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete secret %s: %s", keyParam, err)))
+		common.Logger(g.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete secret %s: %s", keyParam, err)))
 	}
 }
 
 func (g *gcloudService) AddEncryption(_ string, _ map[string]model.TFOutput) error {
-	slog.Warn(common.PrefixWarning("Encryption is not yet supported for GCP"))
+	common.Logger(g.ctx).Warn(common.PrefixWarning("Encryption is not yet supported for GCP"))
 	return nil
 }
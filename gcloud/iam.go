@@ -4,11 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"google.golang.org/api/cloudresourcemanager/v1"
 	"google.golang.org/api/googleapi"
 	iamv1 "google.golang.org/api/iam/v1"
@@ -70,7 +71,7 @@ func (iam *IAM) GetOrCreateServiceAccount(name, displayName string) (*iamv1.Serv
 	if err != nil {
 		return nil, false, err
 	}
-	log.Printf("Created new service account: %s@%s.iam.gserviceaccount.com", name, iam.projectId)
+	common.Logger(iam.ctx).Info(fmt.Sprintf("Created new service account: %s@%s.iam.gserviceaccount.com", name, iam.projectId))
 	return account, true, nil
 }
 
@@ -200,7 +201,7 @@ func (iam *IAM) DeleteServiceAccount(name string) error {
 		}
 		return err
 	}
-	log.Printf("Deleted service account: %s", name)
+	common.Logger(iam.ctx).Info(fmt.Sprintf("Deleted service account: %s", name))
 	return nil
 }
 
@@ -313,7 +314,7 @@ func retry(execute func() error) error {
 			return err
 		}
 		wait := time.Duration(1<<i) * time.Second //  2 to the power of i seconds
-		log.Printf("Service Account not yet propagated, retrying in %v... (Attempt %d/%d)", wait, i+1, maxRetries)
+		slog.Info(fmt.Sprintf("Service Account not yet propagated, retrying in %v... (Attempt %d/%d)", wait, i+1, maxRetries))
 		time.Sleep(wait)
 	}
 	return lastErr
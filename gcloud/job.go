@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"log"
 	"os"
 	"strconv"
 	"strings"
@@ -15,6 +14,7 @@ import (
 	"cloud.google.com/go/run/apiv2/runpb"
 	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/tracing"
 	"github.com/entigolabs/entigo-infralib-agent/util"
 	"github.com/googleapis/gax-go/v2/apierror"
 	"google.golang.org/api/option"
@@ -38,12 +38,22 @@ type Builder struct {
 	cloudPrefix    string
 	campaignId     string
 	pipelineIndex  string
+	sopsAgeKey     bool
+	redactValues   bool
 }
 
 func (b *Builder) SetCampaignId(id string) {
 	b.campaignId = id
 }
 
+func (b *Builder) SetSopsAgeKey(enabled bool) {
+	b.sopsAgeKey = enabled
+}
+
+func (b *Builder) SetRedactValues(enabled bool) {
+	b.redactValues = enabled
+}
+
 func (b *Builder) SetPipelineIndex(index int) {
 	b.pipelineIndex = strconv.Itoa(index)
 }
@@ -405,8 +415,8 @@ func (b *Builder) updateJob(projectName string, stepName string, step model.Step
 	return err
 }
 
-func (b *Builder) executeJob(projectName string, wait bool) (string, error) {
-	log.Printf("Executing job %s\n", projectName)
+func (b *Builder) executeJob(ctx context.Context, projectName string, wait bool) (string, error) {
+	common.Logger(ctx).Info(fmt.Sprintf("Executing job %s", projectName))
 	job, err := b.getJob(projectName)
 	if err != nil {
 		return "", err
@@ -428,6 +438,12 @@ func (b *Builder) executeJob(projectName string, wait bool) (string, error) {
 			Values: &runpb.EnvVar_Value{Value: b.pipelineIndex},
 		})
 	}
+	if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+		envOverrides = append(envOverrides, &runpb.EnvVar{
+			Name:   model.TraceParentEnv,
+			Values: &runpb.EnvVar_Value{Value: traceParent},
+		})
+	}
 	if len(envOverrides) > 0 {
 		req.Overrides = &runpb.RunJobRequest_Overrides{
 			ContainerOverrides: []*runpb.RunJobRequest_Overrides_ContainerOverride{{
@@ -435,14 +451,14 @@ func (b *Builder) executeJob(projectName string, wait bool) (string, error) {
 			}},
 		}
 	}
-	jobOp, err := b.client.RunJob(b.ctx, req)
+	jobOp, err := b.client.RunJob(ctx, req)
 	if err != nil {
 		return "", err
 	}
 	if !wait {
 		return "", err
 	}
-	execution, err := jobOp.Wait(b.ctx)
+	execution, err := jobOp.Wait(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -515,6 +531,16 @@ func (b *Builder) getEnvironmentVariables(projectName string, stepName string, s
 			SecretKeyRef: &runv1.SecretKeySelector{Key: "latest", Name: model.WrapperConfigSecretName(b.cloudPrefix)},
 		}})
 	}
+	if b.sopsAgeKey {
+		envVars = append(envVars, &runv1.EnvVar{Name: model.SopsAgeKeyEnv, ValueFrom: &runv1.EnvVarSource{
+			SecretKeyRef: &runv1.SecretKeySelector{Key: "latest", Name: model.SopsAgeKeySecretName(b.cloudPrefix)},
+		}})
+	}
+	if b.redactValues {
+		envVars = append(envVars, &runv1.EnvVar{Name: model.RedactValuesEnv, ValueFrom: &runv1.EnvVarSource{
+			SecretKeyRef: &runv1.SecretKeySelector{Key: "latest", Name: model.RedactValuesSecretName(b.cloudPrefix)},
+		}})
+	}
 	for source := range authSources {
 		hash := util.HashCode(source)
 		envVars = append(envVars, &runv1.EnvVar{Name: fmt.Sprintf(model.GitUsernameEnvFormat, hash), ValueFrom: &runv1.EnvVarSource{
@@ -540,6 +566,14 @@ func (b *Builder) getJobEnvironmentVariables(projectName, stepName string, step
 		envVars = append(envVars, &runpb.EnvVar{Name: model.WrapperConfigEnv,
 			Values: &runpb.EnvVar_ValueSource{ValueSource: &runpb.EnvVarSource{SecretKeyRef: &runpb.SecretKeySelector{Version: "latest", Secret: model.WrapperConfigSecretName(b.cloudPrefix)}}}})
 	}
+	if b.sopsAgeKey {
+		envVars = append(envVars, &runpb.EnvVar{Name: model.SopsAgeKeyEnv,
+			Values: &runpb.EnvVar_ValueSource{ValueSource: &runpb.EnvVarSource{SecretKeyRef: &runpb.SecretKeySelector{Version: "latest", Secret: model.SopsAgeKeySecretName(b.cloudPrefix)}}}})
+	}
+	if b.redactValues {
+		envVars = append(envVars, &runpb.EnvVar{Name: model.RedactValuesEnv,
+			Values: &runpb.EnvVar_ValueSource{ValueSource: &runpb.EnvVarSource{SecretKeyRef: &runpb.SecretKeySelector{Version: "latest", Secret: model.RedactValuesSecretName(b.cloudPrefix)}}}})
+	}
 	for source := range authSources {
 		hash := util.HashCode(source)
 		envVars = append(envVars, &runpb.EnvVar{Name: fmt.Sprintf(model.GitUsernameEnvFormat, hash),
@@ -585,7 +619,7 @@ func (b *Builder) deleteJob(name string) error {
 	}
 	_, err = jobOp.Wait(b.ctx)
 	if err == nil {
-		log.Printf("Deleted job %s\n", name)
+		common.Logger(b.ctx).Info(fmt.Sprintf("Deleted job %s", name))
 	}
 	return err
 }
@@ -4,8 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"log/slog"
 	"time"
 
 	logging "cloud.google.com/go/logging/apiv2"
@@ -67,10 +65,10 @@ func (l *Logging) GetLogRow(logIterator *logging.LogEntryIterator) (string, erro
 			}
 			if status.Code(err) == codes.ResourceExhausted {
 				if first {
-					slog.Error(common.PrefixError(err))
+					common.Logger(l.ctx).Error(common.PrefixError(err))
 					first = false
 				}
-				log.Printf("Log api resource exhausted, retrying in %d seconds", backoff)
+				common.Logger(l.ctx).Info(fmt.Sprintf("Log api resource exhausted, retrying in %d seconds", backoff))
 				time.Sleep(time.Duration(backoff) * time.Second)
 				backoff = util.MinInt(16, backoff*2)
 				continue
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"strings"
 
 	scheduler "cloud.google.com/go/scheduler/apiv1"
@@ -114,7 +113,7 @@ func (s *Scheduler) createUpdateSchedule(cron, agentJob, serviceAccount string)
 		Job:    s.job(cron, agentJob, serviceAccount),
 	})
 	if err == nil {
-		log.Printf("Created Cloud Scheduler job: %s\n", s.updateScheduleName)
+		common.Logger(s.ctx).Info(fmt.Sprintf("Created Cloud Scheduler job: %s", s.updateScheduleName))
 	}
 	return err
 }
@@ -122,7 +121,7 @@ func (s *Scheduler) createUpdateSchedule(cron, agentJob, serviceAccount string)
 func (s *Scheduler) deleteUpdateSchedule() error {
 	err := s.client.DeleteJob(s.ctx, &schedulerpb.DeleteJobRequest{Name: s.updateSchedule})
 	if err == nil {
-		log.Printf("Deleted Cloud Scheduler job: %s\n", s.updateScheduleName)
+		common.Logger(s.ctx).Info(fmt.Sprintf("Deleted Cloud Scheduler job: %s", s.updateScheduleName))
 		return nil
 	}
 	var apiError *apierror.APIError
@@ -137,7 +136,7 @@ func (s *Scheduler) updateUpdateSchedule(cron, agentJob, serviceAccount string)
 		Job: s.job(cron, agentJob, serviceAccount),
 	})
 	if err == nil {
-		log.Printf("Updated Cloud Scheduler job: %s\n", s.updateScheduleName)
+		common.Logger(s.ctx).Info(fmt.Sprintf("Updated Cloud Scheduler job: %s", s.updateScheduleName))
 	}
 	return err
 }
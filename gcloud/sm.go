@@ -4,13 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"strings"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/tracing"
 	"github.com/googleapis/gax-go/v2/apierror"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	"google.golang.org/grpc/codes"
@@ -35,12 +37,14 @@ func NewSM(ctx context.Context, options []option.ClientOption, projectId string)
 }
 
 func (s *sm) AddEncryptionKeyId(_ string) {
-	slog.Warn("AddEncryptionKeyId is not supported for GCP")
+	common.Logger(s.ctx).Warn("AddEncryptionKeyId is not supported for GCP")
 }
 
-func (s *sm) GetParameter(name string) (*model.Parameter, error) {
+func (s *sm) GetParameter(ctx context.Context, name string) (_ *model.Parameter, err error) {
+	ctx, span := tracing.Start(ctx, "SecretManager.GetParameter", attribute.String("name", name))
+	defer tracing.End(span, &err)
 	name = strings.ReplaceAll(strings.TrimLeft(name, "/"), "/", "-")
-	result, err := s.client.AccessSecretVersion(s.ctx, &secretmanagerpb.AccessSecretVersionRequest{
+	result, err := s.client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{
 		Name: fmt.Sprintf("projects/%s/secrets/%s/versions/latest", s.projectId, name),
 	})
 	if err != nil {
@@ -59,8 +63,10 @@ func (s *sm) GetParameter(name string) (*model.Parameter, error) {
 	}, nil
 }
 
-func (s *sm) ParameterExists(name string) (bool, error) {
-	_, err := s.GetParameter(name)
+func (s *sm) ParameterExists(ctx context.Context, name string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "SecretManager.ParameterExists", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	_, err = s.GetParameter(ctx, name)
 	if err != nil {
 		var notFoundErr *model.ParameterNotFoundError
 		if errors.As(err, &notFoundErr) {
@@ -71,8 +77,10 @@ func (s *sm) ParameterExists(name string) (bool, error) {
 	return true, nil
 }
 
-func (s *sm) PutParameter(name string, value string) error {
-	param, err := s.GetParameter(name)
+func (s *sm) PutParameter(ctx context.Context, name string, value string) (err error) {
+	ctx, span := tracing.Start(ctx, "SecretManager.PutParameter", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	param, err := s.GetParameter(ctx, name)
 	if err != nil {
 		var notFoundErr *model.ParameterNotFoundError
 		if !errors.As(err, &notFoundErr) {
@@ -83,12 +91,12 @@ func (s *sm) PutParameter(name string, value string) error {
 		return nil
 	}
 	if param == nil {
-		err = s.createSecret(name)
+		err = s.createSecret(ctx, name)
 		if err != nil {
 			return err
 		}
 	}
-	_, err = s.client.AddSecretVersion(s.ctx, &secretmanagerpb.AddSecretVersionRequest{
+	_, err = s.client.AddSecretVersion(ctx, &secretmanagerpb.AddSecretVersionRequest{
 		Parent: fmt.Sprintf("projects/%s/secrets/%s", s.projectId, name),
 		Payload: &secretmanagerpb.SecretPayload{
 			Data: []byte(value),
@@ -97,8 +105,8 @@ func (s *sm) PutParameter(name string, value string) error {
 	return err
 }
 
-func (s *sm) createSecret(name string) error {
-	_, err := s.client.CreateSecret(s.ctx, &secretmanagerpb.CreateSecretRequest{
+func (s *sm) createSecret(ctx context.Context, name string) error {
+	_, err := s.client.CreateSecret(ctx, &secretmanagerpb.CreateSecretRequest{
 		Parent:   fmt.Sprintf("projects/%s", s.projectId),
 		SecretId: name,
 		Secret: &secretmanagerpb.Secret{
@@ -111,8 +119,10 @@ func (s *sm) createSecret(name string) error {
 	return err
 }
 
-func (s *sm) DeleteParameter(name string) error {
-	err := s.client.DeleteSecret(s.ctx, &secretmanagerpb.DeleteSecretRequest{
+func (s *sm) DeleteParameter(ctx context.Context, name string) (err error) {
+	ctx, span := tracing.Start(ctx, "SecretManager.DeleteParameter", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	err = s.client.DeleteSecret(ctx, &secretmanagerpb.DeleteSecretRequest{
 		Name: fmt.Sprintf("projects/%s/secrets/%s", s.projectId, name),
 	})
 	if err != nil {
@@ -125,9 +135,11 @@ func (s *sm) DeleteParameter(name string) error {
 	return nil
 }
 
-func (s *sm) ListParameters() ([]string, error) {
+func (s *sm) ListParameters(ctx context.Context) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "SecretManager.ListParameters")
+	defer tracing.End(span, &err)
 	var keys []string
-	secrets := s.client.ListSecrets(s.ctx, &secretmanagerpb.ListSecretsRequest{
+	secrets := s.client.ListSecrets(ctx, &secretmanagerpb.ListSecretsRequest{
 		Parent: fmt.Sprintf("projects/%s", s.projectId),
 		Filter: fmt.Sprintf("labels.%s:%s", model.ResourceTagKey, model.ResourceTagValue),
 	})
@@ -145,10 +157,14 @@ func (s *sm) ListParameters() ([]string, error) {
 	return keys, nil
 }
 
-func (s *sm) PutSecret(name string, value string) error {
-	return s.PutParameter(name, value)
+func (s *sm) PutSecret(ctx context.Context, name string, value string) (err error) {
+	ctx, span := tracing.Start(ctx, "SecretManager.PutSecret", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	return s.PutParameter(ctx, name, value)
 }
 
-func (s *sm) DeleteSecret(name string) error {
-	return s.DeleteParameter(name)
+func (s *sm) DeleteSecret(ctx context.Context, name string) (err error) {
+	ctx, span := tracing.Start(ctx, "SecretManager.DeleteSecret", attribute.String("name", name))
+	defer tracing.End(span, &err)
+	return s.DeleteParameter(ctx, name)
 }
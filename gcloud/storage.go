@@ -5,15 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/tracing"
 	"github.com/entigolabs/entigo-infralib-agent/util"
 	"github.com/googleapis/gax-go/v2"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
@@ -46,7 +48,7 @@ func NewStorage(ctx context.Context, options []option.ClientOption, projectId st
 }
 
 func (g *GStorage) CreateBucket(skipDelay bool) error {
-	exists, err := g.BucketExists()
+	exists, err := g.BucketExists(g.ctx)
 	if err != nil {
 		return err
 	}
@@ -69,22 +71,24 @@ func (g *GStorage) CreateBucket(skipDelay bool) error {
 		Labels: map[string]string{model.ResourceTagKey: model.ResourceTagValue},
 	})
 	if err == nil {
-		log.Printf("Created GCloud Storage Bucket %s\n", g.bucket)
+		common.Logger(g.ctx).Info(fmt.Sprintf("Created GCloud Storage Bucket %s", g.bucket))
 		g.bucketCreated = aws.Bool(true)
 	}
 	return err
 }
 
-func (g *GStorage) Delete() error {
-	exists, err := g.BucketExists()
+func (g *GStorage) Delete(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "GStorage.Delete")
+	defer tracing.End(span, &err)
+	exists, err := g.BucketExists(ctx)
 	if err != nil {
 		return err
 	}
 	if !exists {
 		return nil
 	}
-	log.Printf("Emptying bucket %s...\n", g.bucket)
-	it := g.bucketHandle.Objects(g.ctx, &storage.Query{
+	common.Logger(ctx).Info(fmt.Sprintf("Emptying bucket %s...", g.bucket))
+	it := g.bucketHandle.Objects(ctx, &storage.Query{
 		Versions: true,
 	})
 	for {
@@ -95,23 +99,25 @@ func (g *GStorage) Delete() error {
 		if err != nil {
 			return err
 		}
-		err = g.bucketHandle.Object(attrs.Name).Generation(attrs.Generation).Delete(g.ctx)
+		err = g.bucketHandle.Object(attrs.Name).Generation(attrs.Generation).Delete(ctx)
 		if err != nil {
 			return err
 		}
 	}
-	err = g.bucketHandle.Delete(g.ctx)
+	err = g.bucketHandle.Delete(ctx)
 	if err == nil {
-		log.Printf("Deleted GCloud Storage Bucket %s\n", g.bucket)
+		common.Logger(ctx).Info(fmt.Sprintf("Deleted GCloud Storage Bucket %s", g.bucket))
 	}
 	return err
 }
 
-func (g *GStorage) BucketExists() (bool, error) {
+func (g *GStorage) BucketExists(ctx context.Context) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "GStorage.BucketExists")
+	defer tracing.End(span, &err)
 	if g.bucketCreated != nil {
 		return *g.bucketCreated, nil
 	}
-	_, err := g.bucketHandle.Attrs(g.ctx)
+	_, err = g.bucketHandle.Attrs(ctx)
 	if err == nil {
 		return true, nil
 	}
@@ -121,11 +127,13 @@ func (g *GStorage) BucketExists() (bool, error) {
 	return false, err
 }
 
-func (g *GStorage) GetRepoMetadata() (*model.RepositoryMetadata, error) {
+func (g *GStorage) GetRepoMetadata(ctx context.Context) (_ *model.RepositoryMetadata, err error) {
+	ctx, span := tracing.Start(ctx, "GStorage.GetRepoMetadata")
+	defer tracing.End(span, &err)
 	if g.repoMetadata != nil {
 		return g.repoMetadata, nil
 	}
-	exists, err := g.BucketExists()
+	exists, err := g.BucketExists(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +147,9 @@ func (g *GStorage) GetRepoMetadata() (*model.RepositoryMetadata, error) {
 	return g.repoMetadata, nil
 }
 
-func (g *GStorage) PutFile(file string, content []byte) error {
+func (g *GStorage) PutFile(ctx context.Context, file string, content []byte) (err error) {
+	ctx, span := tracing.Start(ctx, "GStorage.PutFile", attribute.String("file", file))
+	defer tracing.End(span, &err)
 	obj := g.bucketHandle.Object(file).Retryer(
 		storage.WithBackoff(gax.Backoff{
 			Initial:    1 * time.Second,
@@ -148,7 +158,7 @@ func (g *GStorage) PutFile(file string, content []byte) error {
 		}),
 		storage.WithPolicy(storage.RetryAlways),
 	)
-	writer := obj.NewWriter(g.ctx)
+	writer := obj.NewWriter(ctx)
 	if _, err := writer.Write(content); err != nil {
 		_ = writer.Close()
 		return fmt.Errorf("failed to write content: %w", err)
@@ -156,8 +166,10 @@ func (g *GStorage) PutFile(file string, content []byte) error {
 	return writer.Close()
 }
 
-func (g *GStorage) GetFile(file string) ([]byte, error) {
-	reader, err := g.bucketHandle.Object(file).NewReader(g.ctx)
+func (g *GStorage) GetFile(ctx context.Context, file string) (_ []byte, err error) {
+	ctx, span := tracing.Start(ctx, "GStorage.GetFile", attribute.String("file", file))
+	defer tracing.End(span, &err)
+	reader, err := g.bucketHandle.Object(file).NewReader(ctx)
 	if err != nil {
 		if errors.Is(err, storage.ErrObjectNotExist) {
 			return nil, nil
@@ -170,9 +182,11 @@ func (g *GStorage) GetFile(file string) ([]byte, error) {
 	return io.ReadAll(reader)
 }
 
-func (g *GStorage) DeleteFiles(files []string) error {
+func (g *GStorage) DeleteFiles(ctx context.Context, files []string) (err error) {
+	ctx, span := tracing.Start(ctx, "GStorage.DeleteFiles")
+	defer tracing.End(span, &err)
 	for _, file := range files {
-		err := g.bucketHandle.Object(file).Delete(g.ctx)
+		err := g.bucketHandle.Object(file).Delete(ctx)
 		if err != nil {
 			return err
 		}
@@ -180,8 +194,10 @@ func (g *GStorage) DeleteFiles(files []string) error {
 	return nil
 }
 
-func (g *GStorage) DeleteFile(file string) error {
-	err := g.bucketHandle.Object(file).Delete(g.ctx)
+func (g *GStorage) DeleteFile(ctx context.Context, file string) (err error) {
+	ctx, span := tracing.Start(ctx, "GStorage.DeleteFile", attribute.String("file", file))
+	defer tracing.End(span, &err)
+	err = g.bucketHandle.Object(file).Delete(ctx)
 	if err == nil {
 		return nil
 	}
@@ -191,9 +207,11 @@ func (g *GStorage) DeleteFile(file string) error {
 	return err
 }
 
-func (g *GStorage) CheckFolderExists(folder string) (bool, error) {
-	it := g.bucketHandle.Objects(g.ctx, &storage.Query{Prefix: folder})
-	_, err := it.Next()
+func (g *GStorage) CheckFolderExists(ctx context.Context, folder string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "GStorage.CheckFolderExists", attribute.String("folder", folder))
+	defer tracing.End(span, &err)
+	it := g.bucketHandle.Objects(ctx, &storage.Query{Prefix: folder})
+	_, err = it.Next()
 	if errors.Is(err, iterator.Done) {
 		return false, nil
 	} else if err != nil {
@@ -202,11 +220,13 @@ func (g *GStorage) CheckFolderExists(folder string) (bool, error) {
 	return true, nil
 }
 
-func (g *GStorage) ListFolderFiles(folder string) ([]string, error) {
+func (g *GStorage) ListFolderFiles(ctx context.Context, folder string) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "GStorage.ListFolderFiles", attribute.String("folder", folder))
+	defer tracing.End(span, &err)
 	if !strings.HasSuffix(folder, "/") {
 		folder = folder + "/"
 	}
-	it := g.bucketHandle.Objects(g.ctx, &storage.Query{Prefix: folder})
+	it := g.bucketHandle.Objects(ctx, &storage.Query{Prefix: folder})
 	var files []string
 	for {
 		obj, err := it.Next()
@@ -220,11 +240,13 @@ func (g *GStorage) ListFolderFiles(folder string) ([]string, error) {
 	return files, nil
 }
 
-func (g *GStorage) ListFolderFilesWithExclude(folder string, excludeFolders model.Set[string]) ([]string, error) {
+func (g *GStorage) ListFolderFilesWithExclude(ctx context.Context, folder string, excludeFolders model.Set[string]) (_ []string, err error) {
+	ctx, span := tracing.Start(ctx, "GStorage.ListFolderFilesWithExclude", attribute.String("folder", folder))
+	defer tracing.End(span, &err)
 	if !strings.HasSuffix(folder, "/") {
 		folder = folder + "/"
 	}
-	it := g.bucketHandle.Objects(g.ctx, &storage.Query{
+	it := g.bucketHandle.Objects(ctx, &storage.Query{
 		Prefix:                   folder,
 		Delimiter:                "/",
 		IncludeFoldersAsPrefixes: true,
@@ -244,7 +266,7 @@ func (g *GStorage) ListFolderFilesWithExclude(folder string, excludeFolders mode
 		if obj.Prefix == "" || excludeFolders.Contains(strings.TrimSuffix(strings.TrimPrefix(obj.Prefix, folder), "/")) {
 			continue
 		}
-		subFiles, err := g.ListFolderFiles(obj.Prefix)
+		subFiles, err := g.ListFolderFiles(ctx, obj.Prefix)
 		if err != nil {
 			return nil, err
 		}
@@ -9,6 +9,8 @@ package v1alpha1
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -139,6 +141,245 @@ func (Command) EnumDescriptor() ([]byte, []int) {
 	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{1}
 }
 
+// LogLevel matches the agent logging flag values.
+type LogLevel int32
+
+const (
+	LogLevel_LOG_LEVEL_UNSPECIFIED LogLevel = 0
+	LogLevel_LOG_LEVEL_DEBUG       LogLevel = 1
+	LogLevel_LOG_LEVEL_INFO        LogLevel = 2
+	LogLevel_LOG_LEVEL_WARN        LogLevel = 3
+	LogLevel_LOG_LEVEL_ERROR       LogLevel = 4
+)
+
+// Enum value maps for LogLevel.
+var (
+	LogLevel_name = map[int32]string{
+		0: "LOG_LEVEL_UNSPECIFIED",
+		1: "LOG_LEVEL_DEBUG",
+		2: "LOG_LEVEL_INFO",
+		3: "LOG_LEVEL_WARN",
+		4: "LOG_LEVEL_ERROR",
+	}
+	LogLevel_value = map[string]int32{
+		"LOG_LEVEL_UNSPECIFIED": 0,
+		"LOG_LEVEL_DEBUG":       1,
+		"LOG_LEVEL_INFO":        2,
+		"LOG_LEVEL_WARN":        3,
+		"LOG_LEVEL_ERROR":       4,
+	}
+)
+
+func (x LogLevel) Enum() *LogLevel {
+	p := new(LogLevel)
+	*p = x
+	return p
+}
+
+func (x LogLevel) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (LogLevel) Descriptor() protoreflect.EnumDescriptor {
+	return file_wrapper_v1alpha1_wrapper_proto_enumTypes[2].Descriptor()
+}
+
+func (LogLevel) Type() protoreflect.EnumType {
+	return &file_wrapper_v1alpha1_wrapper_proto_enumTypes[2]
+}
+
+func (x LogLevel) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use LogLevel.Descriptor instead.
+func (LogLevel) EnumDescriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{2}
+}
+
+type StructuredEventType int32
+
+const (
+	StructuredEventType_STRUCTURED_EVENT_TYPE_UNSPECIFIED    StructuredEventType = 0
+	StructuredEventType_STRUCTURED_EVENT_TYPE_PLANNED_CHANGE StructuredEventType = 1
+	StructuredEventType_STRUCTURED_EVENT_TYPE_APPLY_START    StructuredEventType = 2
+	StructuredEventType_STRUCTURED_EVENT_TYPE_APPLY_PROGRESS StructuredEventType = 3
+	StructuredEventType_STRUCTURED_EVENT_TYPE_APPLY_COMPLETE StructuredEventType = 4
+	StructuredEventType_STRUCTURED_EVENT_TYPE_APPLY_ERRORED  StructuredEventType = 5
+	StructuredEventType_STRUCTURED_EVENT_TYPE_DIAGNOSTIC     StructuredEventType = 6
+	StructuredEventType_STRUCTURED_EVENT_TYPE_CHANGE_SUMMARY StructuredEventType = 7
+)
+
+// Enum value maps for StructuredEventType.
+var (
+	StructuredEventType_name = map[int32]string{
+		0: "STRUCTURED_EVENT_TYPE_UNSPECIFIED",
+		1: "STRUCTURED_EVENT_TYPE_PLANNED_CHANGE",
+		2: "STRUCTURED_EVENT_TYPE_APPLY_START",
+		3: "STRUCTURED_EVENT_TYPE_APPLY_PROGRESS",
+		4: "STRUCTURED_EVENT_TYPE_APPLY_COMPLETE",
+		5: "STRUCTURED_EVENT_TYPE_APPLY_ERRORED",
+		6: "STRUCTURED_EVENT_TYPE_DIAGNOSTIC",
+		7: "STRUCTURED_EVENT_TYPE_CHANGE_SUMMARY",
+	}
+	StructuredEventType_value = map[string]int32{
+		"STRUCTURED_EVENT_TYPE_UNSPECIFIED":    0,
+		"STRUCTURED_EVENT_TYPE_PLANNED_CHANGE": 1,
+		"STRUCTURED_EVENT_TYPE_APPLY_START":    2,
+		"STRUCTURED_EVENT_TYPE_APPLY_PROGRESS": 3,
+		"STRUCTURED_EVENT_TYPE_APPLY_COMPLETE": 4,
+		"STRUCTURED_EVENT_TYPE_APPLY_ERRORED":  5,
+		"STRUCTURED_EVENT_TYPE_DIAGNOSTIC":     6,
+		"STRUCTURED_EVENT_TYPE_CHANGE_SUMMARY": 7,
+	}
+)
+
+func (x StructuredEventType) Enum() *StructuredEventType {
+	p := new(StructuredEventType)
+	*p = x
+	return p
+}
+
+func (x StructuredEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StructuredEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_wrapper_v1alpha1_wrapper_proto_enumTypes[3].Descriptor()
+}
+
+func (StructuredEventType) Type() protoreflect.EnumType {
+	return &file_wrapper_v1alpha1_wrapper_proto_enumTypes[3]
+}
+
+func (x StructuredEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StructuredEventType.Descriptor instead.
+func (StructuredEventType) EnumDescriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{3}
+}
+
+type ResourceAction int32
+
+const (
+	ResourceAction_RESOURCE_ACTION_UNSPECIFIED ResourceAction = 0
+	ResourceAction_RESOURCE_ACTION_NOOP        ResourceAction = 1
+	ResourceAction_RESOURCE_ACTION_CREATE      ResourceAction = 2
+	ResourceAction_RESOURCE_ACTION_READ        ResourceAction = 3
+	ResourceAction_RESOURCE_ACTION_UPDATE      ResourceAction = 4
+	ResourceAction_RESOURCE_ACTION_REPLACE     ResourceAction = 5
+	ResourceAction_RESOURCE_ACTION_DELETE      ResourceAction = 6
+	ResourceAction_RESOURCE_ACTION_MOVE        ResourceAction = 7
+	ResourceAction_RESOURCE_ACTION_IMPORT      ResourceAction = 8
+	ResourceAction_RESOURCE_ACTION_FORGET      ResourceAction = 9
+)
+
+// Enum value maps for ResourceAction.
+var (
+	ResourceAction_name = map[int32]string{
+		0: "RESOURCE_ACTION_UNSPECIFIED",
+		1: "RESOURCE_ACTION_NOOP",
+		2: "RESOURCE_ACTION_CREATE",
+		3: "RESOURCE_ACTION_READ",
+		4: "RESOURCE_ACTION_UPDATE",
+		5: "RESOURCE_ACTION_REPLACE",
+		6: "RESOURCE_ACTION_DELETE",
+		7: "RESOURCE_ACTION_MOVE",
+		8: "RESOURCE_ACTION_IMPORT",
+		9: "RESOURCE_ACTION_FORGET",
+	}
+	ResourceAction_value = map[string]int32{
+		"RESOURCE_ACTION_UNSPECIFIED": 0,
+		"RESOURCE_ACTION_NOOP":        1,
+		"RESOURCE_ACTION_CREATE":      2,
+		"RESOURCE_ACTION_READ":        3,
+		"RESOURCE_ACTION_UPDATE":      4,
+		"RESOURCE_ACTION_REPLACE":     5,
+		"RESOURCE_ACTION_DELETE":      6,
+		"RESOURCE_ACTION_MOVE":        7,
+		"RESOURCE_ACTION_IMPORT":      8,
+		"RESOURCE_ACTION_FORGET":      9,
+	}
+)
+
+func (x ResourceAction) Enum() *ResourceAction {
+	p := new(ResourceAction)
+	*p = x
+	return p
+}
+
+func (x ResourceAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ResourceAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_wrapper_v1alpha1_wrapper_proto_enumTypes[4].Descriptor()
+}
+
+func (ResourceAction) Type() protoreflect.EnumType {
+	return &file_wrapper_v1alpha1_wrapper_proto_enumTypes[4]
+}
+
+func (x ResourceAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ResourceAction.Descriptor instead.
+func (ResourceAction) EnumDescriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{4}
+}
+
+type DiagnosticSeverity int32
+
+const (
+	DiagnosticSeverity_DIAGNOSTIC_SEVERITY_UNSPECIFIED DiagnosticSeverity = 0
+	DiagnosticSeverity_DIAGNOSTIC_SEVERITY_ERROR       DiagnosticSeverity = 1
+	DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING     DiagnosticSeverity = 2
+)
+
+// Enum value maps for DiagnosticSeverity.
+var (
+	DiagnosticSeverity_name = map[int32]string{
+		0: "DIAGNOSTIC_SEVERITY_UNSPECIFIED",
+		1: "DIAGNOSTIC_SEVERITY_ERROR",
+		2: "DIAGNOSTIC_SEVERITY_WARNING",
+	}
+	DiagnosticSeverity_value = map[string]int32{
+		"DIAGNOSTIC_SEVERITY_UNSPECIFIED": 0,
+		"DIAGNOSTIC_SEVERITY_ERROR":       1,
+		"DIAGNOSTIC_SEVERITY_WARNING":     2,
+	}
+)
+
+func (x DiagnosticSeverity) Enum() *DiagnosticSeverity {
+	p := new(DiagnosticSeverity)
+	*p = x
+	return p
+}
+
+func (x DiagnosticSeverity) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DiagnosticSeverity) Descriptor() protoreflect.EnumDescriptor {
+	return file_wrapper_v1alpha1_wrapper_proto_enumTypes[5].Descriptor()
+}
+
+func (DiagnosticSeverity) Type() protoreflect.EnumType {
+	return &file_wrapper_v1alpha1_wrapper_proto_enumTypes[5]
+}
+
+func (x DiagnosticSeverity) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DiagnosticSeverity.Descriptor instead.
+func (DiagnosticSeverity) EnumDescriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{5}
+}
+
 type StreamLogsRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types that are valid to be assigned to Payload:
@@ -148,6 +389,7 @@ type StreamLogsRequest struct {
 	//	*StreamLogsRequest_Ping
 	//	*StreamLogsRequest_Complete
 	//	*StreamLogsRequest_PlanSummary
+	//	*StreamLogsRequest_StructuredEvent
 	Payload       isStreamLogsRequest_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -235,6 +477,15 @@ func (x *StreamLogsRequest) GetPlanSummary() *PlanSummary {
 	return nil
 }
 
+func (x *StreamLogsRequest) GetStructuredEvent() *StructuredEvent {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamLogsRequest_StructuredEvent); ok {
+			return x.StructuredEvent
+		}
+	}
+	return nil
+}
+
 type isStreamLogsRequest_Payload interface {
 	isStreamLogsRequest_Payload()
 }
@@ -259,6 +510,10 @@ type StreamLogsRequest_PlanSummary struct {
 	PlanSummary *PlanSummary `protobuf:"bytes,5,opt,name=plan_summary,json=planSummary,proto3,oneof"`
 }
 
+type StreamLogsRequest_StructuredEvent struct {
+	StructuredEvent *StructuredEvent `protobuf:"bytes,6,opt,name=structured_event,json=structuredEvent,proto3,oneof"`
+}
+
 func (*StreamLogsRequest_Handshake) isStreamLogsRequest_Payload() {}
 
 func (*StreamLogsRequest_LogBatch) isStreamLogsRequest_Payload() {}
@@ -269,6 +524,8 @@ func (*StreamLogsRequest_Complete) isStreamLogsRequest_Payload() {}
 
 func (*StreamLogsRequest_PlanSummary) isStreamLogsRequest_Payload() {}
 
+func (*StreamLogsRequest_StructuredEvent) isStreamLogsRequest_Payload() {}
+
 type StreamLogsResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types that are valid to be assigned to Payload:
@@ -276,6 +533,10 @@ type StreamLogsResponse struct {
 	//	*StreamLogsResponse_HandshakeAck
 	//	*StreamLogsResponse_Ping
 	//	*StreamLogsResponse_Complete
+	//	*StreamLogsResponse_CancelExecution
+	//	*StreamLogsResponse_ApprovePlan
+	//	*StreamLogsResponse_RejectPlan
+	//	*StreamLogsResponse_SetLogLevel
 	Payload       isStreamLogsResponse_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -345,6 +606,42 @@ func (x *StreamLogsResponse) GetComplete() *StreamComplete {
 	return nil
 }
 
+func (x *StreamLogsResponse) GetCancelExecution() *CancelExecution {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamLogsResponse_CancelExecution); ok {
+			return x.CancelExecution
+		}
+	}
+	return nil
+}
+
+func (x *StreamLogsResponse) GetApprovePlan() *ApprovePlan {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamLogsResponse_ApprovePlan); ok {
+			return x.ApprovePlan
+		}
+	}
+	return nil
+}
+
+func (x *StreamLogsResponse) GetRejectPlan() *RejectPlan {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamLogsResponse_RejectPlan); ok {
+			return x.RejectPlan
+		}
+	}
+	return nil
+}
+
+func (x *StreamLogsResponse) GetSetLogLevel() *SetLogLevel {
+	if x != nil {
+		if x, ok := x.Payload.(*StreamLogsResponse_SetLogLevel); ok {
+			return x.SetLogLevel
+		}
+	}
+	return nil
+}
+
 type isStreamLogsResponse_Payload interface {
 	isStreamLogsResponse_Payload()
 }
@@ -361,12 +658,36 @@ type StreamLogsResponse_Complete struct {
 	Complete *StreamComplete `protobuf:"bytes,3,opt,name=complete,proto3,oneof"`
 }
 
+type StreamLogsResponse_CancelExecution struct {
+	CancelExecution *CancelExecution `protobuf:"bytes,4,opt,name=cancel_execution,json=cancelExecution,proto3,oneof"`
+}
+
+type StreamLogsResponse_ApprovePlan struct {
+	ApprovePlan *ApprovePlan `protobuf:"bytes,5,opt,name=approve_plan,json=approvePlan,proto3,oneof"`
+}
+
+type StreamLogsResponse_RejectPlan struct {
+	RejectPlan *RejectPlan `protobuf:"bytes,6,opt,name=reject_plan,json=rejectPlan,proto3,oneof"`
+}
+
+type StreamLogsResponse_SetLogLevel struct {
+	SetLogLevel *SetLogLevel `protobuf:"bytes,7,opt,name=set_log_level,json=setLogLevel,proto3,oneof"`
+}
+
 func (*StreamLogsResponse_HandshakeAck) isStreamLogsResponse_Payload() {}
 
 func (*StreamLogsResponse_Ping) isStreamLogsResponse_Payload() {}
 
 func (*StreamLogsResponse_Complete) isStreamLogsResponse_Payload() {}
 
+func (*StreamLogsResponse_CancelExecution) isStreamLogsResponse_Payload() {}
+
+func (*StreamLogsResponse_ApprovePlan) isStreamLogsResponse_Payload() {}
+
+func (*StreamLogsResponse_RejectPlan) isStreamLogsResponse_Payload() {}
+
+func (*StreamLogsResponse_SetLogLevel) isStreamLogsResponse_Payload() {}
+
 // Handshake binds the stream to a single wrapper execution. Sent once as the
 // first request message.
 type Handshake struct {
@@ -452,7 +773,11 @@ func (x *Handshake) GetPipelineIndex() int32 {
 // wait for this before sending any LogBatch messages so handshake-time
 // validation errors surface before logs start flowing.
 type HandshakeAck struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Offset of the next log line the server expects for this execution, equal
+	// to the number of lines already stored. The client replays its spool from
+	// this offset after reconnecting. Unset by servers that don't track offsets.
+	ResumeOffset  *uint64 `protobuf:"varint,1,opt,name=resume_offset,json=resumeOffset,proto3,oneof" json:"resume_offset,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -487,12 +812,23 @@ func (*HandshakeAck) Descriptor() ([]byte, []int) {
 	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{3}
 }
 
+func (x *HandshakeAck) GetResumeOffset() uint64 {
+	if x != nil && x.ResumeOffset != nil {
+		return *x.ResumeOffset
+	}
+	return 0
+}
+
 // LogBatch is one or more raw stdout lines from the entrypoint. Lines are
 // batched per Send to amortize gRPC per-message overhead — terraform emits
 // in bursts and a per-line stream would saturate the channel buffer.
 type LogBatch struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Lines         []string               `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Lines []string               `protobuf:"bytes,1,rep,name=lines,proto3" json:"lines,omitempty"`
+	// Offset of the first line in the batch, lines of an execution are numbered
+	// from 0. Lets the server drop lines it has already stored when a batch is
+	// replayed after a reconnect.
+	Offset        *uint64 `protobuf:"varint,2,opt,name=offset,proto3,oneof" json:"offset,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -534,7 +870,16 @@ func (x *LogBatch) GetLines() []string {
 	return nil
 }
 
+func (x *LogBatch) GetOffset() uint64 {
+	if x != nil && x.Offset != nil {
+		return *x.Offset
+	}
+	return 0
+}
+
 // Ping keeps idle connections alive across load balancers that drop HTTP/2 flows after inactivity timeouts.
+// Servers answer pings after storing the preceding log batches, the answer acknowledges the lines sent before the
+// ping.
 type Ping struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -668,34 +1013,29 @@ func (x *StreamComplete) GetTotalReceived() uint64 {
 	return 0
 }
 
-// PlanSummary is the compact view of a step plan.
-type PlanSummary struct {
-	state protoimpl.MessageState `protogen:"open.v1"`
-	// Bare resources and outputs not connected to any module.
-	Root *ModuleChanges `protobuf:"bytes,1,opt,name=root,proto3" json:"root,omitempty"`
-	// First-level modules declared in main.tf, keyed by module name. An entry
-	// exists if the module has resource changes OR outputs. Submodules (any
-	// depth) are rolled up into the parent's resource buckets; their identity
-	// is preserved in the resource address strings.
-	Modules       map[string]*ModuleChanges `protobuf:"bytes,2,rep,name=modules,proto3" json:"modules,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+// CancelExecution asks the wrapper to stop the entrypoint. The wrapper sends
+// SIGTERM to the entrypoint process group and still reports ExecutionComplete.
+type CancelExecution struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reason        string                 `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PlanSummary) Reset() {
-	*x = PlanSummary{}
+func (x *CancelExecution) Reset() {
+	*x = CancelExecution{}
 	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PlanSummary) String() string {
+func (x *CancelExecution) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PlanSummary) ProtoMessage() {}
+func (*CancelExecution) ProtoMessage() {}
 
-func (x *PlanSummary) ProtoReflect() protoreflect.Message {
+func (x *CancelExecution) ProtoReflect() protoreflect.Message {
 	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -707,55 +1047,41 @@ func (x *PlanSummary) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PlanSummary.ProtoReflect.Descriptor instead.
-func (*PlanSummary) Descriptor() ([]byte, []int) {
+// Deprecated: Use CancelExecution.ProtoReflect.Descriptor instead.
+func (*CancelExecution) Descriptor() ([]byte, []int) {
 	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *PlanSummary) GetRoot() *ModuleChanges {
-	if x != nil {
-		return x.Root
-	}
-	return nil
-}
-
-func (x *PlanSummary) GetModules() map[string]*ModuleChanges {
+func (x *CancelExecution) GetReason() string {
 	if x != nil {
-		return x.Modules
+		return x.Reason
 	}
-	return nil
+	return ""
 }
 
-// ModuleChanges is the per-module breakdown of changes. All repeated-string
-// fields hold full resource addresses (e.g. "module.vpc.module.vpc.aws_subnet.foo").
-type ModuleChanges struct {
+// ApprovePlan approves the changes of a plan execution that is waiting for a
+// manual approval. Ignored when nothing is waiting.
+type ApprovePlan struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Added         []string               `protobuf:"bytes,1,rep,name=added,proto3" json:"added,omitempty"`
-	Changed       []string               `protobuf:"bytes,2,rep,name=changed,proto3" json:"changed,omitempty"`
-	Destroyed     []string               `protobuf:"bytes,3,rep,name=destroyed,proto3" json:"destroyed,omitempty"`
-	Replaced      []string               `protobuf:"bytes,4,rep,name=replaced,proto3" json:"replaced,omitempty"`
-	Imported      []string               `protobuf:"bytes,5,rep,name=imported,proto3" json:"imported,omitempty"`
-	Forgotten     []string               `protobuf:"bytes,6,rep,name=forgotten,proto3" json:"forgotten,omitempty"`
-	Moved         []*ResourceMove        `protobuf:"bytes,7,rep,name=moved,proto3" json:"moved,omitempty"`
-	Outputs       *OutputChanges         `protobuf:"bytes,8,opt,name=outputs,proto3" json:"outputs,omitempty"`
+	ApprovedBy    string                 `protobuf:"bytes,1,opt,name=approved_by,json=approvedBy,proto3" json:"approved_by,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ModuleChanges) Reset() {
-	*x = ModuleChanges{}
+func (x *ApprovePlan) Reset() {
+	*x = ApprovePlan{}
 	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ModuleChanges) String() string {
+func (x *ApprovePlan) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ModuleChanges) ProtoMessage() {}
+func (*ApprovePlan) ProtoMessage() {}
 
-func (x *ModuleChanges) ProtoReflect() protoreflect.Message {
+func (x *ApprovePlan) ProtoReflect() protoreflect.Message {
 	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -767,92 +1093,657 @@ func (x *ModuleChanges) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ModuleChanges.ProtoReflect.Descriptor instead.
-func (*ModuleChanges) Descriptor() ([]byte, []int) {
+// Deprecated: Use ApprovePlan.ProtoReflect.Descriptor instead.
+func (*ApprovePlan) Descriptor() ([]byte, []int) {
 	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{9}
 }
 
-func (x *ModuleChanges) GetAdded() []string {
+func (x *ApprovePlan) GetApprovedBy() string {
 	if x != nil {
-		return x.Added
+		return x.ApprovedBy
 	}
-	return nil
+	return ""
 }
 
-func (x *ModuleChanges) GetChanged() []string {
-	if x != nil {
-		return x.Changed
-	}
-	return nil
+// RejectPlan rejects the changes of a plan execution that is waiting for a
+// manual approval. Ignored when nothing is waiting.
+type RejectPlan struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RejectedBy    string                 `protobuf:"bytes,1,opt,name=rejected_by,json=rejectedBy,proto3" json:"rejected_by,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ModuleChanges) GetDestroyed() []string {
-	if x != nil {
-		return x.Destroyed
-	}
-	return nil
+func (x *RejectPlan) Reset() {
+	*x = RejectPlan{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *ModuleChanges) GetReplaced() []string {
-	if x != nil {
-		return x.Replaced
-	}
-	return nil
+func (x *RejectPlan) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *ModuleChanges) GetImported() []string {
+func (*RejectPlan) ProtoMessage() {}
+
+func (x *RejectPlan) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[10]
 	if x != nil {
-		return x.Imported
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-func (x *ModuleChanges) GetForgotten() []string {
-	if x != nil {
-		return x.Forgotten
-	}
-	return nil
+// Deprecated: Use RejectPlan.ProtoReflect.Descriptor instead.
+func (*RejectPlan) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{10}
 }
 
-func (x *ModuleChanges) GetMoved() []*ResourceMove {
+func (x *RejectPlan) GetRejectedBy() string {
 	if x != nil {
-		return x.Moved
+		return x.RejectedBy
 	}
-	return nil
+	return ""
 }
 
-func (x *ModuleChanges) GetOutputs() *OutputChanges {
+func (x *RejectPlan) GetReason() string {
 	if x != nil {
-		return x.Outputs
+		return x.Reason
+	}
+	return ""
+}
+
+// SetLogLevel changes the verbosity of the agent logs for the rest of the
+// execution.
+type SetLogLevel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Level         LogLevel               `protobuf:"varint,1,opt,name=level,proto3,enum=wrapper.v1alpha1.LogLevel" json:"level,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLogLevel) Reset() {
+	*x = SetLogLevel{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLogLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLogLevel) ProtoMessage() {}
+
+func (x *SetLogLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLogLevel.ProtoReflect.Descriptor instead.
+func (*SetLogLevel) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SetLogLevel) GetLevel() LogLevel {
+	if x != nil {
+		return x.Level
+	}
+	return LogLevel_LOG_LEVEL_UNSPECIFIED
+}
+
+// PlanSummary is the compact view of a step plan.
+type PlanSummary struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Bare resources and outputs not connected to any module.
+	Root *ModuleChanges `protobuf:"bytes,1,opt,name=root,proto3" json:"root,omitempty"`
+	// First-level modules declared in main.tf, keyed by module name. An entry
+	// exists if the module has resource changes OR outputs. Submodules (any
+	// depth) are rolled up into the parent's resource buckets; their identity
+	// is preserved in the resource address strings.
+	Modules map[string]*ModuleChanges `protobuf:"bytes,2,rep,name=modules,proto3" json:"modules,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Slowest resources of an apply, slowest first. Only set in the summary
+	// sent after an apply, which carries no plan changes.
+	SlowestResources []*ResourceTiming `protobuf:"bytes,3,rep,name=slowest_resources,json=slowestResources,proto3" json:"slowest_resources,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *PlanSummary) Reset() {
+	*x = PlanSummary{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlanSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlanSummary) ProtoMessage() {}
+
+func (x *PlanSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlanSummary.ProtoReflect.Descriptor instead.
+func (*PlanSummary) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PlanSummary) GetRoot() *ModuleChanges {
+	if x != nil {
+		return x.Root
+	}
+	return nil
+}
+
+func (x *PlanSummary) GetModules() map[string]*ModuleChanges {
+	if x != nil {
+		return x.Modules
+	}
+	return nil
+}
+
+func (x *PlanSummary) GetSlowestResources() []*ResourceTiming {
+	if x != nil {
+		return x.SlowestResources
+	}
+	return nil
+}
+
+// ResourceTiming is the measured duration of a single resource apply.
+type ResourceTiming struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Address string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Action  ResourceAction         `protobuf:"varint,2,opt,name=action,proto3,enum=wrapper.v1alpha1.ResourceAction" json:"action,omitempty"`
+	Started *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=started,proto3" json:"started,omitempty"`
+	// Unset when the resource apply didn't finish.
+	Finished *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=finished,proto3" json:"finished,omitempty"`
+	Duration *durationpb.Duration   `protobuf:"bytes,5,opt,name=duration,proto3" json:"duration,omitempty"`
+	// False when the resource apply errored or was interrupted.
+	Completed     bool `protobuf:"varint,6,opt,name=completed,proto3" json:"completed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResourceTiming) Reset() {
+	*x = ResourceTiming{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResourceTiming) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceTiming) ProtoMessage() {}
+
+func (x *ResourceTiming) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceTiming.ProtoReflect.Descriptor instead.
+func (*ResourceTiming) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ResourceTiming) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *ResourceTiming) GetAction() ResourceAction {
+	if x != nil {
+		return x.Action
+	}
+	return ResourceAction_RESOURCE_ACTION_UNSPECIFIED
+}
+
+func (x *ResourceTiming) GetStarted() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Started
+	}
+	return nil
+}
+
+func (x *ResourceTiming) GetFinished() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Finished
+	}
+	return nil
+}
+
+func (x *ResourceTiming) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+func (x *ResourceTiming) GetCompleted() bool {
+	if x != nil {
+		return x.Completed
+	}
+	return false
+}
+
+// ModuleChanges is the per-module breakdown of changes. All repeated-string
+// fields hold full resource addresses (e.g. "module.vpc.module.vpc.aws_subnet.foo").
+type ModuleChanges struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Added         []string               `protobuf:"bytes,1,rep,name=added,proto3" json:"added,omitempty"`
+	Changed       []string               `protobuf:"bytes,2,rep,name=changed,proto3" json:"changed,omitempty"`
+	Destroyed     []string               `protobuf:"bytes,3,rep,name=destroyed,proto3" json:"destroyed,omitempty"`
+	Replaced      []string               `protobuf:"bytes,4,rep,name=replaced,proto3" json:"replaced,omitempty"`
+	Imported      []string               `protobuf:"bytes,5,rep,name=imported,proto3" json:"imported,omitempty"`
+	Forgotten     []string               `protobuf:"bytes,6,rep,name=forgotten,proto3" json:"forgotten,omitempty"`
+	Moved         []*ResourceMove        `protobuf:"bytes,7,rep,name=moved,proto3" json:"moved,omitempty"`
+	Outputs       *OutputChanges         `protobuf:"bytes,8,opt,name=outputs,proto3" json:"outputs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ModuleChanges) Reset() {
+	*x = ModuleChanges{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ModuleChanges) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ModuleChanges) ProtoMessage() {}
+
+func (x *ModuleChanges) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ModuleChanges.ProtoReflect.Descriptor instead.
+func (*ModuleChanges) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ModuleChanges) GetAdded() []string {
+	if x != nil {
+		return x.Added
+	}
+	return nil
+}
+
+func (x *ModuleChanges) GetChanged() []string {
+	if x != nil {
+		return x.Changed
+	}
+	return nil
+}
+
+func (x *ModuleChanges) GetDestroyed() []string {
+	if x != nil {
+		return x.Destroyed
+	}
+	return nil
+}
+
+func (x *ModuleChanges) GetReplaced() []string {
+	if x != nil {
+		return x.Replaced
+	}
+	return nil
+}
+
+func (x *ModuleChanges) GetImported() []string {
+	if x != nil {
+		return x.Imported
+	}
+	return nil
+}
+
+func (x *ModuleChanges) GetForgotten() []string {
+	if x != nil {
+		return x.Forgotten
+	}
+	return nil
+}
+
+func (x *ModuleChanges) GetMoved() []*ResourceMove {
+	if x != nil {
+		return x.Moved
+	}
+	return nil
+}
+
+func (x *ModuleChanges) GetOutputs() *OutputChanges {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+// ResourceMove is an in-state move. From/To are full resource addresses; the
+// entry is filed under the destination module.
+type ResourceMove struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResourceMove) Reset() {
+	*x = ResourceMove{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResourceMove) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResourceMove) ProtoMessage() {}
+
+func (x *ResourceMove) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResourceMove.ProtoReflect.Descriptor instead.
+func (*ResourceMove) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ResourceMove) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *ResourceMove) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+// OutputChanges is the per-action breakdown of output changes. Only names —
+// values are intentionally omitted from the summary.
+type OutputChanges struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Added         []string               `protobuf:"bytes,1,rep,name=added,proto3" json:"added,omitempty"`
+	Changed       []string               `protobuf:"bytes,2,rep,name=changed,proto3" json:"changed,omitempty"`
+	Destroyed     []string               `protobuf:"bytes,3,rep,name=destroyed,proto3" json:"destroyed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OutputChanges) Reset() {
+	*x = OutputChanges{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OutputChanges) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OutputChanges) ProtoMessage() {}
+
+func (x *OutputChanges) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OutputChanges.ProtoReflect.Descriptor instead.
+func (*OutputChanges) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *OutputChanges) GetAdded() []string {
+	if x != nil {
+		return x.Added
+	}
+	return nil
+}
+
+func (x *OutputChanges) GetChanged() []string {
+	if x != nil {
+		return x.Changed
+	}
+	return nil
+}
+
+func (x *OutputChanges) GetDestroyed() []string {
+	if x != nil {
+		return x.Destroyed
+	}
+	return nil
+}
+
+// StructuredEvent is a terraform/OpenTofu machine-readable UI event parsed from
+// a -json output line of the entrypoint. The raw line is still sent in a
+// LogBatch, events follow the batch that contains their line.
+type StructuredEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  StructuredEventType    `protobuf:"varint,1,opt,name=type,proto3,enum=wrapper.v1alpha1.StructuredEventType" json:"type,omitempty"`
+	// Offset of the log line the event was parsed from.
+	Offset    uint64                 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Timestamp *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Human-readable message of the event.
+	Message string `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*StructuredEvent_Resource
+	//	*StructuredEvent_Diagnostic
+	//	*StructuredEvent_ChangeSummary
+	Event         isStructuredEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StructuredEvent) Reset() {
+	*x = StructuredEvent{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StructuredEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StructuredEvent) ProtoMessage() {}
+
+func (x *StructuredEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StructuredEvent.ProtoReflect.Descriptor instead.
+func (*StructuredEvent) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *StructuredEvent) GetType() StructuredEventType {
+	if x != nil {
+		return x.Type
+	}
+	return StructuredEventType_STRUCTURED_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *StructuredEvent) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *StructuredEvent) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
 	}
 	return nil
 }
 
-// ResourceMove is an in-state move. From/To are full resource addresses; the
-// entry is filed under the destination module.
-type ResourceMove struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	From          string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
-	To            string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+func (x *StructuredEvent) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *StructuredEvent) GetEvent() isStructuredEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *StructuredEvent) GetResource() *ResourceEvent {
+	if x != nil {
+		if x, ok := x.Event.(*StructuredEvent_Resource); ok {
+			return x.Resource
+		}
+	}
+	return nil
+}
+
+func (x *StructuredEvent) GetDiagnostic() *Diagnostic {
+	if x != nil {
+		if x, ok := x.Event.(*StructuredEvent_Diagnostic); ok {
+			return x.Diagnostic
+		}
+	}
+	return nil
+}
+
+func (x *StructuredEvent) GetChangeSummary() *ChangeSummary {
+	if x != nil {
+		if x, ok := x.Event.(*StructuredEvent_ChangeSummary); ok {
+			return x.ChangeSummary
+		}
+	}
+	return nil
+}
+
+type isStructuredEvent_Event interface {
+	isStructuredEvent_Event()
+}
+
+type StructuredEvent_Resource struct {
+	Resource *ResourceEvent `protobuf:"bytes,5,opt,name=resource,proto3,oneof"`
+}
+
+type StructuredEvent_Diagnostic struct {
+	Diagnostic *Diagnostic `protobuf:"bytes,6,opt,name=diagnostic,proto3,oneof"`
+}
+
+type StructuredEvent_ChangeSummary struct {
+	ChangeSummary *ChangeSummary `protobuf:"bytes,7,opt,name=change_summary,json=changeSummary,proto3,oneof"`
+}
+
+func (*StructuredEvent_Resource) isStructuredEvent_Event() {}
+
+func (*StructuredEvent_Diagnostic) isStructuredEvent_Event() {}
+
+func (*StructuredEvent_ChangeSummary) isStructuredEvent_Event() {}
+
+// ResourceEvent is a planned change or an apply hook of a single resource.
+type ResourceEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Full resource address, e.g. "module.vpc.aws_subnet.private[0]".
+	Address string         `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Action  ResourceAction `protobuf:"varint,2,opt,name=action,proto3,enum=wrapper.v1alpha1.ResourceAction" json:"action,omitempty"`
+	// Time since the resource apply started. Set for progress, complete and
+	// errored events.
+	Elapsed *durationpb.Duration `protobuf:"bytes,3,opt,name=elapsed,proto3" json:"elapsed,omitempty"`
+	// Identifying attribute of the applied resource. Set for complete events.
+	IdKey         string `protobuf:"bytes,4,opt,name=id_key,json=idKey,proto3" json:"id_key,omitempty"`
+	IdValue       string `protobuf:"bytes,5,opt,name=id_value,json=idValue,proto3" json:"id_value,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ResourceMove) Reset() {
-	*x = ResourceMove{}
-	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[10]
+func (x *ResourceEvent) Reset() {
+	*x = ResourceEvent{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ResourceMove) String() string {
+func (x *ResourceEvent) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ResourceMove) ProtoMessage() {}
+func (*ResourceEvent) ProtoMessage() {}
 
-func (x *ResourceMove) ProtoReflect() protoreflect.Message {
-	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[10]
+func (x *ResourceEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -863,51 +1754,148 @@ func (x *ResourceMove) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ResourceMove.ProtoReflect.Descriptor instead.
-func (*ResourceMove) Descriptor() ([]byte, []int) {
-	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use ResourceEvent.ProtoReflect.Descriptor instead.
+func (*ResourceEvent) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{18}
 }
 
-func (x *ResourceMove) GetFrom() string {
+func (x *ResourceEvent) GetAddress() string {
 	if x != nil {
-		return x.From
+		return x.Address
 	}
 	return ""
 }
 
-func (x *ResourceMove) GetTo() string {
+func (x *ResourceEvent) GetAction() ResourceAction {
 	if x != nil {
-		return x.To
+		return x.Action
+	}
+	return ResourceAction_RESOURCE_ACTION_UNSPECIFIED
+}
+
+func (x *ResourceEvent) GetElapsed() *durationpb.Duration {
+	if x != nil {
+		return x.Elapsed
+	}
+	return nil
+}
+
+func (x *ResourceEvent) GetIdKey() string {
+	if x != nil {
+		return x.IdKey
 	}
 	return ""
 }
 
-// OutputChanges is the per-action breakdown of output changes. Only names —
-// values are intentionally omitted from the summary.
-type OutputChanges struct {
+func (x *ResourceEvent) GetIdValue() string {
+	if x != nil {
+		return x.IdValue
+	}
+	return ""
+}
+
+type Diagnostic struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Severity DiagnosticSeverity     `protobuf:"varint,1,opt,name=severity,proto3,enum=wrapper.v1alpha1.DiagnosticSeverity" json:"severity,omitempty"`
+	Summary  string                 `protobuf:"bytes,2,opt,name=summary,proto3" json:"summary,omitempty"`
+	Detail   string                 `protobuf:"bytes,3,opt,name=detail,proto3" json:"detail,omitempty"`
+	// Address of the resource the diagnostic relates to, if any.
+	Address string `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	// Configuration source range, unset for diagnostics without a location.
+	Range         *SourceRange `protobuf:"bytes,5,opt,name=range,proto3" json:"range,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Diagnostic) Reset() {
+	*x = Diagnostic{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Diagnostic) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Diagnostic) ProtoMessage() {}
+
+func (x *Diagnostic) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Diagnostic.ProtoReflect.Descriptor instead.
+func (*Diagnostic) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Diagnostic) GetSeverity() DiagnosticSeverity {
+	if x != nil {
+		return x.Severity
+	}
+	return DiagnosticSeverity_DIAGNOSTIC_SEVERITY_UNSPECIFIED
+}
+
+func (x *Diagnostic) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetRange() *SourceRange {
+	if x != nil {
+		return x.Range
+	}
+	return nil
+}
+
+type SourceRange struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Added         []string               `protobuf:"bytes,1,rep,name=added,proto3" json:"added,omitempty"`
-	Changed       []string               `protobuf:"bytes,2,rep,name=changed,proto3" json:"changed,omitempty"`
-	Destroyed     []string               `protobuf:"bytes,3,rep,name=destroyed,proto3" json:"destroyed,omitempty"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Start         *SourcePos             `protobuf:"bytes,2,opt,name=start,proto3" json:"start,omitempty"`
+	End           *SourcePos             `protobuf:"bytes,3,opt,name=end,proto3" json:"end,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *OutputChanges) Reset() {
-	*x = OutputChanges{}
-	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[11]
+func (x *SourceRange) Reset() {
+	*x = SourceRange{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *OutputChanges) String() string {
+func (x *SourceRange) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*OutputChanges) ProtoMessage() {}
+func (*SourceRange) ProtoMessage() {}
 
-func (x *OutputChanges) ProtoReflect() protoreflect.Message {
-	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[11]
+func (x *SourceRange) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -918,48 +1906,192 @@ func (x *OutputChanges) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use OutputChanges.ProtoReflect.Descriptor instead.
-func (*OutputChanges) Descriptor() ([]byte, []int) {
-	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use SourceRange.ProtoReflect.Descriptor instead.
+func (*SourceRange) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{20}
 }
 
-func (x *OutputChanges) GetAdded() []string {
+func (x *SourceRange) GetFilename() string {
 	if x != nil {
-		return x.Added
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *SourceRange) GetStart() *SourcePos {
+	if x != nil {
+		return x.Start
 	}
 	return nil
 }
 
-func (x *OutputChanges) GetChanged() []string {
+func (x *SourceRange) GetEnd() *SourcePos {
 	if x != nil {
-		return x.Changed
+		return x.End
 	}
 	return nil
 }
 
-func (x *OutputChanges) GetDestroyed() []string {
+type SourcePos struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Line          int32                  `protobuf:"varint,1,opt,name=line,proto3" json:"line,omitempty"`
+	Column        int32                  `protobuf:"varint,2,opt,name=column,proto3" json:"column,omitempty"`
+	Byte          int32                  `protobuf:"varint,3,opt,name=byte,proto3" json:"byte,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SourcePos) Reset() {
+	*x = SourcePos{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SourcePos) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SourcePos) ProtoMessage() {}
+
+func (x *SourcePos) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SourcePos.ProtoReflect.Descriptor instead.
+func (*SourcePos) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SourcePos) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *SourcePos) GetColumn() int32 {
+	if x != nil {
+		return x.Column
+	}
+	return 0
+}
+
+func (x *SourcePos) GetByte() int32 {
+	if x != nil {
+		return x.Byte
+	}
+	return 0
+}
+
+// ChangeSummary is the resource change count of a plan or apply.
+type ChangeSummary struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Added     int32                  `protobuf:"varint,1,opt,name=added,proto3" json:"added,omitempty"`
+	Changed   int32                  `protobuf:"varint,2,opt,name=changed,proto3" json:"changed,omitempty"`
+	Imported  int32                  `protobuf:"varint,3,opt,name=imported,proto3" json:"imported,omitempty"`
+	Destroyed int32                  `protobuf:"varint,4,opt,name=destroyed,proto3" json:"destroyed,omitempty"`
+	// Operation the counts belong to: plan, apply or destroy.
+	Operation     string `protobuf:"bytes,5,opt,name=operation,proto3" json:"operation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeSummary) Reset() {
+	*x = ChangeSummary{}
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeSummary) ProtoMessage() {}
+
+func (x *ChangeSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_wrapper_v1alpha1_wrapper_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeSummary.ProtoReflect.Descriptor instead.
+func (*ChangeSummary) Descriptor() ([]byte, []int) {
+	return file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ChangeSummary) GetAdded() int32 {
+	if x != nil {
+		return x.Added
+	}
+	return 0
+}
+
+func (x *ChangeSummary) GetChanged() int32 {
+	if x != nil {
+		return x.Changed
+	}
+	return 0
+}
+
+func (x *ChangeSummary) GetImported() int32 {
+	if x != nil {
+		return x.Imported
+	}
+	return 0
+}
+
+func (x *ChangeSummary) GetDestroyed() int32 {
 	if x != nil {
 		return x.Destroyed
 	}
-	return nil
+	return 0
+}
+
+func (x *ChangeSummary) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
 }
 
 var File_wrapper_v1alpha1_wrapper_proto protoreflect.FileDescriptor
 
 const file_wrapper_v1alpha1_wrapper_proto_rawDesc = "" +
 	"\n" +
-	"\x1ewrapper/v1alpha1/wrapper.proto\x12\x10wrapper.v1alpha1\"\xcb\x02\n" +
+	"\x1ewrapper/v1alpha1/wrapper.proto\x12\x10wrapper.v1alpha1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x9b\x03\n" +
 	"\x11StreamLogsRequest\x12;\n" +
 	"\thandshake\x18\x01 \x01(\v2\x1b.wrapper.v1alpha1.HandshakeH\x00R\thandshake\x129\n" +
 	"\tlog_batch\x18\x02 \x01(\v2\x1a.wrapper.v1alpha1.LogBatchH\x00R\blogBatch\x12,\n" +
 	"\x04ping\x18\x03 \x01(\v2\x16.wrapper.v1alpha1.PingH\x00R\x04ping\x12A\n" +
 	"\bcomplete\x18\x04 \x01(\v2#.wrapper.v1alpha1.ExecutionCompleteH\x00R\bcomplete\x12B\n" +
-	"\fplan_summary\x18\x05 \x01(\v2\x1d.wrapper.v1alpha1.PlanSummaryH\x00R\vplanSummaryB\t\n" +
-	"\apayload\"\xd4\x01\n" +
+	"\fplan_summary\x18\x05 \x01(\v2\x1d.wrapper.v1alpha1.PlanSummaryH\x00R\vplanSummary\x12N\n" +
+	"\x10structured_event\x18\x06 \x01(\v2!.wrapper.v1alpha1.StructuredEventH\x00R\x0fstructuredEventB\t\n" +
+	"\apayload\"\xee\x03\n" +
 	"\x12StreamLogsResponse\x12E\n" +
 	"\rhandshake_ack\x18\x01 \x01(\v2\x1e.wrapper.v1alpha1.HandshakeAckH\x00R\fhandshakeAck\x12,\n" +
 	"\x04ping\x18\x02 \x01(\v2\x16.wrapper.v1alpha1.PingH\x00R\x04ping\x12>\n" +
-	"\bcomplete\x18\x03 \x01(\v2 .wrapper.v1alpha1.StreamCompleteH\x00R\bcompleteB\t\n" +
+	"\bcomplete\x18\x03 \x01(\v2 .wrapper.v1alpha1.StreamCompleteH\x00R\bcomplete\x12N\n" +
+	"\x10cancel_execution\x18\x04 \x01(\v2!.wrapper.v1alpha1.CancelExecutionH\x00R\x0fcancelExecution\x12B\n" +
+	"\fapprove_plan\x18\x05 \x01(\v2\x1d.wrapper.v1alpha1.ApprovePlanH\x00R\vapprovePlan\x12?\n" +
+	"\vreject_plan\x18\x06 \x01(\v2\x1c.wrapper.v1alpha1.RejectPlanH\x00R\n" +
+	"rejectPlan\x12C\n" +
+	"\rset_log_level\x18\a \x01(\v2\x1d.wrapper.v1alpha1.SetLogLevelH\x00R\vsetLogLevelB\t\n" +
 	"\apayload\"\xd5\x01\n" +
 	"\tHandshake\x12\x1f\n" +
 	"\vcampaign_id\x18\x01 \x01(\tR\n" +
@@ -967,22 +2099,46 @@ const file_wrapper_v1alpha1_wrapper_proto_rawDesc = "" +
 	"\x04step\x18\x02 \x01(\tR\x04step\x123\n" +
 	"\acommand\x18\x03 \x01(\x0e2\x19.wrapper.v1alpha1.CommandR\acommand\x127\n" +
 	"\tstep_type\x18\x04 \x01(\x0e2\x1a.wrapper.v1alpha1.StepTypeR\bstepType\x12%\n" +
-	"\x0epipeline_index\x18\x05 \x01(\x05R\rpipelineIndex\"\x0e\n" +
-	"\fHandshakeAck\" \n" +
+	"\x0epipeline_index\x18\x05 \x01(\x05R\rpipelineIndex\"J\n" +
+	"\fHandshakeAck\x12(\n" +
+	"\rresume_offset\x18\x01 \x01(\x04H\x00R\fresumeOffset\x88\x01\x01B\x10\n" +
+	"\x0e_resume_offset\"H\n" +
 	"\bLogBatch\x12\x14\n" +
-	"\x05lines\x18\x01 \x03(\tR\x05lines\"\x06\n" +
+	"\x05lines\x18\x01 \x03(\tR\x05lines\x12\x1b\n" +
+	"\x06offset\x18\x02 \x01(\x04H\x00R\x06offset\x88\x01\x01B\t\n" +
+	"\a_offset\"\x06\n" +
 	"\x04Ping\"F\n" +
 	"\x11ExecutionComplete\x12\x1b\n" +
 	"\texit_code\x18\x01 \x01(\x05R\bexitCode\x12\x14\n" +
 	"\x05error\x18\x02 \x01(\tR\x05error\"7\n" +
 	"\x0eStreamComplete\x12%\n" +
-	"\x0etotal_received\x18\x01 \x01(\x04R\rtotalReceived\"\xe5\x01\n" +
+	"\x0etotal_received\x18\x01 \x01(\x04R\rtotalReceived\")\n" +
+	"\x0fCancelExecution\x12\x16\n" +
+	"\x06reason\x18\x01 \x01(\tR\x06reason\".\n" +
+	"\vApprovePlan\x12\x1f\n" +
+	"\vapproved_by\x18\x01 \x01(\tR\n" +
+	"approvedBy\"E\n" +
+	"\n" +
+	"RejectPlan\x12\x1f\n" +
+	"\vrejected_by\x18\x01 \x01(\tR\n" +
+	"rejectedBy\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"?\n" +
+	"\vSetLogLevel\x120\n" +
+	"\x05level\x18\x01 \x01(\x0e2\x1a.wrapper.v1alpha1.LogLevelR\x05level\"\xb4\x02\n" +
 	"\vPlanSummary\x123\n" +
 	"\x04root\x18\x01 \x01(\v2\x1f.wrapper.v1alpha1.ModuleChangesR\x04root\x12D\n" +
-	"\amodules\x18\x02 \x03(\v2*.wrapper.v1alpha1.PlanSummary.ModulesEntryR\amodules\x1a[\n" +
+	"\amodules\x18\x02 \x03(\v2*.wrapper.v1alpha1.PlanSummary.ModulesEntryR\amodules\x12M\n" +
+	"\x11slowest_resources\x18\x03 \x03(\v2 .wrapper.v1alpha1.ResourceTimingR\x10slowestResources\x1a[\n" +
 	"\fModulesEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x125\n" +
-	"\x05value\x18\x02 \x01(\v2\x1f.wrapper.v1alpha1.ModuleChangesR\x05value:\x028\x01\"\xa4\x02\n" +
+	"\x05value\x18\x02 \x01(\v2\x1f.wrapper.v1alpha1.ModuleChangesR\x05value:\x028\x01\"\xa7\x02\n" +
+	"\x0eResourceTiming\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x128\n" +
+	"\x06action\x18\x02 \x01(\x0e2 .wrapper.v1alpha1.ResourceActionR\x06action\x124\n" +
+	"\astarted\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\astarted\x126\n" +
+	"\bfinished\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\bfinished\x125\n" +
+	"\bduration\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\bduration\x12\x1c\n" +
+	"\tcompleted\x18\x06 \x01(\bR\tcompleted\"\xa4\x02\n" +
 	"\rModuleChanges\x12\x14\n" +
 	"\x05added\x18\x01 \x03(\tR\x05added\x12\x18\n" +
 	"\achanged\x18\x02 \x03(\tR\achanged\x12\x1c\n" +
@@ -998,7 +2154,45 @@ const file_wrapper_v1alpha1_wrapper_proto_rawDesc = "" +
 	"\rOutputChanges\x12\x14\n" +
 	"\x05added\x18\x01 \x03(\tR\x05added\x12\x18\n" +
 	"\achanged\x18\x02 \x03(\tR\achanged\x12\x1c\n" +
-	"\tdestroyed\x18\x03 \x03(\tR\tdestroyed*Y\n" +
+	"\tdestroyed\x18\x03 \x03(\tR\tdestroyed\"\x8a\x03\n" +
+	"\x0fStructuredEvent\x129\n" +
+	"\x04type\x18\x01 \x01(\x0e2%.wrapper.v1alpha1.StructuredEventTypeR\x04type\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x04R\x06offset\x128\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12=\n" +
+	"\bresource\x18\x05 \x01(\v2\x1f.wrapper.v1alpha1.ResourceEventH\x00R\bresource\x12>\n" +
+	"\n" +
+	"diagnostic\x18\x06 \x01(\v2\x1c.wrapper.v1alpha1.DiagnosticH\x00R\n" +
+	"diagnostic\x12H\n" +
+	"\x0echange_summary\x18\a \x01(\v2\x1f.wrapper.v1alpha1.ChangeSummaryH\x00R\rchangeSummaryB\a\n" +
+	"\x05event\"\xca\x01\n" +
+	"\rResourceEvent\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x128\n" +
+	"\x06action\x18\x02 \x01(\x0e2 .wrapper.v1alpha1.ResourceActionR\x06action\x123\n" +
+	"\aelapsed\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\aelapsed\x12\x15\n" +
+	"\x06id_key\x18\x04 \x01(\tR\x05idKey\x12\x19\n" +
+	"\bid_value\x18\x05 \x01(\tR\aidValue\"\xcf\x01\n" +
+	"\n" +
+	"Diagnostic\x12@\n" +
+	"\bseverity\x18\x01 \x01(\x0e2$.wrapper.v1alpha1.DiagnosticSeverityR\bseverity\x12\x18\n" +
+	"\asummary\x18\x02 \x01(\tR\asummary\x12\x16\n" +
+	"\x06detail\x18\x03 \x01(\tR\x06detail\x12\x18\n" +
+	"\aaddress\x18\x04 \x01(\tR\aaddress\x123\n" +
+	"\x05range\x18\x05 \x01(\v2\x1d.wrapper.v1alpha1.SourceRangeR\x05range\"\x8b\x01\n" +
+	"\vSourceRange\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x121\n" +
+	"\x05start\x18\x02 \x01(\v2\x1b.wrapper.v1alpha1.SourcePosR\x05start\x12-\n" +
+	"\x03end\x18\x03 \x01(\v2\x1b.wrapper.v1alpha1.SourcePosR\x03end\"K\n" +
+	"\tSourcePos\x12\x12\n" +
+	"\x04line\x18\x01 \x01(\x05R\x04line\x12\x16\n" +
+	"\x06column\x18\x02 \x01(\x05R\x06column\x12\x12\n" +
+	"\x04byte\x18\x03 \x01(\x05R\x04byte\"\x97\x01\n" +
+	"\rChangeSummary\x12\x14\n" +
+	"\x05added\x18\x01 \x01(\x05R\x05added\x12\x18\n" +
+	"\achanged\x18\x02 \x01(\x05R\achanged\x12\x1a\n" +
+	"\bimported\x18\x03 \x01(\x05R\bimported\x12\x1c\n" +
+	"\tdestroyed\x18\x04 \x01(\x05R\tdestroyed\x12\x1c\n" +
+	"\toperation\x18\x05 \x01(\tR\toperation*Y\n" +
 	"\bStepType\x12\x19\n" +
 	"\x15STEP_TYPE_UNSPECIFIED\x10\x00\x12\x17\n" +
 	"\x13STEP_TYPE_TERRAFORM\x10\x01\x12\x19\n" +
@@ -1012,7 +2206,37 @@ const file_wrapper_v1alpha1_wrapper_proto_rawDesc = "" +
 	"\x13COMMAND_ARGOCD_PLAN\x10\x05\x12\x18\n" +
 	"\x14COMMAND_ARGOCD_APPLY\x10\x06\x12\x1f\n" +
 	"\x1bCOMMAND_ARGOCD_PLAN_DESTROY\x10\a\x12 \n" +
-	"\x1cCOMMAND_ARGOCD_APPLY_DESTROY\x10\b2m\n" +
+	"\x1cCOMMAND_ARGOCD_APPLY_DESTROY\x10\b*w\n" +
+	"\bLogLevel\x12\x19\n" +
+	"\x15LOG_LEVEL_UNSPECIFIED\x10\x00\x12\x13\n" +
+	"\x0fLOG_LEVEL_DEBUG\x10\x01\x12\x12\n" +
+	"\x0eLOG_LEVEL_INFO\x10\x02\x12\x12\n" +
+	"\x0eLOG_LEVEL_WARN\x10\x03\x12\x13\n" +
+	"\x0fLOG_LEVEL_ERROR\x10\x04*\xda\x02\n" +
+	"\x13StructuredEventType\x12%\n" +
+	"!STRUCTURED_EVENT_TYPE_UNSPECIFIED\x10\x00\x12(\n" +
+	"$STRUCTURED_EVENT_TYPE_PLANNED_CHANGE\x10\x01\x12%\n" +
+	"!STRUCTURED_EVENT_TYPE_APPLY_START\x10\x02\x12(\n" +
+	"$STRUCTURED_EVENT_TYPE_APPLY_PROGRESS\x10\x03\x12(\n" +
+	"$STRUCTURED_EVENT_TYPE_APPLY_COMPLETE\x10\x04\x12'\n" +
+	"#STRUCTURED_EVENT_TYPE_APPLY_ERRORED\x10\x05\x12$\n" +
+	" STRUCTURED_EVENT_TYPE_DIAGNOSTIC\x10\x06\x12(\n" +
+	"$STRUCTURED_EVENT_TYPE_CHANGE_SUMMARY\x10\a*\xa8\x02\n" +
+	"\x0eResourceAction\x12\x1f\n" +
+	"\x1bRESOURCE_ACTION_UNSPECIFIED\x10\x00\x12\x18\n" +
+	"\x14RESOURCE_ACTION_NOOP\x10\x01\x12\x1a\n" +
+	"\x16RESOURCE_ACTION_CREATE\x10\x02\x12\x18\n" +
+	"\x14RESOURCE_ACTION_READ\x10\x03\x12\x1a\n" +
+	"\x16RESOURCE_ACTION_UPDATE\x10\x04\x12\x1b\n" +
+	"\x17RESOURCE_ACTION_REPLACE\x10\x05\x12\x1a\n" +
+	"\x16RESOURCE_ACTION_DELETE\x10\x06\x12\x18\n" +
+	"\x14RESOURCE_ACTION_MOVE\x10\a\x12\x1a\n" +
+	"\x16RESOURCE_ACTION_IMPORT\x10\b\x12\x1a\n" +
+	"\x16RESOURCE_ACTION_FORGET\x10\t*y\n" +
+	"\x12DiagnosticSeverity\x12#\n" +
+	"\x1fDIAGNOSTIC_SEVERITY_UNSPECIFIED\x10\x00\x12\x1d\n" +
+	"\x19DIAGNOSTIC_SEVERITY_ERROR\x10\x01\x12\x1f\n" +
+	"\x1bDIAGNOSTIC_SEVERITY_WARNING\x10\x022m\n" +
 	"\x0eWrapperService\x12[\n" +
 	"\n" +
 	"StreamLogs\x12#.wrapper.v1alpha1.StreamLogsRequest\x1a$.wrapper.v1alpha1.StreamLogsResponse(\x010\x01BKZIgithub.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1;v1alpha1b\x06proto3"
@@ -1029,48 +2253,87 @@ func file_wrapper_v1alpha1_wrapper_proto_rawDescGZIP() []byte {
 	return file_wrapper_v1alpha1_wrapper_proto_rawDescData
 }
 
-var file_wrapper_v1alpha1_wrapper_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_wrapper_v1alpha1_wrapper_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_wrapper_v1alpha1_wrapper_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
+var file_wrapper_v1alpha1_wrapper_proto_msgTypes = make([]protoimpl.MessageInfo, 24)
 var file_wrapper_v1alpha1_wrapper_proto_goTypes = []any{
-	(StepType)(0),              // 0: wrapper.v1alpha1.StepType
-	(Command)(0),               // 1: wrapper.v1alpha1.Command
-	(*StreamLogsRequest)(nil),  // 2: wrapper.v1alpha1.StreamLogsRequest
-	(*StreamLogsResponse)(nil), // 3: wrapper.v1alpha1.StreamLogsResponse
-	(*Handshake)(nil),          // 4: wrapper.v1alpha1.Handshake
-	(*HandshakeAck)(nil),       // 5: wrapper.v1alpha1.HandshakeAck
-	(*LogBatch)(nil),           // 6: wrapper.v1alpha1.LogBatch
-	(*Ping)(nil),               // 7: wrapper.v1alpha1.Ping
-	(*ExecutionComplete)(nil),  // 8: wrapper.v1alpha1.ExecutionComplete
-	(*StreamComplete)(nil),     // 9: wrapper.v1alpha1.StreamComplete
-	(*PlanSummary)(nil),        // 10: wrapper.v1alpha1.PlanSummary
-	(*ModuleChanges)(nil),      // 11: wrapper.v1alpha1.ModuleChanges
-	(*ResourceMove)(nil),       // 12: wrapper.v1alpha1.ResourceMove
-	(*OutputChanges)(nil),      // 13: wrapper.v1alpha1.OutputChanges
-	nil,                        // 14: wrapper.v1alpha1.PlanSummary.ModulesEntry
+	(StepType)(0),                 // 0: wrapper.v1alpha1.StepType
+	(Command)(0),                  // 1: wrapper.v1alpha1.Command
+	(LogLevel)(0),                 // 2: wrapper.v1alpha1.LogLevel
+	(StructuredEventType)(0),      // 3: wrapper.v1alpha1.StructuredEventType
+	(ResourceAction)(0),           // 4: wrapper.v1alpha1.ResourceAction
+	(DiagnosticSeverity)(0),       // 5: wrapper.v1alpha1.DiagnosticSeverity
+	(*StreamLogsRequest)(nil),     // 6: wrapper.v1alpha1.StreamLogsRequest
+	(*StreamLogsResponse)(nil),    // 7: wrapper.v1alpha1.StreamLogsResponse
+	(*Handshake)(nil),             // 8: wrapper.v1alpha1.Handshake
+	(*HandshakeAck)(nil),          // 9: wrapper.v1alpha1.HandshakeAck
+	(*LogBatch)(nil),              // 10: wrapper.v1alpha1.LogBatch
+	(*Ping)(nil),                  // 11: wrapper.v1alpha1.Ping
+	(*ExecutionComplete)(nil),     // 12: wrapper.v1alpha1.ExecutionComplete
+	(*StreamComplete)(nil),        // 13: wrapper.v1alpha1.StreamComplete
+	(*CancelExecution)(nil),       // 14: wrapper.v1alpha1.CancelExecution
+	(*ApprovePlan)(nil),           // 15: wrapper.v1alpha1.ApprovePlan
+	(*RejectPlan)(nil),            // 16: wrapper.v1alpha1.RejectPlan
+	(*SetLogLevel)(nil),           // 17: wrapper.v1alpha1.SetLogLevel
+	(*PlanSummary)(nil),           // 18: wrapper.v1alpha1.PlanSummary
+	(*ResourceTiming)(nil),        // 19: wrapper.v1alpha1.ResourceTiming
+	(*ModuleChanges)(nil),         // 20: wrapper.v1alpha1.ModuleChanges
+	(*ResourceMove)(nil),          // 21: wrapper.v1alpha1.ResourceMove
+	(*OutputChanges)(nil),         // 22: wrapper.v1alpha1.OutputChanges
+	(*StructuredEvent)(nil),       // 23: wrapper.v1alpha1.StructuredEvent
+	(*ResourceEvent)(nil),         // 24: wrapper.v1alpha1.ResourceEvent
+	(*Diagnostic)(nil),            // 25: wrapper.v1alpha1.Diagnostic
+	(*SourceRange)(nil),           // 26: wrapper.v1alpha1.SourceRange
+	(*SourcePos)(nil),             // 27: wrapper.v1alpha1.SourcePos
+	(*ChangeSummary)(nil),         // 28: wrapper.v1alpha1.ChangeSummary
+	nil,                           // 29: wrapper.v1alpha1.PlanSummary.ModulesEntry
+	(*timestamppb.Timestamp)(nil), // 30: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 31: google.protobuf.Duration
 }
 var file_wrapper_v1alpha1_wrapper_proto_depIdxs = []int32{
-	4,  // 0: wrapper.v1alpha1.StreamLogsRequest.handshake:type_name -> wrapper.v1alpha1.Handshake
-	6,  // 1: wrapper.v1alpha1.StreamLogsRequest.log_batch:type_name -> wrapper.v1alpha1.LogBatch
-	7,  // 2: wrapper.v1alpha1.StreamLogsRequest.ping:type_name -> wrapper.v1alpha1.Ping
-	8,  // 3: wrapper.v1alpha1.StreamLogsRequest.complete:type_name -> wrapper.v1alpha1.ExecutionComplete
-	10, // 4: wrapper.v1alpha1.StreamLogsRequest.plan_summary:type_name -> wrapper.v1alpha1.PlanSummary
-	5,  // 5: wrapper.v1alpha1.StreamLogsResponse.handshake_ack:type_name -> wrapper.v1alpha1.HandshakeAck
-	7,  // 6: wrapper.v1alpha1.StreamLogsResponse.ping:type_name -> wrapper.v1alpha1.Ping
-	9,  // 7: wrapper.v1alpha1.StreamLogsResponse.complete:type_name -> wrapper.v1alpha1.StreamComplete
-	1,  // 8: wrapper.v1alpha1.Handshake.command:type_name -> wrapper.v1alpha1.Command
-	0,  // 9: wrapper.v1alpha1.Handshake.step_type:type_name -> wrapper.v1alpha1.StepType
-	11, // 10: wrapper.v1alpha1.PlanSummary.root:type_name -> wrapper.v1alpha1.ModuleChanges
-	14, // 11: wrapper.v1alpha1.PlanSummary.modules:type_name -> wrapper.v1alpha1.PlanSummary.ModulesEntry
-	12, // 12: wrapper.v1alpha1.ModuleChanges.moved:type_name -> wrapper.v1alpha1.ResourceMove
-	13, // 13: wrapper.v1alpha1.ModuleChanges.outputs:type_name -> wrapper.v1alpha1.OutputChanges
-	11, // 14: wrapper.v1alpha1.PlanSummary.ModulesEntry.value:type_name -> wrapper.v1alpha1.ModuleChanges
-	2,  // 15: wrapper.v1alpha1.WrapperService.StreamLogs:input_type -> wrapper.v1alpha1.StreamLogsRequest
-	3,  // 16: wrapper.v1alpha1.WrapperService.StreamLogs:output_type -> wrapper.v1alpha1.StreamLogsResponse
-	16, // [16:17] is the sub-list for method output_type
-	15, // [15:16] is the sub-list for method input_type
-	15, // [15:15] is the sub-list for extension type_name
-	15, // [15:15] is the sub-list for extension extendee
-	0,  // [0:15] is the sub-list for field type_name
+	8,  // 0: wrapper.v1alpha1.StreamLogsRequest.handshake:type_name -> wrapper.v1alpha1.Handshake
+	10, // 1: wrapper.v1alpha1.StreamLogsRequest.log_batch:type_name -> wrapper.v1alpha1.LogBatch
+	11, // 2: wrapper.v1alpha1.StreamLogsRequest.ping:type_name -> wrapper.v1alpha1.Ping
+	12, // 3: wrapper.v1alpha1.StreamLogsRequest.complete:type_name -> wrapper.v1alpha1.ExecutionComplete
+	18, // 4: wrapper.v1alpha1.StreamLogsRequest.plan_summary:type_name -> wrapper.v1alpha1.PlanSummary
+	23, // 5: wrapper.v1alpha1.StreamLogsRequest.structured_event:type_name -> wrapper.v1alpha1.StructuredEvent
+	9,  // 6: wrapper.v1alpha1.StreamLogsResponse.handshake_ack:type_name -> wrapper.v1alpha1.HandshakeAck
+	11, // 7: wrapper.v1alpha1.StreamLogsResponse.ping:type_name -> wrapper.v1alpha1.Ping
+	13, // 8: wrapper.v1alpha1.StreamLogsResponse.complete:type_name -> wrapper.v1alpha1.StreamComplete
+	14, // 9: wrapper.v1alpha1.StreamLogsResponse.cancel_execution:type_name -> wrapper.v1alpha1.CancelExecution
+	15, // 10: wrapper.v1alpha1.StreamLogsResponse.approve_plan:type_name -> wrapper.v1alpha1.ApprovePlan
+	16, // 11: wrapper.v1alpha1.StreamLogsResponse.reject_plan:type_name -> wrapper.v1alpha1.RejectPlan
+	17, // 12: wrapper.v1alpha1.StreamLogsResponse.set_log_level:type_name -> wrapper.v1alpha1.SetLogLevel
+	1,  // 13: wrapper.v1alpha1.Handshake.command:type_name -> wrapper.v1alpha1.Command
+	0,  // 14: wrapper.v1alpha1.Handshake.step_type:type_name -> wrapper.v1alpha1.StepType
+	2,  // 15: wrapper.v1alpha1.SetLogLevel.level:type_name -> wrapper.v1alpha1.LogLevel
+	20, // 16: wrapper.v1alpha1.PlanSummary.root:type_name -> wrapper.v1alpha1.ModuleChanges
+	29, // 17: wrapper.v1alpha1.PlanSummary.modules:type_name -> wrapper.v1alpha1.PlanSummary.ModulesEntry
+	19, // 18: wrapper.v1alpha1.PlanSummary.slowest_resources:type_name -> wrapper.v1alpha1.ResourceTiming
+	4,  // 19: wrapper.v1alpha1.ResourceTiming.action:type_name -> wrapper.v1alpha1.ResourceAction
+	30, // 20: wrapper.v1alpha1.ResourceTiming.started:type_name -> google.protobuf.Timestamp
+	30, // 21: wrapper.v1alpha1.ResourceTiming.finished:type_name -> google.protobuf.Timestamp
+	31, // 22: wrapper.v1alpha1.ResourceTiming.duration:type_name -> google.protobuf.Duration
+	21, // 23: wrapper.v1alpha1.ModuleChanges.moved:type_name -> wrapper.v1alpha1.ResourceMove
+	22, // 24: wrapper.v1alpha1.ModuleChanges.outputs:type_name -> wrapper.v1alpha1.OutputChanges
+	3,  // 25: wrapper.v1alpha1.StructuredEvent.type:type_name -> wrapper.v1alpha1.StructuredEventType
+	30, // 26: wrapper.v1alpha1.StructuredEvent.timestamp:type_name -> google.protobuf.Timestamp
+	24, // 27: wrapper.v1alpha1.StructuredEvent.resource:type_name -> wrapper.v1alpha1.ResourceEvent
+	25, // 28: wrapper.v1alpha1.StructuredEvent.diagnostic:type_name -> wrapper.v1alpha1.Diagnostic
+	28, // 29: wrapper.v1alpha1.StructuredEvent.change_summary:type_name -> wrapper.v1alpha1.ChangeSummary
+	4,  // 30: wrapper.v1alpha1.ResourceEvent.action:type_name -> wrapper.v1alpha1.ResourceAction
+	31, // 31: wrapper.v1alpha1.ResourceEvent.elapsed:type_name -> google.protobuf.Duration
+	5,  // 32: wrapper.v1alpha1.Diagnostic.severity:type_name -> wrapper.v1alpha1.DiagnosticSeverity
+	26, // 33: wrapper.v1alpha1.Diagnostic.range:type_name -> wrapper.v1alpha1.SourceRange
+	27, // 34: wrapper.v1alpha1.SourceRange.start:type_name -> wrapper.v1alpha1.SourcePos
+	27, // 35: wrapper.v1alpha1.SourceRange.end:type_name -> wrapper.v1alpha1.SourcePos
+	20, // 36: wrapper.v1alpha1.PlanSummary.ModulesEntry.value:type_name -> wrapper.v1alpha1.ModuleChanges
+	6,  // 37: wrapper.v1alpha1.WrapperService.StreamLogs:input_type -> wrapper.v1alpha1.StreamLogsRequest
+	7,  // 38: wrapper.v1alpha1.WrapperService.StreamLogs:output_type -> wrapper.v1alpha1.StreamLogsResponse
+	38, // [38:39] is the sub-list for method output_type
+	37, // [37:38] is the sub-list for method input_type
+	37, // [37:37] is the sub-list for extension type_name
+	37, // [37:37] is the sub-list for extension extendee
+	0,  // [0:37] is the sub-list for field type_name
 }
 
 func init() { file_wrapper_v1alpha1_wrapper_proto_init() }
@@ -1084,19 +2347,31 @@ func file_wrapper_v1alpha1_wrapper_proto_init() {
 		(*StreamLogsRequest_Ping)(nil),
 		(*StreamLogsRequest_Complete)(nil),
 		(*StreamLogsRequest_PlanSummary)(nil),
+		(*StreamLogsRequest_StructuredEvent)(nil),
 	}
 	file_wrapper_v1alpha1_wrapper_proto_msgTypes[1].OneofWrappers = []any{
 		(*StreamLogsResponse_HandshakeAck)(nil),
 		(*StreamLogsResponse_Ping)(nil),
 		(*StreamLogsResponse_Complete)(nil),
+		(*StreamLogsResponse_CancelExecution)(nil),
+		(*StreamLogsResponse_ApprovePlan)(nil),
+		(*StreamLogsResponse_RejectPlan)(nil),
+		(*StreamLogsResponse_SetLogLevel)(nil),
+	}
+	file_wrapper_v1alpha1_wrapper_proto_msgTypes[3].OneofWrappers = []any{}
+	file_wrapper_v1alpha1_wrapper_proto_msgTypes[4].OneofWrappers = []any{}
+	file_wrapper_v1alpha1_wrapper_proto_msgTypes[17].OneofWrappers = []any{
+		(*StructuredEvent_Resource)(nil),
+		(*StructuredEvent_Diagnostic)(nil),
+		(*StructuredEvent_ChangeSummary)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_wrapper_v1alpha1_wrapper_proto_rawDesc), len(file_wrapper_v1alpha1_wrapper_proto_rawDesc)),
-			NumEnums:      2,
-			NumMessages:   13,
+			NumEnums:      6,
+			NumMessages:   24,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
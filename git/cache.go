@@ -0,0 +1,193 @@
+package git
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/util"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+const (
+	cacheReposDir     = "repos"
+	cacheChecksumsDir = "checksums"
+	cacheLockFile     = ".lock"
+)
+
+// Cache is a content-addressed directory shared between agent runs. It holds bare clones of the source
+// repositories and module checksums keyed by commit hash. Runs hold a shared lock on the cache, prune requires
+// an exclusive one.
+type Cache struct {
+	dir string
+}
+
+func NewCache(ctx context.Context, dir string) (*Cache, error) {
+	cache := &Cache{dir: dir}
+	if err := cache.createDirs(); err != nil {
+		return nil, err
+	}
+	lockFile, err := cache.lock(filepath.Join(dir, cacheLockFile), syscall.LOCK_SH)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock cache %s: %w", dir, err)
+	}
+	go func() {
+		<-ctx.Done()
+		unlock(lockFile)
+	}()
+	common.Logger(ctx).Debug(fmt.Sprintf("Using source cache %s", dir))
+	return cache, nil
+}
+
+func (c *Cache) createDirs() error {
+	for _, dir := range []string{cacheReposDir, cacheChecksumsDir} {
+		if err := os.MkdirAll(filepath.Join(c.dir, dir), 0700); err != nil {
+			return fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+func (c *Cache) repoPath(url string) string {
+	return filepath.Join(c.dir, cacheReposDir, util.HashCode(url))
+}
+
+// lockRepo serializes clones and fetches of the same repository between processes.
+func (c *Cache) lockRepo(repoPath string) (*os.File, error) {
+	return c.lock(repoPath+cacheLockFile, syscall.LOCK_EX)
+}
+
+func (c *Cache) lock(path string, how int) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.Flock(int(file.Fd()), how); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+func unlock(file *os.File) {
+	_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	_ = file.Close()
+}
+
+func touch(path string) {
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+}
+
+func (c *Cache) checksumsPath(hash plumbing.Hash) string {
+	return filepath.Join(c.dir, cacheChecksumsDir, hash.String()+".json")
+}
+
+func (c *Cache) getChecksums(hash plumbing.Hash) (map[string][]byte, bool) {
+	path := c.checksumsPath(hash)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn(common.PrefixWarning(fmt.Sprintf("failed to read cached checksums %s: %v", path, err)))
+		}
+		return nil, false
+	}
+	var checksums map[string][]byte
+	if err = json.Unmarshal(content, &checksums); err != nil {
+		slog.Warn(common.PrefixWarning(fmt.Sprintf("failed to unmarshal cached checksums %s: %v", path, err)))
+		return nil, false
+	}
+	touch(path)
+	return checksums, true
+}
+
+func (c *Cache) putChecksums(hash plumbing.Hash, checksums map[string][]byte) error {
+	content, err := json.Marshal(checksums)
+	if err != nil {
+		return err
+	}
+	path := c.checksumsPath(hash)
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+	if _, err = tmpFile.Write(content); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// PruneCache removes cache entries that haven't been used within maxAge. Zero maxAge removes all entries.
+func PruneCache(dir string, maxAge time.Duration) error {
+	cache := &Cache{dir: dir}
+	lockPath := filepath.Join(dir, cacheLockFile)
+	if _, err := os.Stat(lockPath); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cache directory %s not found", dir)
+	}
+	lockFile, err := cache.lock(lockPath, syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return fmt.Errorf("cache %s is in use by another process", dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock cache %s: %w", dir, err)
+	}
+	defer unlock(lockFile)
+
+	cutoff := time.Now().Add(-maxAge)
+	repos, err := pruneEntries(filepath.Join(dir, cacheReposDir), cutoff, maxAge == 0)
+	if err != nil {
+		return err
+	}
+	checksums, err := pruneEntries(filepath.Join(dir, cacheChecksumsDir), cutoff, maxAge == 0)
+	if err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Pruned %d repositories and %d checksum files from cache %s", repos, checksums, dir))
+	return nil
+}
+
+func pruneEntries(dir string, cutoff time.Time, all bool) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	pruned := 0
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), cacheLockFile) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return pruned, err
+		}
+		if !all && info.ModTime().After(cutoff) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		slog.Debug(fmt.Sprintf("Removing cache entry %s", path))
+		if err = os.RemoveAll(path); err != nil {
+			return pruned, fmt.Errorf("failed to remove cache entry %s: %w", path, err)
+		}
+		_ = os.Remove(path + cacheLockFile)
+		pruned++
+	}
+	return pruned, nil
+}
@@ -0,0 +1,99 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestCacheChecksums(t *testing.T) {
+	cache, err := NewCache(t.Context(), t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	hash := plumbing.NewHash("4bf92f3577b34da6a3ce929d0e0e4736a3ce929d")
+	if _, found := cache.getChecksums(hash); found {
+		t.Fatal("expected no checksums before put")
+	}
+	checksums := map[string][]byte{"aws/vpc": []byte("vpc-checksum"), "aws/eks": []byte("eks-checksum")}
+	if err = cache.putChecksums(hash, checksums); err != nil {
+		t.Fatalf("failed to put checksums: %v", err)
+	}
+	cached, found := cache.getChecksums(hash)
+	if !found || !reflect.DeepEqual(cached, checksums) {
+		t.Fatalf("expected checksums %v, got %v", checksums, cached)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxAge   time.Duration
+		expected []string
+	}{
+		{name: "old entries", maxAge: time.Hour, expected: []string{"checksums/new.json", "repos/new", "repos/new.lock"}},
+		{name: "all entries"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			ctx, cancel := context.WithCancel(t.Context())
+			if _, err := NewCache(ctx, dir); err != nil {
+				t.Fatalf("failed to create cache: %v", err)
+			}
+			old := time.Now().Add(-2 * time.Hour)
+			for _, entry := range []string{"repos/old", "repos/new", "checksums/old.json", "checksums/new.json"} {
+				path := filepath.Join(dir, entry)
+				if err := os.WriteFile(path, nil, 0600); err != nil {
+					t.Fatalf("failed to write entry: %v", err)
+				}
+				if strings.Contains(entry, "old") {
+					_ = os.Chtimes(path, old, old)
+				}
+				if strings.HasPrefix(entry, "repos") {
+					_ = os.WriteFile(path+cacheLockFile, nil, 0600)
+				}
+			}
+			err := PruneCache(dir, test.maxAge)
+			if err == nil || !strings.Contains(err.Error(), "in use by another process") {
+				t.Fatalf("expected cache in use error, got %v", err)
+			}
+			cancel()
+			// The shared lock is released in the background after the context is done.
+			for range 100 {
+				if err = PruneCache(dir, test.maxAge); err == nil {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if err != nil {
+				t.Fatalf("failed to prune cache: %v", err)
+			}
+			var entries []string
+			for _, folder := range []string{cacheReposDir, cacheChecksumsDir} {
+				files, _ := os.ReadDir(filepath.Join(dir, folder))
+				for _, file := range files {
+					entries = append(entries, folder+"/"+file.Name())
+				}
+			}
+			slices.Sort(entries)
+			if !reflect.DeepEqual(entries, test.expected) {
+				t.Fatalf("expected entries %v, got %v", test.expected, entries)
+			}
+		})
+	}
+}
+
+func TestPruneMissingCache(t *testing.T) {
+	err := PruneCache(filepath.Join(t.TempDir(), "missing"), 0)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
@@ -4,13 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-git/v5"
@@ -49,7 +48,7 @@ type DestClient struct {
 }
 
 func NewDestClient(ctx context.Context, name string, config model.Git, CABundle []byte) (*DestClient, error) {
-	log.Printf("Preparing git repository %s", config.URL)
+	common.Logger(ctx).Info(fmt.Sprintf("Preparing git repository %s", config.URL))
 	auth, err := getAuth(config)
 	if err != nil {
 		return nil, err
@@ -426,9 +425,9 @@ func (g *DestClient) hasChanges(folder string) (bool, error) {
 	}
 	hasChanges := !status.IsClean()
 	if hasChanges {
-		slog.Debug(fmt.Sprintf("Destination %s folder %s git status\n%s", g.name, folder, status))
+		common.Logger(g.ctx).Debug(fmt.Sprintf("Destination %s folder %s git status\n%s", g.name, folder, status))
 	} else {
-		slog.Debug(fmt.Sprintf("Destination %s folder %s git status is clean", g.name, folder))
+		common.Logger(g.ctx).Debug(fmt.Sprintf("Destination %s folder %s git status is clean", g.name, folder))
 	}
 	return hasChanges, nil
 }
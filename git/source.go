@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -14,12 +13,14 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
 	"github.com/entigolabs/entigo-infralib-agent/util"
 	"github.com/go-git/go-billy/v5"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
 	"github.com/go-git/go-git/v5/plumbing/transport"
@@ -27,6 +28,8 @@ import (
 	"github.com/hashicorp/go-version"
 )
 
+const agentFile = "agent.yaml"
+
 var (
 	repoMutex = sync.Mutex{}
 )
@@ -45,14 +48,33 @@ type SourceClient struct {
 	pulled         model.Set[string]
 	CABundle       []byte
 	treesCache     map[string]*object.Tree
+	commitsCache   map[string]*object.Commit
+	cache          *Cache
+	verifier       *releaseVerifier
+	verifyMu       sync.Mutex
+	verifications  map[string]model.ReleaseVerification
+	applied        model.Set[string]
+	skipVersions   []string
 }
 
-func NewSourceClient(ctx context.Context, source model.ConfigSource, CABundle []byte) (*SourceClient, error) {
-	log.Printf("Initializing repository for %s", source.GetSourceKey())
+// NewSourceClient initializes the source repository. When cache is not nil and the source doesn't have a
+// repo path, a bare clone in the cache is used and files are read from the git objects instead of a worktree.
+func NewSourceClient(ctx context.Context, source model.ConfigSource, CABundle []byte, cache *Cache) (*SourceClient, error) {
+	common.Logger(ctx).Info(fmt.Sprintf("Initializing repository for %s", source.GetSourceKey()))
+	verifier, err := newReleaseVerifier(source.Verify)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create release verifier: %w", err)
+	}
 	auth := getSourceAuth(source)
 	enableAzureCompatibility(source.URL)
 	defer disableAzureCompatibility(source.URL)
-	repo, err := getSourceRepo(ctx, auth, source, CABundle)
+	var repo *git.Repository
+	if cache != nil && source.RepoPath == "" {
+		repo, err = getCachedSourceRepo(ctx, auth, source, CABundle, cache)
+	} else {
+		cache = nil
+		repo, err = getSourceRepo(ctx, auth, source, CABundle)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -65,27 +87,102 @@ func NewSourceClient(ctx context.Context, source model.ConfigSource, CABundle []
 			return nil, fmt.Errorf("release %s not found in repository", source.Version)
 		}
 	}
-	worktree, err := repo.Worktree()
-	if err != nil {
-		return nil, err
+	var worktree *git.Worktree
+	if cache == nil {
+		worktree, err = repo.Worktree()
+		if err != nil {
+			return nil, err
+		}
 	}
 	releases, releasesSet, err := getReleases(repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tags: %v", err)
 	}
-	return &SourceClient{
-		ctx:         ctx,
-		auth:        auth,
-		insecure:    source.Insecure,
-		url:         source.URL,
-		repo:        repo,
-		worktree:    worktree,
-		releases:    releases,
-		releasesSet: releasesSet,
-		pulled:      model.NewSet[string](),
-		CABundle:    CABundle,
-		treesCache:  make(map[string]*object.Tree),
-	}, nil
+	client := &SourceClient{
+		ctx:           ctx,
+		auth:          auth,
+		insecure:      source.Insecure,
+		url:           source.URL,
+		repo:          repo,
+		worktree:      worktree,
+		releases:      releases,
+		releasesSet:   releasesSet,
+		pulled:        model.NewSet[string](),
+		CABundle:      CABundle,
+		treesCache:    make(map[string]*object.Tree),
+		commitsCache:  make(map[string]*object.Commit),
+		cache:         cache,
+		verifier:      verifier,
+		verifications: make(map[string]model.ReleaseVerification),
+		applied:       model.NewSet[string](),
+	}
+	client.skipVersions = append(client.skipVersions, source.SkipVersions...)
+	remoteSkipVersions, err := client.getRemoteSkipVersions()
+	if err != nil {
+		common.Logger(ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to get skip versions for %s: %s", source.URL, err)))
+	}
+	client.skipVersions = append(client.skipVersions, remoteSkipVersions...)
+	if len(client.skipVersions) > 0 {
+		common.Logger(ctx).Info(fmt.Sprintf("Skipping versions %s for %s", strings.Join(client.skipVersions, ", "), source.URL))
+	}
+	if source.ForceVersion && source.Version != "" {
+		if err = client.checkForcedVersion(source.Version); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
+}
+
+// checkForcedVersion fails when the forced version is not verified or is in the source skip versions
+func (s *SourceClient) checkForcedVersion(release string) error {
+	if err := s.verifyRelease(release); err != nil {
+		return err
+	}
+	forcedVersion, err := version.NewVersion(release)
+	if err == nil && util.IsVersionSkipped(s.skipVersions, forcedVersion) {
+		return fmt.Errorf("forced version %s of %s is in skip versions", release, s.url)
+	}
+	return nil
+}
+
+// getRemoteSkipVersions reads the skip versions from the agent.yaml file in the default branch of the source
+func (s *SourceClient) getRemoteSkipVersions() ([]string, error) {
+	ref, err := getDefaultBranchRef(s.repo)
+	if err != nil {
+		ref, err = s.repo.Head()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get default branch: %w", err)
+		}
+	}
+	commit, err := s.repo.CommitObject(ref.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default branch commit: %w", err)
+	}
+	file, err := commit.File(agentFile)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	agent, err := model.UnmarshalAgentYaml([]byte(content))
+	if err != nil {
+		return nil, err
+	}
+	switch v := agent.(type) {
+	case model.V1Agent:
+		return v.SkipVersions, nil
+	default:
+		return nil, fmt.Errorf("unsupported agent file version: %T", v)
+	}
+}
+
+func (s *SourceClient) GetSkipVersions() []string {
+	return s.skipVersions
 }
 
 func getReleases(repo *git.Repository) ([]*version.Version, model.Set[string], error) {
@@ -132,7 +229,7 @@ func getSourceRepo(ctx context.Context, auth transport.AuthMethod, source model.
 	if err != nil {
 		return nil, err
 	}
-	slog.Debug(fmt.Sprintf("Source %s repository path %s", source.URL, repoPath))
+	common.Logger(ctx).Debug(fmt.Sprintf("Source %s repository path %s", source.URL, repoPath))
 	repoMutex.Lock()
 	defer repoMutex.Unlock()
 	repo, err := openSourceRepo(ctx, auth, source, repoPath, CABundle)
@@ -150,6 +247,43 @@ func getSourceRepo(ctx context.Context, auth transport.AuthMethod, source model.
 	})
 }
 
+func getCachedSourceRepo(ctx context.Context, auth transport.AuthMethod, source model.ConfigSource, CABundle []byte, cache *Cache) (*git.Repository, error) {
+	repoPath := cache.repoPath(source.URL)
+	common.Logger(ctx).Debug(fmt.Sprintf("Source %s cached repository path %s", source.URL, repoPath))
+	repoMutex.Lock()
+	defer repoMutex.Unlock()
+	lockFile, err := cache.lockRepo(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock cached repository %s: %w", repoPath, err)
+	}
+	defer unlock(lockFile)
+	defer touch(repoPath)
+	repo, err := git.PlainOpen(repoPath)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return git.PlainCloneContext(ctx, repoPath, true, &git.CloneOptions{
+			URL:             source.URL,
+			Auth:            auth,
+			Tags:            git.AllTags,
+			InsecureSkipTLS: source.Insecure,
+			CABundle:        CABundle,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		Auth:            auth,
+		Prune:           true,
+		Tags:            git.AllTags,
+		InsecureSkipTLS: source.Insecure,
+		CABundle:        CABundle,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, err
+	}
+	return repo, nil
+}
+
 func enableAzureCompatibility(repo string) {
 	if !util.IsAzureDevOps(repo) {
 		return
@@ -238,16 +372,43 @@ func (s *SourceClient) GetLatestReleaseTag() (*version.Version, error) {
 	if len(s.releases) == 0 {
 		return nil, fmt.Errorf("no releases found")
 	}
-	return s.releases[len(s.releases)-1], nil
+	for i := len(s.releases) - 1; i >= 0; i-- {
+		if util.IsVersionSkipped(s.skipVersions, s.releases[i]) {
+			continue
+		}
+		err := s.verifyRelease(s.releases[i].Original())
+		if err == nil {
+			return s.releases[i], nil
+		}
+		common.Logger(s.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Skipping release: %s", err)))
+	}
+	return nil, fmt.Errorf("no allowed releases found")
 }
 
 func (s *SourceClient) GetRelease(release string) (*version.Version, error) {
 	if !s.releasesSet.Contains(release) {
 		return nil, fmt.Errorf("release %s not found", release)
 	}
+	if err := s.verifyRelease(release); err != nil {
+		return nil, err
+	}
+	return version.NewVersion(release)
+}
+
+// GetAppliedRelease returns the release without verifying it, as the release is already applied. Releases that
+// predate signing would otherwise block every update.
+func (s *SourceClient) GetAppliedRelease(release string) (*version.Version, error) {
+	if !s.releasesSet.Contains(release) {
+		return nil, fmt.Errorf("release %s not found", release)
+	}
+	s.verifyMu.Lock()
+	s.applied.Add(release)
+	s.verifyMu.Unlock()
 	return version.NewVersion(release)
 }
 
+// GetReleases returns releases between the oldest and newest release, skipped and unverified releases are excluded.
+// Oldest release is always included as it may be already applied.
 func (s *SourceClient) GetReleases(oldestRelease, newestRelease *version.Version) ([]*version.Version, error) {
 	var newReleases []*version.Version
 	for _, release := range s.releases {
@@ -257,12 +418,84 @@ func (s *SourceClient) GetReleases(oldestRelease, newestRelease *version.Version
 		if newestRelease != nil && release.GreaterThan(newestRelease) {
 			break
 		}
+		if release.Equal(oldestRelease) {
+			newReleases = append(newReleases, release)
+			continue
+		}
+		if util.IsVersionSkipped(s.skipVersions, release) {
+			common.Logger(s.ctx).Debug(fmt.Sprintf("Skipping release %s of %s", release.Original(), s.url))
+			continue
+		}
+		if err := s.verifyRelease(release.Original()); err != nil {
+			common.Logger(s.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Skipping release: %s", err)))
+			continue
+		}
 		newReleases = append(newReleases, release)
 	}
 	return newReleases, nil
 }
 
+// GetVerifications returns the results of release signature verifications, sorted by release
+func (s *SourceClient) GetVerifications() []model.ReleaseVerification {
+	s.verifyMu.Lock()
+	defer s.verifyMu.Unlock()
+	verifications := make([]model.ReleaseVerification, 0, len(s.verifications))
+	for _, verification := range s.verifications {
+		verifications = append(verifications, verification)
+	}
+	sort.Slice(verifications, func(i, j int) bool {
+		return verifications[i].Release < verifications[j].Release
+	})
+	return verifications
+}
+
+// verifyRelease checks that the release is a tag signed by a trusted key, results are memoized
+func (s *SourceClient) verifyRelease(release string) error {
+	if s.verifier == nil {
+		return nil
+	}
+	s.verifyMu.Lock()
+	defer s.verifyMu.Unlock()
+	if s.applied.Contains(release) {
+		return nil
+	}
+	verification, exists := s.verifications[release]
+	if !exists {
+		verification = s.getVerification(release)
+		s.verifications[release] = verification
+		if verification.Verified {
+			common.Logger(s.ctx).Debug(fmt.Sprintf("Release %s of %s verified with %s, signer %s", release, s.url,
+				verification.Method, verification.Signer))
+		}
+	}
+	if !verification.Verified {
+		return fmt.Errorf("release %s of %s failed verification: %s", release, s.url, verification.Reason)
+	}
+	return nil
+}
+
+func (s *SourceClient) getVerification(release string) model.ReleaseVerification {
+	if !s.releasesSet.Contains(release) {
+		return model.ReleaseVerification{Release: release, Reason: "only signed tags are allowed"}
+	}
+	ref, err := s.repo.Tag(release)
+	if err != nil {
+		return model.ReleaseVerification{Release: release, Reason: fmt.Sprintf("failed to get tag: %s", err)}
+	}
+	tag, err := s.repo.TagObject(ref.Hash())
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return model.ReleaseVerification{Release: release, Reason: "lightweight tags can't be signed"}
+	}
+	if err != nil {
+		return model.ReleaseVerification{Release: release, Reason: fmt.Sprintf("failed to get tag object: %s", err)}
+	}
+	return s.verifier.verifyTag(tag)
+}
+
 func (s *SourceClient) GetFile(path string, release string) ([]byte, error) {
+	if s.worktree == nil {
+		return s.getTreeFile(path, release)
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -285,10 +518,38 @@ func (s *SourceClient) GetFile(path string, release string) ([]byte, error) {
 	return io.ReadAll(file)
 }
 
+func (s *SourceClient) getTreeFile(path string, release string) ([]byte, error) {
+	tree, err := s.getReleaseTree(release)
+	if err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		return nil, fmt.Errorf("release %s not found", release)
+	}
+	file, err := tree.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, model.NewNotFoundError(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	reader, err := file.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	return io.ReadAll(reader)
+}
+
 func (s *SourceClient) checkoutClean(release string) error {
 	if s.currentRelease == release {
 		return nil
 	}
+	if err := s.verifyRelease(release); err != nil {
+		return err
+	}
 	enableAzureCompatibility(s.url)
 	defer disableAzureCompatibility(s.url)
 	var checkoutRef plumbing.ReferenceName
@@ -315,7 +576,12 @@ func (s *SourceClient) checkoutClean(release string) error {
 }
 
 func (s *SourceClient) fetchBranch(release string) error {
-	err := s.repo.Fetch(&git.FetchOptions{
+	unlockRepo, err := s.lockCachedRepo()
+	if err != nil {
+		return err
+	}
+	defer unlockRepo()
+	err = s.repo.FetchContext(s.ctx, &git.FetchOptions{
 		RefSpecs: []config.RefSpec{
 			config.RefSpec(fmt.Sprintf("+refs/heads/%[1]s:refs/remotes/origin/%[1]s", release)),
 		},
@@ -330,6 +596,24 @@ func (s *SourceClient) fetchBranch(release string) error {
 	return nil
 }
 
+// lockCachedRepo takes the same locks as getCachedSourceRepo before writing refs or objects into the shared cache.
+func (s *SourceClient) lockCachedRepo() (func(), error) {
+	if s.cache == nil {
+		return func() {}, nil
+	}
+	repoPath := s.cache.repoPath(s.url)
+	repoMutex.Lock()
+	lockFile, err := s.cache.lockRepo(repoPath)
+	if err != nil {
+		repoMutex.Unlock()
+		return nil, fmt.Errorf("failed to lock cached repository %s: %w", repoPath, err)
+	}
+	return func() {
+		unlock(lockFile)
+		repoMutex.Unlock()
+	}, nil
+}
+
 func (s *SourceClient) FileExists(path string, release string) bool {
 	tree, err := s.getReleaseTree(release)
 	if err != nil || tree == nil {
@@ -364,6 +648,29 @@ func (s *SourceClient) getReleaseTree(release string) (*object.Tree, error) {
 	if cachedTree, exists := s.treesCache[release]; exists {
 		return cachedTree, nil
 	}
+	commit, err := s.getReleaseCommit(release)
+	if err != nil {
+		return nil, err
+	}
+	if commit == nil {
+		return nil, nil // Release doesn't exist
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+	s.treesCache[release] = tree
+	return tree, nil
+}
+
+func (s *SourceClient) getReleaseCommit(release string) (*object.Commit, error) {
+	if cachedCommit, exists := s.commitsCache[release]; exists {
+		return cachedCommit, nil
+	}
+	if err := s.verifyRelease(release); err != nil {
+		return nil, err
+	}
 	if !s.releasesSet.Contains(release) && !s.pulled.Contains(release) {
 		_ = s.fetchBranch(release) // Ignore error here; ResolveRevision will catch missing refs
 	}
@@ -373,20 +680,15 @@ func (s *SourceClient) getReleaseTree(release string) (*object.Tree, error) {
 		return nil, fmt.Errorf("failed to get release hash: %w", err)
 	}
 	if hash == nil {
-		return nil, nil // Release doesn't exist
+		return nil, nil
 	}
 
 	commit, err := s.repo.CommitObject(*hash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get commit object: %w", err)
 	}
-
-	tree, err := commit.Tree()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get commit tree: %w", err)
-	}
-	s.treesCache[release] = tree
-	return tree, nil
+	s.commitsCache[release] = commit
+	return commit, nil
 }
 
 func releaseExists(repo *git.Repository, release string) (bool, error) {
@@ -419,6 +721,35 @@ func (s *SourceClient) CalculateChecksums(release string) (map[string][]byte, er
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.cache == nil {
+		return s.calculateWorktreeChecksums(release)
+	}
+	commit, err := s.getReleaseCommit(release)
+	if err != nil {
+		return nil, err
+	}
+	if commit == nil {
+		return nil, fmt.Errorf("release %s not found", release)
+	}
+	if checksums, found := s.cache.getChecksums(commit.Hash); found {
+		common.Logger(s.ctx).Debug(fmt.Sprintf("Using cached checksums for %s release %s", s.url, release))
+		return checksums, nil
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit tree: %w", err)
+	}
+	checksums, err := s.calculateTreeChecksums(tree)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.cache.putChecksums(commit.Hash, checksums); err != nil {
+		common.Logger(s.ctx).Warn(common.PrefixWarning(fmt.Sprintf("failed to cache checksums for %s release %s: %v", s.url, release, err)))
+	}
+	return checksums, nil
+}
+
+func (s *SourceClient) calculateWorktreeChecksums(release string) (map[string][]byte, error) {
 	err := s.checkoutClean(release)
 	if err != nil {
 		return nil, err
@@ -436,6 +767,124 @@ func (s *SourceClient) CalculateChecksums(release string) (map[string][]byte, er
 	return checksums, nil
 }
 
+// calculateTreeChecksums calculates the same checksums as the worktree functions, but from git objects
+func (s *SourceClient) calculateTreeChecksums(tree *object.Tree) (map[string][]byte, error) {
+	checksums := make(map[string][]byte)
+	modules, err := getSubTree(tree, "modules")
+	if err != nil || modules == nil {
+		return checksums, err
+	}
+	for _, parent := range modules.Entries {
+		if parent.Mode != filemode.Dir {
+			continue
+		}
+		parentTree, err := s.repo.TreeObject(parent.Hash)
+		if err != nil {
+			return nil, err
+		}
+		for _, module := range parentTree.Entries {
+			if module.Mode != filemode.Dir {
+				continue
+			}
+			moduleTree, err := s.repo.TreeObject(module.Hash)
+			if err != nil {
+				return nil, err
+			}
+			sum, err := s.treeChecksum(moduleTree)
+			if err != nil {
+				return nil, err
+			}
+			checksums[filepath.Join("modules", parent.Name, module.Name)] = sum
+		}
+	}
+	providers, err := getSubTree(tree, "providers")
+	if err != nil || providers == nil {
+		return checksums, err
+	}
+	for _, entry := range providers.Entries {
+		if !entry.Mode.IsFile() {
+			continue
+		}
+		if strings.HasPrefix(entry.Name, "go.") || strings.HasPrefix(entry.Name, "README.") || strings.HasPrefix(entry.Name, "test") {
+			continue
+		}
+		sum, err := s.blobChecksum(entry.Hash)
+		if err != nil {
+			return nil, err
+		}
+		checksums[filepath.Join("providers", entry.Name)] = sum
+	}
+	return checksums, nil
+}
+
+func getSubTree(tree *object.Tree, path string) (*object.Tree, error) {
+	subTree, err := tree.Tree(path)
+	if errors.Is(err, object.ErrDirectoryNotFound) || errors.Is(err, object.ErrEntryNotFound) {
+		return nil, nil
+	}
+	return subTree, err
+}
+
+func (s *SourceClient) treeChecksum(tree *object.Tree) ([]byte, error) {
+	var keys []string
+	sums := make(map[string][]byte)
+	for _, entry := range tree.Entries {
+		lowered := strings.ToLower(entry.Name)
+		if strings.HasPrefix(lowered, "test") || strings.HasPrefix(lowered, "readme") || strings.HasPrefix(lowered, "go.") {
+			continue
+		}
+		var sum []byte
+		var err error
+		switch entry.Mode {
+		case filemode.Dir:
+			var subTree *object.Tree
+			subTree, err = s.repo.TreeObject(entry.Hash)
+			if err == nil {
+				sum, err = s.treeChecksum(subTree)
+			}
+		case filemode.Submodule:
+			sum = sha256.New().Sum(nil) // Submodules are empty directories in the worktree
+		default:
+			sum, err = s.blobChecksum(entry.Hash)
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, entry.Name)
+		sums[entry.Name] = sum
+	}
+
+	h := sha256.New()
+	sort.Strings(keys)
+	for _, key := range keys {
+		_, err := h.Write(sums[key])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+func (s *SourceClient) blobChecksum(hash plumbing.Hash) ([]byte, error) {
+	blob, err := s.repo.BlobObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+
+	h := sha256.New()
+	if _, err = io.Copy(h, reader); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
 func (s *SourceClient) generateModulesChecksums(checksums map[string][]byte) error {
 	exists, err := s.directoryExists("modules")
 	if !exists || err != nil {
@@ -0,0 +1,60 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+)
+
+func TestGetReleases(t *testing.T) {
+	var releases []*version.Version
+	for _, release := range []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0"} {
+		releases = append(releases, version.Must(version.NewVersion(release)))
+	}
+	client := &SourceClient{
+		ctx:          context.Background(),
+		releases:     releases,
+		skipVersions: []string{"v1.0.0", "v1.2.0"},
+	}
+
+	got, err := client.GetReleases(releases[0], nil)
+	if err != nil {
+		t.Fatalf("failed to get releases: %v", err)
+	}
+	if len(got) != 3 || got[0] != releases[0] || got[1] != releases[1] || got[2] != releases[3] {
+		t.Fatalf("expected skipped oldest release to be included, got %v", got)
+	}
+
+	got, err = client.GetReleases(releases[3], releases[2])
+	if err != nil || len(got) != 0 {
+		t.Fatalf("expected no releases without an error, got %v, %v", got, err)
+	}
+}
+
+func TestCheckForcedVersion(t *testing.T) {
+	client := &SourceClient{
+		ctx:          context.Background(),
+		url:          "https://example.com/repo",
+		skipVersions: []string{"v1.2.0"},
+	}
+	tests := []struct {
+		release string
+		err     string
+	}{
+		{release: "v1.1.0"},
+		{release: "v1.2.0", err: "forced version v1.2.0 of https://example.com/repo is in skip versions"},
+		{release: "1.2.0", err: "is in skip versions"},
+		{release: "main"},
+	}
+	for _, test := range tests {
+		err := client.checkForcedVersion(test.release)
+		if test.err == "" && err != nil {
+			t.Fatalf("expected %s to be allowed, got %v", test.release, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Fatalf("expected %s to fail with %q, got %v", test.release, test.err, err)
+		}
+	}
+}
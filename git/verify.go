@@ -0,0 +1,290 @@
+package git
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	VerifyMethodGPG = "gpg"
+	VerifyMethodSSH = "ssh"
+	VerifyMethodKey = "key"
+
+	pgpSignaturePrefix    = "-----BEGIN PGP SIGNATURE-----"
+	sshSignaturePrefix    = "-----BEGIN SSH SIGNATURE-----"
+	sshSignatureMagic     = "SSHSIG"
+	sshSignatureNamespace = "git"
+	keySignatureTrailer   = "Release-Signature:"
+)
+
+type releaseVerifier struct {
+	gpgKeyRing  string
+	sshSigners  []allowedSigner
+	publicKey   crypto.PublicKey
+	publicKeyId string
+}
+
+type allowedSigner struct {
+	principals string
+	key        ssh.PublicKey
+	namespaces []string
+}
+
+func newReleaseVerifier(verify *model.SourceVerify) (*releaseVerifier, error) {
+	if verify == nil {
+		return nil, nil
+	}
+	verifier := &releaseVerifier{gpgKeyRing: strings.Join(verify.GPGKeys, "\n")}
+	if verify.SSHAllowedSigners != "" {
+		signers, err := parseAllowedSigners(verify.SSHAllowedSigners)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ssh allowed signers: %w", err)
+		}
+		verifier.sshSigners = signers
+	}
+	if verify.PublicKey != "" {
+		block, _ := pem.Decode([]byte(verify.PublicKey))
+		if block == nil {
+			return nil, errors.New("failed to decode public key PEM")
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+		verifier.publicKey = key
+		sum := sha256.Sum256(block.Bytes)
+		verifier.publicKeyId = "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+	}
+	return verifier, nil
+}
+
+func parseAllowedSigners(content string) ([]allowedSigner, error) {
+	var signers []allowedSigner
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		separator := strings.IndexAny(line, " \t")
+		if separator == -1 {
+			return nil, fmt.Errorf("line %d: missing public key", i+1)
+		}
+		key, _, options, _, err := ssh.ParseAuthorizedKey([]byte(line[separator+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		signer := allowedSigner{principals: line[:separator], key: key}
+		for _, option := range options {
+			if value, found := strings.CutPrefix(option, "namespaces="); found {
+				signer.namespaces = strings.Split(strings.Trim(value, `"`), ",")
+			}
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) == 0 {
+		return nil, errors.New("no signers found")
+	}
+	return signers, nil
+}
+
+func (v *releaseVerifier) verifyTag(tag *object.Tag) model.ReleaseVerification {
+	verification := model.ReleaseVerification{Release: tag.Name}
+	method, signer, err := v.verify(tag)
+	if err != nil {
+		verification.Reason = err.Error()
+		return verification
+	}
+	verification.Verified = true
+	verification.Method = method
+	verification.Signer = signer
+	return verification
+}
+
+func (v *releaseVerifier) verify(tag *object.Tag) (string, string, error) {
+	var errs []error
+	if v.publicKey != nil {
+		signer, err := v.verifyKeySignature(tag)
+		if err == nil {
+			return VerifyMethodKey, signer, nil
+		}
+		errs = append(errs, err)
+	}
+	switch {
+	case strings.HasPrefix(tag.PGPSignature, pgpSignaturePrefix):
+		if v.gpgKeyRing == "" {
+			errs = append(errs, errors.New("tag has a gpg signature but no gpg keys are trusted"))
+			break
+		}
+		entity, err := tag.Verify(v.gpgKeyRing)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("gpg signature: %w", err))
+			break
+		}
+		signer := entity.PrimaryKey.KeyIdString()
+		if identity := entity.PrimaryIdentity(); identity != nil {
+			signer = identity.Name
+		}
+		return VerifyMethodGPG, signer, nil
+	case strings.HasPrefix(tag.PGPSignature, sshSignaturePrefix):
+		if len(v.sshSigners) == 0 {
+			errs = append(errs, errors.New("tag has a ssh signature but no ssh signers are trusted"))
+			break
+		}
+		signer, err := v.verifySSH(tag)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ssh signature: %w", err))
+			break
+		}
+		return VerifyMethodSSH, signer, nil
+	case tag.PGPSignature != "":
+		errs = append(errs, errors.New("unsupported tag signature format"))
+	case v.publicKey == nil:
+		errs = append(errs, errors.New("tag is not signed"))
+	}
+	return "", "", errors.Join(errs...)
+}
+
+func getTagPayload(tag *object.Tag) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := tag.EncodeWithoutSignature(encoded); err != nil {
+		return nil, err
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func(reader io.ReadCloser) {
+		_ = reader.Close()
+	}(reader)
+	return io.ReadAll(reader)
+}
+
+func (v *releaseVerifier) verifySSH(tag *object.Tag) (string, error) {
+	block, _ := pem.Decode([]byte(tag.PGPSignature))
+	if block == nil || !bytes.HasPrefix(block.Bytes, []byte(sshSignatureMagic)) {
+		return "", errors.New("invalid signature armor")
+	}
+	var sig struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}
+	if err := ssh.Unmarshal(block.Bytes[len(sshSignatureMagic):], &sig); err != nil {
+		return "", fmt.Errorf("invalid signature: %w", err)
+	}
+	if sig.Version != 1 {
+		return "", fmt.Errorf("unsupported signature version %d", sig.Version)
+	}
+	if sig.Namespace != sshSignatureNamespace {
+		return "", fmt.Errorf("unexpected signature namespace %s", sig.Namespace)
+	}
+	publicKey, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid signature public key: %w", err)
+	}
+	signer := v.getSSHSigner(publicKey)
+	if signer == nil {
+		return "", fmt.Errorf("key %s is not an allowed signer", ssh.FingerprintSHA256(publicKey))
+	}
+	payload, err := getTagPayload(tag)
+	if err != nil {
+		return "", err
+	}
+	var hash []byte
+	switch sig.HashAlgorithm {
+	case "sha256":
+		sum := sha256.Sum256(payload)
+		hash = sum[:]
+	case "sha512":
+		sum := sha512.Sum512(payload)
+		hash = sum[:]
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm %s", sig.HashAlgorithm)
+	}
+	signedData := append([]byte(sshSignatureMagic), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{sig.Namespace, sig.Reserved, sig.HashAlgorithm, hash})...)
+	signature := &ssh.Signature{}
+	if err = ssh.Unmarshal(sig.Signature, signature); err != nil {
+		return "", fmt.Errorf("invalid signature blob: %w", err)
+	}
+	if err = publicKey.Verify(signedData, signature); err != nil {
+		return "", err
+	}
+	return signer.principals, nil
+}
+
+func (v *releaseVerifier) getSSHSigner(publicKey ssh.PublicKey) *allowedSigner {
+	for _, signer := range v.sshSigners {
+		if !bytes.Equal(signer.key.Marshal(), publicKey.Marshal()) {
+			continue
+		}
+		if len(signer.namespaces) == 0 || model.ToSet(signer.namespaces).Contains(sshSignatureNamespace) {
+			return &signer
+		}
+	}
+	return nil
+}
+
+// verifyKeySignature verifies the signature from the Release-Signature trailer of the tag message. This is an agent
+// specific scheme, not a sigstore bundle. Signed payload is "object <commit hash>\ntag <tag name>\n", ECDSA and RSA
+// signatures are over the SHA-256 digest of the payload.
+func (v *releaseVerifier) verifyKeySignature(tag *object.Tag) (string, error) {
+	var encodedSignature string
+	for _, line := range strings.Split(tag.Message, "\n") {
+		if value, found := strings.CutPrefix(strings.TrimSpace(line), keySignatureTrailer); found {
+			encodedSignature = strings.TrimSpace(value)
+		}
+	}
+	if encodedSignature == "" {
+		return "", errors.New("release signature not found in tag message")
+	}
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode release signature: %w", err)
+	}
+	payload := getKeySignaturePayload(tag)
+	digest := sha256.Sum256(payload)
+	var valid bool
+	switch key := v.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, payload, signature)
+	default:
+		return "", fmt.Errorf("unsupported public key type %T", key)
+	}
+	if !valid {
+		return "", errors.New("release signature is invalid")
+	}
+	return v.publicKeyId, nil
+}
+
+func getKeySignaturePayload(tag *object.Tag) []byte {
+	return []byte(fmt.Sprintf("object %s\ntag %s\n", tag.Target, tag.Name))
+}
@@ -0,0 +1,229 @@
+package git
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestTag(name string) *object.Tag {
+	return &object.Tag{
+		Name: name,
+		Tagger: object.Signature{
+			Name:  "Release",
+			Email: "release@example.com",
+			When:  time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		},
+		Message:    "Release " + name + "\n",
+		TargetType: plumbing.CommitObject,
+		Target:     plumbing.NewHash("0123456789abcdef0123456789abcdef01234567"),
+	}
+}
+
+func getTestPayload(t *testing.T, tag *object.Tag) []byte {
+	payload, err := getTagPayload(tag)
+	if err != nil {
+		t.Fatalf("failed to encode tag: %v", err)
+	}
+	return payload
+}
+
+func newGPGEntity(t *testing.T, name string) (*openpgp.Entity, string) {
+	entity, err := openpgp.NewEntity(name, "", "release@example.com", nil)
+	if err != nil {
+		t.Fatalf("failed to create gpg entity: %v", err)
+	}
+	var publicKey bytes.Buffer
+	writer, err := armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatalf("failed to armor gpg key: %v", err)
+	}
+	if err = entity.Serialize(writer); err != nil {
+		t.Fatalf("failed to serialize gpg key: %v", err)
+	}
+	_ = writer.Close()
+	return entity, publicKey.String()
+}
+
+func signGPG(t *testing.T, tag *object.Tag, entity *openpgp.Entity) {
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, entity, bytes.NewReader(getTestPayload(t, tag)), nil); err != nil {
+		t.Fatalf("failed to sign tag: %v", err)
+	}
+	tag.PGPSignature = signature.String() + "\n"
+}
+
+func signSSH(t *testing.T, tag *object.Tag, signer ssh.Signer, namespace string) {
+	hash := sha512.Sum512(getTestPayload(t, tag))
+	signedData := append([]byte(sshSignatureMagic), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          []byte
+	}{namespace, "", "sha512", hash[:]})...)
+	signature, err := signer.Sign(rand.Reader, signedData)
+	if err != nil {
+		t.Fatalf("failed to sign tag: %v", err)
+	}
+	blob := append([]byte(sshSignatureMagic), ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}{1, signer.PublicKey().Marshal(), namespace, "", "sha512", ssh.Marshal(signature)})...)
+	tag.PGPSignature = string(pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob}))
+}
+
+func newSSHSigner(t *testing.T) ssh.Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ssh key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("failed to create ssh signer: %v", err)
+	}
+	return signer
+}
+
+func signKey(t *testing.T, tag *object.Tag, key *ecdsa.PrivateKey) {
+	digest := sha256.Sum256(getKeySignaturePayload(tag))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign tag: %v", err)
+	}
+	tag.Message += "\n" + keySignatureTrailer + " " + base64.StdEncoding.EncodeToString(signature) + "\n"
+}
+
+func newECDSAKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+	return key, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestReleaseVerifierVerify(t *testing.T) {
+	trustedEntity, trustedGPGKey := newGPGEntity(t, "Release")
+	otherEntity, _ := newGPGEntity(t, "Other")
+	trustedSSHSigner := newSSHSigner(t)
+	otherSSHSigner := newSSHSigner(t)
+	allowedSigners := "release@example.com " + string(ssh.MarshalAuthorizedKey(trustedSSHSigner.PublicKey()))
+	trustedKey, trustedPublicKey := newECDSAKey(t)
+	otherKey, _ := newECDSAKey(t)
+	verifier, err := newReleaseVerifier(&model.SourceVerify{
+		GPGKeys:           []string{trustedGPGKey},
+		SSHAllowedSigners: allowedSigners,
+		PublicKey:         trustedPublicKey,
+	})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		sign   func(tag *object.Tag)
+		method string
+		signer string
+		reason string
+	}{
+		{
+			name:   "gpg",
+			sign:   func(tag *object.Tag) { signGPG(t, tag, trustedEntity) },
+			method: VerifyMethodGPG,
+			signer: "Release <release@example.com>",
+		},
+		{
+			name:   "gpg unknown key",
+			sign:   func(tag *object.Tag) { signGPG(t, tag, otherEntity) },
+			reason: "gpg signature",
+		},
+		{
+			name:   "ssh",
+			sign:   func(tag *object.Tag) { signSSH(t, tag, trustedSSHSigner, sshSignatureNamespace) },
+			method: VerifyMethodSSH,
+			signer: "release@example.com",
+		},
+		{
+			name:   "ssh unknown key",
+			sign:   func(tag *object.Tag) { signSSH(t, tag, otherSSHSigner, sshSignatureNamespace) },
+			reason: "is not an allowed signer",
+		},
+		{
+			name:   "ssh wrong namespace",
+			sign:   func(tag *object.Tag) { signSSH(t, tag, trustedSSHSigner, "file") },
+			reason: "unexpected signature namespace",
+		},
+		{
+			name:   "key",
+			sign:   func(tag *object.Tag) { signKey(t, tag, trustedKey) },
+			method: VerifyMethodKey,
+			signer: verifier.publicKeyId,
+		},
+		{
+			name:   "key unknown key",
+			sign:   func(tag *object.Tag) { signKey(t, tag, otherKey) },
+			reason: "release signature is invalid",
+		},
+		{
+			name: "key other tag",
+			sign: func(tag *object.Tag) {
+				tag.Name = "v0.9.0"
+				signKey(t, tag, trustedKey)
+				tag.Name = "v1.0.0"
+			},
+			reason: "release signature is invalid",
+		},
+		{
+			name:   "unsigned",
+			sign:   func(tag *object.Tag) {},
+			reason: "release signature not found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tag := newTestTag("v1.0.0")
+			test.sign(tag)
+			verification := verifier.verifyTag(tag)
+			if test.reason != "" {
+				if verification.Verified {
+					t.Fatalf("expected verification to fail")
+				}
+				if !strings.Contains(verification.Reason, test.reason) {
+					t.Fatalf("expected reason to contain %q, got %q", test.reason, verification.Reason)
+				}
+				return
+			}
+			if !verification.Verified {
+				t.Fatalf("expected verification to succeed, got %s", verification.Reason)
+			}
+			if verification.Method != test.method || verification.Signer != test.signer {
+				t.Fatalf("expected %s signed by %s, got %s signed by %s", test.method, test.signer,
+					verification.Method, verification.Signer)
+			}
+		})
+	}
+}
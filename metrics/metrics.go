@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+const (
+	namespace       = "infralib_agent"
+	pushJob         = "entigo-infralib-agent"
+	shutdownTimeout = 5 * time.Second
+)
+
+var (
+	registry = prometheus.NewRegistry()
+	// exitRegistry holds the gauges exported at exit. Counters and histograms would start from zero on every run,
+	// so they are only served by the metrics listener.
+	exitRegistry = prometheus.NewRegistry()
+)
+
+var (
+	stepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "step_duration_seconds",
+		Help:      "Duration of step pipeline executions, including the wait for approvals.",
+		Buckets:   prometheus.ExponentialBuckets(10, 2, 10),
+	}, []string{"step", "status"})
+	planResources = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "plan_resources_total",
+		Help:      "Resources planned to be imported, added, changed or destroyed.",
+	}, []string{"step", "action"})
+	pendingApprovals = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_approvals",
+		Help:      "Steps waiting for a manual approval.",
+	}, []string{"step"})
+	campaigns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "campaigns_total",
+		Help:      "Finished campaigns by command and outcome.",
+	}, []string{"command", "status"})
+	releasesBehind = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "module_releases_behind",
+		Help:      "Source releases newer than the applied module version.",
+	}, []string{"step", "module", "source"})
+	lastStepDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_step_duration_seconds",
+		Help:      "Duration of the last step pipeline execution, including the wait for approvals.",
+	}, []string{"step", "status"})
+	lastPlanResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_plan_resources",
+		Help:      "Resources planned to be imported, added, changed or destroyed in the last plan of the step.",
+	}, []string{"step", "action"})
+	lastCampaign = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_campaign_status",
+		Help:      "Outcome of the last campaign, the status label is set to 1.",
+	}, []string{"command", "status"})
+	lastCampaignTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_campaign_timestamp_seconds",
+		Help:      "Unix time when the last campaign finished.",
+	}, []string{"command"})
+)
+
+func init() {
+	registry.MustRegister(stepDuration, planResources, pendingApprovals, campaigns, releasesBehind, lastStepDuration,
+		lastPlanResources, lastCampaign, lastCampaignTimestamp)
+	exitRegistry.MustRegister(pendingApprovals, releasesBehind, lastStepDuration, lastPlanResources, lastCampaign,
+		lastCampaignTimestamp)
+}
+
+// Start starts the optional metrics listener. The returned function pushes the gauges to the Pushgateway and writes
+// them to the textfile when configured, then stops the listener.
+func Start(flags common.Metrics, prefix string) (func(context.Context), error) {
+	server, err := listen(flags.Address)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) {
+		export(ctx, flags, prefix)
+		if server == nil {
+			return
+		}
+		shutdownCtx, cancel := context.WithTimeout(ctx, shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Warn(common.PrefixWarning(fmt.Sprintf("metrics server shutdown failed: %v", err)))
+		}
+	}, nil
+}
+
+func listen(address string) (*http.Server, error) {
+	if address == "" {
+		return nil, nil
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", address, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		slog.Info(fmt.Sprintf("Metrics server listening on %s", listener.Addr().String()))
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn(common.PrefixWarning(fmt.Sprintf("metrics server failed: %v", err)))
+		}
+	}()
+	return server, nil
+}
+
+func export(ctx context.Context, flags common.Metrics, prefix string) {
+	if flags.Pushgateway != "" {
+		pusher := push.New(flags.Pushgateway, pushJob).Gatherer(exitRegistry)
+		if prefix != "" {
+			pusher = pusher.Grouping("prefix", prefix)
+		}
+		if err := pusher.PushContext(ctx); err != nil {
+			slog.Warn(common.PrefixWarning(fmt.Sprintf("failed to push metrics to %s: %v", flags.Pushgateway, err)))
+		}
+	}
+	if flags.Textfile != "" {
+		if err := prometheus.WriteToTextfile(flags.Textfile, exitRegistry); err != nil {
+			slog.Warn(common.PrefixWarning(fmt.Sprintf("failed to write metrics to %s: %v", flags.Textfile, err)))
+		}
+	}
+}
+
+// StepFinished records the duration of a step pipeline execution.
+func StepFinished(step string, status model.ApplyStatus, duration time.Duration) {
+	stepDuration.WithLabelValues(step, string(status)).Observe(duration.Seconds())
+	lastStepDuration.WithLabelValues(step, string(status)).Set(duration.Seconds())
+}
+
+// PlanChanges counts the planned resource changes of a step.
+func PlanChanges(step string, changes model.PipelineChanges) {
+	planResources.WithLabelValues(step, "import").Add(float64(changes.Imported))
+	planResources.WithLabelValues(step, "add").Add(float64(changes.Added))
+	planResources.WithLabelValues(step, "change").Add(float64(changes.Changed))
+	planResources.WithLabelValues(step, "destroy").Add(float64(changes.Destroyed))
+	lastPlanResources.WithLabelValues(step, "import").Set(float64(changes.Imported))
+	lastPlanResources.WithLabelValues(step, "add").Set(float64(changes.Added))
+	lastPlanResources.WithLabelValues(step, "change").Set(float64(changes.Changed))
+	lastPlanResources.WithLabelValues(step, "destroy").Set(float64(changes.Destroyed))
+}
+
+// ApprovalPending marks whether the step is waiting for a manual approval.
+func ApprovalPending(step string, pending bool) {
+	if pending {
+		pendingApprovals.WithLabelValues(step).Set(1)
+	} else {
+		pendingApprovals.WithLabelValues(step).Set(0)
+	}
+}
+
+// CampaignFinished counts the campaign outcome and sets it as the last outcome of the command.
+func CampaignFinished(command common.Command, status model.CampaignStatus) {
+	campaigns.WithLabelValues(string(command), string(status)).Inc()
+	lastCampaign.DeletePartialMatch(prometheus.Labels{"command": string(command)})
+	lastCampaign.WithLabelValues(string(command), string(status)).Set(1)
+	lastCampaignTimestamp.WithLabelValues(string(command)).SetToCurrentTime()
+}
+
+// ReleasesBehind sets the number of source releases newer than the module version.
+func ReleasesBehind(step, module, source string, behind int) {
+	releasesBehind.WithLabelValues(step, module, source).Set(float64(behind))
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+func TestExportTextfile(t *testing.T) {
+	StepFinished("net", model.ApplyStatusSuccess, 30*time.Second)
+	PlanChanges("net", model.PipelineChanges{Added: 2, Destroyed: 1})
+	PlanChanges("net", model.PipelineChanges{Changed: 3})
+	CampaignFinished(common.UpdateCommand, model.CampaignStatusFailure)
+	CampaignFinished(common.UpdateCommand, model.CampaignStatusSuccess)
+	ReleasesBehind("net", "vpc", "github.com/entigo/modules", 2)
+
+	textfile := filepath.Join(t.TempDir(), "agent.prom")
+	export(context.Background(), common.Metrics{Textfile: textfile}, "")
+	content, err := os.ReadFile(textfile)
+	if err != nil {
+		t.Fatalf("failed to read textfile: %v", err)
+	}
+	tests := []struct {
+		name     string
+		metric   string
+		exported bool
+	}{
+		{name: "step duration", metric: `infralib_agent_last_step_duration_seconds{status="success",step="net"} 30`, exported: true},
+		{name: "last plan", metric: `infralib_agent_last_plan_resources{action="change",step="net"} 3`, exported: true},
+		{name: "previous plan", metric: `infralib_agent_last_plan_resources{action="add",step="net"} 0`, exported: true},
+		{name: "last campaign", metric: `infralib_agent_last_campaign_status{command="update",status="success"} 1`, exported: true},
+		{name: "previous campaign", metric: `status="failure"`},
+		{name: "campaign timestamp", metric: `infralib_agent_last_campaign_timestamp_seconds{command="update"}`, exported: true},
+		{name: "releases behind", metric: `infralib_agent_module_releases_behind{module="vpc"`, exported: true},
+		{name: "counter", metric: "infralib_agent_campaigns_total"},
+		{name: "histogram", metric: "infralib_agent_step_duration_seconds"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if strings.Contains(string(content), test.metric) != test.exported {
+				t.Fatalf("expected %s exported to be %t, got:\n%s", test.metric, test.exported, content)
+			}
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
-	"log"
 	"log/slog"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
@@ -41,7 +40,7 @@ func NewConfigGenerator(ctx context.Context, flags common.Migrate) (ConfigGenera
 }
 
 func (u *configGenerator) Generate() {
-	log.Println("Generating config based on the state file")
+	slog.Info("Generating config based on the state file")
 	resourcesWithIndexes := make(map[ResourceKey][]interface{})
 	for _, item := range u.config.Import {
 		itemResources, err := u.processItem(item)
@@ -58,18 +57,18 @@ func (u *configGenerator) Generate() {
 	}
 	missingResources := u.findMissingResources(resourcesWithIndexes)
 	if len(missingResources) == 0 {
-		log.Println("No unmatched resources found.")
+		slog.Info("No unmatched resources found.")
 		return
 	}
-	log.Printf("%d resources found\n", len(missingResources))
+	slog.Info(fmt.Sprintf("%d resources found", len(missingResources)))
 	importYaml, err := yaml.Marshal(importConfig{Import: missingResources})
 	if err == nil {
 		fmt.Println(string(importYaml))
 	} else {
 		slog.Error("Failed to marshal resources to YAML", "error", err)
 		for _, item := range missingResources {
-			log.Printf("Type: %s, Name: %s, Module: %s, IndexKeys: %v\n",
-				item.Type, item.Name, item.Module, formatIndexKeys(item.IndexKeys))
+			slog.Info(fmt.Sprintf("Type: %s, Name: %s, Module: %s, IndexKeys: %v",
+				item.Type, item.Name, item.Module, formatIndexKeys(item.IndexKeys)))
 		}
 	}
 }
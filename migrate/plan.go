@@ -5,7 +5,6 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
-	"log"
 	"log/slog"
 	"os"
 	"regexp"
@@ -143,7 +142,7 @@ func getConfig(stateFile string) (importConfig, error) {
 }
 
 func (p *planner) Plan() {
-	log.Println("Planning migration")
+	slog.Info("Planning migration")
 	var imports []string
 	var removes []string
 	for _, item := range p.config.Import {
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"log/slog"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
@@ -47,11 +46,11 @@ func NewValidator(ctx context.Context, flags common.Migrate) (Validator, error)
 }
 
 func (v *validator) Validate() {
-	log.Println("Validating state resources")
+	slog.Info("Validating state resources")
 	v.validatePlanState()
-	log.Println("Validating import config types")
+	slog.Info("Validating import config types")
 	v.validateConfigTypes()
-	log.Println("Validating changed values")
+	slog.Info("Validating changed values")
 	v.validateChangedValues()
 }
 
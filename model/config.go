@@ -19,25 +19,37 @@ type Config struct {
 	EnableOpenTofu   bool                 `yaml:"enable_opentofu,omitempty"`
 	Destinations     []ConfigDestination  `yaml:"destinations,omitempty"`
 	Notifications    []ConfigNotification `yaml:"notifications,omitempty"`
+	Secrets          []ConfigSecret       `yaml:"secrets,omitempty"`
+	Remotes          []ConfigRemote       `yaml:"remotes,omitempty"`
 	Schedule         Schedule             `yaml:"schedule,omitempty"`
 	Provider         Provider             `yaml:"provider,omitempty"`
 	Steps            []Step               `yaml:"steps,omitempty"`
+	RedactPatterns   []string             `yaml:"redact_patterns,omitempty"`
+	PipelineAgeKey   string               `yaml:"pipeline_age_key,omitempty"`
 	Certs            []File               `yaml:"-"`
 }
 
 const TofuTfTool = "tofu"
 
 type ConfigSource struct {
-	URL          string   `yaml:"url"`
-	Version      string   `yaml:"version,omitempty"`
-	ForceVersion bool     `yaml:"force_version,omitempty"`
-	Include      []string `yaml:"include,omitempty"`
-	Exclude      []string `yaml:"exclude,omitempty"`
-	Username     string   `yaml:"username,omitempty"`
-	Password     string   `yaml:"password,omitempty"`
-	Insecure     bool     `yaml:"insecure,omitempty"`
-	RepoPath     string   `yaml:"repo_path,omitempty"`
-	CAFile       string   `yaml:"ca_file,omitempty"`
+	URL          string        `yaml:"url"`
+	Version      string        `yaml:"version,omitempty"`
+	ForceVersion bool          `yaml:"force_version,omitempty"`
+	Include      []string      `yaml:"include,omitempty"`
+	Exclude      []string      `yaml:"exclude,omitempty"`
+	Username     string        `yaml:"username,omitempty"`
+	Password     string        `yaml:"password,omitempty"`
+	Insecure     bool          `yaml:"insecure,omitempty"`
+	RepoPath     string        `yaml:"repo_path,omitempty"`
+	CAFile       string        `yaml:"ca_file,omitempty"`
+	Verify       *SourceVerify `yaml:"verify,omitempty"`
+	SkipVersions []string      `yaml:"skip_versions,omitempty"`
+}
+
+type SourceVerify struct {
+	GPGKeys           []string `yaml:"gpg_keys,omitempty"`
+	SSHAllowedSigners string   `yaml:"ssh_allowed_signers,omitempty"`
+	PublicKey         string   `yaml:"public_key,omitempty"`
 }
 
 func (s ConfigSource) GetSourceKey() SourceKey {
@@ -73,29 +85,98 @@ type Git struct {
 	CAFile          string `yaml:"ca_file,omitempty"`
 }
 
+type ConfigSecret struct {
+	Name  string      `yaml:"name,omitempty"`
+	Vault *Vault      `yaml:"vault,omitempty"`
+	File  *SecretFile `yaml:"file,omitempty"`
+}
+
+type Vault struct {
+	Address   string        `yaml:"address,omitempty"`
+	Namespace string        `yaml:"namespace,omitempty"`
+	Token     string        `yaml:"token,omitempty"`
+	AppRole   *VaultAppRole `yaml:"approle,omitempty"`
+	Insecure  bool          `yaml:"insecure,omitempty"`
+	CAFile    string        `yaml:"ca_file,omitempty"`
+}
+
+type VaultAppRole struct {
+	RoleId   string `yaml:"role_id,omitempty"`
+	SecretId string `yaml:"secret_id,omitempty"`
+	Mount    string `yaml:"mount,omitempty"`
+}
+
+type SecretFile struct {
+	Path string `yaml:"path,omitempty"`
+}
+
+type ConfigRemote struct {
+	Prefix         string `yaml:"prefix,omitempty"`
+	AWSRole        string `yaml:"aws_role,omitempty"`
+	AWSRegion      string `yaml:"aws_region,omitempty"`
+	GCloudProject  string `yaml:"gcloud_project,omitempty"`
+	GCloudLocation string `yaml:"gcloud_location,omitempty"`
+}
+
 type ConfigNotification struct {
 	Name         string           `yaml:"name,omitempty"`
 	Context      string           `yaml:"context,omitempty"`
 	MessageTypes []MessageType    `yaml:"message_types,omitempty"`
+	Steps        []string         `yaml:"steps,omitempty"`
+	Modules      []string         `yaml:"modules,omitempty"`
+	ExcludeSteps []string         `yaml:"exclude_steps,omitempty"`
+	MinSeverity  Severity         `yaml:"min_severity,omitempty"`
 	Slack        *Slack           `yaml:"slack,omitempty"`
 	Teams        *Teams           `yaml:"teams,omitempty"`
 	Api          *NotificationApi `yaml:"api,omitempty"`
+	Webhook      *Webhook         `yaml:"webhook,omitempty"`
+	Email        *Email           `yaml:"email,omitempty"`
 }
 
 type Slack struct {
-	Token     string `yaml:"token,omitempty"`
-	ChannelId string `yaml:"channel_id,omitempty"`
+	Token             string `yaml:"token,omitempty"`
+	ChannelId         string `yaml:"channel_id,omitempty"`
+	BroadcastFailures bool   `yaml:"broadcast_failures,omitempty"`
 }
 
 type Teams struct {
 	WebhookUrl string `yaml:"webhook_url,omitempty"`
 }
 
+type Webhook struct {
+	URL       string                 `yaml:"url,omitempty"`
+	Method    string                 `yaml:"method,omitempty"`
+	Headers   map[string]string      `yaml:"headers,omitempty"`
+	Secret    string                 `yaml:"secret,omitempty"`
+	Templates map[MessageType]string `yaml:"templates,omitempty"`
+}
+
+type Email struct {
+	Host     string        `yaml:"host,omitempty"`
+	Port     int           `yaml:"port,omitempty"`
+	Security EmailSecurity `yaml:"security,omitempty"`
+	Username string        `yaml:"username,omitempty"`
+	Password string        `yaml:"password,omitempty"`
+	From     string        `yaml:"from,omitempty"`
+	To       []string      `yaml:"to,omitempty"`
+	Subject  string        `yaml:"subject,omitempty"`
+}
+
+type EmailSecurity string
+
+const (
+	EmailSecurityStartTLS EmailSecurity = "starttls"
+	EmailSecurityTLS      EmailSecurity = "tls"
+	EmailSecurityNone     EmailSecurity = "none"
+)
+
 type NotificationApi struct {
 	URL        string            `yaml:"url,omitempty"`
 	WrapperURL string            `yaml:"wrapper_url,omitempty"`
 	Headers    map[string]string `yaml:"headers,omitempty"`
 	OAuth      *ApiOauth         `yaml:"oauth,omitempty"`
+	// RedactPatterns is copied from the config root for wrappers running in cloud pipelines.
+	RedactPatterns []string `yaml:"redact_patterns,omitempty"`
 }
 
 type ApiOauth struct {
@@ -176,9 +257,21 @@ type KubernetesProvider struct {
 }
 
 type File struct {
-	Name     string `yaml:"-"`
-	Content  []byte `yaml:"-"`
-	Checksum []byte `yaml:"-"`
+	Name      string `yaml:"-"`
+	Content   []byte `yaml:"-"`
+	Checksum  []byte `yaml:"-"`
+	Encrypted bool   `yaml:"-"`
+	// Decrypted name and content of an encrypted file for agent use, the bucket only gets the encrypted content
+	PlainName string `yaml:"-"`
+	Plaintext []byte `yaml:"-"`
+}
+
+// Plain returns the decrypted name and content of an encrypted file, otherwise the file name and content.
+func (f File) Plain() (string, []byte) {
+	if f.Encrypted && f.Plaintext != nil {
+		return f.PlainName, f.Plaintext
+	}
+	return f.Name, f.Content
 }
 
 func (p Provider) IsEmpty() bool {
@@ -208,11 +301,13 @@ type Module struct {
 	HttpPassword   string                 `yaml:"http_password,omitempty"`
 	Version        string                 `yaml:"version,omitempty"`
 	DefaultModule  bool                   `yaml:"default_module,omitempty"`
+	SkipVersions   []string               `yaml:"skip_versions,omitempty"`
 	Inputs         map[string]interface{} `yaml:"inputs,omitempty"`
 	ConfigInputs   map[string]interface{} `yaml:"-"`
 	InputsChecksum []byte                 `yaml:"-"`
 	InputsFile     string                 `yaml:"-"`
 	FileContent    []byte                 `yaml:"-"`
+	FileEncrypted  bool                   `yaml:"-"`
 	Metadata       map[string]string      `yaml:"-"`
 }
 
@@ -244,6 +339,9 @@ const (
 	ReplaceTypeModule          ReplaceType = "module"
 	ReplaceTypeInput           ReplaceType = "input"
 	ReplaceTypeSelfOutput      ReplaceType = "sout"
+	ReplaceTypeVault           ReplaceType = "vault"
+	ReplaceTypeSecret          ReplaceType = "secret"
+	ReplaceTypeRemote          ReplaceType = "remote"
 )
 
 type AgentReplaceType string
@@ -330,6 +428,16 @@ type Source struct {
 	CurrentChecksums  map[string][]byte
 	Includes          Set[string]
 	Excludes          Set[string]
+	Verifications     []ReleaseVerification
+	SkipVersions      []string
+}
+
+type ReleaseVerification struct {
+	Release  string
+	Verified bool
+	Method   string
+	Signer   string
+	Reason   string
 }
 
 type SourceAuth struct {
@@ -351,9 +459,10 @@ type ModuleVersion struct {
 }
 
 type V1Agent struct {
-	Version     string            `json:"version" yaml:"version"`
-	Metadata    map[string]string `json:"metadata" yaml:"metadata"`
-	ModuleTypes []string          `json:"module_types" yaml:"module_types"`
+	Version      string            `json:"version" yaml:"version"`
+	Metadata     map[string]string `json:"metadata" yaml:"metadata"`
+	ModuleTypes  []string          `json:"module_types" yaml:"module_types"`
+	SkipVersions []string          `json:"skip_versions" yaml:"skip_versions"`
 }
 
 func UnmarshalAgentYaml(yamlData []byte) (interface{}, error) {
@@ -8,6 +8,7 @@ import (
 
 type Message interface {
 	Type() MessageType
+	Severity() Severity
 	Dispatch(Notifier) error
 }
 
@@ -42,6 +43,13 @@ func (m CampaignMessage) Type() MessageType {
 	}
 }
 
+func (m CampaignMessage) Severity() Severity {
+	if m.Type() == MessageTypeFailure {
+		return SeverityError
+	}
+	return SeverityInfo
+}
+
 func (m CampaignMessage) Dispatch(n Notifier) error {
 	return n.HandleCampaign(m)
 }
@@ -64,6 +72,10 @@ func (m ScheduleMessage) Type() MessageType {
 	return MessageTypeSchedule
 }
 
+func (ScheduleMessage) Severity() Severity {
+	return SeverityInfo
+}
+
 func (m ScheduleMessage) Dispatch(n Notifier) error {
 	return n.HandleSchedule(m)
 }
@@ -79,6 +91,10 @@ func (m ApprovalMessage) Type() MessageType {
 	return MessageTypeApprovals
 }
 
+func (ApprovalMessage) Severity() Severity {
+	return SeverityInfo
+}
+
 func (m ApprovalMessage) Dispatch(n Notifier) error {
 	return n.HandleApproval(m)
 }
@@ -95,15 +111,28 @@ func (ManualApprovalMessage) Type() MessageType {
 	return MessageTypeApprovals
 }
 
+func (ManualApprovalMessage) Severity() Severity {
+	return SeverityWarning
+}
+
 func (m ManualApprovalMessage) Dispatch(n Notifier) error {
 	return n.HandleManualApproval(m)
 }
 
+// PlanChangesMessage is delivered only to notifiers implementing PlanNotifier, it's not retried or stored in the outbox.
+type PlanChangesMessage struct {
+	PipelineIndex int32
+	PipelineName  string
+	Step          string
+	Changes       PipelineChanges
+}
+
 type StepStateMessage struct {
 	PipelineIndex int32
 	Status        ApplyStatus
 	StateStep     StateStep
 	Step          *Step
+	SlowResources []ResourceTiming
 	Err           error
 }
 
@@ -111,6 +140,13 @@ func (StepStateMessage) Type() MessageType {
 	return MessageTypeProgress
 }
 
+func (m StepStateMessage) Severity() Severity {
+	if m.Status == ApplyStatusFailure {
+		return SeverityError
+	}
+	return SeverityInfo
+}
+
 func (m StepStateMessage) Dispatch(n Notifier) error {
 	return n.HandleStepState(m)
 }
@@ -126,6 +162,13 @@ func (PipelineStateMessage) Type() MessageType {
 	return MessageTypeProgress
 }
 
+func (m PipelineStateMessage) Severity() Severity {
+	if m.Status == ApplyStatusFailure {
+		return SeverityError
+	}
+	return SeverityInfo
+}
+
 func (m PipelineStateMessage) Dispatch(n Notifier) error {
 	return n.HandlePipelineState(m)
 }
@@ -140,6 +183,10 @@ func (ModulesMessage) Type() MessageType {
 	return MessageTypeModules
 }
 
+func (ModulesMessage) Severity() Severity {
+	return SeverityInfo
+}
+
 func (m ModulesMessage) Dispatch(n Notifier) error {
 	return n.HandleModules(m)
 }
@@ -152,6 +199,10 @@ func (SourcesMessage) Type() MessageType {
 	return MessageTypeSources
 }
 
+func (SourcesMessage) Severity() Severity {
+	return SeverityInfo
+}
+
 func (m SourcesMessage) Dispatch(n Notifier) error {
 	return n.HandleSources(m)
 }
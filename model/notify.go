@@ -14,10 +14,12 @@ type NotificationManager interface {
 	Schedule(command common.Command, status ScheduleAction, schedule string)
 	Approval(pipeline, step, approvedBy string)
 	ManualApproval(pipelineName, step string, changes PipelineChanges, link string)
-	StepState(status ApplyStatus, stepState StateStep, step *Step, err error)
+	PlanChanges(pipelineName, step string, changes PipelineChanges)
+	StepState(status ApplyStatus, stepState StateStep, step *Step, slowest []ResourceTiming, err error)
 	Modules(resources Resources, command common.Command, config Config)
 	Sources(sources map[SourceKey]*Source)
 	PipelineState(status ApplyStatus, sourceVersions []SourceVersion, err error)
+	Flush(ctx context.Context) error
 }
 
 type Notifier interface {
@@ -34,6 +36,12 @@ type Notifier interface {
 	HandleSchedule(ScheduleMessage) error
 }
 
+// PlanNotifier is implemented by notifiers that also receive the planned changes of every step, including the
+// automatically approved ones.
+type PlanNotifier interface {
+	HandlePlanChanges(PlanChangesMessage)
+}
+
 type MessageType string
 
 const (
@@ -48,6 +56,28 @@ const (
 	MessageTypeUnknown   MessageType = "unknown" // Meta invalid type for handling message structs with multiple types
 )
 
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Level returns the order of the severity, unknown severities have the lowest level.
+func (s Severity) Level() int {
+	switch s {
+	case SeverityInfo:
+		return 1
+	case SeverityWarning:
+		return 2
+	case SeverityError:
+		return 3
+	default:
+		return 0
+	}
+}
+
 type BaseNotifier struct {
 	Name         string
 	Context      string
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
@@ -44,11 +45,16 @@ const (
 	GitPasswordEnvFormat = "GIT_AUTH_PASSWORD_%s"
 
 	WrapperConfigEnv = "WRAPPER_CONFIG"
+	SopsAgeKeyEnv    = "SOPS_AGE_KEY"
+	RedactValuesEnv  = "REDACT_VALUES"
+	// TraceParentEnv holds the W3C trace context of the span that started the pipeline.
+	TraceParentEnv = "TRACEPARENT"
 
 	// CampaignSentinelNone is the on-the-wire value used when there is no
 	// active campaign. AWS CodePipeline rejects empty DefaultValue on pipeline
 	// variables, so we can't represent "no campaign" as "". The wrapper treats
 	// this sentinel identically to an empty CAMPAIGN_ID — transparent mode.
+	// CodePipeline also uses it as the default TRACEPARENT, which is ignored as invalid.
 	CampaignSentinelNone = "none"
 )
 
@@ -56,6 +62,14 @@ func WrapperConfigSecretName(prefix string) string {
 	return fmt.Sprintf("entigo-infralib-%s-wrapper-config", prefix)
 }
 
+func SopsAgeKeySecretName(prefix string) string {
+	return fmt.Sprintf("entigo-infralib-%s-sops-age-key", prefix)
+}
+
+func RedactValuesSecretName(prefix string) string {
+	return fmt.Sprintf("entigo-infralib-%s-redact-values", prefix)
+}
+
 type CloudProvider interface {
 	SetupResources(manager NotificationManager, config Config) (Resources, error)
 	SetupMinimalResources() (Resources, error)
@@ -86,30 +100,34 @@ type Resources interface {
 }
 
 type Bucket interface {
-	GetRepoMetadata() (*RepositoryMetadata, error)
-	BucketExists() (bool, error)
-	PutFile(file string, content []byte) error
-	GetFile(file string) ([]byte, error)
-	DeleteFile(file string) error
-	DeleteFiles(files []string) error
-	CheckFolderExists(folder string) (bool, error)
-	ListFolderFiles(folder string) ([]string, error)
-	ListFolderFilesWithExclude(folder string, excludeFolders Set[string]) ([]string, error)
-	Delete() error
+	GetRepoMetadata(ctx context.Context) (*RepositoryMetadata, error)
+	BucketExists(ctx context.Context) (bool, error)
+	PutFile(ctx context.Context, file string, content []byte) error
+	GetFile(ctx context.Context, file string) ([]byte, error)
+	DeleteFile(ctx context.Context, file string) error
+	DeleteFiles(ctx context.Context, files []string) error
+	CheckFolderExists(ctx context.Context, folder string) (bool, error)
+	ListFolderFiles(ctx context.Context, folder string) ([]string, error)
+	ListFolderFilesWithExclude(ctx context.Context, folder string, excludeFolders Set[string]) ([]string, error)
+	Delete(ctx context.Context) error
 }
 
 type Pipeline interface {
-	CreatePipeline(projectName, stepName string, step Step, bucket Bucket, authSources map[string]SourceAuth) (*string, error)
+	CreatePipeline(ctx context.Context, projectName, stepName string, step Step, bucket Bucket, authSources map[string]SourceAuth) (*string, error)
 	CreateAgentPipelines(prefix, projectName, bucket string, run bool) error
 	UpdatePipeline(pipelineName, stepName string, step Step, bucket string, authSources map[string]SourceAuth) error
 	StartAgentExecution(pipelineName string) error
-	StartPipelineExecution(pipelineName, stepName string, step Step, customRepo string) (*string, error)
-	WaitPipelineExecution(pipelineName, projectName string, executionId *string, autoApprove bool, step Step, approve ManualApprove) error
+	StartPipelineExecution(ctx context.Context, pipelineName, stepName string, step Step, customRepo string) (*string, error)
+	WaitPipelineExecution(ctx context.Context, pipelineName, projectName string, executionId *string, autoApprove bool, step Step, approve ManualApprove) error
 	DeletePipeline(projectName string) error
 	StartDestroyExecution(projectName string, step Step) error
 	// Empty campaignId means "no campaign" — wrapper runs transparently.
 	SetCampaignId(campaignId string)
 	SetPipelineIndex(index int)
+	// Pipelines get the age identities for decrypting step files from the SOPS age key secret.
+	SetSopsAgeKey(enabled bool)
+	// Pipelines get the secret values that the wrapper masks in logs from the redact values secret.
+	SetRedactValues(enabled bool)
 }
 
 type Builder interface {
@@ -123,13 +141,18 @@ type Builder interface {
 
 type SSM interface {
 	AddEncryptionKeyId(keyId string)
-	GetParameter(name string) (*Parameter, error)
-	ParameterExists(name string) (bool, error)
-	PutParameter(name string, value string) error
-	ListParameters() ([]string, error)
-	DeleteParameter(name string) error
-	PutSecret(name string, value string) error
-	DeleteSecret(name string) error
+	GetParameter(ctx context.Context, name string) (*Parameter, error)
+	ParameterExists(ctx context.Context, name string) (bool, error)
+	PutParameter(ctx context.Context, name string, value string) error
+	ListParameters(ctx context.Context) ([]string, error)
+	DeleteParameter(ctx context.Context, name string) error
+	PutSecret(ctx context.Context, name string, value string) error
+	DeleteSecret(ctx context.Context, name string) error
+}
+
+// SecretResolver returns the key-value data of a secret from an external secret backend.
+type SecretResolver interface {
+	GetSecret(path string) (map[string]interface{}, error)
 }
 
 type Destination interface {
@@ -249,4 +272,14 @@ type PipelineChanges struct {
 	Changed   int
 	Destroyed int
 	NoChanges bool
+	Modules   []ModuleChanges
+}
+
+// ModuleChanges holds the planned changes of a single module, replaced resources count as added and destroyed.
+type ModuleChanges struct {
+	Name      string
+	Imported  int
+	Added     int
+	Changed   int
+	Destroyed int
 }
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// SlowestResourcesCount limits the resources included in notifications and backend plan summaries.
+const SlowestResourcesCount = 5
+
+// ResourceTiming holds the start and finish of a single resource operation from the terraform apply output.
+// Completed is false when the operation errored or the apply was interrupted.
+type ResourceTiming struct {
+	Address   string        `json:"address" yaml:"address"`
+	Action    string        `json:"action" yaml:"action"`
+	Started   time.Time     `json:"started,omitzero" yaml:"started,omitempty"`
+	Finished  time.Time     `json:"finished,omitzero" yaml:"finished,omitempty"`
+	Duration  time.Duration `json:"duration" yaml:"duration"`
+	Completed bool          `json:"completed" yaml:"completed"`
+}
+
+// TimingReport is stored in the bucket with each step apply.
+type TimingReport struct {
+	Step      string           `json:"step"`
+	Command   ActionCommand    `json:"command"`
+	Started   time.Time        `json:"started"`
+	Finished  time.Time        `json:"finished"`
+	Resources []ResourceTiming `json:"resources"`
+}
+
+// SortTimings orders the timings from slowest to fastest, ties by address.
+func SortTimings(timings []ResourceTiming) {
+	slices.SortStableFunc(timings, func(a, b ResourceTiming) int {
+		return cmp.Or(cmp.Compare(b.Duration, a.Duration), cmp.Compare(a.Address, b.Address))
+	})
+}
+
+// SlowestResources returns up to count slowest resources, the timings must be sorted.
+func SlowestResources(timings []ResourceTiming, count int) []ResourceTiming {
+	if len(timings) <= count {
+		return timings
+	}
+	return timings[:count]
+}
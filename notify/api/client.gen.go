@@ -332,8 +332,9 @@ type ManualApprovalNotificationKind string
 
 // ModuleEntity defines model for ModuleEntity.
 type ModuleEntity struct {
-	Name   string `json:"name"`
-	Source string `json:"source"`
+	Name            string    `json:"name"`
+	SkippedVersions *[]string `json:"skippedVersions,omitempty"`
+	Source          string    `json:"source"`
 }
 
 // ModulesNotification defines model for ModulesNotification.
@@ -411,6 +412,18 @@ type PlanEntity struct {
 // ProviderType defines model for ProviderType.
 type ProviderType string
 
+// ReleaseVerificationEntity defines model for ReleaseVerificationEntity.
+type ReleaseVerificationEntity struct {
+	// Method Signature type used to verify the release tag, gpg, ssh or key
+	Method *string `json:"method,omitempty"`
+
+	// Reason Reason why the release tag failed verification
+	Reason   *string `json:"reason,omitempty"`
+	Release  string  `json:"release"`
+	Signer   *string `json:"signer,omitempty"`
+	Verified bool    `json:"verified"`
+}
+
 // ScheduleNotification defines model for ScheduleNotification.
 type ScheduleNotification struct {
 	Action  ScheduleNotificationAction `json:"action,omitempty"`
@@ -436,11 +449,13 @@ type ScheduleNotificationKind string
 
 // SourceEntity defines model for SourceEntity.
 type SourceEntity struct {
-	ForcedVersion *string   `json:"forcedVersion,omitempty"`
-	Modules       *[]string `json:"modules,omitempty"`
-	Releases      *[]string `json:"releases,omitempty"`
-	Url           string    `json:"url"`
-	Version       *string   `json:"version,omitempty"`
+	ForcedVersion   *string                      `json:"forcedVersion,omitempty"`
+	Modules         *[]string                    `json:"modules,omitempty"`
+	Releases        *[]string                    `json:"releases,omitempty"`
+	SkippedVersions *[]string                    `json:"skippedVersions,omitempty"`
+	Url             string                       `json:"url"`
+	Verifications   *[]ReleaseVerificationEntity `json:"verifications,omitempty"`
+	Version         *string                      `json:"version,omitempty"`
 }
 
 // SourcesNotification defines model for SourcesNotification.
@@ -154,7 +154,11 @@ func toModulesNotification(context string, msg model.ModulesMessage) (Notificati
 	for _, step := range msg.Config.Steps {
 		modules := make([]ModuleEntity, 0, len(step.Modules))
 		for _, m := range step.Modules {
-			modules = append(modules, ModuleEntity{Name: m.Name, Source: m.Source})
+			module := ModuleEntity{Name: m.Name, Source: m.Source}
+			if len(m.SkipVersions) > 0 {
+				module.SkippedVersions = new(m.SkipVersions)
+			}
+			modules = append(modules, module)
 		}
 		steps = append(steps, StepEntity{
 			Name:    step.Name,
@@ -206,6 +210,12 @@ func toSourcesNotification(context string, msg model.SourcesMessage) (Notificati
 		if src.Modules != nil {
 			entity.Modules = new(src.Modules.ToSlice())
 		}
+		if len(src.SkipVersions) > 0 {
+			entity.SkippedVersions = new(src.SkipVersions)
+		}
+		if len(src.Verifications) > 0 {
+			entity.Verifications = new(toReleaseVerificationEntities(src.Verifications))
+		}
 		sources = append(sources, entity)
 	}
 	notification := SourcesNotification{
@@ -222,6 +232,27 @@ func toSourcesNotification(context string, msg model.SourcesMessage) (Notificati
 	return n, nil
 }
 
+func toReleaseVerificationEntities(verifications []model.ReleaseVerification) []ReleaseVerificationEntity {
+	entities := make([]ReleaseVerificationEntity, 0, len(verifications))
+	for _, verification := range verifications {
+		entity := ReleaseVerificationEntity{
+			Release:  verification.Release,
+			Verified: verification.Verified,
+		}
+		if verification.Method != "" {
+			entity.Method = &verification.Method
+		}
+		if verification.Signer != "" {
+			entity.Signer = &verification.Signer
+		}
+		if verification.Reason != "" {
+			entity.Reason = &verification.Reason
+		}
+		entities = append(entities, entity)
+	}
+	return entities
+}
+
 func toStepModuleStatuses(stepModules []*model.StateModule, step *model.Step) []StepModuleStatus {
 	modules := make([]StepModuleStatus, 0, len(stepModules))
 	for _, m := range stepModules {
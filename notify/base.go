@@ -3,6 +3,7 @@ package notify
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/entigolabs/entigo-infralib-agent/model"
 )
@@ -69,6 +70,12 @@ func (b *BaseNotifier) HandleStepState(msg model.StepStateMessage) error {
 			fmt.Fprintf(&sb, ", applied version: %s", *module.AppliedVersion)
 		}
 	}
+	if len(msg.SlowResources) > 0 {
+		fmt.Fprint(&sb, "\nSlowest resources:")
+		for _, resource := range msg.SlowResources {
+			fmt.Fprintf(&sb, "\n- %s", timingString(resource))
+		}
+	}
 	return b.sendMessage(sb.String())
 }
 
@@ -79,6 +86,9 @@ func (b *BaseNotifier) HandleModules(msg model.ModulesMessage) error {
 		fmt.Fprintf(&sb, "\nStep '%s':", step.Name)
 		for _, module := range step.Modules {
 			fmt.Fprintf(&sb, "\n- Module '%s' source: %s", module.Name, module.Source)
+			if len(module.SkipVersions) > 0 {
+				fmt.Fprintf(&sb, ", skipped versions: %s", strings.Join(module.SkipVersions, ", "))
+			}
 		}
 	}
 	return b.sendMessage(sb.String())
@@ -108,6 +118,17 @@ func (b *BaseNotifier) HandleSources(msg model.SourcesMessage) error {
 		if len(source.Modules) > 0 {
 			fmt.Fprintf(&sb, "\n  Modules: %s", strings.Join(source.Modules.ToSlice(), ", "))
 		}
+		if len(source.SkipVersions) > 0 {
+			fmt.Fprintf(&sb, "\n  Skipped versions: %s", strings.Join(source.SkipVersions, ", "))
+		}
+		for _, verification := range source.Verifications {
+			if verification.Verified {
+				fmt.Fprintf(&sb, "\n  Release %s verified with %s, signer: %s", verification.Release,
+					verification.Method, verification.Signer)
+			} else {
+				fmt.Fprintf(&sb, "\n  Release %s rejected: %s", verification.Release, verification.Reason)
+			}
+		}
 	}
 	return b.sendMessage(sb.String())
 }
@@ -130,6 +151,14 @@ func (b *BaseNotifier) HandlePipelineState(msg model.PipelineStateMessage) error
 	return b.sendMessage(sb.String())
 }
 
+func timingString(timing model.ResourceTiming) string {
+	message := fmt.Sprintf("%s %s: %s", timing.Address, timing.Action, timing.Duration.Round(time.Second))
+	if !timing.Completed {
+		message += " (not completed)"
+	}
+	return message
+}
+
 func (b *BaseNotifier) sendMessage(message string) error {
 	if b.Context != "" {
 		message = fmt.Sprintf("%s %s", b.Context, message)
@@ -0,0 +1,443 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+const (
+	emailTimeout        = 30 * time.Second
+	emailDefaultSubject = "Entigo Infralib Agent"
+	emailTimeFormat     = "2006-01-02 15:04:05 MST"
+)
+
+var (
+	_ model.Notifier        = (*emailNotifier)(nil)
+	_ campaignStateNotifier = (*emailNotifier)(nil)
+)
+
+var emailTextTemplate = template.Must(template.New("text").Parse(`{{ .Title }}
+{{ if .Context }}Context: {{ .Context }}
+{{ end }}Status: {{ .Status }}
+Command: {{ .Command }}
+Prefix: {{ .Prefix }}
+Provider: {{ .Provider }}
+{{ .AccountLabel }}: {{ .Account }}
+{{ .RegionLabel }}: {{ .Region }}
+{{ if not .Started.IsZero }}Started: {{ .Started.Format .TimeFormat }}
+{{ end }}Finished: {{ .Finished.Format .TimeFormat }}
+{{ if .Error }}Error: {{ .Error }}
+{{ end }}{{ if .StepCounts }}Steps: {{ .StepCounts }}
+{{ end }}{{ if .Events }}
+Events:
+{{ range .Events }}- {{ .Time.Format "15:04:05" }} {{ .Kind }} {{ .Name }}: {{ .Status }}
+{{ range .Details }}    {{ . }}
+{{ end }}{{ if .Error }}    Error: {{ .Error }}
+{{ end }}{{ end }}{{ end }}`))
+
+var emailHTMLTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(htmltemplate.FuncMap{
+	"color": emailStatusColor,
+}).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; font-size: 14px;">
+<h2 style="color: {{ color .Status }};">{{ .Title }}</h2>
+<table cellpadding="4">
+{{ if .Context }}<tr><td><b>Context</b></td><td>{{ .Context }}</td></tr>
+{{ end }}<tr><td><b>Status</b></td><td style="color: {{ color .Status }};">{{ .Status }}</td></tr>
+<tr><td><b>Command</b></td><td>{{ .Command }}</td></tr>
+<tr><td><b>Prefix</b></td><td>{{ .Prefix }}</td></tr>
+<tr><td><b>Provider</b></td><td>{{ .Provider }}</td></tr>
+<tr><td><b>{{ .AccountLabel }}</b></td><td>{{ .Account }}</td></tr>
+<tr><td><b>{{ .RegionLabel }}</b></td><td>{{ .Region }}</td></tr>
+{{ if not .Started.IsZero }}<tr><td><b>Started</b></td><td>{{ .Started.Format .TimeFormat }}</td></tr>
+{{ end }}<tr><td><b>Finished</b></td><td>{{ .Finished.Format .TimeFormat }}</td></tr>
+{{ if .Error }}<tr><td><b>Error</b></td><td style="color: {{ color "failure" }};">{{ .Error }}</td></tr>
+{{ end }}{{ if .StepCounts }}<tr><td><b>Steps</b></td><td>{{ .StepCounts }}</td></tr>
+{{ end }}</table>
+{{ if .Events }}<h3>Events</h3>
+<table cellpadding="4" border="1" style="border-collapse: collapse;">
+<tr><th>Time</th><th>Type</th><th>Name</th><th>Status</th><th>Details</th></tr>
+{{ range .Events }}<tr>
+<td>{{ .Time.Format "15:04:05" }}</td>
+<td>{{ .Kind }}</td>
+<td>{{ .Name }}</td>
+<td style="color: {{ color .Status }};">{{ .Status }}</td>
+<td>{{ range .Details }}{{ . }}<br>{{ end }}{{ if .Error }}<span style="color: {{ color "failure" }};">{{ .Error }}</span>{{ end }}</td>
+</tr>
+{{ end }}</table>
+{{ end }}</body>
+</html>
+`))
+
+type emailNotifier struct {
+	model.BaseNotifier
+	config  model.Email
+	lock    sync.Mutex
+	started time.Time
+	events  []emailEvent
+}
+
+type emailEvent struct {
+	Time    time.Time
+	Kind    string
+	Name    string
+	Status  string
+	Details []string
+	Error   string
+}
+
+type emailSummary struct {
+	Title        string
+	Context      string
+	Status       model.CampaignStatus
+	Command      string
+	Prefix       string
+	Provider     model.ProviderType
+	AccountLabel string
+	Account      string
+	RegionLabel  string
+	Region       string
+	Started      time.Time
+	Finished     time.Time
+	TimeFormat   string
+	Error        string
+	StepCounts   string
+	Events       []emailEvent
+}
+
+func newEmailNotifier(baseNotifier model.BaseNotifier, configEmail model.Email) *emailNotifier {
+	if configEmail.Security == "" {
+		configEmail.Security = model.EmailSecurityStartTLS
+	}
+	if configEmail.Port == 0 {
+		configEmail.Port = getDefaultEmailPort(configEmail.Security)
+	}
+	if configEmail.Subject == "" {
+		configEmail.Subject = emailDefaultSubject
+	}
+	return &emailNotifier{
+		BaseNotifier: baseNotifier,
+		config:       configEmail,
+	}
+}
+
+func getDefaultEmailPort(security model.EmailSecurity) int {
+	switch security {
+	case model.EmailSecurityTLS:
+		return 465
+	case model.EmailSecurityNone:
+		return 25
+	default:
+		return 587
+	}
+}
+
+// Includes always accepts started, progress and approval messages as they are collected into the campaign digest.
+func (e *emailNotifier) Includes(messageType model.MessageType) bool {
+	switch messageType {
+	case model.MessageTypeStarted, model.MessageTypeProgress, model.MessageTypeApprovals:
+		return true
+	case model.MessageTypeSuccess, model.MessageTypeFailure:
+		return e.BaseNotifier.Includes(messageType)
+	default:
+		return false
+	}
+}
+
+func (e *emailNotifier) collectsCampaignState() {}
+
+func (e *emailNotifier) HandleCampaign(msg model.CampaignMessage) error {
+	e.lock.Lock()
+	if msg.Type() == model.MessageTypeStarted {
+		e.started = time.Now()
+		e.events = nil
+		e.lock.Unlock()
+		return nil
+	}
+	started, events := e.started, e.events
+	e.started, e.events = time.Time{}, nil
+	e.lock.Unlock()
+	summary := e.getSummary(msg, started, events)
+	return e.send(summary)
+}
+
+func (e *emailNotifier) HandleApproval(msg model.ApprovalMessage) error {
+	var details []string
+	if msg.ApprovedBy != "" {
+		details = append(details, fmt.Sprintf("Approved by %s", msg.ApprovedBy))
+	}
+	e.addEvent(emailEvent{Kind: "approval", Name: msg.PipelineName, Status: "approved", Details: details})
+	return nil
+}
+
+func (e *emailNotifier) HandleManualApproval(msg model.ManualApprovalMessage) error {
+	details := []string{fmt.Sprintf("Plan: %d to import, %d to add, %d to change, %d to destroy", msg.Changes.Imported,
+		msg.Changes.Added, msg.Changes.Changed, msg.Changes.Destroyed)}
+	if msg.Link != "" {
+		details = append(details, fmt.Sprintf("Pipeline: %s", msg.Link))
+	}
+	e.addEvent(emailEvent{Kind: "approval", Name: msg.PipelineName, Status: "waiting", Details: details})
+	return nil
+}
+
+func (e *emailNotifier) HandleStepState(msg model.StepStateMessage) error {
+	details := make([]string, 0, len(msg.StateStep.Modules))
+	for _, module := range msg.StateStep.Modules {
+		detail := fmt.Sprintf("Module %s version %s", module.Name, module.Version)
+		if module.AppliedVersion != nil {
+			detail += fmt.Sprintf(", applied version %s", *module.AppliedVersion)
+		}
+		details = append(details, detail)
+	}
+	for _, resource := range msg.SlowResources {
+		details = append(details, fmt.Sprintf("Slow resource %s", timingString(resource)))
+	}
+	e.addEvent(emailEvent{Kind: "step", Name: msg.StateStep.Name, Status: string(msg.Status), Details: details,
+		Error: errorString(msg.Err)})
+	return nil
+}
+
+func (e *emailNotifier) HandlePipelineState(msg model.PipelineStateMessage) error {
+	details := make([]string, 0, len(msg.SourceVersions))
+	for _, source := range msg.SourceVersions {
+		detail := fmt.Sprintf("Source %s", source.URL)
+		if source.Version != nil {
+			detail += fmt.Sprintf(", version %s", source.Version)
+		}
+		if source.ForcedVersion != "" {
+			detail += fmt.Sprintf(", forced version %s", source.ForcedVersion)
+		}
+		details = append(details, detail)
+	}
+	e.addEvent(emailEvent{Kind: "pipeline", Name: fmt.Sprintf("pipeline %d", msg.Index), Status: string(msg.Status),
+		Details: details, Error: errorString(msg.Err)})
+	return nil
+}
+
+func (e *emailNotifier) HandleModules(model.ModulesMessage) error {
+	return nil
+}
+
+func (e *emailNotifier) HandleSources(model.SourcesMessage) error {
+	return nil
+}
+
+func (e *emailNotifier) HandleSchedule(model.ScheduleMessage) error {
+	return nil
+}
+
+func (e *emailNotifier) addEvent(event emailEvent) {
+	event.Time = time.Now()
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.events = append(e.events, event)
+}
+
+func (e *emailNotifier) getSummary(msg model.CampaignMessage, started time.Time, events []emailEvent) emailSummary {
+	provider := msg.Resources.GetProviderType()
+	summary := emailSummary{
+		Context:      e.Context,
+		Status:       msg.Status,
+		Command:      string(msg.Command),
+		Prefix:       msg.Resources.GetCloudPrefix(),
+		Provider:     provider,
+		AccountLabel: "Account Id",
+		Account:      msg.Resources.GetAccount(),
+		RegionLabel:  "Region",
+		Region:       msg.Resources.GetRegion(),
+		Started:      started,
+		Finished:     time.Now(),
+		TimeFormat:   emailTimeFormat,
+		Error:        errorString(msg.Err),
+		StepCounts:   getStepCounts(events),
+		Events:       events,
+	}
+	if provider == model.GCLOUD {
+		summary.AccountLabel = "Project Id"
+		summary.RegionLabel = "Location"
+	}
+	summary.Title = fmt.Sprintf("Agent %s %s: prefix %s", summary.Command, summary.Status, summary.Prefix)
+	return summary
+}
+
+// getStepCounts counts the final status of each step, e.g. "3 success, 1 failure".
+func getStepCounts(events []emailEvent) string {
+	statuses := make(map[string]string)
+	var names []string
+	for _, event := range events {
+		if event.Kind != "step" {
+			continue
+		}
+		if _, found := statuses[event.Name]; !found {
+			names = append(names, event.Name)
+		}
+		statuses[event.Name] = event.Status
+	}
+	counts := make(map[string]int)
+	var order []string
+	for _, name := range names {
+		status := statuses[name]
+		if counts[status] == 0 {
+			order = append(order, status)
+		}
+		counts[status]++
+	}
+	parts := make([]string, 0, len(order))
+	for _, status := range order {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (e *emailNotifier) send(summary emailSummary) error {
+	var text, html bytes.Buffer
+	if err := emailTextTemplate.Execute(&text, summary); err != nil {
+		return fmt.Errorf("failed to render email text: %w", err)
+	}
+	if err := emailHTMLTemplate.Execute(&html, summary); err != nil {
+		return fmt.Errorf("failed to render email html: %w", err)
+	}
+	subject := fmt.Sprintf("%s: %s", e.config.Subject, summary.Title)
+	if e.Context != "" {
+		subject = fmt.Sprintf("%s %s", e.Context, subject)
+	}
+	message, err := buildEmail(e.config.From, e.config.To, subject, text.String(), html.String())
+	if err != nil {
+		return err
+	}
+	return e.sendMail(message)
+}
+
+func (e *emailNotifier) sendMail(message []byte) error {
+	client, err := e.dial()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+	if e.config.Username != "" {
+		auth := smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+		if err = client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate with smtp server: %w", err)
+		}
+	}
+	if err = client.Mail(e.config.From); err != nil {
+		return fmt.Errorf("failed to set email sender: %w", err)
+	}
+	for _, to := range e.config.To {
+		if err = client.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to set email recipient %s: %w", to, err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start email data: %w", err)
+	}
+	if _, err = writer.Write(message); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err = writer.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
+
+func (e *emailNotifier) dial() (*smtp.Client, error) {
+	address := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+	tlsConfig := &tls.Config{ServerName: e.config.Host}
+	dialer := &net.Dialer{Timeout: emailTimeout}
+	var conn net.Conn
+	var err error
+	if e.config.Security == model.EmailSecurityTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to smtp server %s: %w", address, err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(emailTimeout))
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to create smtp client: %w", err)
+	}
+	if e.config.Security == model.EmailSecurityStartTLS {
+		if err = client.StartTLS(tlsConfig); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("failed to start tls with smtp server: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// buildEmail creates a multipart/alternative email with plaintext and html bodies.
+func buildEmail(from string, to []string, subject, text, html string) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email part: %w", err)
+		}
+		qpWriter := quotedprintable.NewWriter(partWriter)
+		if _, err = qpWriter.Write([]byte(part.content)); err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+		if err = qpWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close email body: %w", err)
+	}
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprint(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", writer.Boundary())
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
+
+func emailStatusColor(status any) string {
+	switch fmt.Sprint(status) {
+	case string(model.CampaignStatusSuccess):
+		return "#2e7d32"
+	case string(model.CampaignStatusFailure), string(model.CampaignStatusTerminated):
+		return "#c62828"
+	case string(model.CampaignStatusSkipped):
+		return "#757575"
+	default:
+		return "#1565c0"
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,267 @@
+package notify
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+// smtpServer is a minimal smtp server that records the commands and the message of a single session.
+type smtpServer struct {
+	listener net.Listener
+	authFail bool
+	done     chan struct{}
+	commands []string
+	auth     string
+	data     string
+}
+
+func newSMTPServer(t *testing.T, authFail bool) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := &smtpServer{listener: listener, authFail: authFail, done: make(chan struct{})}
+	t.Cleanup(func() { _ = listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve() {
+	defer close(s.done)
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 localhost ESMTP test")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		s.commands = append(s.commands, line)
+		command, _, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(command) {
+		case "EHLO":
+			_ = text.PrintfLine("250-localhost")
+			_ = text.PrintfLine("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth = line
+			if s.authFail {
+				_ = text.PrintfLine("535 5.7.8 Authentication credentials invalid")
+			} else {
+				_ = text.PrintfLine("235 2.7.0 Authentication successful")
+			}
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			_ = text.PrintfLine("250 OK")
+		case "DATA":
+			_ = text.PrintfLine("354 Start mail input")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			s.data = string(data)
+			_ = text.PrintfLine("250 OK")
+		case "QUIT":
+			_ = text.PrintfLine("221 Bye")
+			return
+		default:
+			_ = text.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) wait(t *testing.T) {
+	t.Helper()
+	_ = s.listener.Close()
+	<-s.done
+}
+
+func newTestEmailNotifier(port int, security model.EmailSecurity, username string) *emailNotifier {
+	return newEmailNotifier(model.BaseNotifier{Name: "email", Context: "test"}, model.Email{
+		Host:     "127.0.0.1",
+		Port:     port,
+		Security: security,
+		Username: username,
+		Password: "secret",
+		From:     "agent@example.com",
+		To:       []string{"ops@example.com", "dev@example.com"},
+	})
+}
+
+type testResources struct {
+	model.CloudResources
+}
+
+func (testResources) GetBackendConfigVars(string) map[string]string {
+	return nil
+}
+
+func successMessage() model.CampaignMessage {
+	return model.CampaignMessage{
+		Status:  model.CampaignStatusSuccess,
+		Command: common.RunCommand,
+		Resources: testResources{CloudResources: model.CloudResources{
+			ProviderType: model.AWS,
+			CloudPrefix:  "dev",
+			Region:       "eu-north-1",
+			Account:      "123456789012",
+		}},
+	}
+}
+
+func TestEmailSend(t *testing.T) {
+	server := newSMTPServer(t, false)
+	notifier := newTestEmailNotifier(server.port(), model.EmailSecurityNone, "user")
+	if err := notifier.HandleCampaign(successMessage()); err != nil {
+		t.Fatalf("failed to send email: %v", err)
+	}
+	server.wait(t)
+
+	expectedCommands := []string{"MAIL FROM:<agent@example.com>", "RCPT TO:<ops@example.com>",
+		"RCPT TO:<dev@example.com>", "DATA", "QUIT"}
+	var envelope []string
+	for _, command := range server.commands {
+		if !strings.HasPrefix(command, "EHLO") && !strings.HasPrefix(command, "AUTH") {
+			envelope = append(envelope, strings.SplitN(command, " BODY=", 2)[0])
+		}
+	}
+	if strings.Join(envelope, "\n") != strings.Join(expectedCommands, "\n") {
+		t.Fatalf("expected commands %v, got %v", expectedCommands, server.commands)
+	}
+	for _, command := range server.commands {
+		if strings.EqualFold(command, "STARTTLS") {
+			t.Fatal("tls must not be started when security is none")
+		}
+	}
+	credentials, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(server.auth, "AUTH PLAIN "))
+	if err != nil {
+		t.Fatalf("failed to decode auth %q: %v", server.auth, err)
+	}
+	if string(credentials) != "\x00user\x00secret" {
+		t.Fatalf("unexpected credentials %q", credentials)
+	}
+
+	message, err := mail.ReadMessage(strings.NewReader(server.data))
+	if err != nil {
+		t.Fatalf("failed to parse email: %v\n%s", err, server.data)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
+	}
+	headers := map[string]string{
+		"From":         "agent@example.com",
+		"To":           "ops@example.com, dev@example.com",
+		"Subject":      "test Entigo Infralib Agent: Agent run success: prefix dev",
+		"MIME-Version": "1.0",
+	}
+	for name, expected := range headers {
+		value := message.Header.Get(name)
+		if name == "Subject" {
+			value = subject
+		}
+		if value != expected {
+			t.Errorf("expected header %s %q, got %q", name, expected, value)
+		}
+	}
+	if _, err = mail.ParseDate(message.Header.Get("Date")); err != nil {
+		t.Errorf("invalid date header: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %s: %v", message.Header.Get("Content-Type"), err)
+	}
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	expectedParts := map[string]string{
+		"text/plain; charset=UTF-8": "Account Id: 123456789012",
+		"text/html; charset=UTF-8":  "<td><b>Account Id</b></td><td>123456789012</td>",
+	}
+	parts := 0
+	for {
+		part, err := reader.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read email part: %v", err)
+		}
+		parts++
+		contentType := part.Header.Get("Content-Type")
+		expected, found := expectedParts[contentType]
+		if !found {
+			t.Fatalf("unexpected email part %s", contentType)
+		}
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatalf("failed to decode email part %s: %v", contentType, err)
+		}
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("email part %s does not contain %q:\n%s", contentType, expected, body)
+		}
+	}
+	if parts != len(expectedParts) {
+		t.Fatalf("expected %d email parts, got %d", len(expectedParts), parts)
+	}
+}
+
+func TestEmailSendErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		security model.EmailSecurity
+		authFail bool
+		err      string
+	}{
+		{name: "auth failure", security: model.EmailSecurityNone, authFail: true,
+			err: "failed to authenticate with smtp server"},
+		{name: "starttls not supported", security: model.EmailSecurityStartTLS,
+			err: "failed to start tls with smtp server"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSMTPServer(t, tt.authFail)
+			notifier := newTestEmailNotifier(server.port(), tt.security, "user")
+			err := notifier.HandleCampaign(successMessage())
+			server.wait(t)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+			for _, command := range server.commands {
+				if strings.HasPrefix(command, "MAIL") {
+					t.Fatalf("email must not be sent after an error, got commands %v", server.commands)
+				}
+			}
+		})
+	}
+}
+
+func TestEmailDefaultPort(t *testing.T) {
+	for security, port := range map[model.EmailSecurity]int{
+		"":                          587,
+		model.EmailSecurityStartTLS: 587,
+		model.EmailSecurityTLS:      465,
+		model.EmailSecurityNone:     25,
+	} {
+		notifier := newEmailNotifier(model.BaseNotifier{}, model.Email{Security: security})
+		if notifier.config.Port != port {
+			t.Errorf("expected port %d for security %q, got %d", port, security, notifier.config.Port)
+		}
+	}
+}
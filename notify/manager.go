@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
@@ -14,20 +15,61 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	deliveryAttempts = 3
+	deliveryBackoff  = 2 * time.Second
+)
+
 type NotificationManager struct {
+	ctx           context.Context
 	notifiers     []model.Notifier
 	pipelineIndex atomic.Int32
+	outbox        *outbox
+	routes        map[string]route
+	stepModules   *stepModules
 }
 
 var _ model.NotificationManager = (*NotificationManager)(nil)
 
-func NewNotificationManager(ctx context.Context, configNotifiers []model.ConfigNotification, campaignId uuid.UUID) (model.NotificationManager, error) {
+// observer receives every message before the message type and route filters, e.g. to keep a campaign summary up to
+// date. Observed messages aren't retried or stored in the outbox.
+type observer interface {
+	Observe(msg model.Message)
+}
+
+// campaignStateNotifier collects messages into the state of the current campaign. Stored campaign and step state
+// messages belong to earlier campaigns, so they are dropped from the outbox instead of re-sent to these notifiers.
+type campaignStateNotifier interface {
+	collectsCampaignState()
+}
+
+func isCampaignStateMessage(msg model.Message) bool {
+	switch msg.(type) {
+	case model.CampaignMessage, model.StepStateMessage:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewNotificationManager creates notifiers from config. When bucket is set, undelivered messages are stored in its outbox.
+// Internal notifiers, like report collectors, receive all messages and are not filtered by the notifier routes.
+func NewNotificationManager(ctx context.Context, configNotifiers []model.ConfigNotification, campaignId uuid.UUID, bucket model.Bucket, internal ...model.Notifier) (model.NotificationManager, error) {
 	notifiers, err := createNotifiers(ctx, configNotifiers, campaignId)
 	if err != nil {
 		return nil, err
 	}
+	notifiers = append(notifiers, internal...)
+	routes := make(map[string]route, len(configNotifiers))
+	for _, configNotifier := range configNotifiers {
+		routes[configNotifier.Name] = newRoute(configNotifier)
+	}
 	return &NotificationManager{
-		notifiers: notifiers,
+		ctx:         ctx,
+		notifiers:   notifiers,
+		outbox:      newOutbox(bucket),
+		routes:      routes,
+		stepModules: newStepModules(),
 	}, nil
 }
 
@@ -45,7 +87,9 @@ func createNotifiers(ctx context.Context, configNotifiers []model.ConfigNotifica
 
 func createNotifier(ctx context.Context, configNotifier model.ConfigNotification, campaignId uuid.UUID) (model.Notifier, error) {
 	var messageTypes model.Set[model.MessageType]
-	if len(configNotifier.MessageTypes) == 0 {
+	if len(configNotifier.MessageTypes) == 0 && configNotifier.Email != nil {
+		messageTypes = model.NewSet(model.MessageTypeSuccess, model.MessageTypeFailure)
+	} else if len(configNotifier.MessageTypes) == 0 {
 		messageTypes = model.NewSet(model.MessageTypeApprovals, model.MessageTypeFailure)
 	} else {
 		messageTypes = model.ToSet(configNotifier.MessageTypes)
@@ -64,6 +108,12 @@ func createNotifier(ctx context.Context, configNotifier model.ConfigNotification
 	if configNotifier.Api != nil {
 		return createApiNotifier(ctx, baseNotifier, *configNotifier.Api, campaignId)
 	}
+	if configNotifier.Webhook != nil {
+		return createWebhookNotifier(ctx, baseNotifier, *configNotifier.Webhook)
+	}
+	if configNotifier.Email != nil {
+		return createEmailNotifier(baseNotifier, *configNotifier.Email)
+	}
 	return nil, errors.New("has no subtype specified")
 }
 
@@ -125,12 +175,26 @@ func (n *NotificationManager) ManualApproval(pipelineName, step string, changes
 	n.Notify(model.ManualApprovalMessage{PipelineIndex: index, PipelineName: pipelineName, Step: step, Changes: changes, Link: link})
 }
 
-func (n *NotificationManager) StepState(status model.ApplyStatus, stepState model.StateStep, step *model.Step, err error) {
+func (n *NotificationManager) PlanChanges(pipelineName, step string, changes model.PipelineChanges) {
 	index, ok := n.getPipelineIndex()
 	if !ok {
 		return
 	}
-	n.Notify(model.StepStateMessage{PipelineIndex: index, Status: status, StateStep: stepState, Step: step, Err: err})
+	msg := model.PlanChangesMessage{PipelineIndex: index, PipelineName: pipelineName, Step: step, Changes: changes}
+	for _, notifier := range n.notifiers {
+		if planNotifier, ok := notifier.(model.PlanNotifier); ok {
+			planNotifier.HandlePlanChanges(msg)
+		}
+	}
+}
+
+func (n *NotificationManager) StepState(status model.ApplyStatus, stepState model.StateStep, step *model.Step, slowest []model.ResourceTiming, err error) {
+	index, ok := n.getPipelineIndex()
+	if !ok {
+		return
+	}
+	n.Notify(model.StepStateMessage{PipelineIndex: index, Status: status, StateStep: stepState, Step: step,
+		SlowResources: slowest, Err: err})
 }
 
 func (n *NotificationManager) Modules(resources model.Resources, command common.Command, config model.Config) {
@@ -155,27 +219,175 @@ func (n *NotificationManager) Notify(msg model.Message) {
 		slog.Error(common.PrefixError(fmt.Errorf("message type unknown, msg: %v", msg)))
 		return
 	}
-	n.fanout(msgType, msg.Dispatch)
+	if modulesMsg, ok := msg.(model.ModulesMessage); ok {
+		n.stepModules.set(modulesMsg.Config)
+	}
+	n.observe(msg)
+	n.fanout(msg)
 }
 
-func (n *NotificationManager) fanout(kind model.MessageType, dispatch func(model.Notifier) error) {
+func (n *NotificationManager) observe(msg model.Message) {
+	for _, notifier := range n.notifiers {
+		if o, ok := notifier.(observer); ok {
+			o.Observe(msg)
+		}
+	}
+}
+
+func (n *NotificationManager) fanout(msg model.Message) {
+	kind := msg.Type()
 	var wg sync.WaitGroup
 	for _, notifier := range n.notifiers {
-		if !notifier.Includes(kind) {
+		if !notifier.Includes(kind) || !n.routes[notifier.GetName()].matches(msg, n.stepModules) {
 			continue
 		}
 		wg.Add(1)
 		go func(notifier model.Notifier) {
 			defer wg.Done()
 			slog.Debug(fmt.Sprintf("Sending %s notification to %s notifier", kind, notifier.GetName()))
-			if err := dispatch(notifier); err != nil {
-				if errors.Is(err, context.Canceled) {
-					slog.Debug(fmt.Sprintf("notifier '%s' skipped during shutdown: %v", notifier.GetName(), err))
-					return
+			attempts, err := n.deliver(notifier, msg)
+			if err == nil {
+				if attempts > 1 {
+					slog.Info(fmt.Sprintf("Delivered %s notification to '%s' after %d attempts", kind, notifier.GetName(), attempts))
 				}
-				slog.Error(common.PrefixError(fmt.Errorf("failed to notify '%s': %v", notifier.GetName(), err)))
+				return
+			}
+			if errors.Is(err, context.Canceled) {
+				slog.Debug(fmt.Sprintf("notifier '%s' skipped during shutdown: %v", notifier.GetName(), err))
+				return
 			}
+			n.storeUndelivered(notifier, msg, attempts, err)
 		}(notifier)
 	}
 	wg.Wait()
 }
+
+// deliver dispatches the message to the notifier, retrying failed attempts with exponential backoff.
+func (n *NotificationManager) deliver(notifier model.Notifier, msg model.Message) (int, error) {
+	backoff := deliveryBackoff
+	for attempt := 1; ; attempt++ {
+		err := msg.Dispatch(notifier)
+		if err == nil || attempt == deliveryAttempts || errors.Is(err, context.Canceled) {
+			return attempt, err
+		}
+		slog.Debug(fmt.Sprintf("Attempt %d to notify '%s' failed, retrying in %s: %v", attempt, notifier.GetName(),
+			backoff, err))
+		select {
+		case <-n.ctx.Done():
+			return attempt, n.ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (n *NotificationManager) storeUndelivered(notifier model.Notifier, msg model.Message, attempts int, err error) {
+	if n.outbox == nil {
+		slog.Error(common.PrefixError(fmt.Errorf("failed to notify '%s' after %d attempts: %v", notifier.GetName(),
+			attempts, err)))
+		return
+	}
+	stored, storeErr := n.outbox.add(n.ctx, notifier.GetName(), msg, attempts, err)
+	if storeErr != nil {
+		slog.Error(common.PrefixError(fmt.Errorf("failed to notify '%s' after %d attempts: %v, failed to store message in outbox: %v",
+			notifier.GetName(), attempts, err, storeErr)))
+	} else if stored {
+		slog.Error(common.PrefixError(fmt.Errorf("failed to notify '%s' after %d attempts, message stored in outbox: %v",
+			notifier.GetName(), attempts, err)))
+	} else {
+		slog.Error(common.PrefixError(fmt.Errorf("failed to notify '%s' after %d attempts: %v", notifier.GetName(),
+			attempts, err)))
+	}
+}
+
+// Flush re-sends messages from the outbox. Delivered messages, messages older than a week and messages of removed
+// notifiers are removed from the outbox.
+func (n *NotificationManager) Flush(ctx context.Context) error {
+	if n.outbox == nil {
+		return nil
+	}
+	entries, err := n.outbox.read(ctx)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	slog.Info(fmt.Sprintf("Flushing %d notifications from outbox", len(entries)))
+	var errs []error
+	delivered, dropped, remaining := 0, 0, 0
+	for _, entry := range entries {
+		remove, sent := n.flushEntry(&entry)
+		if !remove {
+			remaining++
+			errs = append(errs, n.outbox.write(ctx, entry))
+			continue
+		}
+		if sent {
+			delivered++
+		} else {
+			dropped++
+		}
+		errs = append(errs, n.outbox.remove(ctx, entry))
+	}
+	slog.Info(fmt.Sprintf("Notification outbox flushed: %d delivered, %d dropped, %d remaining", delivered, dropped,
+		remaining))
+	return errors.Join(errs...)
+}
+
+// flushEntry re-sends the entry, returns whether the entry can be removed from the outbox and whether it was delivered.
+func (n *NotificationManager) flushEntry(entry *outboxEntry) (bool, bool) {
+	if time.Since(entry.Created) > outboxMaxAge {
+		slog.Warn(common.PrefixWarning(fmt.Sprintf("dropping %s notification for '%s' from outbox, message is older than %s",
+			entry.Type, entry.Notifier, outboxMaxAge)))
+		return true, false
+	}
+	notifier := n.getNotifier(entry.Notifier)
+	if notifier == nil || !notifier.Includes(entry.Type) {
+		slog.Warn(common.PrefixWarning(fmt.Sprintf("dropping %s notification for '%s' from outbox, notifier doesn't exist or doesn't include the message type",
+			entry.Type, entry.Notifier)))
+		return true, false
+	}
+	msg, err := entry.toMessage(n.ctx)
+	if err != nil {
+		slog.Warn(common.PrefixWarning(fmt.Sprintf("dropping notification from outbox: %v", err)))
+		return true, false
+	}
+	if _, ok := notifier.(campaignStateNotifier); ok && isCampaignStateMessage(msg) {
+		slog.Warn(common.PrefixWarning(fmt.Sprintf("dropping %s notification for '%s' from outbox, notifier only collects the current campaign state",
+			entry.Type, entry.Notifier)))
+		return true, false
+	}
+	if !n.routes[notifier.GetName()].matches(msg, n.stepModules) {
+		slog.Warn(common.PrefixWarning(fmt.Sprintf("dropping %s notification for '%s' from outbox, message doesn't match the notifier filters",
+			entry.Type, entry.Notifier)))
+		return true, false
+	}
+	attempts, err := n.deliver(notifier, msg)
+	entry.Attempts += attempts
+	if err != nil {
+		entry.LastError = err.Error()
+		slog.Error(common.PrefixError(fmt.Errorf("failed to re-send %s notification to '%s', total attempts %d: %v",
+			entry.Type, entry.Notifier, entry.Attempts, err)))
+		return false, false
+	}
+	slog.Info(fmt.Sprintf("Delivered %s notification from outbox to '%s'", entry.Type, entry.Notifier))
+	return true, true
+}
+
+func (n *NotificationManager) getNotifier(name string) model.Notifier {
+	for _, notifier := range n.notifiers {
+		if notifier.GetName() == name {
+			return notifier
+		}
+	}
+	return nil
+}
+
+func createWebhookNotifier(ctx context.Context, baseNotifier model.BaseNotifier, webhook model.Webhook) (model.Notifier, error) {
+	return newWebhookNotifier(ctx, baseNotifier, webhook)
+}
+
+func createEmailNotifier(baseNotifier model.BaseNotifier, email model.Email) (model.Notifier, error) {
+	return newEmailNotifier(baseNotifier, email), nil
+}
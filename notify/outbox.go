@@ -0,0 +1,234 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outboxFolder = "notifications/outbox"
+	outboxMaxAge = 7 * 24 * time.Hour
+)
+
+// outbox stores undelivered messages in the bucket, so they can be re-sent by the next agent execution. Every message
+// is a separate file, so concurrent agents don't overwrite each other's messages.
+// Modules and sources messages are not stored as they are re-sent by every execution.
+type outbox struct {
+	bucket model.Bucket
+}
+
+type outboxEntry struct {
+	Id             string                       `yaml:"id"`
+	Notifier       string                       `yaml:"notifier"`
+	Type           model.MessageType            `yaml:"type"`
+	Created        time.Time                    `yaml:"created"`
+	Attempts       int                          `yaml:"attempts"`
+	LastError      string                       `yaml:"last_error,omitempty"`
+	Campaign       *outboxCampaign              `yaml:"campaign,omitempty"`
+	Schedule       *model.ScheduleMessage       `yaml:"schedule,omitempty"`
+	Approval       *model.ApprovalMessage       `yaml:"approval,omitempty"`
+	ManualApproval *model.ManualApprovalMessage `yaml:"manual_approval,omitempty"`
+	StepState      *outboxStepState             `yaml:"step_state,omitempty"`
+	PipelineState  *outboxPipelineState         `yaml:"pipeline_state,omitempty"`
+}
+
+type outboxCampaign struct {
+	Status   model.CampaignStatus `yaml:"status"`
+	Command  string               `yaml:"command"`
+	Provider model.ProviderType   `yaml:"provider"`
+	Prefix   string               `yaml:"prefix"`
+	Account  string               `yaml:"account"`
+	Region   string               `yaml:"region"`
+	Err      string               `yaml:"error,omitempty"`
+}
+
+// outboxStepState stores only the module metadata of the step, as step config may include sensitive values.
+type outboxStepState struct {
+	PipelineIndex  int32                        `yaml:"pipeline_index"`
+	Status         model.ApplyStatus            `yaml:"status"`
+	StateStep      model.StateStep              `yaml:"state_step"`
+	ModuleMetadata map[string]map[string]string `yaml:"module_metadata,omitempty"`
+	SlowResources  []model.ResourceTiming       `yaml:"slow_resources,omitempty"`
+	Err            string                       `yaml:"error,omitempty"`
+}
+
+type outboxPipelineState struct {
+	Index          int32                 `yaml:"index"`
+	Status         model.ApplyStatus     `yaml:"status"`
+	SourceVersions []model.SourceVersion `yaml:"source_versions,omitempty"`
+	Err            string                `yaml:"error,omitempty"`
+}
+
+// outboxResources provides the resource values used by notifiers for restored campaign messages.
+type outboxResources struct {
+	model.CloudResources
+}
+
+func (outboxResources) GetBackendConfigVars(string) map[string]string {
+	return nil
+}
+
+func newOutbox(bucket model.Bucket) *outbox {
+	if bucket == nil {
+		return nil
+	}
+	return &outbox{bucket: bucket}
+}
+
+func (o *outbox) add(ctx context.Context, notifier string, msg model.Message, attempts int, err error) (bool, error) {
+	entry, stored := toOutboxEntry(msg)
+	if !stored {
+		return false, nil
+	}
+	entry.Id = uuid.NewString()
+	entry.Notifier = notifier
+	entry.Type = msg.Type()
+	entry.Created = time.Now()
+	entry.Attempts = attempts
+	entry.LastError = err.Error()
+	return true, o.write(ctx, entry)
+}
+
+// read returns the stored entries, entries that can't be parsed are skipped.
+func (o *outbox) read(ctx context.Context) ([]outboxEntry, error) {
+	files, err := o.bucket.ListFolderFiles(ctx, outboxFolder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification outbox: %v", err)
+	}
+	var entries []outboxEntry
+	for _, file := range files {
+		content, err := o.bucket.GetFile(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get notification outbox file %s: %v", file, err)
+		}
+		if content == nil {
+			continue
+		}
+		var entry outboxEntry
+		if err = yaml.Unmarshal(content, &entry); err != nil || entry.Id == "" ||
+			getOutboxFile(entry.Id) != file {
+			slog.Warn(common.PrefixWarning(fmt.Sprintf("skipping invalid notification outbox file %s", file)))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (o *outbox) write(ctx context.Context, entry outboxEntry) error {
+	bytes, err := yaml.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification outbox entry: %v", err)
+	}
+	return o.bucket.PutFile(ctx, getOutboxFile(entry.Id), bytes)
+}
+
+func (o *outbox) remove(ctx context.Context, entry outboxEntry) error {
+	return o.bucket.DeleteFile(ctx, getOutboxFile(entry.Id))
+}
+
+func getOutboxFile(id string) string {
+	return path.Join(outboxFolder, id+".yaml")
+}
+
+func toOutboxEntry(msg model.Message) (outboxEntry, bool) {
+	switch m := msg.(type) {
+	case model.CampaignMessage:
+		campaign := &outboxCampaign{Status: m.Status, Command: string(m.Command), Err: errorString(m.Err)}
+		if m.Resources != nil {
+			campaign.Provider = m.Resources.GetProviderType()
+			campaign.Prefix = m.Resources.GetCloudPrefix()
+			campaign.Account = m.Resources.GetAccount()
+			campaign.Region = m.Resources.GetRegion()
+		}
+		return outboxEntry{Campaign: campaign}, true
+	case model.ScheduleMessage:
+		return outboxEntry{Schedule: &m}, true
+	case model.ApprovalMessage:
+		return outboxEntry{Approval: &m}, true
+	case model.ManualApprovalMessage:
+		return outboxEntry{ManualApproval: &m}, true
+	case model.StepStateMessage:
+		return outboxEntry{StepState: &outboxStepState{PipelineIndex: m.PipelineIndex, Status: m.Status,
+			StateStep: m.StateStep, ModuleMetadata: getModuleMetadata(m.Step), SlowResources: m.SlowResources,
+			Err: errorString(m.Err)}}, true
+	case model.PipelineStateMessage:
+		return outboxEntry{PipelineState: &outboxPipelineState{Index: m.Index, Status: m.Status,
+			SourceVersions: m.SourceVersions, Err: errorString(m.Err)}}, true
+	default:
+		return outboxEntry{}, false
+	}
+}
+
+func (e outboxEntry) toMessage(ctx context.Context) (model.Message, error) {
+	switch {
+	case e.Campaign != nil:
+		return model.CampaignMessage{
+			Ctx:    ctx,
+			Status: e.Campaign.Status,
+			Resources: outboxResources{model.CloudResources{ProviderType: e.Campaign.Provider,
+				CloudPrefix: e.Campaign.Prefix, Account: e.Campaign.Account, Region: e.Campaign.Region}},
+			Command: common.Command(e.Campaign.Command),
+			Err:     toError(e.Campaign.Err),
+		}, nil
+	case e.Schedule != nil:
+		return *e.Schedule, nil
+	case e.Approval != nil:
+		return *e.Approval, nil
+	case e.ManualApproval != nil:
+		return *e.ManualApproval, nil
+	case e.StepState != nil:
+		return model.StepStateMessage{PipelineIndex: e.StepState.PipelineIndex, Status: e.StepState.Status,
+			StateStep: e.StepState.StateStep, Step: e.StepState.toStep(), SlowResources: e.StepState.SlowResources,
+			Err: toError(e.StepState.Err)}, nil
+	case e.PipelineState != nil:
+		return model.PipelineStateMessage{Index: e.PipelineState.Index, Status: e.PipelineState.Status,
+			SourceVersions: e.PipelineState.SourceVersions, Err: toError(e.PipelineState.Err)}, nil
+	default:
+		return nil, fmt.Errorf("outbox entry %s has no message", e.Id)
+	}
+}
+
+func getModuleMetadata(step *model.Step) map[string]map[string]string {
+	if step == nil {
+		return nil
+	}
+	metadata := make(map[string]map[string]string)
+	for _, module := range step.Modules {
+		if len(module.Metadata) > 0 {
+			metadata[module.Name] = module.Metadata
+		}
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+// toStep restores the step with the module metadata that notifiers use.
+func (s *outboxStepState) toStep() *model.Step {
+	if len(s.ModuleMetadata) == 0 {
+		return nil
+	}
+	step := &model.Step{Name: s.StateStep.Name}
+	for name, metadata := range s.ModuleMetadata {
+		step.Modules = append(step.Modules, model.Module{Name: name, Metadata: metadata})
+	}
+	return step
+}
+
+func toError(message string) error {
+	if message == "" {
+		return nil
+	}
+	return errors.New(message)
+}
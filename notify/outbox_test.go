@@ -0,0 +1,146 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+type memoryBucket struct {
+	model.Bucket
+	lock  sync.Mutex
+	files map[string][]byte
+}
+
+func newMemoryBucket() *memoryBucket {
+	return &memoryBucket{files: make(map[string][]byte)}
+}
+
+func (b *memoryBucket) PutFile(_ context.Context, file string, content []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.files[file] = content
+	return nil
+}
+
+func (b *memoryBucket) GetFile(_ context.Context, file string) ([]byte, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.files[file], nil
+}
+
+func (b *memoryBucket) DeleteFile(_ context.Context, file string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(b.files, file)
+	return nil
+}
+
+func (b *memoryBucket) ListFolderFiles(_ context.Context, folder string) ([]string, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var files []string
+	for file := range b.files {
+		if strings.HasPrefix(file, folder+"/") {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func TestOutboxConcurrentAdd(t *testing.T) {
+	bucket := newMemoryBucket()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Every agent execution has its own outbox for the same bucket
+			stored, err := newOutbox(bucket).add(context.Background(), "webhook", model.ApprovalMessage{Step: "net"}, 3,
+				errors.New("timeout"))
+			if err != nil || !stored {
+				t.Errorf("failed to store message: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	entries, err := newOutbox(bucket).read(context.Background())
+	if err != nil {
+		t.Fatalf("failed to read outbox: %v", err)
+	}
+	if len(entries) != 10 {
+		t.Fatalf("expected 10 entries, got %d", len(entries))
+	}
+}
+
+func TestOutboxStepState(t *testing.T) {
+	bucket := newMemoryBucket()
+	box := newOutbox(bucket)
+	msg := model.StepStateMessage{
+		Status:    model.ApplyStatusFailure,
+		StateStep: model.StateStep{Name: "net", Modules: []*model.StateModule{{Name: "vpc", Version: "v1.0.0"}}},
+		Step: &model.Step{Name: "net", Modules: []model.Module{
+			{Name: "vpc", Inputs: map[string]interface{}{"password": "plaintext"}, HttpPassword: "token",
+				Metadata: map[string]string{"team": "network"}},
+			{Name: "dns", Inputs: map[string]interface{}{"zone": "example.com"}},
+		}},
+		Err: errors.New("apply failed"),
+	}
+	if _, err := box.add(context.Background(), "webhook", msg, 3, errors.New("timeout")); err != nil {
+		t.Fatalf("failed to store message: %v", err)
+	}
+	for file, content := range bucket.files {
+		for _, value := range []string{"plaintext", "token", "example.com"} {
+			if strings.Contains(string(content), value) {
+				t.Fatalf("outbox file %s contains step config value %s:\n%s", file, value, content)
+			}
+		}
+	}
+	entries, err := box.read(context.Background())
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d: %v", len(entries), err)
+	}
+	restored, err := entries[0].toMessage(context.Background())
+	if err != nil {
+		t.Fatalf("failed to restore message: %v", err)
+	}
+	expected := model.StepStateMessage{
+		Status:    msg.Status,
+		StateStep: msg.StateStep,
+		Step: &model.Step{Name: "net", Modules: []model.Module{
+			{Name: "vpc", Metadata: map[string]string{"team": "network"}},
+		}},
+		Err: msg.Err,
+	}
+	if !reflect.DeepEqual(restored, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, restored)
+	}
+	if err = box.remove(context.Background(), entries[0]); err != nil {
+		t.Fatalf("failed to remove entry: %v", err)
+	}
+	if len(bucket.files) != 0 {
+		t.Fatalf("expected empty outbox, got %v", bucket.files)
+	}
+}
+
+func TestOutboxSkipsInvalidFiles(t *testing.T) {
+	bucket := newMemoryBucket()
+	box := newOutbox(bucket)
+	if _, err := box.add(context.Background(), "webhook", model.ApprovalMessage{Step: "net"}, 3, errors.New("timeout")); err != nil {
+		t.Fatalf("failed to store message: %v", err)
+	}
+	bucket.files[outboxFolder+"/broken.yaml"] = []byte("id: [")
+	bucket.files[outboxFolder+"/other.yaml"] = []byte("id: another-id\n")
+	entries, err := box.read(context.Background())
+	if err != nil {
+		t.Fatalf("failed to read outbox: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Approval == nil {
+		t.Fatalf("expected only the approval entry, got %+v", entries)
+	}
+}
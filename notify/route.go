@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"path"
+	"sync"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+// route filters messages of a notifier by step, module and severity.
+type route struct {
+	steps        []string
+	modules      []string
+	excludeSteps []string
+	minSeverity  model.Severity
+}
+
+// stepModules holds the module names of config steps, used for routing messages that only carry the step name.
+type stepModules struct {
+	lock    sync.RWMutex
+	modules map[string][]string
+}
+
+func newRoute(configNotifier model.ConfigNotification) route {
+	return route{
+		steps:        configNotifier.Steps,
+		modules:      configNotifier.Modules,
+		excludeSteps: configNotifier.ExcludeSteps,
+		minSeverity:  configNotifier.MinSeverity,
+	}
+}
+
+// matches checks if the message passes the route. Messages without step information are only filtered by severity.
+// Module filter is skipped when the modules of the step are unknown.
+func (r route) matches(msg model.Message, steps *stepModules) bool {
+	if msg.Severity().Level() < r.minSeverity.Level() {
+		return false
+	}
+	step, modules, found := getMessageStep(msg, steps)
+	if !found {
+		return true
+	}
+	if len(r.steps) > 0 && !matchesAny(r.steps, step) {
+		return false
+	}
+	if matchesAny(r.excludeSteps, step) {
+		return false
+	}
+	if len(r.modules) == 0 || modules == nil {
+		return true
+	}
+	for _, module := range modules {
+		if matchesAny(r.modules, module) {
+			return true
+		}
+	}
+	return false
+}
+
+func getMessageStep(msg model.Message, steps *stepModules) (string, []string, bool) {
+	switch m := msg.(type) {
+	case model.StepStateMessage:
+		if len(m.StateStep.Modules) == 0 {
+			return m.StateStep.Name, steps.get(m.StateStep.Name), true
+		}
+		modules := make([]string, 0, len(m.StateStep.Modules))
+		for _, module := range m.StateStep.Modules {
+			modules = append(modules, module.Name)
+		}
+		return m.StateStep.Name, modules, true
+	case model.ManualApprovalMessage:
+		return m.Step, steps.get(m.Step), true
+	case model.ApprovalMessage:
+		return m.Step, steps.get(m.Step), true
+	default:
+		return "", nil, false
+	}
+}
+
+// matchesAny checks if the value matches any of the glob patterns.
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, value); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func newStepModules() *stepModules {
+	return &stepModules{modules: make(map[string][]string)}
+}
+
+func (s *stepModules) set(config model.Config) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, step := range config.Steps {
+		modules := make([]string, 0, len(step.Modules))
+		for _, module := range step.Modules {
+			modules = append(modules, module.Name)
+		}
+		s.modules[step.Name] = modules
+	}
+}
+
+func (s *stepModules) get(step string) []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.modules[step]
+}
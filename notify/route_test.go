@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+func TestRouteMatches(t *testing.T) {
+	steps := newStepModules()
+	steps.set(model.Config{Steps: []model.Step{
+		{Name: "net-main", Modules: []model.Module{{Name: "vpc"}, {Name: "route53"}}},
+		{Name: "infra-eks", Modules: []model.Module{{Name: "eks"}}},
+	}})
+	stateMessage := func(step string, status model.ApplyStatus, modules ...string) model.StepStateMessage {
+		stateStep := model.StateStep{Name: step}
+		for _, module := range modules {
+			stateStep.Modules = append(stateStep.Modules, &model.StateModule{Name: module})
+		}
+		return model.StepStateMessage{Status: status, StateStep: stateStep}
+	}
+	tests := []struct {
+		name     string
+		route    route
+		msg      model.Message
+		expected bool
+	}{
+		{name: "empty route", msg: stateMessage("net-main", model.ApplyStatusSuccess), expected: true},
+		{
+			name:     "step glob",
+			route:    route{steps: []string{"net-*"}},
+			msg:      model.ManualApprovalMessage{Step: "net-main"},
+			expected: true,
+		},
+		{
+			name:  "step glob mismatch",
+			route: route{steps: []string{"net-*"}},
+			msg:   model.ApprovalMessage{Step: "infra-eks"},
+		},
+		{
+			name:  "excluded step",
+			route: route{steps: []string{"*"}, excludeSteps: []string{"infra-?ks"}},
+			msg:   model.ApprovalMessage{Step: "infra-eks"},
+		},
+		{
+			name:     "invalid pattern",
+			route:    route{excludeSteps: []string{"[net"}},
+			msg:      model.ApprovalMessage{Step: "[net"},
+			expected: true,
+		},
+		{
+			name:     "module glob from config",
+			route:    route{modules: []string{"route*"}},
+			msg:      model.ManualApprovalMessage{Step: "net-main"},
+			expected: true,
+		},
+		{
+			name:  "module mismatch from config",
+			route: route{modules: []string{"route*"}},
+			msg:   model.ManualApprovalMessage{Step: "infra-eks"},
+		},
+		{
+			name:     "module from state",
+			route:    route{modules: []string{"eks"}},
+			msg:      stateMessage("net-main", model.ApplyStatusSuccess, "eks"),
+			expected: true,
+		},
+		{
+			name:     "unknown step modules",
+			route:    route{modules: []string{"eks"}},
+			msg:      model.ApprovalMessage{Step: "apps"},
+			expected: true,
+		},
+		{
+			name:  "severity below minimum",
+			route: route{minSeverity: model.SeverityWarning},
+			msg:   stateMessage("net-main", model.ApplyStatusSuccess),
+		},
+		{
+			name:     "severity at minimum",
+			route:    route{minSeverity: model.SeverityWarning},
+			msg:      model.ManualApprovalMessage{Step: "net-main"},
+			expected: true,
+		},
+		{
+			name:     "severity above minimum",
+			route:    route{minSeverity: model.SeverityWarning},
+			msg:      stateMessage("net-main", model.ApplyStatusFailure),
+			expected: true,
+		},
+		{
+			name:     "message without step",
+			route:    route{steps: []string{"net-*"}, modules: []string{"vpc"}},
+			msg:      model.ModulesMessage{},
+			expected: true,
+		},
+		{
+			name:  "message without step below minimum",
+			route: route{steps: []string{"net-*"}, minSeverity: model.SeverityError},
+			msg:   model.ModulesMessage{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if matched := test.route.matches(test.msg, steps); matched != test.expected {
+				t.Fatalf("expected match %t, got %t", test.expected, matched)
+			}
+		})
+	}
+}
@@ -1,21 +1,277 @@
 package notify
 
 import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
 	"github.com/slack-go/slack"
 )
 
-func newSlackClient(baseNotifier model.BaseNotifier, configSlack model.Slack) *BaseNotifier {
-	client := slack.New(configSlack.Token)
-	return &BaseNotifier{
+const (
+	slackColorRunning = "#439FE0"
+	slackColorSuccess = "good"
+	slackColorFailure = "danger"
+	slackColorSkipped = "#9E9E9E"
+)
+
+var (
+	_ model.Notifier        = (*slackNotifier)(nil)
+	_ observer              = (*slackNotifier)(nil)
+	_ campaignStateNotifier = (*slackNotifier)(nil)
+)
+
+// slackNotifier posts a parent message per campaign and replies to its thread.
+// Parent message is updated in place with the campaign status and step summary.
+type slackNotifier struct {
+	model.BaseNotifier
+	client   *slack.Client
+	config   model.Slack
+	lock     sync.Mutex
+	threadTs string
+	campaign slackCampaign
+}
+
+type slackCampaign struct {
+	status     string
+	command    string
+	resources  model.Resources
+	pipeline   string
+	totalSteps int
+	stepOrder  []string
+	steps      map[string]model.ApplyStatus
+}
+
+func newSlackClient(baseNotifier model.BaseNotifier, configSlack model.Slack) *slackNotifier {
+	return &slackNotifier{
 		BaseNotifier: baseNotifier,
+		client:       slack.New(configSlack.Token),
+		config:       configSlack,
+		campaign:     newSlackCampaign(),
+	}
+}
+
+func newSlackCampaign() slackCampaign {
+	return slackCampaign{steps: make(map[string]model.ApplyStatus)}
+}
+
+// Observe updates the campaign summary from every message, including messages the notifier doesn't include, and
+// updates the parent message in place. Failed updates are only logged, as the summary is refreshed by later messages.
+func (s *slackNotifier) Observe(msg model.Message) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch msg := msg.(type) {
+	case model.CampaignMessage:
+		if msg.Type() == model.MessageTypeStarted {
+			s.threadTs = ""
+			s.campaign = newSlackCampaign()
+			s.campaign.status = "running"
+		} else {
+			s.campaign.status = string(msg.Status)
+		}
+		s.campaign.command = string(msg.Command)
+		if msg.Resources != nil {
+			s.campaign.resources = msg.Resources
+		}
+	case model.StepStateMessage:
+		if _, found := s.campaign.steps[msg.StateStep.Name]; !found {
+			s.campaign.stepOrder = append(s.campaign.stepOrder, msg.StateStep.Name)
+		}
+		s.campaign.steps[msg.StateStep.Name] = msg.Status
+	case model.PipelineStateMessage:
+		s.campaign.pipeline = fmt.Sprintf("%d %s", msg.Index, msg.Status)
+	case model.ModulesMessage:
+		s.campaign.totalSteps = len(msg.Config.Steps)
+	default:
+		return
+	}
+	if s.threadTs == "" {
+		return
+	}
+	if err := s.updateParent(); err != nil {
+		slog.Warn(common.PrefixWarning(fmt.Sprintf("notifier '%s': %v", s.Name, err)))
+	}
+}
+
+func (s *slackNotifier) collectsCampaignState() {}
+
+func (s *slackNotifier) HandleCampaign(msg model.CampaignMessage) error {
+	return s.handle(msg.Type() == model.MessageTypeFailure, func(base *BaseNotifier) error {
+		return base.HandleCampaign(msg)
+	})
+}
+
+func (s *slackNotifier) HandleSchedule(msg model.ScheduleMessage) error {
+	return s.handle(false, func(base *BaseNotifier) error {
+		return base.HandleSchedule(msg)
+	})
+}
+
+func (s *slackNotifier) HandleApproval(msg model.ApprovalMessage) error {
+	return s.handle(false, func(base *BaseNotifier) error {
+		return base.HandleApproval(msg)
+	})
+}
+
+func (s *slackNotifier) HandleManualApproval(msg model.ManualApprovalMessage) error {
+	return s.handle(false, func(base *BaseNotifier) error {
+		return base.HandleManualApproval(msg)
+	})
+}
+
+func (s *slackNotifier) HandleStepState(msg model.StepStateMessage) error {
+	return s.handle(msg.Status == model.ApplyStatusFailure, func(base *BaseNotifier) error {
+		return base.HandleStepState(msg)
+	})
+}
+
+func (s *slackNotifier) HandlePipelineState(msg model.PipelineStateMessage) error {
+	return s.handle(msg.Status == model.ApplyStatusFailure, func(base *BaseNotifier) error {
+		return base.HandlePipelineState(msg)
+	})
+}
+
+func (s *slackNotifier) HandleModules(msg model.ModulesMessage) error {
+	return s.handle(false, func(base *BaseNotifier) error {
+		return base.HandleModules(msg)
+	})
+}
+
+func (s *slackNotifier) HandleSources(msg model.SourcesMessage) error {
+	return s.handle(false, func(base *BaseNotifier) error {
+		return base.HandleSources(msg)
+	})
+}
+
+// handle posts the message to the campaign thread. Parent message is posted with the first included message, so
+// campaigns without included messages stay silent.
+func (s *slackNotifier) handle(failure bool, format func(*BaseNotifier) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.threadTs == "" {
+		if err := s.postParent(); err != nil {
+			return err
+		}
+	}
+	broadcast := failure && s.config.BroadcastFailures
+	base := &BaseNotifier{
+		BaseNotifier: s.BaseNotifier,
 		MessageFunc: func(message string) error {
-			return slackMessage(client, configSlack.ChannelId, message)
+			return s.postReply(message, broadcast)
 		},
 	}
+	return format(base)
 }
 
-func slackMessage(client *slack.Client, channelId, message string) error {
-	_, _, err := client.PostMessage(channelId, slack.MsgOptionText(message, false))
+func (s *slackNotifier) postParent() error {
+	_, ts, err := s.client.PostMessage(s.config.ChannelId, s.getParentOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to post slack parent message: %w", err)
+	}
+	s.threadTs = ts
+	return nil
+}
+
+func (s *slackNotifier) updateParent() error {
+	_, _, _, err := s.client.UpdateMessage(s.config.ChannelId, s.threadTs, s.getParentOptions()...)
+	if err != nil {
+		return fmt.Errorf("failed to update slack parent message: %w", err)
+	}
+	return nil
+}
+
+func (s *slackNotifier) postReply(message string, broadcast bool) error {
+	options := []slack.MsgOption{slack.MsgOptionText(message, false), slack.MsgOptionTS(s.threadTs)}
+	if broadcast {
+		options = append(options, slack.MsgOptionBroadcast())
+	}
+	_, _, err := s.client.PostMessage(s.config.ChannelId, options...)
 	return err
 }
+
+func (s *slackNotifier) getParentOptions() []slack.MsgOption {
+	title := s.getParentTitle()
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(slack.NewTextBlockObject(slack.PlainTextType, title, false, false)),
+	}
+	if fields := s.getResourceFields(); len(fields) > 0 {
+		blocks = append(blocks, slack.NewSectionBlock(nil, fields, nil))
+	}
+	blocks = append(blocks, slack.NewSectionBlock(
+		slack.NewTextBlockObject(slack.MarkdownType, s.getStepSummary(), false, false), nil, nil))
+	if s.campaign.pipeline != "" {
+		blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject(slack.MarkdownType,
+			fmt.Sprintf("Pipeline %s", s.campaign.pipeline), false, false)))
+	}
+	attachment := slack.Attachment{
+		Color:  getSlackColor(s.campaign.status),
+		Blocks: slack.Blocks{BlockSet: blocks},
+	}
+	return []slack.MsgOption{slack.MsgOptionText(title, false), slack.MsgOptionAttachments(attachment)}
+}
+
+func (s *slackNotifier) getParentTitle() string {
+	title := strings.TrimSpace(fmt.Sprintf("Agent %s %s", s.campaign.command, s.campaign.status))
+	if s.campaign.resources != nil {
+		title += fmt.Sprintf(": prefix %s", s.campaign.resources.GetCloudPrefix())
+	}
+	if s.Context != "" {
+		title = fmt.Sprintf("%s %s", s.Context, title)
+	}
+	return title
+}
+
+func (s *slackNotifier) getResourceFields() []*slack.TextBlockObject {
+	resources := s.campaign.resources
+	if resources == nil {
+		return nil
+	}
+	accountLabel, regionLabel := "Account Id", "Region"
+	if resources.GetProviderType() == model.GCLOUD {
+		accountLabel, regionLabel = "Project Id", "Location"
+	}
+	return []*slack.TextBlockObject{
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*Provider*\n%s", resources.GetProviderType()), false, false),
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*%s*\n%s", accountLabel, resources.GetAccount()), false, false),
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("*%s*\n%s", regionLabel, resources.GetRegion()), false, false),
+	}
+}
+
+// getStepSummary counts done, failed and pending steps, pending includes running steps and steps that haven't started.
+func (s *slackNotifier) getStepSummary() string {
+	done, failed := 0, 0
+	for _, status := range s.campaign.steps {
+		switch status {
+		case model.ApplyStatusSuccess, model.ApplyStatusSkipped:
+			done++
+		case model.ApplyStatusFailure:
+			failed++
+		}
+	}
+	total := max(s.campaign.totalSteps, len(s.campaign.steps))
+	pending := total - done - failed
+	summary := fmt.Sprintf("*Steps:* :white_check_mark: %d done   :x: %d failed   :hourglass_flowing_sand: %d pending",
+		done, failed, pending)
+	for _, name := range s.campaign.stepOrder {
+		if s.campaign.steps[name] == model.ApplyStatusStarting {
+			summary += fmt.Sprintf("\nRunning step `%s`", name)
+		}
+	}
+	return summary
+}
+
+func getSlackColor(status string) string {
+	switch status {
+	case string(model.CampaignStatusSuccess):
+		return slackColorSuccess
+	case string(model.CampaignStatusFailure), string(model.CampaignStatusTerminated):
+		return slackColorFailure
+	case string(model.CampaignStatusSkipped):
+		return slackColorSkipped
+	default:
+		return slackColorRunning
+	}
+}
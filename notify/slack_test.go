@@ -0,0 +1,136 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/slack-go/slack"
+)
+
+type slackServer struct {
+	lock  sync.Mutex
+	calls []string
+}
+
+func (s *slackServer) getCalls() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	calls := s.calls
+	s.calls = nil
+	return calls
+}
+
+func newSlackTestNotifier(t *testing.T, messageTypes ...model.MessageType) (*slackNotifier, *slackServer) {
+	server := &slackServer{}
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.lock.Lock()
+		server.calls = append(server.calls, strings.TrimPrefix(r.URL.Path, "/"))
+		server.lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok": true, "channel": "C1", "ts": "1.0"}`))
+	}))
+	t.Cleanup(httpServer.Close)
+	types := model.NewSet[model.MessageType]()
+	for _, messageType := range messageTypes {
+		types.Add(messageType)
+	}
+	notifier := newSlackClient(model.BaseNotifier{Name: "slack", MessageTypes: types},
+		model.Slack{ChannelId: "C1"})
+	notifier.client = slack.New("token", slack.OptionAPIURL(httpServer.URL+"/"))
+	return notifier, server
+}
+
+func TestSlackSummaryOfExcludedMessages(t *testing.T) {
+	notifier, server := newSlackTestNotifier(t, model.MessageTypeFailure)
+	manager := &NotificationManager{
+		ctx:         context.Background(),
+		notifiers:   []model.Notifier{notifier},
+		routes:      map[string]route{},
+		stepModules: newStepModules(),
+	}
+	resources := testResources{CloudResources: model.CloudResources{ProviderType: model.AWS, CloudPrefix: "dev"}}
+	config := model.Config{Steps: []model.Step{{Name: "net"}, {Name: "apps"}}}
+	tests := []struct {
+		name  string
+		msg   model.Message
+		calls []string
+	}{
+		{
+			name: "started",
+			msg: model.CampaignMessage{Ctx: context.Background(), Status: model.CampaignStatusStarted,
+				Resources: resources, Command: common.RunCommand},
+		},
+		{
+			name: "modules",
+			msg:  model.ModulesMessage{Command: common.RunCommand, Config: config},
+		},
+		{
+			name: "step success",
+			msg:  model.StepStateMessage{Status: model.ApplyStatusSuccess, StateStep: model.StateStep{Name: "net"}},
+		},
+		{
+			name: "campaign failure",
+			msg: model.CampaignMessage{Ctx: context.Background(), Status: model.CampaignStatusFailure,
+				Resources: resources, Command: common.RunCommand},
+			calls: []string{"chat.postMessage", "chat.postMessage"},
+		},
+		{
+			name:  "step failure after thread",
+			msg:   model.StepStateMessage{Status: model.ApplyStatusFailure, StateStep: model.StateStep{Name: "apps"}},
+			calls: []string{"chat.update"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager.Notify(test.msg)
+			calls := server.getCalls()
+			if strings.Join(calls, ",") != strings.Join(test.calls, ",") {
+				t.Fatalf("expected calls %v, got %v", test.calls, calls)
+			}
+		})
+	}
+	if notifier.campaign.totalSteps != 2 || notifier.campaign.steps["net"] != model.ApplyStatusSuccess ||
+		notifier.campaign.steps["apps"] != model.ApplyStatusFailure {
+		t.Fatalf("unexpected campaign summary: %+v", notifier.campaign)
+	}
+}
+
+func TestSlackFlushDropsCampaignState(t *testing.T) {
+	notifier, server := newSlackTestNotifier(t, model.MessageTypeProgress, model.MessageTypeApprovals)
+	bucket := newMemoryBucket()
+	manager := &NotificationManager{
+		ctx:         context.Background(),
+		notifiers:   []model.Notifier{notifier},
+		outbox:      newOutbox(bucket),
+		routes:      map[string]route{},
+		stepModules: newStepModules(),
+	}
+	messages := []model.Message{
+		model.StepStateMessage{Status: model.ApplyStatusFailure, StateStep: model.StateStep{Name: "net"}},
+		model.ApprovalMessage{PipelineName: "dev-net", Step: "net", ApprovedBy: "ops"},
+	}
+	for _, msg := range messages {
+		if _, err := manager.outbox.add(context.Background(), notifier.GetName(), msg, 3, errors.New("timeout")); err != nil {
+			t.Fatalf("failed to store message: %v", err)
+		}
+	}
+	if err := manager.Flush(context.Background()); err != nil {
+		t.Fatalf("failed to flush outbox: %v", err)
+	}
+	if calls := server.getCalls(); len(calls) != 2 {
+		t.Fatalf("expected parent and approval reply, got %v", calls)
+	}
+	if len(bucket.files) != 0 {
+		t.Fatalf("expected empty outbox, got %v", bucket.files)
+	}
+	if len(notifier.campaign.steps) != 0 {
+		t.Fatalf("expected stored step state to be dropped, got %v", notifier.campaign.steps)
+	}
+}
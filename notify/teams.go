@@ -1,21 +1,386 @@
 package notify
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
 	goteamsnotify "github.com/atc0005/go-teams-notify/v2"
 	"github.com/atc0005/go-teams-notify/v2/adaptivecard"
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
-	"strings"
+	"github.com/hashicorp/go-version"
 )
 
-func newTeamsClient(baseNotifier model.BaseNotifier, configTeams model.Teams) *BaseNotifier {
+const (
+	teamsErrorId = "error-details"
+	// teamsCardVersion is the lowest Adaptive Card version that supports tables.
+	teamsCardVersion = "1.5"
+)
+
+// teamsCardRejections are parts of webhook 400 responses that mean the webhook doesn't support the cards.
+var teamsCardRejections = []string{"adaptivecard", "adaptive card", "attachment", "card version", "schema"}
+
+var _ model.Notifier = (*teamsNotifier)(nil)
+
+// teamsNotifier sends Adaptive Cards for the main message kinds. When a card can't be sent, the message is re-sent as
+// a plain text card. Plain text is used for the rest of the execution only when the webhook doesn't support the cards.
+type teamsNotifier struct {
+	BaseNotifier
+	client     *goteamsnotify.TeamsClient
+	webhookUrl string
+	plainText  atomic.Bool
+}
+
+func newTeamsClient(baseNotifier model.BaseNotifier, configTeams model.Teams) *teamsNotifier {
 	client := goteamsnotify.NewTeamsClient()
-	return &BaseNotifier{
-		BaseNotifier: baseNotifier,
-		MessageFunc: func(message string) error {
-			return teamsMessage(client, configTeams.WebhookUrl, message)
+	return &teamsNotifier{
+		BaseNotifier: BaseNotifier{
+			BaseNotifier: baseNotifier,
+			MessageFunc: func(message string) error {
+				return teamsMessage(client, configTeams.WebhookUrl, message)
+			},
 		},
+		client:     client,
+		webhookUrl: configTeams.WebhookUrl,
+	}
+}
+
+func (t *teamsNotifier) HandleCampaign(msg model.CampaignMessage) error {
+	return t.sendCard(func() (adaptivecard.Card, error) {
+		return t.getCampaignCard(msg)
+	}, func() error {
+		return t.BaseNotifier.HandleCampaign(msg)
+	})
+}
+
+func (t *teamsNotifier) HandleManualApproval(msg model.ManualApprovalMessage) error {
+	return t.sendCard(func() (adaptivecard.Card, error) {
+		return t.getManualApprovalCard(msg)
+	}, func() error {
+		return t.BaseNotifier.HandleManualApproval(msg)
+	})
+}
+
+func (t *teamsNotifier) HandleStepState(msg model.StepStateMessage) error {
+	return t.sendCard(func() (adaptivecard.Card, error) {
+		return t.getStepStateCard(msg)
+	}, func() error {
+		return t.BaseNotifier.HandleStepState(msg)
+	})
+}
+
+func (t *teamsNotifier) HandlePipelineState(msg model.PipelineStateMessage) error {
+	return t.sendCard(func() (adaptivecard.Card, error) {
+		return t.getPipelineStateCard(msg)
+	}, func() error {
+		return t.BaseNotifier.HandlePipelineState(msg)
+	})
+}
+
+func (t *teamsNotifier) HandleModules(msg model.ModulesMessage) error {
+	return t.sendCard(func() (adaptivecard.Card, error) {
+		return t.getModulesCard(msg)
+	}, func() error {
+		return t.BaseNotifier.HandleModules(msg)
+	})
+}
+
+func (t *teamsNotifier) HandleSources(msg model.SourcesMessage) error {
+	return t.sendCard(func() (adaptivecard.Card, error) {
+		return t.getSourcesCard(msg)
+	}, func() error {
+		return t.BaseNotifier.HandleSources(msg)
+	})
+}
+
+// sendCard sends the card and falls back to the plain text message if the card can't be built or is rejected.
+func (t *teamsNotifier) sendCard(build func() (adaptivecard.Card, error), fallback func() error) error {
+	if t.plainText.Load() {
+		return fallback()
+	}
+	card, err := build()
+	if err == nil {
+		err = t.send(card)
+	}
+	if err == nil {
+		return nil
+	}
+	if fallbackErr := fallback(); fallbackErr != nil {
+		return errors.Join(err, fallbackErr)
+	}
+	if isCardUnsupported(err) {
+		slog.Warn(common.PrefixWarning(fmt.Sprintf("teams notifier %s doesn't support adaptive cards, using plain text: %v",
+			t.Name, err)))
+		t.plainText.Store(true)
+	} else {
+		slog.Warn(common.PrefixWarning(fmt.Sprintf("teams notifier %s failed to send adaptive card, sent plain text: %v",
+			t.Name, err)))
+	}
+	return nil
+}
+
+func isCardUnsupported(err error) bool {
+	message := strings.ToLower(err.Error())
+	if !strings.Contains(message, "400 bad request") {
+		return false
+	}
+	for _, rejection := range teamsCardRejections {
+		if strings.Contains(message, rejection) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *teamsNotifier) send(card adaptivecard.Card) error {
+	msg, err := adaptivecard.NewMessageFromCard(card)
+	if err != nil {
+		return err
+	}
+	return t.client.Send(t.webhookUrl, msg)
+}
+
+func (t *teamsNotifier) getCampaignCard(msg model.CampaignMessage) (adaptivecard.Card, error) {
+	color := adaptivecard.ColorGood
+	switch msg.Type() {
+	case model.MessageTypeStarted:
+		color = adaptivecard.ColorDefault
+	case model.MessageTypeFailure:
+		color = adaptivecard.ColorAttention
+	}
+	card := t.newCard(fmt.Sprintf("Agent %s %s", msg.Command, msg.Status), color)
+	if msg.Resources != nil {
+		accountLabel, regionLabel := "Account Id", "Region"
+		if msg.Resources.GetProviderType() == model.GCLOUD {
+			accountLabel, regionLabel = "Project Id", "Location"
+		}
+		err := addFacts(&card,
+			adaptivecard.Fact{Title: "Prefix", Value: msg.Resources.GetCloudPrefix()},
+			adaptivecard.Fact{Title: "Provider", Value: string(msg.Resources.GetProviderType())},
+			adaptivecard.Fact{Title: accountLabel, Value: msg.Resources.GetAccount()},
+			adaptivecard.Fact{Title: regionLabel, Value: msg.Resources.GetRegion()})
+		if err != nil {
+			return card, err
+		}
+	}
+	if msg.Err != nil {
+		card.Body = append(card.Body, adaptivecard.Element{
+			Type:  adaptivecard.TypeElementTextBlock,
+			Text:  msg.Err.Error(),
+			Color: adaptivecard.ColorAttention,
+			Wrap:  true,
+		})
+	}
+	return card, nil
+}
+
+func (t *teamsNotifier) getManualApprovalCard(msg model.ManualApprovalMessage) (adaptivecard.Card, error) {
+	card := t.newCard(fmt.Sprintf("Waiting for manual approval of pipeline %s", msg.PipelineName),
+		adaptivecard.ColorWarning)
+	facts := []adaptivecard.Fact{{Title: "Step", Value: msg.Step}}
+	if msg.Changes.Imported != 0 {
+		facts = append(facts, adaptivecard.Fact{Title: "Import", Value: fmt.Sprint(msg.Changes.Imported)})
+	}
+	facts = append(facts,
+		adaptivecard.Fact{Title: "Add", Value: fmt.Sprint(msg.Changes.Added)},
+		adaptivecard.Fact{Title: "Change", Value: fmt.Sprint(msg.Changes.Changed)},
+		adaptivecard.Fact{Title: "Destroy", Value: fmt.Sprint(msg.Changes.Destroyed)})
+	if err := addFacts(&card, facts...); err != nil {
+		return card, err
+	}
+	if len(msg.Changes.Modules) > 0 {
+		rows := [][]any{{"Module", "Import", "Add", "Change", "Destroy"}}
+		for _, module := range msg.Changes.Modules {
+			rows = append(rows, []any{module.Name, module.Imported, module.Added, module.Changed, module.Destroyed})
+		}
+		if err := addTable(&card, rows); err != nil {
+			return card, err
+		}
+	}
+	if msg.Link != "" {
+		action, err := adaptivecard.NewActionOpenURL(msg.Link, "Open pipeline")
+		if err != nil {
+			return card, err
+		}
+		if err = card.AddAction(false, action); err != nil {
+			return card, err
+		}
+	}
+	return card, nil
+}
+
+func (t *teamsNotifier) getStepStateCard(msg model.StepStateMessage) (adaptivecard.Card, error) {
+	card := t.newCard(fmt.Sprintf("Step %s %s", msg.StateStep.Name, msg.Status), getTeamsColor(msg.Status))
+	if len(msg.StateStep.Modules) > 0 {
+		rows := [][]any{{"Module", "Version", "Applied version"}}
+		for _, module := range msg.StateStep.Modules {
+			applied := ""
+			if module.AppliedVersion != nil {
+				applied = *module.AppliedVersion
+			}
+			rows = append(rows, []any{module.Name, module.Version, applied})
+		}
+		if err := addTable(&card, rows); err != nil {
+			return card, err
+		}
+	}
+	if len(msg.SlowResources) > 0 {
+		rows := [][]any{{"Slowest resource", "Action", "Duration"}}
+		for _, resource := range msg.SlowResources {
+			duration := resource.Duration.Round(time.Second).String()
+			if !resource.Completed {
+				duration += " (not completed)"
+			}
+			rows = append(rows, []any{resource.Address, resource.Action, duration})
+		}
+		if err := addTable(&card, rows); err != nil {
+			return card, err
+		}
+	}
+	return card, addErrorDetails(&card, msg.Err)
+}
+
+func (t *teamsNotifier) getPipelineStateCard(msg model.PipelineStateMessage) (adaptivecard.Card, error) {
+	card := t.newCard(fmt.Sprintf("Pipeline %s", msg.Status), getTeamsColor(msg.Status))
+	if len(msg.SourceVersions) > 0 {
+		rows := [][]any{{"Source", "Version", "Forced version"}}
+		for _, source := range msg.SourceVersions {
+			rows = append(rows, []any{source.URL, versionString(source.Version), source.ForcedVersion})
+		}
+		if err := addTable(&card, rows); err != nil {
+			return card, err
+		}
+	}
+	return card, addErrorDetails(&card, msg.Err)
+}
+
+func (t *teamsNotifier) getModulesCard(msg model.ModulesMessage) (adaptivecard.Card, error) {
+	card := t.newCard(fmt.Sprintf("Steps for account %s in region %s", msg.Resources.GetAccount(),
+		msg.Resources.GetRegion()), adaptivecard.ColorDefault)
+	rows := [][]any{{"Step", "Module", "Source", "Skipped versions"}}
+	for _, step := range msg.Config.Steps {
+		for _, module := range step.Modules {
+			rows = append(rows, []any{step.Name, module.Name, module.Source, strings.Join(module.SkipVersions, ", ")})
+		}
+	}
+	if len(rows) == 1 {
+		return card, nil
+	}
+	return card, addTable(&card, rows)
+}
+
+func (t *teamsNotifier) getSourcesCard(msg model.SourcesMessage) (adaptivecard.Card, error) {
+	card := t.newCard("Configured sources", adaptivecard.ColorDefault)
+	sources := make([]*model.Source, 0, len(msg.Sources))
+	for _, source := range msg.Sources {
+		sources = append(sources, source)
+	}
+	slices.SortFunc(sources, func(a, b *model.Source) int {
+		return cmp.Compare(a.URL, b.URL)
+	})
+	rows := [][]any{{"Source", "Version", "Forced version", "Modules"}}
+	for _, source := range sources {
+		modules := source.Modules.ToSlice()
+		slices.Sort(modules)
+		rows = append(rows, []any{source.URL, versionString(source.Version), source.ForcedVersion,
+			strings.Join(modules, ", ")})
+	}
+	if len(rows) == 1 {
+		return card, nil
+	}
+	return card, addTable(&card, rows)
+}
+
+func (t *teamsNotifier) newCard(title string, color string) adaptivecard.Card {
+	if t.Context != "" {
+		title = fmt.Sprintf("%s %s", t.Context, title)
+	}
+	card := adaptivecard.NewCard()
+	card.Version = teamsCardVersion
+	card.Body = append(card.Body, adaptivecard.Element{
+		Type:   adaptivecard.TypeElementTextBlock,
+		Text:   title,
+		Size:   adaptivecard.SizeMedium,
+		Weight: adaptivecard.WeightBolder,
+		Color:  color,
+		Wrap:   true,
+	})
+	return card
+}
+
+func addFacts(card *adaptivecard.Card, facts ...adaptivecard.Fact) error {
+	factSet := adaptivecard.NewFactSet()
+	if err := factSet.AddFact(facts...); err != nil {
+		return err
+	}
+	return card.AddFactSet(false, factSet)
+}
+
+// addTable adds a table to the card, the first row is used as headers.
+func addTable(card *adaptivecard.Card, rows [][]any) error {
+	cells := make([][]adaptivecard.TableCell, 0, len(rows))
+	for _, row := range rows {
+		for i, value := range row {
+			if value == "" {
+				row[i] = "-"
+			}
+		}
+		rowCells, err := adaptivecard.NewTableCellsWithTextBlock(row)
+		if err != nil {
+			return err
+		}
+		cells = append(cells, rowCells)
+	}
+	table, err := adaptivecard.NewTableFromTableCells(cells, 0, true, true)
+	if err != nil {
+		return err
+	}
+	return card.AddElement(false, table)
+}
+
+// addErrorDetails adds the error in a hidden container with an action to toggle its visibility.
+func addErrorDetails(card *adaptivecard.Card, err error) error {
+	if err == nil {
+		return nil
+	}
+	container := adaptivecard.NewHiddenContainer()
+	container.ID = teamsErrorId
+	container.Style = adaptivecard.ContainerStyleAttention
+	if addErr := container.AddElement(false, adaptivecard.NewTextBlock(err.Error(), true)); addErr != nil {
+		return addErr
+	}
+	if addErr := card.AddContainer(false, container); addErr != nil {
+		return addErr
+	}
+	action := adaptivecard.NewActionToggleVisibility("Show error")
+	if addErr := action.AddTargetElementID(nil, teamsErrorId); addErr != nil {
+		return addErr
+	}
+	return card.AddAction(false, action)
+}
+
+func getTeamsColor(status model.ApplyStatus) string {
+	switch status {
+	case model.ApplyStatusSuccess:
+		return adaptivecard.ColorGood
+	case model.ApplyStatusFailure:
+		return adaptivecard.ColorAttention
+	default:
+		return adaptivecard.ColorDefault
+	}
+}
+
+func versionString(version *version.Version) string {
+	if version == nil {
+		return ""
 	}
+	return version.Original()
 }
 
 func teamsMessage(client *goteamsnotify.TeamsClient, webhookUrl, message string) error {
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+type teamsResponse struct {
+	status int
+	body   string
+}
+
+// teamsServer answers table cards with the queued responses and accepts plain text cards.
+type teamsServer struct {
+	lock      sync.Mutex
+	responses []teamsResponse
+	requests  []string
+}
+
+func (s *teamsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if !strings.Contains(string(body), `"type":"Table"`) {
+		s.requests = append(s.requests, "text")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	s.requests = append(s.requests, "card")
+	response := teamsResponse{status: http.StatusAccepted}
+	if len(s.responses) > 0 {
+		response, s.responses = s.responses[0], s.responses[1:]
+	}
+	w.WriteHeader(response.status)
+	_, _ = w.Write([]byte(response.body))
+}
+
+func newTestTeamsNotifier(t *testing.T, server *teamsServer) *teamsNotifier {
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	notifier := newTeamsClient(model.BaseNotifier{Name: "teams"}, model.Teams{WebhookUrl: httpServer.URL})
+	notifier.client.SkipWebhookURLValidationOnSend(true)
+	return notifier
+}
+
+func TestTeamsCardFallback(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []teamsResponse
+		requests  []string
+		plainText bool
+	}{
+		{
+			name:     "cards accepted",
+			requests: []string{"card", "card"},
+		},
+		{
+			name:      "server error falls back for one message",
+			responses: []teamsResponse{{status: http.StatusInternalServerError, body: "internal error"}},
+			requests:  []string{"card", "text", "card"},
+		},
+		{
+			name:      "unrelated bad request falls back for one message",
+			responses: []teamsResponse{{status: http.StatusBadRequest, body: "Text is too long"}},
+			requests:  []string{"card", "text", "card"},
+		},
+		{
+			name: "unsupported cards switch to plain text",
+			responses: []teamsResponse{{status: http.StatusBadRequest,
+				body: "AdaptiveCard version 1.5 is not supported"}},
+			requests:  []string{"card", "text", "text"},
+			plainText: true,
+		},
+	}
+	msg := model.PipelineStateMessage{Status: model.ApplyStatusSuccess,
+		SourceVersions: []model.SourceVersion{{URL: "https://github.com/entigolabs/entigo-infralib"}}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &teamsServer{responses: tt.responses}
+			notifier := newTestTeamsNotifier(t, server)
+			for i := 0; i < 2; i++ {
+				if err := notifier.HandlePipelineState(msg); err != nil {
+					t.Fatalf("failed to send message %d: %v", i, err)
+				}
+			}
+			if strings.Join(server.requests, ",") != strings.Join(tt.requests, ",") {
+				t.Fatalf("expected requests %v, got %v", tt.requests, server.requests)
+			}
+			if notifier.plainText.Load() != tt.plainText {
+				t.Fatalf("expected plain text %t, got %t", tt.plainText, notifier.plainText.Load())
+			}
+		})
+	}
+}
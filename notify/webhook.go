@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+const (
+	webhookTimeout         = 30 * time.Second
+	webhookSignatureHeader = "X-Signature-256"
+)
+
+var _ model.Notifier = (*webhookNotifier)(nil)
+
+type webhookNotifier struct {
+	BaseNotifier
+	ctx       context.Context
+	client    *http.Client
+	config    model.Webhook
+	templates map[model.MessageType]*template.Template
+}
+
+type webhookText struct {
+	Text string `json:"text"`
+}
+
+func newWebhookNotifier(ctx context.Context, baseNotifier model.BaseNotifier, configWebhook model.Webhook) (*webhookNotifier, error) {
+	configWebhook.Method = strings.ToUpper(configWebhook.Method)
+	if configWebhook.Method == "" {
+		configWebhook.Method = http.MethodPost
+	}
+	notifier := &webhookNotifier{
+		ctx:       ctx,
+		client:    &http.Client{Timeout: webhookTimeout},
+		config:    configWebhook,
+		templates: make(map[model.MessageType]*template.Template),
+	}
+	notifier.BaseNotifier = BaseNotifier{
+		BaseNotifier: baseNotifier,
+		MessageFunc: func(message string) error {
+			return notifier.sendText(ctx, message)
+		},
+	}
+	funcs := template.FuncMap{
+		"json": func(value any) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+		"context": func() string {
+			return baseNotifier.Context
+		},
+	}
+	for messageType, text := range configWebhook.Templates {
+		tmpl, err := template.New(string(messageType)).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse webhook template %s: %w", messageType, err)
+		}
+		notifier.templates[messageType] = tmpl
+	}
+	return notifier, nil
+}
+
+func (w *webhookNotifier) HandleCampaign(msg model.CampaignMessage) error {
+	ctx := msg.Ctx
+	if ctx == nil {
+		ctx = w.ctx
+	}
+	// Campaign messages carry their own context which stays valid while the agent is shutting down
+	base := BaseNotifier{
+		BaseNotifier: w.BaseNotifier.BaseNotifier,
+		MessageFunc: func(message string) error {
+			return w.sendText(ctx, message)
+		},
+	}
+	return w.handle(ctx, msg, func() error { return base.HandleCampaign(msg) })
+}
+
+func (w *webhookNotifier) HandleSchedule(msg model.ScheduleMessage) error {
+	return w.handle(w.ctx, msg, func() error { return w.BaseNotifier.HandleSchedule(msg) })
+}
+
+func (w *webhookNotifier) HandleApproval(msg model.ApprovalMessage) error {
+	return w.handle(w.ctx, msg, func() error { return w.BaseNotifier.HandleApproval(msg) })
+}
+
+func (w *webhookNotifier) HandleManualApproval(msg model.ManualApprovalMessage) error {
+	return w.handle(w.ctx, msg, func() error { return w.BaseNotifier.HandleManualApproval(msg) })
+}
+
+func (w *webhookNotifier) HandleStepState(msg model.StepStateMessage) error {
+	return w.handle(w.ctx, msg, func() error { return w.BaseNotifier.HandleStepState(msg) })
+}
+
+func (w *webhookNotifier) HandlePipelineState(msg model.PipelineStateMessage) error {
+	return w.handle(w.ctx, msg, func() error { return w.BaseNotifier.HandlePipelineState(msg) })
+}
+
+func (w *webhookNotifier) HandleModules(msg model.ModulesMessage) error {
+	return w.handle(w.ctx, msg, func() error { return w.BaseNotifier.HandleModules(msg) })
+}
+
+func (w *webhookNotifier) HandleSources(msg model.SourcesMessage) error {
+	return w.handle(w.ctx, msg, func() error { return w.BaseNotifier.HandleSources(msg) })
+}
+
+// handle renders the template configured for the message type, messages without a template use the default text body.
+func (w *webhookNotifier) handle(ctx context.Context, msg model.Message, fallback func() error) error {
+	tmpl, found := w.templates[msg.Type()]
+	if !found {
+		return fallback()
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, msg); err != nil {
+		return fmt.Errorf("failed to render webhook template %s: %w", msg.Type(), err)
+	}
+	return w.send(ctx, body.Bytes())
+}
+
+func (w *webhookNotifier) sendText(ctx context.Context, message string) error {
+	body, err := json.Marshal(webhookText{Text: message})
+	if err != nil {
+		return err
+	}
+	return w.send(ctx, body)
+}
+
+func (w *webhookNotifier) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, w.config.Method, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.config.Headers {
+		req.Header.Set(key, value)
+	}
+	if w.config.Secret != "" {
+		req.Header.Set(webhookSignatureHeader, "sha256="+signBody(w.config.Secret, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+func signBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
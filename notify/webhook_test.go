@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+type webhookRequest struct {
+	method    string
+	header    http.Header
+	body      string
+	signature string
+}
+
+func newWebhookServer(t *testing.T, status int) (*httptest.Server, *[]webhookRequest) {
+	var requests []webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, webhookRequest{method: r.Method, header: r.Header, body: string(body),
+			signature: r.Header.Get(webhookSignatureHeader)})
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("webhook response"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestWebhookSend(t *testing.T) {
+	tests := []struct {
+		name      string
+		webhook   model.Webhook
+		msg       model.Message
+		method    string
+		body      string
+		signature bool
+	}{
+		{
+			name: "template",
+			webhook: model.Webhook{
+				Method:  "put",
+				Headers: map[string]string{"X-Team": "infra"},
+				Templates: map[model.MessageType]string{model.MessageTypeProgress: `{"context": "{{ context }}", ` +
+					`"step": {{ json .StateStep.Name }}, "status": "{{ .Status }}"}`},
+			},
+			msg:    model.StepStateMessage{Status: model.ApplyStatusSuccess, StateStep: model.StateStep{Name: "net"}},
+			method: http.MethodPut,
+			body:   `{"context": "dev", "step": "net", "status": "success"}`,
+		},
+		{
+			name:      "default text",
+			webhook:   model.Webhook{Secret: "signing-secret"},
+			msg:       model.ApprovalMessage{PipelineName: "dev-net"},
+			method:    http.MethodPost,
+			body:      `{"text":"dev Pipeline dev-net was approved"}`,
+			signature: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, requests := newWebhookServer(t, http.StatusOK)
+			test.webhook.URL = server.URL
+			notifier, err := newWebhookNotifier(t.Context(), model.BaseNotifier{Name: "webhook", Context: "dev"},
+				test.webhook)
+			if err != nil {
+				t.Fatalf("failed to create notifier: %v", err)
+			}
+			if err = test.msg.Dispatch(notifier); err != nil {
+				t.Fatalf("failed to send message: %v", err)
+			}
+			if len(*requests) != 1 {
+				t.Fatalf("expected 1 request, got %d", len(*requests))
+			}
+			request := (*requests)[0]
+			if request.method != test.method || !strings.Contains(request.body, test.body) {
+				t.Fatalf("expected %s request with body %s, got %s %s", test.method, test.body, request.method,
+					request.body)
+			}
+			for key, value := range test.webhook.Headers {
+				if request.header.Get(key) != value {
+					t.Fatalf("expected header %s to be %s, got %s", key, value, request.header.Get(key))
+				}
+			}
+			expectedSignature := ""
+			if test.signature {
+				expectedSignature = "sha256=" + signBody(test.webhook.Secret, []byte(request.body))
+			}
+			if request.signature != expectedSignature {
+				t.Fatalf("expected signature %q, got %q", expectedSignature, request.signature)
+			}
+		})
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	server, _ := newWebhookServer(t, http.StatusBadRequest)
+	_, err := newWebhookNotifier(t.Context(), model.BaseNotifier{}, model.Webhook{URL: server.URL,
+		Templates: map[model.MessageType]string{model.MessageTypeProgress: "{{ .Status"}})
+	if err == nil || !strings.Contains(err.Error(), "failed to parse webhook template progress") {
+		t.Fatalf("expected template parse error, got %v", err)
+	}
+	notifier, err := newWebhookNotifier(t.Context(), model.BaseNotifier{}, model.Webhook{URL: server.URL,
+		Templates: map[model.MessageType]string{model.MessageTypeProgress: "{{ .Missing }}"}})
+	if err != nil {
+		t.Fatalf("failed to create notifier: %v", err)
+	}
+	err = model.StepStateMessage{}.Dispatch(notifier)
+	if err == nil || !strings.Contains(err.Error(), "failed to render webhook template progress") {
+		t.Fatalf("expected template render error, got %v", err)
+	}
+	err = model.ApprovalMessage{PipelineName: "dev-net"}.Dispatch(notifier)
+	if err == nil || err.Error() != "webhook returned status 400: webhook response" {
+		t.Fatalf("expected status error, got %v", err)
+	}
+}
@@ -0,0 +1,313 @@
+package redact
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	Mask = "***"
+
+	// Shorter values would mask ordinary words and numbers in the output.
+	minValueLength = 4
+	hashLength     = 16
+)
+
+var defaultFilter = &filter{values: make(map[string]struct{}), ignored: make(map[string]struct{}),
+	hashes: make(map[int]map[valueHash]struct{})}
+
+type valueHash [hashLength]byte
+
+type filter struct {
+	lock        sync.RWMutex
+	values      map[string]struct{}
+	ignored     map[string]struct{}
+	replacer    *strings.Replacer
+	patterns    []*regexp.Regexp
+	salt        []byte
+	hashes      map[int]map[valueHash]struct{}
+	hashLengths []int
+}
+
+// Hashes are salted hashes of redacted values grouped by the value length. Processes that redact the same output
+// mask the values by their hashes without receiving the values.
+type Hashes struct {
+	Salt   []byte           `json:"salt"`
+	Values map[int][]string `json:"values"`
+}
+
+// AddValues registers secret values that are masked in all redacted output. Multi-line values are registered
+// line by line as the output is redacted per line.
+func AddValues(values ...string) {
+	defaultFilter.addValues(values...)
+}
+
+// AddPatterns registers regex patterns whose matches are masked in all redacted output.
+func AddPatterns(patterns ...string) error {
+	return defaultFilter.addPatterns(patterns...)
+}
+
+// Ignore excludes values that are stored as secrets but aren't sensitive, like source URLs, from masking.
+func Ignore(values ...string) {
+	defaultFilter.ignore(values...)
+}
+
+// Values returns the registered values, sorted.
+func Values() []string {
+	defaultFilter.lock.RLock()
+	defer defaultFilter.lock.RUnlock()
+	return slices.Sorted(maps.Keys(defaultFilter.values))
+}
+
+// GetHashes returns the salted hashes of the registered values, sorted, for passing them to processes that redact
+// the same output.
+func GetHashes(salt []byte) Hashes {
+	hashes := Hashes{Salt: salt, Values: make(map[int][]string)}
+	for _, value := range Values() {
+		hash := hashValue(nil, salt, value)
+		hashes.Values[len(value)] = append(hashes.Values[len(value)], base64.RawStdEncoding.EncodeToString(hash[:]))
+	}
+	for length := range hashes.Values {
+		slices.Sort(hashes.Values[length])
+	}
+	return hashes
+}
+
+// AddHashes registers hashed values that are masked in all redacted output. Hashes with a different salt replace
+// the previously added hashes.
+func AddHashes(hashes Hashes) error {
+	return defaultFilter.addHashes(hashes)
+}
+
+// Line returns the line with registered values and pattern matches masked.
+func Line(line string) string {
+	return defaultFilter.line(line)
+}
+
+// Contains reports whether the value includes registered values or pattern matches.
+func Contains(value string) bool {
+	for _, line := range strings.Split(value, "\n") {
+		if defaultFilter.line(line) != line {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *filter) addValues(values ...string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	added := false
+	for _, value := range values {
+		for _, line := range strings.Split(value, "\n") {
+			line = strings.TrimSpace(line)
+			if len(line) < minValueLength {
+				continue
+			}
+			if _, found := f.values[line]; found {
+				continue
+			}
+			if _, found := f.ignored[line]; found {
+				continue
+			}
+			f.values[line] = struct{}{}
+			added = true
+		}
+	}
+	if added {
+		f.replacer = f.newReplacer()
+	}
+}
+
+func (f *filter) ignore(values ...string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	removed := false
+	for _, value := range values {
+		f.ignored[value] = struct{}{}
+		if _, found := f.values[value]; found {
+			delete(f.values, value)
+			removed = true
+		}
+	}
+	if removed {
+		f.replacer = f.newReplacer()
+	}
+}
+
+// newReplacer orders the values from longest to shortest, so a value containing another one is masked whole.
+func (f *filter) newReplacer() *strings.Replacer {
+	values := make([]string, 0, len(f.values))
+	for value := range f.values {
+		values = append(values, value)
+	}
+	slices.SortFunc(values, func(a, b string) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), strings.Compare(a, b))
+	})
+	pairs := make([]string, 0, len(values)*2)
+	for _, value := range values {
+		pairs = append(pairs, value, Mask)
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+func (f *filter) addHashes(hashes Hashes) error {
+	decoded := make(map[int]map[valueHash]struct{}, len(hashes.Values))
+	for length, values := range hashes.Values {
+		if length < minValueLength {
+			continue
+		}
+		decoded[length] = make(map[valueHash]struct{}, len(values))
+		for _, value := range values {
+			hash, err := base64.RawStdEncoding.DecodeString(value)
+			if err != nil || len(hash) != hashLength {
+				return fmt.Errorf("invalid redact hash %s", value)
+			}
+			decoded[length][valueHash(hash)] = struct{}{}
+		}
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if !bytes.Equal(f.salt, hashes.Salt) {
+		f.salt = hashes.Salt
+		f.hashes = make(map[int]map[valueHash]struct{})
+	}
+	for length, values := range decoded {
+		if f.hashes[length] == nil {
+			f.hashes[length] = values
+			continue
+		}
+		maps.Copy(f.hashes[length], values)
+	}
+	f.hashLengths = slices.SortedFunc(maps.Keys(f.hashes), func(a, b int) int {
+		return cmp.Compare(b, a)
+	})
+	return nil
+}
+
+// hashValue returns the truncated sha256 hash of the salted value, buffer is reused between calls.
+func hashValue(buffer, salt []byte, value string) valueHash {
+	buffer = append(append(buffer[:0], salt...), value...)
+	sum := sha256.Sum256(buffer)
+	return valueHash(sum[:hashLength])
+}
+
+// maskHashes masks the substrings whose hashes are registered, from the longest to the shortest length, so a value
+// containing another one is masked whole.
+func (f *filter) maskHashes(line string) string {
+	for _, length := range f.hashLengths {
+		if len(line) < length {
+			continue
+		}
+		hashes := f.hashes[length]
+		var masked strings.Builder
+		buffer := make([]byte, 0, len(f.salt)+length)
+		start := 0
+		for i := 0; i+length <= len(line); {
+			if _, found := hashes[hashValue(buffer, f.salt, line[i:i+length])]; !found {
+				i++
+				continue
+			}
+			masked.WriteString(line[start:i])
+			masked.WriteString(Mask)
+			i += length
+			start = i
+		}
+		if start > 0 {
+			masked.WriteString(line[start:])
+			line = masked.String()
+		}
+	}
+	return line
+}
+
+func (f *filter) addPatterns(patterns ...string) error {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid redact pattern %s: %v", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.patterns = append(f.patterns, compiled...)
+	return nil
+}
+
+func (f *filter) line(line string) string {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	if f.replacer != nil {
+		line = f.replacer.Replace(line)
+	}
+	line = f.maskHashes(line)
+	for _, pattern := range f.patterns {
+		line = pattern.ReplaceAllString(line, Mask)
+	}
+	return line
+}
+
+// Writer redacts complete lines before writing them to the underlying writer. An unterminated last line is
+// held back until Flush or Close.
+type Writer struct {
+	lock   sync.Mutex
+	writer io.Writer
+	buffer []byte
+}
+
+func NewWriter(writer io.Writer) *Writer {
+	return &Writer{writer: writer}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.buffer = append(w.buffer, p...)
+	end := bytes.LastIndexByte(w.buffer, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	lines := strings.Split(string(w.buffer[:end]), "\n")
+	for i, line := range lines {
+		lines[i] = defaultFilter.line(line)
+	}
+	w.buffer = slices.Clone(w.buffer[end+1:])
+	if _, err := io.WriteString(w.writer, strings.Join(lines, "\n")+"\n"); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes the held back unterminated line.
+func (w *Writer) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	line := defaultFilter.line(string(w.buffer))
+	w.buffer = nil
+	_, err := io.WriteString(w.writer, line)
+	return err
+}
+
+// Close flushes the writer and closes the underlying writer if it's a closer.
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if closer, ok := w.writer.(io.Closer); ok {
+		return errors.Join(err, closer.Close())
+	}
+	return err
+}
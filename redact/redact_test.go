@@ -0,0 +1,48 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashes(t *testing.T) {
+	AddValues("db-password", "password", "multi\nline-value")
+	hashes := GetHashes([]byte("salt"))
+	for _, values := range hashes.Values {
+		for _, value := range values {
+			if strings.Contains(value, "password") {
+				t.Fatalf("hashes must not contain the values, got %v", hashes.Values)
+			}
+		}
+	}
+	wrapper := &filter{values: make(map[string]struct{}), ignored: make(map[string]struct{})}
+	if err := wrapper.addHashes(hashes); err != nil {
+		t.Fatalf("failed to add hashes: %v", err)
+	}
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{name: "value", line: "password", expected: Mask},
+		{name: "longest value", line: "url db-password@host", expected: "url ***@host"},
+		{name: "repeated values", line: "password,password", expected: "***,***"},
+		{name: "value line", line: "value: line-value", expected: "value: ***"},
+		{name: "other value", line: "passwor d", expected: "passwor d"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if masked := wrapper.line(test.line); masked != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, masked)
+			}
+		})
+	}
+}
+
+func TestAddHashesErrors(t *testing.T) {
+	wrapper := &filter{}
+	err := wrapper.addHashes(Hashes{Salt: []byte("salt"), Values: map[int][]string{8: {"not-a-hash"}}})
+	if err == nil || !strings.Contains(err.Error(), "invalid redact hash") {
+		t.Fatalf("expected invalid hash error, got %v", err)
+	}
+}
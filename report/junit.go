@@ -0,0 +1,119 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+const campaignCase = "campaign"
+
+type testSuites struct {
+	XMLName  xml.Name    `xml:"testsuites"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Suites   []testSuite `xml:"testsuite"`
+}
+
+type testSuite struct {
+	Name     string     `xml:"name,attr"`
+	Tests    int        `xml:"tests,attr"`
+	Failures int        `xml:"failures,attr"`
+	Skipped  int        `xml:"skipped,attr"`
+	Time     string     `xml:"time,attr"`
+	Cases    []testCase `xml:"testcase"`
+}
+
+type testCase struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Time      string   `xml:"time,attr"`
+	Failure   *failure `xml:"failure,omitempty"`
+	Skipped   *skipped `xml:"skipped,omitempty"`
+}
+
+type failure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type skipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+func (c *Collector) junit() ([]byte, error) {
+	suites := testSuites{Name: c.suiteName(), Time: seconds(c.campaign.duration())}
+	failedSteps := 0
+	var durations []time.Duration
+	for _, s := range c.getSteps() {
+		name := releaseName(s.pipelineIndex)
+		if len(suites.Suites) == 0 || suites.Suites[len(suites.Suites)-1].Name != name {
+			suites.Suites = append(suites.Suites, testSuite{Name: name})
+			durations = append(durations, 0)
+		}
+		current := &suites.Suites[len(suites.Suites)-1]
+		durations[len(durations)-1] += s.duration()
+		test := testCase{Name: s.name, ClassName: name, Time: seconds(s.duration())}
+		switch {
+		case s.failed():
+			message := s.failureMessage()
+			test.Failure = &failure{Message: message, Text: message}
+			current.Failures++
+			failedSteps++
+		case s.status == model.ApplyStatusSkipped:
+			test.Skipped = &skipped{}
+			current.Skipped++
+		}
+		current.Tests++
+		current.Cases = append(current.Cases, test)
+	}
+	if c.campaign.err != nil && failedSteps == 0 {
+		message := c.campaign.err.Error()
+		suites.Suites = append(suites.Suites, testSuite{
+			Name:     campaignCase,
+			Tests:    1,
+			Failures: 1,
+			Time:     seconds(c.campaign.duration()),
+			Cases: []testCase{{
+				Name:      string(c.campaign.command),
+				ClassName: campaignCase,
+				Time:      seconds(c.campaign.duration()),
+				Failure:   &failure{Message: message, Text: message},
+			}},
+		})
+	}
+	for i := range suites.Suites {
+		suite := &suites.Suites[i]
+		if i < len(durations) {
+			suite.Time = seconds(durations[i])
+		}
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+	}
+	content, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(content, '\n')...), nil
+}
+
+func (c *Collector) suiteName() string {
+	if c.campaign.prefix == "" {
+		return fmt.Sprintf("entigo-infralib-agent %s", c.campaign.command)
+	}
+	return fmt.Sprintf("entigo-infralib-agent %s %s", c.campaign.command, c.campaign.prefix)
+}
+
+func releaseName(pipelineIndex int32) string {
+	return fmt.Sprintf("release-%d", pipelineIndex)
+}
+
+func seconds(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -0,0 +1,150 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+func (c *Collector) markdown() []byte {
+	var buffer bytes.Buffer
+	c.writeHeader(&buffer)
+	steps := c.getSteps()
+	c.writeReleases(&buffer)
+	writeSteps(&buffer, steps)
+	writeModules(&buffer, steps)
+	writeFailures(&buffer, steps)
+	return buffer.Bytes()
+}
+
+func (c *Collector) writeHeader(buffer *bytes.Buffer) {
+	_, _ = fmt.Fprintf(buffer, "## Infralib agent %s: %s\n\n", c.campaign.command, campaignStatus(c.campaign.status))
+	if c.campaign.prefix != "" {
+		_, _ = fmt.Fprintf(buffer, "- Prefix: `%s`\n", c.campaign.prefix)
+	}
+	_, _ = fmt.Fprintf(buffer, "- Duration: %s\n", formatDuration(c.campaign.duration()))
+	if c.campaign.err != nil {
+		_, _ = fmt.Fprintf(buffer, "- Error: %s\n", escape(c.campaign.err.Error()))
+	}
+	buffer.WriteString("\n")
+}
+
+func (c *Collector) writeReleases(buffer *bytes.Buffer) {
+	if len(c.releases) == 0 {
+		return
+	}
+	indexes := make([]int32, 0, len(c.releases))
+	for index := range c.releases {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+	buffer.WriteString("### Releases\n\n| Release | Source | Version |\n| --- | --- | --- |\n")
+	for _, index := range indexes {
+		for _, source := range c.releases[index] {
+			_, _ = fmt.Fprintf(buffer, "| %d | %s | %s |\n", index, escape(source.URL), sourceVersion(source))
+		}
+	}
+	buffer.WriteString("\n")
+}
+
+func writeSteps(buffer *bytes.Buffer, steps []*step) {
+	if len(steps) == 0 {
+		buffer.WriteString("No steps were applied.\n")
+		return
+	}
+	buffer.WriteString("### Steps\n\n| Release | Step | Status | Duration | Add | Change | Destroy | Approval |\n")
+	buffer.WriteString("| --- | --- | --- | --- | --- | --- | --- | --- |\n")
+	for _, s := range steps {
+		add, change, destroy := "-", "-", "-"
+		if s.changes != nil {
+			add = fmt.Sprint(s.changes.Added + s.changes.Imported)
+			change = fmt.Sprint(s.changes.Changed)
+			destroy = fmt.Sprint(s.changes.Destroyed)
+		}
+		_, _ = fmt.Fprintf(buffer, "| %d | %s | %s | %s | %s | %s | %s | %s |\n", s.pipelineIndex, escape(s.name),
+			stepStatus(s.status), formatDuration(s.duration()), add, change, destroy, escape(s.approval()))
+	}
+	buffer.WriteString("\n")
+}
+
+func writeModules(buffer *bytes.Buffer, steps []*step) {
+	var rows []string
+	for _, s := range steps {
+		for _, module := range s.modules {
+			from := module.from
+			if from == "" {
+				from = "new"
+			}
+			rows = append(rows, fmt.Sprintf("| %d | %s | %s | %s | %s |", s.pipelineIndex, escape(s.name),
+				escape(module.name), escape(from), escape(module.to)))
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	buffer.WriteString("### Module versions\n\n| Release | Step | Module | From | To |\n| --- | --- | --- | --- | --- |\n")
+	buffer.WriteString(strings.Join(rows, "\n"))
+	buffer.WriteString("\n\n")
+}
+
+func writeFailures(buffer *bytes.Buffer, steps []*step) {
+	var failures []*step
+	for _, s := range steps {
+		if s.failed() {
+			failures = append(failures, s)
+		}
+	}
+	if len(failures) == 0 {
+		return
+	}
+	buffer.WriteString("### Failures\n\n")
+	for _, s := range failures {
+		_, _ = fmt.Fprintf(buffer, "- **%s** (release %d): %s\n", escape(s.name), s.pipelineIndex,
+			escape(s.failureMessage()))
+	}
+	buffer.WriteString("\n")
+}
+
+func campaignStatus(status model.CampaignStatus) string {
+	if status == "" || status == model.CampaignStatusStarted {
+		return "unfinished"
+	}
+	return string(status)
+}
+
+func stepStatus(status model.ApplyStatus) string {
+	switch status {
+	case model.ApplyStatusSuccess:
+		return ":white_check_mark: success"
+	case model.ApplyStatusFailure:
+		return ":x: failure"
+	case model.ApplyStatusSkipped:
+		return ":fast_forward: skipped"
+	default:
+		return ":hourglass: unfinished"
+	}
+}
+
+func sourceVersion(source model.SourceVersion) string {
+	if source.ForcedVersion != "" {
+		return source.ForcedVersion
+	}
+	if source.Version == nil {
+		return "-"
+	}
+	return source.Version.Original()
+}
+
+func formatDuration(duration time.Duration) string {
+	return duration.Round(time.Second).String()
+}
+
+// escape keeps the value from breaking the table layout.
+func escape(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
@@ -0,0 +1,281 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+const collectorName = "campaign-report"
+
+// Collector gathers the campaign, step and approval notifications for writing the JUnit and Markdown reports.
+type Collector struct {
+	model.BaseNotifier
+	flags    common.Report
+	now      func() time.Time
+	lock     sync.Mutex
+	campaign campaign
+	releases map[int32][]model.SourceVersion
+	steps    map[stepKey]*step
+	order    []stepKey
+}
+
+type campaign struct {
+	command  common.Command
+	prefix   string
+	status   model.CampaignStatus
+	err      error
+	started  time.Time
+	finished time.Time
+}
+
+type stepKey struct {
+	pipelineIndex int32
+	name          string
+}
+
+type step struct {
+	name           string
+	pipelineIndex  int32
+	status         model.ApplyStatus
+	err            error
+	started        time.Time
+	finished       time.Time
+	modules        []moduleChange
+	changes        *model.PipelineChanges
+	manualApproval bool
+	approvedBy     string
+}
+
+type moduleChange struct {
+	name string
+	from string
+	to   string
+}
+
+var _ model.Notifier = (*Collector)(nil)
+var _ model.PlanNotifier = (*Collector)(nil)
+
+// NewCollector returns nil when no report paths are set.
+func NewCollector(flags common.Report) *Collector {
+	if flags.JUnit == "" && flags.Markdown == "" {
+		return nil
+	}
+	return &Collector{
+		BaseNotifier: model.BaseNotifier{
+			Name: collectorName,
+			MessageTypes: model.NewSet(model.MessageTypeStarted, model.MessageTypeProgress,
+				model.MessageTypeApprovals, model.MessageTypeSuccess, model.MessageTypeFailure),
+		},
+		flags:    flags,
+		now:      time.Now,
+		releases: make(map[int32][]model.SourceVersion),
+		steps:    make(map[stepKey]*step),
+	}
+}
+
+// Write writes the configured report files.
+func (c *Collector) Write() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.campaign.finished.IsZero() {
+		c.campaign.finished = c.now()
+	}
+	if c.flags.JUnit != "" {
+		content, err := c.junit()
+		if err != nil {
+			return fmt.Errorf("failed to create junit report: %v", err)
+		}
+		if err = writeFile(c.flags.JUnit, content); err != nil {
+			return fmt.Errorf("failed to write junit report: %v", err)
+		}
+	}
+	if c.flags.Markdown != "" {
+		if err := writeFile(c.flags.Markdown, c.markdown()); err != nil {
+			return fmt.Errorf("failed to write markdown report: %v", err)
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, content []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+func (c *Collector) HandleCampaign(msg model.CampaignMessage) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.campaign.command = msg.Command
+	if msg.Resources != nil {
+		c.campaign.prefix = msg.Resources.GetCloudPrefix()
+	}
+	c.campaign.status = msg.Status
+	if msg.Status == model.CampaignStatusStarted {
+		c.campaign.started = c.now()
+		return nil
+	}
+	c.campaign.err = msg.Err
+	c.campaign.finished = c.now()
+	return nil
+}
+
+func (c *Collector) HandleApproval(msg model.ApprovalMessage) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	current := c.getStep(msg.PipelineIndex, msg.Step)
+	current.manualApproval = true
+	current.approvedBy = msg.ApprovedBy
+	return nil
+}
+
+func (c *Collector) HandleManualApproval(msg model.ManualApprovalMessage) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	current := c.getStep(msg.PipelineIndex, msg.Step)
+	current.manualApproval = true
+	current.changes = &msg.Changes
+	return nil
+}
+
+func (c *Collector) HandlePlanChanges(msg model.PlanChangesMessage) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.getStep(msg.PipelineIndex, msg.Step).changes = &msg.Changes
+}
+
+func (c *Collector) HandleStepState(msg model.StepStateMessage) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	current := c.getStep(msg.PipelineIndex, msg.StateStep.Name)
+	now := c.now()
+	if msg.Status == model.ApplyStatusStarting {
+		current.started = now
+		current.status = ""
+		current.err = nil
+		current.modules = getModuleChanges(msg.StateStep)
+		return nil
+	}
+	if current.started.IsZero() {
+		current.started = now
+	}
+	current.status = msg.Status
+	current.err = msg.Err
+	current.finished = now
+	return nil
+}
+
+func (c *Collector) HandlePipelineState(msg model.PipelineStateMessage) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.releases[msg.Index] = msg.SourceVersions
+	return nil
+}
+
+func (c *Collector) HandleModules(model.ModulesMessage) error {
+	return nil
+}
+
+func (c *Collector) HandleSources(model.SourcesMessage) error {
+	return nil
+}
+
+func (c *Collector) HandleSchedule(model.ScheduleMessage) error {
+	return nil
+}
+
+func (c *Collector) getStep(pipelineIndex int32, name string) *step {
+	key := stepKey{pipelineIndex: pipelineIndex, name: name}
+	current, found := c.steps[key]
+	if !found {
+		current = &step{name: name, pipelineIndex: pipelineIndex}
+		c.steps[key] = current
+		c.order = append(c.order, key)
+	}
+	return current
+}
+
+// getModuleChanges returns the modules whose version differs from the applied version, new modules have no from version.
+func getModuleChanges(stateStep model.StateStep) []moduleChange {
+	var changes []moduleChange
+	for _, module := range stateStep.Modules {
+		if module == nil || module.Type != nil {
+			continue
+		}
+		from := ""
+		if module.AppliedVersion != nil {
+			from = *module.AppliedVersion
+		}
+		if from == module.Version {
+			continue
+		}
+		changes = append(changes, moduleChange{name: module.Name, from: from, to: module.Version})
+	}
+	return changes
+}
+
+// getSteps returns the steps in the order they were started, grouped by the pipeline index.
+func (c *Collector) getSteps() []*step {
+	steps := make([]*step, 0, len(c.order))
+	for _, key := range c.order {
+		steps = append(steps, c.steps[key])
+	}
+	slices.SortStableFunc(steps, func(a, b *step) int {
+		return cmp.Compare(a.pipelineIndex, b.pipelineIndex)
+	})
+	return steps
+}
+
+func (s *step) duration() time.Duration {
+	if s.started.IsZero() || s.finished.IsZero() {
+		return 0
+	}
+	return s.finished.Sub(s.started)
+}
+
+func (s *step) failed() bool {
+	return s.status == model.ApplyStatusFailure || s.status == ""
+}
+
+func (s *step) failureMessage() string {
+	if s.err != nil {
+		return s.err.Error()
+	}
+	if s.status == "" {
+		return "step didn't finish"
+	}
+	return "step failed"
+}
+
+func (s *step) approval() string {
+	switch {
+	case !s.manualApproval:
+		return "auto"
+	case s.approvedBy != "":
+		return fmt.Sprintf("approved by %s", s.approvedBy)
+	case s.status == model.ApplyStatusSuccess:
+		return "approved"
+	case s.status == "":
+		return "waiting"
+	default:
+		return "not approved"
+	}
+}
+
+func (c *campaign) duration() time.Duration {
+	if c.started.IsZero() {
+		return 0
+	}
+	return c.finished.Sub(c.started)
+}
@@ -0,0 +1,180 @@
+package report
+
+import (
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/hashicorp/go-version"
+)
+
+func TestNewCollectorWithoutPaths(t *testing.T) {
+	if collector := NewCollector(common.Report{}); collector != nil {
+		t.Fatalf("expected no collector without report paths")
+	}
+}
+
+func TestCollectorWrite(t *testing.T) {
+	dir := t.TempDir()
+	junitPath := filepath.Join(dir, "reports", "junit.xml")
+	markdownPath := filepath.Join(dir, "reports", "summary.md")
+	collector := newTestCollector(common.Report{JUnit: junitPath, Markdown: markdownPath})
+
+	applied := "v1.0.0"
+	custom := model.ModuleTypeCustom
+	mustHandle(t, collector.HandleCampaign(model.CampaignMessage{Status: model.CampaignStatusStarted,
+		Command: common.RunCommand}))
+	mustHandle(t, collector.HandlePipelineState(model.PipelineStateMessage{Index: 0, SourceVersions: []model.SourceVersion{
+		{URL: "https://github.com/entigolabs/entigo-infralib-release", Version: version.Must(version.NewVersion("v1.1.0"))},
+		{URL: "https://github.com/example/modules", ForcedVersion: "main"},
+	}}))
+	mustHandle(t, collector.HandleStepState(model.StepStateMessage{PipelineIndex: 0, Status: model.ApplyStatusStarting,
+		StateStep: model.StateStep{Name: "net", Modules: []*model.StateModule{
+			{Name: "vpc", Version: "v1.1.0", AppliedVersion: &applied},
+			{Name: "route53", Version: "v1.1.0"},
+			{Name: "same", Version: "v1.0.0", AppliedVersion: &applied},
+			{Name: "custom", Version: "v2.0.0", Type: &custom},
+		}}}))
+	collector.HandlePlanChanges(model.PlanChangesMessage{PipelineIndex: 0, Step: "net",
+		Changes: model.PipelineChanges{Imported: 1, Added: 2, Changed: 3, Destroyed: 4}})
+	mustHandle(t, collector.HandleManualApproval(model.ManualApprovalMessage{PipelineIndex: 0, Step: "net",
+		Changes: model.PipelineChanges{Imported: 1, Added: 2, Changed: 3, Destroyed: 4}}))
+	mustHandle(t, collector.HandleApproval(model.ApprovalMessage{PipelineIndex: 0, Step: "net", ApprovedBy: "jane"}))
+	mustHandle(t, collector.HandleStepState(model.StepStateMessage{PipelineIndex: 0, Status: model.ApplyStatusSuccess,
+		StateStep: model.StateStep{Name: "net"}}))
+	mustHandle(t, collector.HandleStepState(model.StepStateMessage{PipelineIndex: 1, Status: model.ApplyStatusStarting,
+		StateStep: model.StateStep{Name: "apps"}}))
+	mustHandle(t, collector.HandleStepState(model.StepStateMessage{PipelineIndex: 1, Status: model.ApplyStatusFailure,
+		StateStep: model.StateStep{Name: "apps"}, Err: errors.New("apply failed | exit 1\nsee logs")}))
+	mustHandle(t, collector.HandleStepState(model.StepStateMessage{PipelineIndex: 1, Status: model.ApplyStatusSkipped,
+		StateStep: model.StateStep{Name: "dns"}}))
+	mustHandle(t, collector.HandleStepState(model.StepStateMessage{PipelineIndex: 1, Status: model.ApplyStatusStarting,
+		StateStep: model.StateStep{Name: "eks"}}))
+	mustHandle(t, collector.HandleCampaign(model.CampaignMessage{Status: model.CampaignStatusFailure,
+		Command: common.RunCommand, Err: errors.New("campaign failed")}))
+
+	if err := collector.Write(); err != nil {
+		t.Fatalf("failed to write reports: %v", err)
+	}
+
+	suites := readJUnit(t, junitPath)
+	if suites.Name != "entigo-infralib-agent run" || suites.Tests != 4 || suites.Failures != 2 || suites.Skipped != 1 {
+		t.Fatalf("unexpected totals: %s tests=%d failures=%d skipped=%d", suites.Name, suites.Tests,
+			suites.Failures, suites.Skipped)
+	}
+	if len(suites.Suites) != 2 {
+		t.Fatalf("expected a suite per release without a campaign suite, got %d", len(suites.Suites))
+	}
+	if suites.Suites[0].Name != "release-0" || suites.Suites[0].Time != "60.000" {
+		t.Fatalf("unexpected first suite %s time %s", suites.Suites[0].Name, suites.Suites[0].Time)
+	}
+	cases := suites.Suites[1].Cases
+	if len(cases) != 3 {
+		t.Fatalf("expected 3 cases in release-1, got %d", len(cases))
+	}
+	if cases[0].Failure == nil || cases[0].Failure.Message != "apply failed | exit 1\nsee logs" {
+		t.Fatalf("unexpected apps failure %+v", cases[0].Failure)
+	}
+	if cases[1].Skipped == nil {
+		t.Fatalf("expected dns to be skipped")
+	}
+	if cases[2].Failure == nil || cases[2].Failure.Message != "step didn't finish" {
+		t.Fatalf("unexpected eks failure %+v", cases[2].Failure)
+	}
+
+	markdown := readFile(t, markdownPath)
+	for _, expected := range []string{
+		"## Infralib agent run: failure\n",
+		"- Error: campaign failed\n",
+		"| 0 | https://github.com/entigolabs/entigo-infralib-release | v1.1.0 |\n",
+		"| 0 | https://github.com/example/modules | main |\n",
+		"| 0 | net | :white_check_mark: success | 1m0s | 3 | 3 | 4 | approved by jane |\n",
+		"| 1 | apps | :x: failure | 1m0s | - | - | - | auto |\n",
+		"| 1 | dns | :fast_forward: skipped | 0s | - | - | - | auto |\n",
+		"| 1 | eks | :hourglass: unfinished | 0s | - | - | - | auto |\n",
+		"| 0 | net | vpc | v1.0.0 | v1.1.0 |\n| 0 | net | route53 | new | v1.1.0 |\n\n",
+		"- **apps** (release 1): apply failed \\| exit 1<br>see logs\n",
+		"- **eks** (release 1): step didn't finish\n",
+	} {
+		if !strings.Contains(markdown, expected) {
+			t.Fatalf("markdown doesn't contain %q:\n%s", expected, markdown)
+		}
+	}
+	for _, unexpected := range []string{"| same |", "| custom |"} {
+		if strings.Contains(markdown, unexpected) {
+			t.Fatalf("markdown contains %q:\n%s", unexpected, markdown)
+		}
+	}
+}
+
+func TestCollectorCampaignFailure(t *testing.T) {
+	junitPath := filepath.Join(t.TempDir(), "junit.xml")
+	collector := newTestCollector(common.Report{JUnit: junitPath})
+
+	mustHandle(t, collector.HandleCampaign(model.CampaignMessage{Status: model.CampaignStatusStarted,
+		Command: common.UpdateCommand}))
+	mustHandle(t, collector.HandleStepState(model.StepStateMessage{PipelineIndex: 0, Status: model.ApplyStatusSuccess,
+		StateStep: model.StateStep{Name: "net"}}))
+	mustHandle(t, collector.HandleCampaign(model.CampaignMessage{Status: model.CampaignStatusFailure,
+		Command: common.UpdateCommand, Err: errors.New("failed to update state")}))
+
+	if err := collector.Write(); err != nil {
+		t.Fatalf("failed to write reports: %v", err)
+	}
+	suites := readJUnit(t, junitPath)
+	if suites.Tests != 2 || suites.Failures != 1 || len(suites.Suites) != 2 {
+		t.Fatalf("unexpected totals: tests=%d failures=%d suites=%d", suites.Tests, suites.Failures,
+			len(suites.Suites))
+	}
+	campaign := suites.Suites[1]
+	if campaign.Name != campaignCase || campaign.Time != "120.000" || len(campaign.Cases) != 1 {
+		t.Fatalf("unexpected campaign suite %+v", campaign)
+	}
+	test := campaign.Cases[0]
+	if test.Name != string(common.UpdateCommand) || test.Failure == nil ||
+		test.Failure.Message != "failed to update state" {
+		t.Fatalf("unexpected campaign case %+v", test)
+	}
+}
+
+// newTestCollector returns a collector whose clock advances a minute on every read.
+func newTestCollector(flags common.Report) *Collector {
+	collector := NewCollector(flags)
+	current := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	collector.now = func() time.Time {
+		current = current.Add(time.Minute)
+		return current
+	}
+	return collector
+}
+
+func mustHandle(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("failed to handle message: %v", err)
+	}
+}
+
+func readJUnit(t *testing.T, path string) testSuites {
+	t.Helper()
+	var suites testSuites
+	if err := xml.Unmarshal([]byte(readFile(t, path)), &suites); err != nil {
+		t.Fatalf("failed to parse junit report: %v", err)
+	}
+	return suites
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(content)
+}
@@ -0,0 +1,47 @@
+package secret
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"gopkg.in/yaml.v3"
+)
+
+// FileClient reads secrets from a local yaml or json file where top level keys are secret paths, meant for tests and
+// local development.
+type FileClient struct {
+	path    string
+	once    sync.Once
+	secrets map[string]map[string]interface{}
+	err     error
+}
+
+func NewFileClient(config model.SecretFile) (*FileClient, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("secret file path is not set")
+	}
+	return &FileClient{path: config.Path}, nil
+}
+
+func (f *FileClient) GetSecret(path string) (map[string]interface{}, error) {
+	f.once.Do(func() {
+		content, err := os.ReadFile(f.path)
+		if err != nil {
+			f.err = fmt.Errorf("failed to read secret file %s: %w", f.path, err)
+			return
+		}
+		if err = yaml.Unmarshal(content, &f.secrets); err != nil {
+			f.err = fmt.Errorf("failed to unmarshal secret file %s: %w", f.path, err)
+		}
+	})
+	if f.err != nil {
+		return nil, f.err
+	}
+	secret, found := f.secrets[path]
+	if !found {
+		return nil, model.NewNotFoundError(fmt.Sprintf("secret %s", path))
+	}
+	return secret, nil
+}
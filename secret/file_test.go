@@ -0,0 +1,48 @@
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+func TestFileGetSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.yaml")
+	content := "app/db:\n  password: db-password\n  port: 5432\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write secrets: %v", err)
+	}
+	client, err := NewFileClient(model.SecretFile{Path: path})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	secret, err := client.GetSecret("app/db")
+	if err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if expected := map[string]interface{}{"password": "db-password", "port": 5432}; !reflect.DeepEqual(secret, expected) {
+		t.Fatalf("expected secret %v, got %v", expected, secret)
+	}
+	_, err = client.GetSecret("app/missing")
+	if _, ok := errors.AsType[model.NotFoundError](err); !ok {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestFileGetSecretErrors(t *testing.T) {
+	if _, err := NewFileClient(model.SecretFile{}); err == nil {
+		t.Fatal("expected error for empty path")
+	}
+	client, err := NewFileClient(model.SecretFile{Path: filepath.Join(t.TempDir(), "missing.yaml")})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if _, err = client.GetSecret("app/db"); err == nil || !strings.Contains(err.Error(), "failed to read secret file") {
+		t.Fatalf("expected read error, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+const (
+	VaultAddressEnv   = "VAULT_ADDR"
+	VaultTokenEnv     = "VAULT_TOKEN"
+	VaultNamespaceEnv = "VAULT_NAMESPACE"
+	VaultRoleIdEnv    = "VAULT_ROLE_ID"
+	VaultSecretIdEnv  = "VAULT_SECRET_ID"
+
+	defaultAppRoleMount = "approle"
+	vaultTimeout        = 30 * time.Second
+)
+
+// VaultClient reads secrets from a Vault KV v2 secrets engine. Token is used as is, AppRole logs in on the first read.
+type VaultClient struct {
+	ctx       context.Context
+	name      string
+	client    *http.Client
+	address   string
+	namespace string
+	appRole   *model.VaultAppRole
+	token     string
+	tokenMu   sync.Mutex
+}
+
+type vaultResponse struct {
+	Data *struct {
+		Data map[string]interface{} `json:"data"`
+	} `json:"data"`
+	Auth *struct {
+		ClientToken string `json:"client_token"`
+	} `json:"auth"`
+	Errors []string `json:"errors"`
+}
+
+func NewVaultClient(ctx context.Context, name string, config model.Vault, CABundle []byte) (*VaultClient, error) {
+	address := getOrEnv(config.Address, VaultAddressEnv)
+	if address == "" {
+		return nil, fmt.Errorf("vault address is not set, use address or %s", VaultAddressEnv)
+	}
+	appRole := getAppRole(config.AppRole)
+	token := getOrEnv(config.Token, VaultTokenEnv)
+	if token == "" && appRole == nil {
+		return nil, fmt.Errorf("vault token or approle is not set, use token, approle or %s", VaultTokenEnv)
+	}
+	client, err := getHttpClient(config.Insecure, CABundle)
+	if err != nil {
+		return nil, err
+	}
+	return &VaultClient{
+		ctx:       ctx,
+		name:      name,
+		client:    client,
+		address:   strings.TrimSuffix(address, "/"),
+		namespace: getOrEnv(config.Namespace, VaultNamespaceEnv),
+		appRole:   appRole,
+		token:     token,
+	}, nil
+}
+
+func getOrEnv(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+func getAppRole(appRole *model.VaultAppRole) *model.VaultAppRole {
+	if appRole == nil {
+		roleId := os.Getenv(VaultRoleIdEnv)
+		if roleId == "" {
+			return nil
+		}
+		appRole = &model.VaultAppRole{RoleId: roleId}
+	}
+	return &model.VaultAppRole{
+		RoleId:   appRole.RoleId,
+		SecretId: getOrEnv(appRole.SecretId, VaultSecretIdEnv),
+		Mount:    appRole.Mount,
+	}
+}
+
+func getHttpClient(insecure bool, CABundle []byte) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
+	if len(CABundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(CABundle) {
+			return nil, errors.New("failed to parse vault CA bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: vaultTimeout}, nil
+}
+
+// GetSecret reads the latest version of a KV v2 secret. Path format is <mount>/<path>.
+func (v *VaultClient) GetSecret(path string) (map[string]interface{}, error) {
+	mount, secretPath, found := strings.Cut(strings.Trim(path, "/"), "/")
+	if !found || secretPath == "" {
+		return nil, fmt.Errorf("invalid vault secret path %s, expected <mount>/<path>", path)
+	}
+	token, err := v.getToken()
+	if err != nil {
+		return nil, err
+	}
+	secretUrl, err := url.JoinPath(v.address, "v1", mount, "data", secretPath)
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug(fmt.Sprintf("Reading secret %s from vault %s", path, v.name))
+	response, err := v.request(http.MethodGet, secretUrl, token, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s: %w", path, err)
+	}
+	if response.Data == nil || response.Data.Data == nil {
+		return nil, fmt.Errorf("vault secret %s has no data, latest version may be deleted", path)
+	}
+	return response.Data.Data, nil
+}
+
+func (v *VaultClient) getToken() (string, error) {
+	v.tokenMu.Lock()
+	defer v.tokenMu.Unlock()
+	if v.token != "" {
+		return v.token, nil
+	}
+	mount := v.appRole.Mount
+	if mount == "" {
+		mount = defaultAppRoleMount
+	}
+	loginUrl, err := url.JoinPath(v.address, "v1", "auth", mount, "login")
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(map[string]string{"role_id": v.appRole.RoleId, "secret_id": v.appRole.SecretId})
+	if err != nil {
+		return "", err
+	}
+	response, err := v.request(http.MethodPost, loginUrl, "", body)
+	if err != nil {
+		return "", fmt.Errorf("vault %s approle login failed: %w", v.name, err)
+	}
+	if response.Auth == nil || response.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault %s approle login returned no token", v.name)
+	}
+	v.token = response.Auth.ClientToken
+	return v.token, nil
+}
+
+func (v *VaultClient) request(method, requestUrl, token string, body []byte) (*vaultResponse, error) {
+	req, err := http.NewRequestWithContext(v.ctx, method, requestUrl, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(body io.ReadCloser) {
+		_ = body.Close()
+	}(resp.Body)
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var response vaultResponse
+	if len(content) > 0 {
+		if err = json.Unmarshal(content, &response); err != nil && resp.StatusCode/100 == 2 {
+			return nil, fmt.Errorf("failed to unmarshal vault response: %w", err)
+		}
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, model.NewNotFoundError("secret")
+	case resp.StatusCode/100 != 2 && len(response.Errors) > 0:
+		return nil, fmt.Errorf("status code %d: %s", resp.StatusCode, strings.Join(response.Errors, ", "))
+	case resp.StatusCode/100 != 2:
+		return nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	return &response, nil
+}
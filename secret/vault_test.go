@@ -0,0 +1,147 @@
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+const (
+	testVaultToken    = "vault-token"
+	testAppRoleToken  = "approle-token"
+	testVaultSecretId = "secret-id"
+)
+
+func newVaultServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/auth/custom/login":
+			var login map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&login)
+			if login["role_id"] != "role-id" || login["secret_id"] != testVaultSecretId {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors": ["invalid role or secret ID"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "` + testAppRoleToken + `"}}`))
+		case r.Header.Get("X-Vault-Token") != testVaultToken && r.Header.Get("X-Vault-Token") != testAppRoleToken:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+		case r.URL.Path == "/v1/kv/data/app/db" && r.Header.Get("X-Vault-Namespace") == "team":
+			_, _ = w.Write([]byte(`{"data": {"data": {"password": "db-password"}}}`))
+		case r.URL.Path == "/v1/kv/data/app/deleted":
+			_, _ = w.Write([]byte(`{"data": {"data": null}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVaultGetSecret(t *testing.T) {
+	server := newVaultServer(t)
+	tests := []struct {
+		name     string
+		config   model.Vault
+		path     string
+		expected map[string]interface{}
+		err      string
+	}{
+		{
+			name:     "token",
+			config:   model.Vault{Token: testVaultToken, Namespace: "team"},
+			path:     "kv/app/db",
+			expected: map[string]interface{}{"password": "db-password"},
+		},
+		{
+			name: "approle",
+			config: model.Vault{Namespace: "team", AppRole: &model.VaultAppRole{RoleId: "role-id",
+				SecretId: testVaultSecretId, Mount: "custom"}},
+			path:     "/kv/app/db/",
+			expected: map[string]interface{}{"password": "db-password"},
+		},
+		{
+			name:   "approle login failure",
+			config: model.Vault{AppRole: &model.VaultAppRole{RoleId: "role-id", SecretId: "wrong", Mount: "custom"}},
+			path:   "kv/app/db",
+			err:    "approle login failed: status code 400: invalid role or secret ID",
+		},
+		{
+			name:   "permission denied",
+			config: model.Vault{Token: "wrong"},
+			path:   "kv/app/db",
+			err:    "status code 403: permission denied",
+		},
+		{name: "deleted version", config: model.Vault{Token: testVaultToken}, path: "kv/app/deleted", err: "has no data"},
+		{name: "not found", config: model.Vault{Token: testVaultToken}, path: "kv/app/missing", err: "not found"},
+		{name: "invalid path", config: model.Vault{Token: testVaultToken}, path: "kv", err: "invalid vault secret path"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.Address = server.URL + "/"
+			client, err := NewVaultClient(t.Context(), "vault", test.config, nil)
+			if err != nil {
+				t.Fatalf("failed to create client: %v", err)
+			}
+			secret, err := client.GetSecret(test.path)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get secret: %v", err)
+			}
+			if !reflect.DeepEqual(secret, test.expected) {
+				t.Fatalf("expected secret %v, got %v", test.expected, secret)
+			}
+		})
+	}
+}
+
+func TestVaultNotFoundError(t *testing.T) {
+	server := newVaultServer(t)
+	client, err := NewVaultClient(t.Context(), "vault", model.Vault{Address: server.URL, Token: testVaultToken}, nil)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	_, err = client.GetSecret("kv/app/missing")
+	if _, ok := errors.AsType[model.NotFoundError](err); !ok {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestNewVaultClientErrors(t *testing.T) {
+	for _, env := range []string{VaultAddressEnv, VaultTokenEnv, VaultRoleIdEnv} {
+		t.Setenv(env, "")
+	}
+	tests := []struct {
+		name   string
+		config model.Vault
+		err    string
+	}{
+		{name: "no address", config: model.Vault{Token: testVaultToken}, err: "vault address is not set"},
+		{name: "no auth", config: model.Vault{Address: "http://vault"}, err: "vault token or approle is not set"},
+		{
+			name:   "invalid ca bundle",
+			config: model.Vault{Address: "http://vault", Token: testVaultToken},
+			err:    "failed to parse vault CA bundle",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewVaultClient(t.Context(), "vault", test.config, []byte("not-a-pem"))
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
@@ -2,7 +2,7 @@ package service
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
@@ -44,9 +44,9 @@ func (a *agent) CreatePipeline(version string, start bool) error {
 		return err
 	}
 	if start {
-		log.Println("Agent run pipeline execution started")
+		slog.Info("Agent run pipeline execution started")
 	} else {
-		log.Println("Agent run pipeline execution not started")
+		slog.Info("Agent run pipeline execution not started")
 	}
 	return nil
 }
@@ -105,6 +105,6 @@ func (a *agent) updateProjectImage(project *model.Project, version string) (bool
 	if err != nil {
 		return false, err
 	}
-	log.Printf("Updated Agent CodeBuild project %s image version to %s\n", project.Name, version)
+	slog.Info(fmt.Sprintf("Updated Agent CodeBuild project %s image version to %s", project.Name, version))
 	return true, nil
 }
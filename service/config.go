@@ -1,16 +1,24 @@
 package service
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
+	"maps"
+	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/redact"
+	"github.com/entigolabs/entigo-infralib-agent/sops"
 	"github.com/entigolabs/entigo-infralib-agent/util"
 	"github.com/hashicorp/go-version"
 	"gopkg.in/yaml.v3"
@@ -51,64 +59,88 @@ func GetProviderPrefix(flags *common.Flags) (string, error) {
 	return prefix, nil
 }
 
-func GetFullConfig(ssm model.SSM, prefix, configFile string, bucket model.Bucket) (model.Config, error) {
-	return getConfig(ssm, prefix, configFile, bucket, true)
+func GetFullConfig(ctx context.Context, ssm model.SSM, prefix, configFile string, bucket model.Bucket) (model.Config, error) {
+	config, err := getConfig(ctx, ssm, prefix, configFile, bucket, true)
+	if err != nil {
+		return config, err
+	}
+	if err = DecryptConfigFiles(ctx, &config); err != nil {
+		return config, err
+	}
+	return config, nil
 }
 
-func GetRootConfig(ssm model.SSM, prefix, configFile string, bucket model.Bucket) (model.Config, error) {
+func GetRootConfig(ctx context.Context, ssm model.SSM, prefix, configFile string, bucket model.Bucket) (model.Config, error) {
 	var config model.Config
 	var err error
 	if configFile != "" {
 		config, err = getLocalConfigFile(configFile)
 	} else {
-		config, err = getRemoteConfigFile(bucket)
+		config, err = getRemoteConfigFile(ctx, bucket)
 	}
 	if err != nil {
 		return config, err
 	}
-	return replaceConfigValues(ssm, prefix, config)
+	return replaceConfigValues(ctx, ssm, prefix, config)
 }
 
-func GetBaseConfig(prefix, configFile string, bucket model.Bucket) (model.Config, error) {
-	return getConfig(nil, prefix, configFile, bucket, false)
+func GetBaseConfig(ctx context.Context, prefix, configFile string, bucket model.Bucket) (model.Config, error) {
+	return getConfig(ctx, nil, prefix, configFile, bucket, false)
 }
 
-func getConfig(ssm model.SSM, prefix, configFile string, bucket model.Bucket, addInputs bool) (model.Config, error) {
+func getConfig(ctx context.Context, ssm model.SSM, prefix, configFile string, bucket model.Bucket, addInputs bool) (model.Config, error) {
 	if configFile != "" {
-		return GetLocalConfig(ssm, prefix, configFile, bucket, addInputs)
+		return GetLocalConfig(ctx, ssm, prefix, configFile, bucket, addInputs)
 	}
-	return GetRemoteConfig(ssm, prefix, bucket, addInputs)
+	return GetRemoteConfig(ctx, ssm, prefix, bucket, addInputs)
 }
 
-func GetLocalConfig(ssm model.SSM, prefix, configFile string, bucket model.Bucket, addInputs bool) (model.Config, error) {
+func GetLocalConfig(ctx context.Context, ssm model.SSM, prefix, configFile string, bucket model.Bucket, addInputs bool) (model.Config, error) {
 	config, err := getLocalConfigFile(configFile)
 	if err != nil {
 		return config, err
 	}
-	if err = PutConfig(bucket, config); err != nil {
+	if err = PutConfig(ctx, bucket, config); err != nil {
 		return config, err
 	}
-	config, err = replaceConfigValues(ssm, prefix, config)
+	config, err = replaceConfigValues(ctx, ssm, prefix, config)
 	if err != nil {
 		return config, err
 	}
-	reserveAppsFiles(config)
-	basePath := filepath.Dir(configFile) + "/"
-	if err = AddCertFilesFromFolder(&config, basePath); err != nil {
+	if err = addLocalFiles(&config, configFile, addInputs); err != nil {
 		return config, err
 	}
-	if err = AddStepsFilesFromFolder(&config, basePath); err != nil {
+	if err = PutAdditionalFiles(ctx, bucket, config); err != nil {
 		return config, err
 	}
-	if err = AddModuleInputFiles(&config, basePath, os.ReadFile, addInputs); err != nil {
+	return config, nil
+}
+
+// ReadLocalConfig reads the local config with the certificates, step include files and module input files, without
+// replacing values or uploading the files.
+func ReadLocalConfig(configFile string) (model.Config, error) {
+	config, err := getLocalConfigFile(configFile)
+	if err != nil {
 		return config, err
 	}
-	if err = PutAdditionalFiles(bucket, config); err != nil {
+	if err = addLocalFiles(&config, configFile, false); err != nil {
 		return config, err
 	}
 	return config, nil
 }
 
+func addLocalFiles(config *model.Config, configFile string, addInputs bool) error {
+	reserveAppsFiles(*config)
+	basePath := filepath.Dir(configFile) + "/"
+	if err := AddCertFilesFromFolder(config, basePath); err != nil {
+		return err
+	}
+	if err := AddStepsFilesFromFolder(config, basePath); err != nil {
+		return err
+	}
+	return AddModuleInputFiles(config, basePath, os.ReadFile, addInputs)
+}
+
 func getLocalConfigFile(configFile string) (model.Config, error) {
 	fileBytes, err := os.ReadFile(configFile)
 	if err != nil {
@@ -151,8 +183,9 @@ func AddCertFilesFromFolder(config *model.Config, basePath string) error {
 			return fmt.Errorf("failed to read file %s: %v", filePath, err)
 		}
 		config.Certs = append(config.Certs, model.File{
-			Name:    filePath,
-			Content: fileBytes,
+			Name:      filePath,
+			Content:   fileBytes,
+			Encrypted: sops.IsEncrypted(fileBytes),
 		})
 	}
 	return nil
@@ -182,9 +215,10 @@ func addStepFilesFromFolder(step *model.Step, basePath, folder string) error {
 			}
 			continue
 		}
-		if step.Type == model.StepTypeTerraform && ReservedTFFiles.Contains(entry.Name()) {
+		name := strings.TrimSuffix(entry.Name(), sops.AgeExtension)
+		if step.Type == model.StepTypeTerraform && ReservedTFFiles.Contains(name) {
 			return fmt.Errorf("can't include files %s in step %s", ReservedTFFiles, step.Name)
-		} else if step.Type == model.StepTypeArgoCD && ReservedAppsFiles.Contains(entry.Name()) {
+		} else if step.Type == model.StepTypeArgoCD && ReservedAppsFiles.Contains(name) {
 			return fmt.Errorf("can't include files %s in step %s", ReservedAppsFiles, step.Name)
 		}
 		filePath := fmt.Sprintf("%s/%s", folder, entry.Name())
@@ -193,42 +227,43 @@ func addStepFilesFromFolder(step *model.Step, basePath, folder string) error {
 			return fmt.Errorf("failed to read file %s: %v", filePath, err)
 		}
 		step.Files = append(step.Files, model.File{
-			Name:    filePath,
-			Content: fileBytes,
+			Name:      filePath,
+			Content:   fileBytes,
+			Encrypted: sops.IsEncrypted(fileBytes),
 		})
 	}
 	return nil
 }
 
-func PutConfig(bucket model.Bucket, config model.Config) error {
+func PutConfig(ctx context.Context, bucket model.Bucket, config model.Config) error {
 	bytes, err := yaml.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %s", err)
 	}
-	err = bucket.PutFile(ConfigFile, bytes)
+	err = bucket.PutFile(ctx, ConfigFile, bytes)
 	if err != nil {
 		return fmt.Errorf("failed to put config: %s", err)
 	}
 	return nil
 }
 
-func PutAdditionalFiles(bucket model.Bucket, config model.Config) error {
+func PutAdditionalFiles(ctx context.Context, bucket model.Bucket, config model.Config) error {
 	if len(config.Certs) == 0 {
-		if err := removeFolder(bucket, certsFolder); err != nil {
+		if err := removeFolder(ctx, bucket, certsFolder); err != nil {
 			return err
 		}
 	} else {
-		if err := putFolderFiles(bucket, certsFolder, config.Certs); err != nil {
+		if err := putFolderFiles(ctx, bucket, certsFolder, config.Certs); err != nil {
 			return err
 		}
 	}
 	for _, step := range config.Steps {
 		if len(step.Files) == 0 {
-			if err := removeFolder(bucket, fmt.Sprintf(IncludeFormat, step.Name)); err != nil {
+			if err := removeFolder(ctx, bucket, fmt.Sprintf(IncludeFormat, step.Name)); err != nil {
 				return err
 			}
 		} else if step.Files != nil {
-			if err := putFolderFiles(bucket, fmt.Sprintf(IncludeFormat, step.Name), step.Files); err != nil {
+			if err := putFolderFiles(ctx, bucket, fmt.Sprintf(IncludeFormat, step.Name), step.Files); err != nil {
 				return err
 			}
 		}
@@ -236,7 +271,7 @@ func PutAdditionalFiles(bucket model.Bucket, config model.Config) error {
 			continue
 		}
 		for _, module := range step.Modules {
-			if err := putModuleFiles(step, module, bucket); err != nil {
+			if err := putModuleFiles(ctx, step, module, bucket); err != nil {
 				return err
 			}
 		}
@@ -244,21 +279,21 @@ func PutAdditionalFiles(bucket model.Bucket, config model.Config) error {
 	return nil
 }
 
-func putModuleFiles(step model.Step, module model.Module, bucket model.Bucket) error {
+func putModuleFiles(ctx context.Context, step model.Step, module model.Module, bucket model.Bucket) error {
 	if module.InputsFile == "" {
 		inputsFile := fmt.Sprintf("config/%s/%s.yaml", step.Name, module.Name)
-		bytes, err := bucket.GetFile(inputsFile)
+		bytes, err := bucket.GetFile(ctx, inputsFile)
 		if err != nil {
 			return fmt.Errorf("failed to get module %s inputs file: %s", module.Name, err)
 		}
 		if bytes != nil {
-			err = bucket.DeleteFile(inputsFile)
+			err = bucket.DeleteFile(ctx, inputsFile)
 			if err != nil {
 				return fmt.Errorf("failed to delete module %s inputs file: %s", module.Name, err)
 			}
 		}
 	} else {
-		err := bucket.PutFile(module.InputsFile, module.FileContent)
+		err := bucket.PutFile(ctx, module.InputsFile, module.FileContent)
 		if err != nil {
 			return fmt.Errorf("failed to put module %s inputs file: %s", module.Name, err)
 		}
@@ -268,17 +303,17 @@ func putModuleFiles(step model.Step, module model.Module, bucket model.Bucket) e
 	return nil
 }
 
-func removeFolder(bucket model.Bucket, folder string) error {
-	files, err := bucket.ListFolderFiles(folder)
+func removeFolder(ctx context.Context, bucket model.Bucket, folder string) error {
+	files, err := bucket.ListFolderFiles(ctx, folder)
 	if err != nil {
 		return fmt.Errorf("failed to list folder %s files: %s", folder, err)
 	}
 	if len(files) == 0 {
 		return nil
 	}
-	log.Printf("Removing bucket folder %s", folder)
+	slog.Info(fmt.Sprintf("Removing bucket folder %s", folder))
 	for _, file := range files {
-		err = bucket.DeleteFile(file)
+		err = bucket.DeleteFile(ctx, file)
 		if err != nil {
 			return fmt.Errorf("failed to delete file %s: %s", file, err)
 		}
@@ -286,17 +321,17 @@ func removeFolder(bucket model.Bucket, folder string) error {
 	return nil
 }
 
-func putFolderFiles(bucket model.Bucket, folder string, files []model.File) error {
+func putFolderFiles(ctx context.Context, bucket model.Bucket, folder string, files []model.File) error {
 	allFiles := model.NewSet[string]()
 	for _, file := range files {
 
-		err := bucket.PutFile(file.Name, file.Content)
+		err := bucket.PutFile(ctx, file.Name, file.Content)
 		if err != nil {
 			return fmt.Errorf("failed to put step file %s: %s", file.Name, err)
 		}
 		allFiles.Add(file.Name)
 	}
-	bucketFiles, err := bucket.ListFolderFiles(folder)
+	bucketFiles, err := bucket.ListFolderFiles(ctx, folder)
 	if err != nil {
 		return fmt.Errorf("failed to list folder allFiles: %s", err)
 	}
@@ -304,7 +339,7 @@ func putFolderFiles(bucket model.Bucket, folder string, files []model.File) erro
 		if allFiles.Contains(bucketFile) {
 			continue
 		}
-		err = bucket.DeleteFile(bucketFile)
+		err = bucket.DeleteFile(ctx, bucketFile)
 		if err != nil {
 			return fmt.Errorf("failed to delete file %s: %s", bucketFile, err)
 		}
@@ -312,30 +347,116 @@ func putFolderFiles(bucket model.Bucket, folder string, files []model.File) erro
 	return nil
 }
 
-func GetRemoteConfig(ssm model.SSM, prefix string, bucket model.Bucket, addInputs bool) (model.Config, error) {
-	config, err := getRemoteConfigFile(bucket)
+// ConfigFileChange is a bucket file that differs from the local config files. Nil content means that the file is
+// missing, local nil content means that PutAdditionalFiles deletes the file.
+type ConfigFileChange struct {
+	Name   string
+	Remote []byte
+	Local  []byte
+}
+
+// GetConfigChanges compares the files that PutConfig and PutAdditionalFiles would upload or delete with the bucket.
+func GetConfigChanges(ctx context.Context, bucket model.Bucket, config model.Config) ([]ConfigFileChange, error) {
+	configBytes, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %s", err)
+	}
+	files := []model.File{{Name: ConfigFile, Content: configBytes}}
+	folderFiles, err := getFolderFiles(ctx, bucket, certsFolder, config.Certs)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, folderFiles...)
+	for _, step := range config.Steps {
+		folderFiles, err = getFolderFiles(ctx, bucket, fmt.Sprintf(IncludeFormat, step.Name), step.Files)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, folderFiles...)
+		for _, module := range step.Modules {
+			if module.InputsFile == "" {
+				files = append(files, model.File{Name: fmt.Sprintf("config/%s/%s.yaml", step.Name, module.Name)})
+			} else {
+				files = append(files, model.File{Name: module.InputsFile, Content: module.FileContent})
+			}
+		}
+	}
+	changes := make([]ConfigFileChange, 0)
+	for _, file := range files {
+		remote, err := bucket.GetFile(ctx, file.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get file %s: %s", file.Name, err)
+		}
+		if (remote == nil) == (file.Content == nil) && bytes.Equal(remote, file.Content) {
+			continue
+		}
+		changes = append(changes, ConfigFileChange{Name: file.Name, Remote: remote, Local: file.Content})
+	}
+	return changes, nil
+}
+
+// GetLocalConfigChanges reads the local config files and compares them with the bucket files.
+func GetLocalConfigChanges(ctx context.Context, bucket model.Bucket, configFile string) (model.Config, []ConfigFileChange, error) {
+	exists, err := bucket.BucketExists(ctx)
+	if err != nil {
+		return model.Config{}, nil, fmt.Errorf("failed to check if bucket exists: %v", err)
+	}
+	if !exists {
+		return model.Config{}, nil, errors.New("bucket doesn't exist, use run or bootstrap to create it")
+	}
+	config, err := ReadLocalConfig(configFile)
+	if err != nil {
+		return config, nil, err
+	}
+	changes, err := GetConfigChanges(ctx, bucket, config)
+	return config, changes, err
+}
+
+// getFolderFiles returns the local files and the bucket folder files missing locally with nil content.
+func getFolderFiles(ctx context.Context, bucket model.Bucket, folder string, files []model.File) ([]model.File, error) {
+	bucketFiles, err := bucket.ListFolderFiles(ctx, folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list folder %s files: %s", folder, err)
+	}
+	localFiles := model.NewSet[string]()
+	for _, file := range files {
+		localFiles.Add(file.Name)
+	}
+	folderFiles := slices.Clone(files)
+	for _, bucketFile := range bucketFiles {
+		if !localFiles.Contains(bucketFile) {
+			folderFiles = append(folderFiles, model.File{Name: bucketFile})
+		}
+	}
+	return folderFiles, nil
+}
+
+func GetRemoteConfig(ctx context.Context, ssm model.SSM, prefix string, bucket model.Bucket, addInputs bool) (model.Config, error) {
+	config, err := getRemoteConfigFile(ctx, bucket)
 	if err != nil {
 		return config, err
 	}
-	config, err = replaceConfigValues(ssm, prefix, config)
+	config, err = replaceConfigValues(ctx, ssm, prefix, config)
 	if err != nil {
 		return config, err
 	}
 	reserveAppsFiles(config)
-	if err = AddCertFilesFromBucket(&config, bucket); err != nil {
+	if err = AddCertFilesFromBucket(ctx, &config, bucket); err != nil {
 		return config, err
 	}
-	if err = AddStepsFilesFromBucket(&config, bucket); err != nil {
+	if err = AddStepsFilesFromBucket(ctx, &config, bucket); err != nil {
 		return config, err
 	}
-	if err = AddModuleInputFiles(&config, "", bucket.GetFile, addInputs); err != nil {
+	if err = AddModuleInputFiles(&config, "", func(file string) ([]byte, error) {
+		return bucket.GetFile(ctx, file)
+	}, addInputs); err != nil {
 		return config, err
 	}
 	return config, nil
 }
 
-func getRemoteConfigFile(bucket model.Bucket) (model.Config, error) {
-	bytes, err := bucket.GetFile(ConfigFile)
+func getRemoteConfigFile(ctx context.Context, bucket model.Bucket) (model.Config, error) {
+	bytes, err := bucket.GetFile(ctx, ConfigFile)
 	if err != nil {
 		return model.Config{}, fmt.Errorf("failed to get config: %s", err)
 	}
@@ -350,13 +471,13 @@ func getRemoteConfigFile(bucket model.Bucket) (model.Config, error) {
 	return config, nil
 }
 
-func AddCertFilesFromBucket(config *model.Config, bucket model.Bucket) error {
-	files, err := bucket.ListFolderFiles(certsFolder)
+func AddCertFilesFromBucket(ctx context.Context, config *model.Config, bucket model.Bucket) error {
+	files, err := bucket.ListFolderFiles(ctx, certsFolder)
 	if err != nil {
 		return fmt.Errorf("failed to list %s folder files: %s", certsFolder, err)
 	}
 	for _, file := range files {
-		fileBytes, err := bucket.GetFile(file)
+		fileBytes, err := bucket.GetFile(ctx, file)
 		if err != nil {
 			return fmt.Errorf("failed to get file %s: %s", file, err)
 		}
@@ -364,36 +485,38 @@ func AddCertFilesFromBucket(config *model.Config, bucket model.Bucket) error {
 			continue
 		}
 		config.Certs = append(config.Certs, model.File{
-			Name:    file,
-			Content: fileBytes,
+			Name:      file,
+			Content:   fileBytes,
+			Encrypted: sops.IsEncrypted(fileBytes),
 		})
 	}
 	return nil
 }
 
-func AddStepsFilesFromBucket(config *model.Config, bucket model.Bucket) error {
+func AddStepsFilesFromBucket(ctx context.Context, config *model.Config, bucket model.Bucket) error {
 	for i := range config.Steps {
 		step := &config.Steps[i]
-		if err := addStepFilesFromBucket(step, bucket); err != nil {
+		if err := addStepFilesFromBucket(ctx, step, bucket); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func addStepFilesFromBucket(step *model.Step, bucket model.Bucket) error {
+func addStepFilesFromBucket(ctx context.Context, step *model.Step, bucket model.Bucket) error {
 	folder := fmt.Sprintf(IncludeFormat, step.Name)
-	files, err := bucket.ListFolderFiles(folder)
+	files, err := bucket.ListFolderFiles(ctx, folder)
 	if err != nil {
 		return fmt.Errorf("failed to list folder files: %s", err)
 	}
 	for _, file := range files {
-		if step.Type == model.StepTypeTerraform && ReservedTFFiles.Contains(strings.TrimPrefix(file, folder+"/")) {
+		name := strings.TrimSuffix(strings.TrimPrefix(file, folder+"/"), sops.AgeExtension)
+		if step.Type == model.StepTypeTerraform && ReservedTFFiles.Contains(name) {
 			return fmt.Errorf("can't include files %s in step %s", ReservedTFFiles, step.Name)
-		} else if step.Type == model.StepTypeArgoCD && ReservedAppsFiles.Contains(strings.TrimPrefix(file, folder+"/")) {
+		} else if step.Type == model.StepTypeArgoCD && ReservedAppsFiles.Contains(name) {
 			return fmt.Errorf("can't include files %s in step %s", ReservedAppsFiles, step.Name)
 		}
-		fileBytes, err := bucket.GetFile(file)
+		fileBytes, err := bucket.GetFile(ctx, file)
 		if err != nil {
 			return fmt.Errorf("failed to get file %s: %s", file, err)
 		}
@@ -401,8 +524,9 @@ func addStepFilesFromBucket(step *model.Step, bucket model.Bucket) error {
 			continue
 		}
 		step.Files = append(step.Files, model.File{
-			Name:    file,
-			Content: fileBytes,
+			Name:      file,
+			Content:   fileBytes,
+			Encrypted: sops.IsEncrypted(fileBytes),
 		})
 	}
 	return nil
@@ -447,10 +571,18 @@ func processModuleInputs(stepName string, module *model.Module, basePath string,
 	}
 	module.InputsFile = strings.TrimPrefix(yamlFile, basePath)
 	module.FileContent = bytes
+	if sops.IsEncrypted(bytes) {
+		module.FileEncrypted = true // Inputs are added after decryption
+		return nil
+	}
 	if !addInputs {
 		return nil
 	}
-	err = yaml.Unmarshal(bytes, &module.Inputs)
+	return unmarshalModuleInputs(module, bytes, yamlFile)
+}
+
+func unmarshalModuleInputs(module *model.Module, bytes []byte, yamlFile string) error {
+	err := yaml.Unmarshal(bytes, &module.Inputs)
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal input file %s: %v", yamlFile, err)
 	}
@@ -461,6 +593,79 @@ func processModuleInputs(stepName string, module *model.Module, basePath string,
 	return nil
 }
 
+// DecryptConfigFiles decrypts module input files and CA certificates in memory. Included step files and CA
+// certificates are uploaded to the bucket encrypted and decrypted by the pipeline, see DecryptStepFiles.
+func DecryptConfigFiles(ctx context.Context, config *model.Config) error {
+	decryptor := sops.NewDecryptor()
+	for i := range config.Certs {
+		if err := decryptFile(decryptor, &config.Certs[i]); err != nil {
+			return err
+		}
+	}
+	for i := range config.Steps {
+		step := &config.Steps[i]
+		for j := range step.Modules {
+			module := &step.Modules[j]
+			if !module.FileEncrypted {
+				continue
+			}
+			_, content, err := decryptor.Decrypt(module.InputsFile, module.FileContent)
+			if err != nil {
+				return err
+			}
+			if err = unmarshalModuleInputs(module, content, module.InputsFile); err != nil {
+				return err
+			}
+			common.Logger(ctx).Debug(fmt.Sprintf("Decrypted module %s inputs file %s", module.Name, module.InputsFile))
+		}
+	}
+	return nil
+}
+
+// decryptFile keeps the encrypted content for the bucket, agent uses the decrypted content.
+func decryptFile(decryptor *sops.Decryptor, file *model.File) error {
+	if !file.Encrypted {
+		return nil
+	}
+	name, content, err := decryptor.Decrypt(file.Name, file.Content)
+	if err != nil {
+		return err
+	}
+	slog.Debug(fmt.Sprintf("Decrypted file %s", file.Name))
+	file.PlainName = name
+	file.Plaintext = content
+	return nil
+}
+
+// DecryptStepFiles decrypts the encrypted step files and CA certificates in the pipeline workspace step folder.
+func DecryptStepFiles(ctx context.Context, folder string) error {
+	files, err := sops.NewDecryptor().DecryptFolder(folder)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt step files in %s: %w", folder, err)
+	}
+	for _, file := range files {
+		common.Logger(ctx).Debug(fmt.Sprintf("Decrypted step file %s", file))
+	}
+	return nil
+}
+
+// hasEncryptedStepFiles reports whether the pipelines decrypt step files or CA certificates.
+func hasEncryptedStepFiles(config model.Config) bool {
+	for _, cert := range config.Certs {
+		if cert.Encrypted {
+			return true
+		}
+	}
+	for _, step := range config.Steps {
+		for _, file := range step.Files {
+			if file.Encrypted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func ProcessConfig(config *model.Config, providerType model.ProviderType) {
 	processSources(config)
 	processSteps(config, providerType)
@@ -527,6 +732,29 @@ func processStepVpcIds(step *model.Step, providerType model.ProviderType) {
 	}
 }
 
+// addRedactions masks source credentials, notification api credentials and the configured patterns in pipeline logs.
+func addRedactions(config model.Config) error {
+	for _, source := range config.Sources {
+		redact.Ignore(source.URL)
+		redact.AddValues(source.Username, source.Password)
+	}
+	for _, step := range config.Steps {
+		for _, module := range step.Modules {
+			redact.AddValues(module.HttpPassword)
+		}
+	}
+	for _, notifier := range config.Notifications {
+		if notifier.Api == nil {
+			continue
+		}
+		redact.AddValues(slices.Collect(maps.Values(notifier.Api.Headers))...)
+		if notifier.Api.OAuth != nil {
+			redact.AddValues(notifier.Api.OAuth.ClientSecret)
+		}
+	}
+	return redact.AddPatterns(config.RedactPatterns...)
+}
+
 func ValidateConfig(config model.Config, state *model.State) error {
 	if len(config.Sources) == 0 {
 		return fmt.Errorf("at least one source must be provided")
@@ -550,6 +778,31 @@ func ValidateConfig(config model.Config, state *model.State) error {
 	if err != nil {
 		return err
 	}
+	secrets := model.NewSet[string]()
+	for index, configSecret := range config.Secrets {
+		if err = validateSecret(index, configSecret); err != nil {
+			return err
+		}
+		if secrets.Contains(configSecret.Name) {
+			return fmt.Errorf("secret backend name %s is not unique", configSecret.Name)
+		}
+		secrets.Add(configSecret.Name)
+	}
+	remotes := model.NewSet[string]()
+	for index, remote := range config.Remotes {
+		if err = validateRemote(index, remote); err != nil {
+			return err
+		}
+		if remotes.Contains(strings.ToLower(remote.Prefix)) {
+			return fmt.Errorf("remote prefix %s is not unique", remote.Prefix)
+		}
+		remotes.Add(strings.ToLower(remote.Prefix))
+	}
+	for _, pattern := range config.RedactPatterns {
+		if _, err = regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("redact pattern %s is invalid: %v", pattern, err)
+		}
+	}
 	return validateSteps(config, state)
 }
 
@@ -563,6 +816,12 @@ func validateSource(index int, source model.ConfigSource) error {
 	if source.Version == "" && source.ForceVersion {
 		return fmt.Errorf("source %s force version is set but version is not", source.URL)
 	}
+	if err := validateSourceVerify(source); err != nil {
+		return err
+	}
+	if err := validateSkipVersions(source.SkipVersions); err != nil {
+		return fmt.Errorf("source %s %w", source.URL, err)
+	}
 	if source.ForceVersion {
 		return nil
 	}
@@ -581,6 +840,19 @@ func validateSource(index int, source model.ConfigSource) error {
 	return nil
 }
 
+func validateSourceVerify(source model.ConfigSource) error {
+	if source.Verify == nil {
+		return nil
+	}
+	if util.IsLocalSource(source.URL) {
+		return fmt.Errorf("source %s verify is not supported for local sources", source.URL)
+	}
+	if len(source.Verify.GPGKeys) == 0 && source.Verify.SSHAllowedSigners == "" && source.Verify.PublicKey == "" {
+		return fmt.Errorf("source %s verify must have gpg_keys, ssh_allowed_signers or public_key", source.URL)
+	}
+	return nil
+}
+
 func validateDestination(index int, destination model.ConfigDestination) error {
 	if destination.Name == "" {
 		return fmt.Errorf("%d. destination name is not set", index+1)
@@ -605,6 +877,44 @@ func validateDestination(index int, destination model.ConfigDestination) error {
 	return nil
 }
 
+func validateRemote(index int, remote model.ConfigRemote) error {
+	if remote.Prefix == "" {
+		return fmt.Errorf("%d. remote prefix is not set", index+1)
+	}
+	if strings.Contains(remote.Prefix, ".") {
+		return fmt.Errorf("%d. remote prefix %s can't contain dots", index+1, remote.Prefix)
+	}
+	if (remote.AWSRole != "" || remote.AWSRegion != "") && (remote.GCloudProject != "" || remote.GCloudLocation != "") {
+		return fmt.Errorf("%d. remote %s can't have both aws and gcloud fields set", index+1, remote.Prefix)
+	}
+	return nil
+}
+
+func validateSecret(index int, configSecret model.ConfigSecret) error {
+	if configSecret.Name == "" {
+		return fmt.Errorf("%d. secret backend name is not set", index+1)
+	}
+	if strings.Contains(configSecret.Name, ".") {
+		return fmt.Errorf("%d. secret backend name %s can't contain dots", index+1, configSecret.Name)
+	}
+	if (configSecret.Vault == nil) == (configSecret.File == nil) {
+		return fmt.Errorf("%d. secret backend %s must have exactly one of vault or file set", index+1, configSecret.Name)
+	}
+	if configSecret.File != nil && configSecret.File.Path == "" {
+		return fmt.Errorf("%d. secret backend %s file path is not set", index+1, configSecret.Name)
+	}
+	if configSecret.Vault == nil || configSecret.Vault.AppRole == nil {
+		return nil
+	}
+	if configSecret.Vault.Token != "" {
+		return fmt.Errorf("%d. secret backend %s vault token and approle can't be set together", index+1, configSecret.Name)
+	}
+	if configSecret.Vault.AppRole.RoleId == "" {
+		return fmt.Errorf("%d. secret backend %s vault approle role_id is not set", index+1, configSecret.Name)
+	}
+	return nil
+}
+
 func validateNotifiers(config model.Config) error {
 	notifiers := model.NewSet[string]()
 	hasWrapper := false
@@ -630,9 +940,14 @@ func validateNotifier(index int, notifier model.ConfigNotification) error {
 	if notifier.Name == "" {
 		return fmt.Errorf("configNotifier[%d] name is empty", index)
 	}
+	if err := validateNotifierRoute(notifier); err != nil {
+		return err
+	}
 	if (util.BoolToInt(notifier.Slack != nil) +
 		util.BoolToInt(notifier.Api != nil) +
-		util.BoolToInt(notifier.Teams != nil)) != 1 {
+		util.BoolToInt(notifier.Teams != nil) +
+		util.BoolToInt(notifier.Webhook != nil) +
+		util.BoolToInt(notifier.Email != nil)) != 1 {
 		return fmt.Errorf("configNotifier %s must have exactly 1 subtype specified", notifier.Name)
 	}
 	if notifier.Slack != nil {
@@ -641,9 +956,30 @@ func validateNotifier(index int, notifier model.ConfigNotification) error {
 	if notifier.Teams != nil {
 		return validateTeamsNotifier(notifier.Name, notifier.Teams)
 	}
+	if notifier.Webhook != nil {
+		return validateWebhookNotifier(notifier.Name, notifier.Webhook)
+	}
+	if notifier.Email != nil {
+		return validateEmailNotifier(notifier.Name, notifier.Email)
+	}
 	return validateAPINotifier(notifier.Name, notifier.Api)
 }
 
+func validateNotifierRoute(notifier model.ConfigNotification) error {
+	switch notifier.MinSeverity {
+	case "", model.SeverityInfo, model.SeverityWarning, model.SeverityError:
+	default:
+		return fmt.Errorf("configNotifier %s min_severity %s is not supported, allowed values are %s, %s and %s",
+			notifier.Name, notifier.MinSeverity, model.SeverityInfo, model.SeverityWarning, model.SeverityError)
+	}
+	for _, pattern := range slices.Concat(notifier.Steps, notifier.Modules, notifier.ExcludeSteps) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("configNotifier %s has an invalid pattern %s: %v", notifier.Name, pattern, err)
+		}
+	}
+	return nil
+}
+
 func validateSlackNotifier(name string, slack *model.Slack) error {
 	if slack.Token == "" {
 		return fmt.Errorf("configNotifier %s slack token is required", name)
@@ -661,6 +997,52 @@ func validateTeamsNotifier(name string, teams *model.Teams) error {
 	return nil
 }
 
+func validateWebhookNotifier(name string, webhook *model.Webhook) error {
+	if webhook.URL == "" {
+		return fmt.Errorf("configNotifier %s webhook url is required", name)
+	}
+	switch strings.ToUpper(webhook.Method) {
+	case "", http.MethodPost, http.MethodPut, http.MethodPatch:
+	default:
+		return fmt.Errorf("configNotifier %s webhook method %s is not supported, allowed methods are POST, PUT and PATCH",
+			name, webhook.Method)
+	}
+	for messageType := range webhook.Templates {
+		switch messageType {
+		case model.MessageTypeStarted, model.MessageTypeProgress, model.MessageTypeApprovals, model.MessageTypeSuccess,
+			model.MessageTypeFailure, model.MessageTypeModules, model.MessageTypeSchedule, model.MessageTypeSources:
+		default:
+			return fmt.Errorf("configNotifier %s webhook template has an invalid message type %s", name, messageType)
+		}
+	}
+	return nil
+}
+
+func validateEmailNotifier(name string, email *model.Email) error {
+	if email.Host == "" {
+		return fmt.Errorf("configNotifier %s email host is required", name)
+	}
+	if email.Port < 0 || email.Port > 65535 {
+		return fmt.Errorf("configNotifier %s email port %d is invalid", name, email.Port)
+	}
+	switch email.Security {
+	case "", model.EmailSecurityStartTLS, model.EmailSecurityTLS, model.EmailSecurityNone:
+	default:
+		return fmt.Errorf("configNotifier %s email security %s is not supported, allowed values are %s, %s and %s", name,
+			email.Security, model.EmailSecurityStartTLS, model.EmailSecurityTLS, model.EmailSecurityNone)
+	}
+	if email.Password != "" && email.Username == "" {
+		return fmt.Errorf("configNotifier %s email username is required when password is set", name)
+	}
+	if email.From == "" {
+		return fmt.Errorf("configNotifier %s email from is required", name)
+	}
+	if len(email.To) == 0 {
+		return fmt.Errorf("configNotifier %s email to is required", name)
+	}
+	return nil
+}
+
 func validateAPINotifier(name string, api *model.NotificationApi) error {
 	if api.URL == "" {
 		return fmt.Errorf("configNotifier %s api url is required", name)
@@ -752,6 +1134,18 @@ func validateModule(module model.Module, stepName string) error {
 	if module.Source == "" {
 		return fmt.Errorf("module Source is not set for module %s in step %s", module.Name, stepName)
 	}
+	if err := validateSkipVersions(module.SkipVersions); err != nil {
+		return fmt.Errorf("module %s in step %s %w", module.Name, stepName, err)
+	}
+	return nil
+}
+
+func validateSkipVersions(skipVersions []string) error {
+	for _, skipVersion := range skipVersions {
+		if _, err := version.NewVersion(skipVersion); err != nil {
+			return fmt.Errorf("skip version %s must follow semantic versioning: %s", skipVersion, err)
+		}
+	}
 	return nil
 }
 
@@ -854,27 +1248,27 @@ func addNewSteps(config model.Config, state *model.State) {
 	}
 }
 
-func removeUnusedSteps(prefix string, config model.Config, state *model.State, bucket model.Bucket) {
+func removeUnusedSteps(ctx context.Context, prefix string, config model.Config, state *model.State, bucket model.Bucket) {
 	for i := len(state.Steps) - 1; i >= 0; i-- {
 		stepState := state.Steps[i]
 		stepFound := false
 		for _, step := range config.Steps {
 			if stepState.Name == step.Name {
 				stepFound = true
-				removeUnusedModules(step, stepState, bucket)
+				removeUnusedModules(ctx, step, stepState, bucket)
 				break
 			}
 		}
 		if !stepFound {
 			state.Steps = append(state.Steps[:i], state.Steps[i+1:]...)
-			log.Printf("Removing unused step %s files", stepState.Name)
-			removeUnusedFiles(bucket, fmt.Sprintf("steps/%s-%s", prefix, stepState.Name))
-			removeUnusedFiles(bucket, fmt.Sprintf("config/%s", stepState.Name))
+			slog.Info(fmt.Sprintf("Removing unused step %s files", stepState.Name))
+			removeUnusedFiles(ctx, bucket, fmt.Sprintf("steps/%s-%s", prefix, stepState.Name))
+			removeUnusedFiles(ctx, bucket, fmt.Sprintf("config/%s", stepState.Name))
 		}
 	}
 }
 
-func removeUnusedModules(step model.Step, stepState *model.StateStep, bucket model.Bucket) {
+func removeUnusedModules(ctx context.Context, step model.Step, stepState *model.StateStep, bucket model.Bucket) {
 	for i := len(stepState.Modules) - 1; i >= 0; i-- {
 		moduleState := stepState.Modules[i]
 		moduleFound := false
@@ -887,18 +1281,18 @@ func removeUnusedModules(step model.Step, stepState *model.StateStep, bucket mod
 		if !moduleFound {
 			stepState.Modules = append(stepState.Modules[:i], stepState.Modules[i+1:]...)
 			inputsFile := fmt.Sprintf("config/%s/%s.yaml", step.Name, moduleState.Name)
-			_ = bucket.DeleteFile(inputsFile)
+			_ = bucket.DeleteFile(ctx, inputsFile)
 		}
 	}
 }
 
-func removeUnusedFiles(bucket model.Bucket, folder string) {
-	stepFiles, err := bucket.ListFolderFiles(folder)
+func removeUnusedFiles(ctx context.Context, bucket model.Bucket, folder string) {
+	stepFiles, err := bucket.ListFolderFiles(ctx, folder)
 	if err != nil {
 		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to list files in unused folder %s: %v", folder, err)))
 		return
 	}
-	err = bucket.DeleteFiles(stepFiles)
+	err = bucket.DeleteFiles(ctx, stepFiles)
 	if err != nil {
 		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete unused files in folder %s: %v", folder, err)))
 	}
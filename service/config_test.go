@@ -0,0 +1,135 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+func TestValidateRemotes(t *testing.T) {
+	tests := []struct {
+		name    string
+		remotes []model.ConfigRemote
+		err     string
+	}{
+		{
+			name: "remotes",
+			remotes: []model.ConfigRemote{{Prefix: "net", AWSRole: "arn:aws:iam::1:role/agent"},
+				{Prefix: "shared", GCloudProject: "project"}},
+		},
+		{name: "no prefix", remotes: []model.ConfigRemote{{}}, err: "1. remote prefix is not set"},
+		{name: "dotted prefix", remotes: []model.ConfigRemote{{Prefix: "net.main"}}, err: "can't contain dots"},
+		{
+			name:    "aws and gcloud",
+			remotes: []model.ConfigRemote{{Prefix: "net", AWSRegion: "eu-north-1", GCloudLocation: "europe-north1"}},
+			err:     "can't have both aws and gcloud fields set",
+		},
+		{
+			name:    "duplicate prefix",
+			remotes: []model.ConfigRemote{{Prefix: "net"}, {Prefix: "NET"}},
+			err:     "remote prefix NET is not unique",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := model.Config{
+				Sources: []model.ConfigSource{{URL: "https://github.com/entigolabs/entigo-infralib-release"}},
+				Remotes: test.remotes,
+			}
+			err := ValidateConfig(config, nil)
+			if test.err == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Fatalf("expected error %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestGetLocalConfigChanges(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yaml")
+	writeTestFile(t, configFile, "prefix: dev\nsteps:\n  - name: net\n    type: terraform\n    modules:\n"+
+		"      - name: vpc\n        source: aws/vpc\n      - name: dns\n        source: aws/route53\n")
+	writeTestFile(t, filepath.Join(dir, "config", "net", "vpc.yaml"), "cidr: 10.0.0.0/16\n")
+	writeTestFile(t, filepath.Join(dir, "config", "net", "include", "locals.tf"), "locals {}\n")
+	bucket := &memoryBucket{files: map[string][]byte{
+		"config/net/dns.yaml":         []byte("zone: example.com\n"),
+		"config/net/include/old.tf":   []byte("locals {}\n"),
+		"ca-certificates/removed.pem": []byte("cert"),
+	}}
+
+	config, changes, err := GetLocalConfigChanges(t.Context(), bucket, configFile)
+	if err != nil {
+		t.Fatalf("failed to get changes: %v", err)
+	}
+	expected := map[string]string{
+		ConfigFile:                     "added",
+		"ca-certificates/removed.pem":  "deleted",
+		"config/net/include/locals.tf": "added",
+		"config/net/include/old.tf":    "deleted",
+		"config/net/vpc.yaml":          "added",
+		"config/net/dns.yaml":          "deleted",
+	}
+	names := make([]string, 0, len(changes))
+	for _, change := range changes {
+		names = append(names, change.Name)
+		changeType := "modified"
+		if change.Remote == nil {
+			changeType = "added"
+		} else if change.Local == nil {
+			changeType = "deleted"
+		}
+		if expected[change.Name] != changeType {
+			t.Fatalf("expected %s to be %s, got %s", change.Name, expected[change.Name], changeType)
+		}
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %d changes, got %v", len(expected), names)
+	}
+
+	if err = PutConfig(t.Context(), bucket, config); err != nil {
+		t.Fatalf("failed to put config: %v", err)
+	}
+	if err = PutAdditionalFiles(t.Context(), bucket, config); err != nil {
+		t.Fatalf("failed to put files: %v", err)
+	}
+	_, changes, err = GetLocalConfigChanges(t.Context(), bucket, configFile)
+	if err != nil {
+		t.Fatalf("failed to get changes after push: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes after push, got %+v", changes)
+	}
+
+	writeTestFile(t, filepath.Join(dir, "config", "net", "vpc.yaml"), "cidr: 10.1.0.0/16\n")
+	_, changes, err = GetLocalConfigChanges(t.Context(), bucket, configFile)
+	if err != nil {
+		t.Fatalf("failed to get changes after edit: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Name != "config/net/vpc.yaml" ||
+		string(changes[0].Remote) != "cidr: 10.0.0.0/16\n" || string(changes[0].Local) != "cidr: 10.1.0.0/16\n" {
+		t.Fatalf("expected the modified inputs file, got %+v", changes)
+	}
+}
+
+func TestGetLocalConfigChangesMissingBucket(t *testing.T) {
+	_, _, err := GetLocalConfigChanges(t.Context(), &memoryBucket{}, "config.yaml")
+	if err == nil || !strings.Contains(err.Error(), "bucket doesn't exist") {
+		t.Fatalf("expected missing bucket error, got %v", err)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
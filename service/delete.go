@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
@@ -19,6 +18,7 @@ type Deleter interface {
 }
 
 type deleter struct {
+	ctx                  context.Context
 	config               model.Config
 	steps                []model.Step
 	provider             model.CloudProvider
@@ -37,29 +37,34 @@ func NewDeleter(ctx context.Context, flags *common.Flags) (Deleter, error) {
 	if err != nil {
 		return nil, err
 	}
-	repo, err := resources.GetBucket().GetRepoMetadata()
+	repo, err := resources.GetBucket().GetRepoMetadata(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get repository metadata: %s", err)
 	}
 	if repo == nil && flags.Config == "" {
 		return &deleter{
+			ctx:       ctx,
 			config:    model.Config{},
 			provider:  provider,
 			resources: resources,
 		}, nil
 	}
-	config, err := getBaseConfig(resources.GetCloudPrefix(), flags.Config, resources.GetBucket())
+	config, err := getBaseConfig(ctx, resources.GetCloudPrefix(), flags.Config, resources.GetBucket())
 	if err != nil {
 		return nil, err
 	}
 	if err = ValidateConfig(config, nil); err != nil {
 		return nil, fmt.Errorf("failed to validate config: %v", err)
 	}
+	if err = addRedactions(config); err != nil {
+		return nil, err
+	}
 	steps, err := getRunnableSteps(config, flags.Steps)
 	if err != nil {
 		return nil, err
 	}
 	return &deleter{
+		ctx:                  ctx,
 		config:               config,
 		steps:                steps,
 		provider:             provider,
@@ -70,18 +75,18 @@ func NewDeleter(ctx context.Context, flags *common.Flags) (Deleter, error) {
 	}, nil
 }
 
-func getBaseConfig(prefix, configFile string, bucket model.Bucket) (model.Config, error) {
+func getBaseConfig(ctx context.Context, prefix, configFile string, bucket model.Bucket) (model.Config, error) {
 	var config model.Config
 	var err error
 	if configFile != "" {
 		config, err = getLocalConfigFile(configFile)
 	} else {
-		config, err = getRemoteConfigFile(bucket)
+		config, err = getRemoteConfigFile(ctx, bucket)
 	}
 	if err != nil {
 		return config, err
 	}
-	return replaceConfigValues(nil, prefix, config)
+	return replaceConfigValues(ctx, nil, prefix, config)
 }
 
 func (d *deleter) Delete() error {
@@ -99,6 +104,8 @@ func (d *deleter) Delete() error {
 	}
 	d.deleteSourceSecrets()
 	d.deleteSecret(model.WrapperConfigSecretName(d.resources.GetCloudPrefix()))
+	d.deleteSecret(model.SopsAgeKeySecretName(d.resources.GetCloudPrefix()))
+	d.deleteSecret(model.RedactValuesSecretName(d.resources.GetCloudPrefix()))
 	return d.provider.DeleteResources(d.deleteBucket, d.deleteServiceAccount)
 }
 
@@ -115,21 +122,21 @@ func (d *deleter) deleteSourceSecrets() {
 }
 
 func (d *deleter) deleteSecret(secret string) {
-	err := d.resources.GetSSM().DeleteSecret(secret)
+	err := d.resources.GetSSM().DeleteSecret(d.ctx, secret)
 	if err != nil {
 		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to delete secret %s: %s", secret, err)))
 	}
 }
 
 func (d *deleter) Destroy() error {
-	state, err := getLatestState(d.resources.GetBucket())
+	state, err := getLatestState(d.ctx, d.resources.GetBucket())
 	if err != nil {
 		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to get latest state: %v", err)))
 	}
 	for i := len(d.steps) - 1; i >= 0; i-- {
 		step := d.steps[i]
 		projectName := fmt.Sprintf("%s-%s", d.resources.GetCloudPrefix(), step.Name)
-		log.Printf("Starting destroy execution pipeline for step %s\n", step.Name)
+		slog.Info(fmt.Sprintf("Starting destroy execution pipeline for step %s", step.Name))
 		step.Approve = model.ApproveForce
 		var err error
 		if d.localPipeline != nil {
@@ -145,7 +152,7 @@ func (d *deleter) Destroy() error {
 			}
 			return fmt.Errorf("failed to run destroy pipeline %s: %s", projectName, err)
 		}
-		log.Printf("Successfully executed destroy pipeline for step %s\n", step.Name)
+		slog.Info(fmt.Sprintf("Successfully executed destroy pipeline for step %s", step.Name))
 		if err = d.removeStepFromState(state, step); err != nil {
 			slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to remove step %s from state: %v", step.Name, err)))
 		}
@@ -187,5 +194,5 @@ func (d *deleter) removeStepFromState(state *model.State, step model.Step) error
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
-	return d.resources.GetBucket().PutFile(stateFile, stateBytes)
+	return d.resources.GetBucket().PutFile(d.ctx, stateFile, stateBytes)
 }
@@ -1,31 +1,31 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"log/slog"
 
 	"github.com/entigolabs/entigo-infralib-agent/aws"
 	"github.com/entigolabs/entigo-infralib-agent/model"
 )
 
-func GetEncryptionKey(providerType model.ProviderType, prefix, configFlag string, bucket model.Bucket) (string, error) {
+func GetEncryptionKey(ctx context.Context, providerType model.ProviderType, prefix, configFlag string, bucket model.Bucket) (string, error) {
 	if providerType != model.AWS {
 		return "", nil // TODO Remove when GCP encryption is implemented
 	}
-	exists, err := bucket.BucketExists()
+	exists, err := bucket.BucketExists(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to check bucket existence: %s", err)
 	}
 	if !exists {
 		return "", nil
 	}
-	config, err := GetBaseConfig(prefix, configFlag, bucket)
+	config, err := GetBaseConfig(ctx, prefix, configFlag, bucket)
 	if err != nil {
 		return "", err
 	}
-	moduleName, outputs, err := GetEncryptionOutputs(config, prefix, bucket)
+	moduleName, outputs, err := GetEncryptionOutputs(ctx, config, prefix, bucket)
 	if err != nil {
 		return "", fmt.Errorf("failed to get encryption outputs: %s", err)
 	}
@@ -39,13 +39,13 @@ func GetEncryptionKey(providerType model.ProviderType, prefix, configFlag string
 	return keyId, nil
 }
 
-func GetEncryptionOutputs(config model.Config, prefix string, bucket model.Bucket) (string, map[string]model.TFOutput, error) {
+func GetEncryptionOutputs(ctx context.Context, config model.Config, prefix string, bucket model.Bucket) (string, map[string]model.TFOutput, error) {
 	step, module := getEncryptionModule(config)
 	if step == nil || module == nil {
 		return "", nil, nil
 	}
-	log.Printf("Processing encryption based on %s module %s\n", step.Name, module.Name)
-	outputs, err := getModuleOutputs(*step, prefix, bucket)
+	slog.Info(fmt.Sprintf("Processing encryption based on %s module %s", step.Name, module.Name))
+	outputs, err := getModuleOutputs(ctx, *step, prefix, bucket)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to get outputs for %s: %v", step.Name, err)
 	}
@@ -65,9 +65,9 @@ func getEncryptionModule(config model.Config) (*model.Step, *model.Module) {
 	return nil, nil
 }
 
-func getModuleOutputs(step model.Step, prefix string, bucket model.Bucket) (map[string]model.TFOutput, error) {
+func getModuleOutputs(ctx context.Context, step model.Step, prefix string, bucket model.Bucket) (map[string]model.TFOutput, error) {
 	filePath := fmt.Sprintf("%s-%s/%s", prefix, step.Name, terraformOutput)
-	file, err := bucket.GetFile(filePath)
+	file, err := bucket.GetFile(ctx, filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/entigolabs/entigo-infralib-agent/redact"
+	"gopkg.in/yaml.v3"
+)
+
+type tagFunction struct {
+	Name string
+	Args []string
+}
+
+type tagFunctions []tagFunction
+
+type tagFunctionDef struct {
+	args int
+	call func(value interface{}, args []string) (interface{}, error)
+}
+
+var tagFunctionDefs = map[string]tagFunctionDef{
+	"default": {args: 1, call: defaultFunction},
+	"join":    {args: 1, call: joinFunction},
+	"split":   {args: 1, call: splitFunction},
+	"upper":   {args: 0, call: stringFunction(strings.ToUpper)},
+	"lower":   {args: 0, call: stringFunction(strings.ToLower)},
+	"replace": {args: 2, call: replaceFunction},
+	"b64enc":  {args: 0, call: stringFunction(encodeBase64)},
+	"toJson":  {args: 0, call: toJsonFunction},
+	"toYaml":  {args: 0, call: toYamlFunction},
+	"index":   {args: 1, call: indexFunction},
+}
+
+// parseTagFunction parses a function segment of a replace tag, e.g. `join " "`.
+func parseTagFunction(segment, replaceTag string) (tagFunction, error) {
+	tokens, err := splitQuoted(segment, ' ')
+	if err != nil {
+		return tagFunction{}, fmt.Errorf("invalid function %s in replace tag %s: %w", segment, replaceTag, err)
+	}
+	function := tagFunction{Name: tokens[0]}
+	def, found := tagFunctionDefs[function.Name]
+	if !found {
+		return tagFunction{}, fmt.Errorf("unknown function %s in replace tag %s, supported functions are: %s",
+			function.Name, replaceTag, strings.Join(slices.Sorted(maps.Keys(tagFunctionDefs)), ", "))
+	}
+	for _, token := range tokens[1:] {
+		arg, err := unquoteArg(token)
+		if err != nil {
+			return tagFunction{}, fmt.Errorf("invalid argument %s for function %s in replace tag %s: %w",
+				token, function.Name, replaceTag, err)
+		}
+		function.Args = append(function.Args, arg)
+	}
+	if len(function.Args) != def.args {
+		return tagFunction{}, fmt.Errorf("function %s expects %d arguments, got %d in replace tag %s",
+			function.Name, def.args, len(function.Args), replaceTag)
+	}
+	return function, nil
+}
+
+// apply runs the functions in order on the replacement value. Lists are represented as comma separated values.
+// Results of sensitive values are masked in logs too, as the redact filter only knows the original values.
+func (f tagFunctions) apply(value string) (string, error) {
+	if len(f) == 0 {
+		return value, nil
+	}
+	var result interface{} = value
+	for _, function := range f {
+		var err error
+		result, err = tagFunctionDefs[function.Name].call(result, function.Args)
+		if err != nil {
+			return "", fmt.Errorf("function %s failed: %w", function.Name, err)
+		}
+	}
+	output, err := valueToString(result)
+	if err != nil {
+		return "", err
+	}
+	if redact.Contains(value) {
+		redact.AddValues(output)
+	}
+	return output, nil
+}
+
+func defaultFunction(value interface{}, args []string) (interface{}, error) {
+	switch v := toStructured(value).(type) {
+	case nil:
+		return args[0], nil
+	case string:
+		if v == "" {
+			return args[0], nil
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return args[0], nil
+		}
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return args[0], nil
+		}
+	}
+	return value, nil
+}
+
+func joinFunction(value interface{}, args []string) (interface{}, error) {
+	values := make([]string, 0)
+	for _, item := range toList(value) {
+		itemValue, err := valueToString(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, itemValue)
+	}
+	return strings.Join(values, args[0]), nil
+}
+
+func splitFunction(value interface{}, args []string) (interface{}, error) {
+	str, err := valueToString(value)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, 0)
+	if str == "" {
+		return values, nil
+	}
+	for _, item := range strings.Split(str, args[0]) {
+		values = append(values, item)
+	}
+	return values, nil
+}
+
+func replaceFunction(value interface{}, args []string) (interface{}, error) {
+	str, err := valueToString(value)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ReplaceAll(str, args[0], args[1]), nil
+}
+
+func stringFunction(call func(string) string) func(interface{}, []string) (interface{}, error) {
+	return func(value interface{}, _ []string) (interface{}, error) {
+		str, err := valueToString(value)
+		if err != nil {
+			return nil, err
+		}
+		return call(str), nil
+	}
+}
+
+func encodeBase64(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func toJsonFunction(value interface{}, _ []string) (interface{}, error) {
+	bytes, err := json.Marshal(toStructured(value))
+	if err != nil {
+		return nil, err
+	}
+	return string(bytes), nil
+}
+
+func toYamlFunction(value interface{}, _ []string) (interface{}, error) {
+	bytes, err := yaml.Marshal(toStructured(value))
+	if err != nil {
+		return nil, err
+	}
+	return strings.TrimSuffix(string(bytes), "\n"), nil
+}
+
+func indexFunction(value interface{}, args []string) (interface{}, error) {
+	if v, ok := toStructured(value).(map[string]interface{}); ok {
+		item, found := v[args[0]]
+		if !found {
+			return nil, fmt.Errorf("key %s not found", args[0])
+		}
+		return item, nil
+	}
+	index, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("list index %s is not a number", args[0])
+	}
+	values := toList(value)
+	if index < 0 || index >= len(values) {
+		return nil, fmt.Errorf("index %d out of range, list has %d items", index, len(values))
+	}
+	return values[index], nil
+}
+
+// toStructured converts json and yaml lists and maps and the quoted comma separated items of replaced list outputs
+// into lists and maps. Other values stay strings, use split for separated values.
+func toStructured(value interface{}) interface{} {
+	str, ok := value.(string)
+	if !ok {
+		return value
+	}
+	trimmed := strings.TrimSpace(str)
+	if strings.HasPrefix(trimmed, `"`) {
+		if list, ok := parseQuotedList(trimmed); ok && len(list) > 1 {
+			return list
+		}
+		return value
+	}
+	// Block yaml maps must be multiline, so single line values like "key: value" stay strings
+	if !strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "- ") &&
+		!strings.Contains(trimmed, "\n") {
+		return value
+	}
+	var structured interface{}
+	if err := yaml.Unmarshal([]byte(trimmed), &structured); err != nil {
+		return value
+	}
+	switch structured.(type) {
+	case []interface{}, map[string]interface{}:
+		return structured
+	}
+	return value
+}
+
+// parseQuotedList parses the `"a","b"` format of replaced list outputs, every item must be a quoted string.
+func parseQuotedList(value string) ([]interface{}, bool) {
+	var items []interface{}
+	if err := json.Unmarshal([]byte("["+value+"]"), &items); err != nil {
+		return nil, false
+	}
+	for _, item := range items {
+		if _, ok := item.(string); !ok {
+			return nil, false
+		}
+	}
+	return items, true
+}
+
+func toList(value interface{}) []interface{} {
+	switch v := toStructured(value).(type) {
+	case []interface{}:
+		return v
+	case nil:
+		return []interface{}{}
+	case string:
+		if v == "" {
+			return []interface{}{}
+		}
+		if trimmed := strings.TrimSpace(v); strings.HasPrefix(trimmed, `"`) {
+			if list, ok := parseQuotedList(trimmed); ok {
+				return list
+			}
+		}
+		return []interface{}{v}
+	default:
+		return []interface{}{v}
+	}
+}
+
+func valueToString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			itemValue, err := valueToString(item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, itemValue)
+		}
+		return strings.Join(values, ","), nil
+	default:
+		bytes, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes), nil
+	}
+}
+
+// splitQuoted splits the value by the separator, ignoring separators inside double-quoted strings.
+func splitQuoted(value string, separator rune) ([]string, error) {
+	var parts []string
+	var current strings.Builder
+	quoted, escaped := false, false
+	for _, char := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && char == '\\':
+			escaped = true
+		case char == '"':
+			quoted = !quoted
+		case !quoted && char == separator:
+			parts = append(parts, current.String())
+			current.Reset()
+			continue
+		}
+		current.WriteRune(char)
+	}
+	if quoted {
+		return nil, errors.New("unterminated quoted string")
+	}
+	parts = append(parts, current.String())
+	if separator != ' ' {
+		return parts, nil
+	}
+	tokens := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			tokens = append(tokens, part)
+		}
+	}
+	return tokens, nil
+}
+
+func unquoteArg(arg string) (string, error) {
+	if !strings.HasPrefix(arg, `"`) {
+		return arg, nil
+	}
+	return strconv.Unquote(arg)
+}
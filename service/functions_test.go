@@ -0,0 +1,237 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/redact"
+)
+
+func parseTestTag(t *testing.T, tag string) ([]keyType, tagFunctions) {
+	t.Helper()
+	match := replaceRegex.FindStringSubmatch(tag)
+	if match == nil {
+		t.Fatalf("tag %s does not match the replace regex", tag)
+	}
+	keyTypes, functions, err := parseReplaceTag(match)
+	if err != nil {
+		t.Fatalf("failed to parse tag %s: %v", tag, err)
+	}
+	return keyTypes, functions
+}
+
+func TestParseReplaceTag(t *testing.T) {
+	tests := []struct {
+		name      string
+		tag       string
+		keyTypes  []keyType
+		functions tagFunctions
+	}{
+		{
+			name:     "key only",
+			tag:      "{{ .output.net.vpc.vpc_id }}",
+			keyTypes: []keyType{{ReplaceKey: "output.net.vpc.vpc_id", ReplaceType: "output"}},
+		},
+		{
+			name: "fallback values",
+			tag:  `{{ .optout.net.vpc.key | .config.prefix | "none" }}`,
+			keyTypes: []keyType{
+				{ReplaceKey: "optout.net.vpc.key", ReplaceType: "optout"},
+				{ReplaceKey: "config.prefix", ReplaceType: "config"},
+				{ReplaceKey: `"none"`},
+			},
+		},
+		{
+			name:      "functions without arguments",
+			tag:       "{{ .config.prefix | upper | b64enc }}",
+			keyTypes:  []keyType{{ReplaceKey: "config.prefix", ReplaceType: "config"}},
+			functions: tagFunctions{{Name: "upper"}, {Name: "b64enc"}},
+		},
+		{
+			name:      "quoted arguments with spaces and separators",
+			tag:       `{{ .config.prefix | join " | " | replace "a b" "c\"d" }}`,
+			keyTypes:  []keyType{{ReplaceKey: "config.prefix", ReplaceType: "config"}},
+			functions: tagFunctions{{Name: "join", Args: []string{" | "}}, {Name: "replace", Args: []string{"a b", `c"d`}}},
+		},
+		{
+			name:      "unquoted argument",
+			tag:       "{{ .output.net.vpc.subnets | index 1 }}",
+			keyTypes:  []keyType{{ReplaceKey: "output.net.vpc.subnets", ReplaceType: "output"}},
+			functions: tagFunctions{{Name: "index", Args: []string{"1"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyTypes, functions := parseTestTag(t, tt.tag)
+			if !reflect.DeepEqual(keyTypes, tt.keyTypes) {
+				t.Errorf("expected keys %v, got %v", tt.keyTypes, keyTypes)
+			}
+			if !reflect.DeepEqual(functions, tt.functions) {
+				t.Errorf("expected functions %v, got %v", tt.functions, functions)
+			}
+		})
+	}
+}
+
+func TestParseReplaceTagErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		err  string
+	}{
+		{name: "unknown function", tag: "{{ .config.prefix | title }}", err: "unknown function title"},
+		{name: "missing argument", tag: "{{ .config.prefix | join }}", err: "expects 1 arguments, got 0"},
+		{name: "too many arguments", tag: "{{ .config.prefix | upper x }}", err: "expects 0 arguments, got 1"},
+		{name: "unterminated quote", tag: `{{ .config.prefix | join "x }}`, err: "unterminated quoted string"},
+		{name: "invalid escape", tag: `{{ .config.prefix | join "\q" }}`, err: "invalid argument"},
+		{name: "key after function", tag: "{{ .config.prefix | upper | .config.region }}", err: "must be before functions"},
+		{name: "functions only", tag: "{{ upper }}", err: "has no keys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := replaceRegex.FindStringSubmatch(tt.tag)
+			if match == nil {
+				t.Fatalf("tag %s does not match the replace regex", tt.tag)
+			}
+			_, _, err := parseReplaceTag(match)
+			if err == nil {
+				t.Fatalf("expected error containing %q", tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestApplyFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      string
+		value    string
+		expected string
+	}{
+		{name: "no functions", tag: "{{ .config.prefix }}", value: "a,b", expected: "a,b"},
+		{name: "default on empty", tag: `{{ .config.prefix | default "none" }}`, value: "", expected: "none"},
+		{name: "default on value", tag: `{{ .config.prefix | default "none" }}`, value: "value", expected: "value"},
+		{name: "default on empty list", tag: `{{ .config.prefix | default "none" }}`, value: "[]", expected: "none"},
+		{name: "default on empty map", tag: `{{ .config.prefix | default "none" }}`, value: "{}", expected: "none"},
+		{name: "default then upper", tag: `{{ .config.prefix | default "none" | upper }}`, value: "", expected: "NONE"},
+		{name: "join list output", tag: `{{ .config.prefix | join " " }}`, value: `"a","b"`, expected: "a b"},
+		{name: "join single list output item", tag: `{{ .config.prefix | join " " }}`, value: `"a"`, expected: "a"},
+		{name: "join json list", tag: `{{ .config.prefix | join ";" }}`, value: `["a", "b"]`, expected: "a;b"},
+		{name: "join yaml list", tag: `{{ .config.prefix | join ";" }}`, value: "- a\n- b", expected: "a;b"},
+		{name: "join keeps commas in strings", tag: `{{ .config.prefix | join ";" }}`, value: "a,b", expected: "a,b"},
+		{name: "join empty", tag: `{{ .config.prefix | join ";" }}`, value: "", expected: ""},
+		{name: "split and join", tag: `{{ .config.prefix | split "," | join " " }}`, value: "a,b", expected: "a b"},
+		{name: "split and index", tag: `{{ .config.prefix | split ";" | index 1 }}`, value: "a;b;c", expected: "b"},
+		{name: "index list output", tag: "{{ .config.prefix | index 1 }}", value: `"a","b"`, expected: "b"},
+		{name: "index nested json", tag: `{{ .config.prefix | index "a" | index "b" }}`, value: `{"a": {"b": "c"}}`,
+			expected: "c"},
+		{name: "index nested list", tag: `{{ .config.prefix | index "a" | index 0 | toJson }}`,
+			value: `{"a": [{"b": 1}]}`, expected: `{"b":1}`},
+		{name: "to json list output", tag: "{{ .config.prefix | toJson }}", value: `"a","b"`, expected: `["a","b"]`},
+		{name: "to json string with commas", tag: "{{ .config.prefix | toJson }}", value: "a,b", expected: `"a,b"`},
+		{name: "to json key value string", tag: "{{ .config.prefix | toJson }}", value: "key: value",
+			expected: `"key: value"`},
+		{name: "to yaml json map", tag: "{{ .config.prefix | toYaml }}", value: `{"a": "b"}`, expected: "a: b"},
+		{name: "replace", tag: `{{ .config.prefix | replace "-" "_" | lower }}`, value: "A-B", expected: "a_b"},
+		{name: "base64", tag: "{{ .config.prefix | b64enc }}", value: "value", expected: "dmFsdWU="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, functions := parseTestTag(t, tt.tag)
+			result, err := functions.apply(tt.value)
+			if err != nil {
+				t.Fatalf("failed to apply functions: %v", err)
+			}
+			if result != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestApplyFunctionsRedactsSensitiveValues(t *testing.T) {
+	redact.AddValues("secret-Value-031")
+	tests := []struct {
+		name     string
+		tag      string
+		value    string
+		redacted bool
+	}{
+		{name: "base64", tag: "{{ .config.prefix | b64enc }}", value: "secret-Value-031", redacted: true},
+		{name: "upper", tag: "{{ .config.prefix | upper }}", value: "secret-Value-031", redacted: true},
+		{name: "lower", tag: "{{ .config.prefix | lower }}", value: "secret-Value-031", redacted: true},
+		{name: "replace", tag: `{{ .config.prefix | replace "-" "_" }}`, value: "secret-Value-031", redacted: true},
+		{name: "list item", tag: "{{ .config.prefix | toJson }}", value: `"plain","secret-Value-031"`, redacted: true},
+		{name: "plain value", tag: "{{ .config.prefix | upper }}", value: "plain-value-031"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, functions := parseTestTag(t, tt.tag)
+			result, err := functions.apply(tt.value)
+			if err != nil {
+				t.Fatalf("failed to apply functions: %v", err)
+			}
+			if redacted := redact.Line(result) != result; redacted != tt.redacted {
+				t.Fatalf("expected %q redacted to be %t", result, tt.redacted)
+			}
+		})
+	}
+}
+
+func TestApplyFunctionErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		tag   string
+		value string
+		err   string
+	}{
+		{name: "index out of range", tag: "{{ .config.prefix | index 2 }}", value: `"a","b"`, err: "out of range"},
+		{name: "index string", tag: "{{ .config.prefix | index 1 }}", value: "a,b", err: "function index failed"},
+		{name: "missing map key", tag: `{{ .config.prefix | index "b" }}`, value: `{"a": "b"}`, err: "function index failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, functions := parseTestTag(t, tt.tag)
+			_, err := functions.apply(tt.value)
+			if err == nil {
+				t.Fatalf("expected error containing %q", tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestReplaceModuleInputsValues(t *testing.T) {
+	module := model.Module{Name: "vpc"}
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "name", content: "name: {{ .module.name | upper }}", expected: "name: VPC"},
+		{name: "default on empty source", content: `source: {{ .module.source | default "local" }}`,
+			expected: "source: local"},
+		{name: "empty source without functions", content: "source: {{ .module.source }}",
+			expected: "source: {{ .module.source }}"},
+		{name: "other tags", content: "prefix: {{ .config.prefix }}", expected: "prefix: {{ .config.prefix }}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := replaceModuleInputsValues(module, tt.content,
+				replaceRegex.FindAllStringSubmatch(tt.content, -1))
+			if err != nil {
+				t.Fatalf("failed to replace module values: %v", err)
+			}
+			if result != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
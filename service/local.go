@@ -6,8 +6,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"log"
-	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -17,7 +15,9 @@ import (
 
 	"github.com/entigolabs/entigo-infralib-agent/argocd"
 	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/metrics"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/redact"
 	"github.com/entigolabs/entigo-infralib-agent/terraform"
 	"github.com/entigolabs/entigo-infralib-agent/util"
 	"github.com/entigolabs/entigo-infralib-agent/wrapper"
@@ -33,6 +33,7 @@ type LocalPipeline struct {
 	project        string
 	zone           string
 	bucket         string
+	storage        model.Bucket
 	enableOpenTofu bool
 	pipeline       common.Pipeline
 	inputLock      sync.Mutex
@@ -63,51 +64,100 @@ func NewLocalPipeline(ctx context.Context, resources model.Resources, pipeline c
 		project:        project,
 		zone:           zone,
 		bucket:         resources.GetBucketName(),
+		storage:        resources.GetBucket(),
 		pipeline:       pipeline,
 		manager:        manager,
 		enableOpenTofu: config.EnableOpenTofu,
-		wrapper:        getWrapperConfig(config.Notifications),
+		wrapper:        getWrapperConfig(config),
 		campaignId:     campaignId,
 	}
 }
 
-func (l *LocalPipeline) executeLocalPipeline(step model.Step, autoApprove bool, sourceAuths map[string]model.SourceAuth, approve model.ManualApprove) error {
+// executeLocalPipeline returns the resource timings of the apply, sorted from slowest to fastest.
+func (l *LocalPipeline) executeLocalPipeline(ctx context.Context, step model.Step, autoApprove bool, sourceAuths map[string]model.SourceAuth, approve model.ManualApprove) ([]model.ResourceTiming, error) {
 	prefixStep := fmt.Sprintf("%s-%s", l.prefix, step.Name)
-	log.Printf("Starting local pipeline %s", prefixStep)
+	common.Logger(ctx).Info(fmt.Sprintf("Starting local pipeline %s", prefixStep))
 	planCommand, applyCommand := model.GetCommands(step.Type)
-	output, err := l.executeWrapper(prefixStep, planCommand, step, sourceAuths)
+	wrap, output, err := l.runWrapper(ctx, prefixStep, planCommand, step, sourceAuths)
 	if err != nil {
-		return fmt.Errorf("failed to execute %s for %s: %v", planCommand, prefixStep, err)
+		if wrap != nil {
+			wrap.Close()
+		}
+		return nil, fmt.Errorf("failed to execute %s for %s: %v", planCommand, prefixStep, err)
 	}
-	approved, err := l.getApproval(prefixStep, step, autoApprove, output, approve)
+	// Plan stream stays open during the approval, so the backend can approve or reject the changes.
+	approved, err := l.getApproval(ctx, prefixStep, step, autoApprove, output, approve, wrap.WaitApproval)
+	wrap.Close()
 	if err != nil {
-		return fmt.Errorf("failed to get approval for %s: %v", prefixStep, err)
+		return nil, fmt.Errorf("failed to get approval for %s: %v", prefixStep, err)
 	}
 	if !approved {
-		return nil
+		return nil, nil
 	}
-	_, err = l.executeWrapper(prefixStep, applyCommand, step, sourceAuths)
+	timings, err := l.executeTimedWrapper(ctx, prefixStep, applyCommand, step, sourceAuths)
 	if err != nil {
-		return fmt.Errorf("failed to execute %s for %s: %v", applyCommand, prefixStep, err)
+		return timings, fmt.Errorf("failed to execute %s for %s: %v", applyCommand, prefixStep, err)
 	}
-	return nil
+	return timings, nil
 }
 
 func (l *LocalPipeline) startDestroyExecution(step model.Step, sourceAuths map[string]model.SourceAuth) error {
 	prefixStep := fmt.Sprintf("%s-%s", l.prefix, step.Name)
 	planCommand, applyCommand := model.GetDestroyCommands(step.Type)
-	_, err := l.executeWrapper(prefixStep, planCommand, step, sourceAuths)
+	_, err := l.executeWrapper(l.ctx, prefixStep, planCommand, step, sourceAuths)
 	if err != nil {
 		return fmt.Errorf("failed to execute %s for %s: %v", planCommand, prefixStep, err)
 	}
-	_, err = l.executeWrapper(prefixStep, applyCommand, step, sourceAuths)
+	_, err = l.executeTimedWrapper(l.ctx, prefixStep, applyCommand, step, sourceAuths)
 	if err != nil {
 		return fmt.Errorf("failed to execute %s for %s: %v", applyCommand, prefixStep, err)
 	}
 	return nil
 }
 
-func (l *LocalPipeline) executeWrapper(prefixStep string, command model.ActionCommand, step model.Step, sourceAuths map[string]model.SourceAuth) ([]byte, error) {
+func (l *LocalPipeline) executeWrapper(ctx context.Context, prefixStep string, command model.ActionCommand, step model.Step, sourceAuths map[string]model.SourceAuth) ([]byte, error) {
+	wrap, output, err := l.runWrapper(ctx, prefixStep, command, step, sourceAuths)
+	if wrap != nil {
+		wrap.Close()
+	}
+	return output, err
+}
+
+// executeTimedWrapper runs the apply command and stores a timing report of the applied resources in the bucket,
+// also when the apply fails.
+func (l *LocalPipeline) executeTimedWrapper(ctx context.Context, prefixStep string, command model.ActionCommand, step model.Step, sourceAuths map[string]model.SourceAuth) ([]model.ResourceTiming, error) {
+	started := time.Now().UTC()
+	wrap, _, err := l.runWrapper(ctx, prefixStep, command, step, sourceAuths)
+	if wrap == nil {
+		return nil, err
+	}
+	wrap.Close()
+	timings := wrap.Timings()
+	if len(timings) == 0 {
+		return nil, err
+	}
+	report := model.TimingReport{
+		Step:      step.Name,
+		Command:   command,
+		Started:   started,
+		Finished:  time.Now().UTC(),
+		Resources: timings,
+	}
+	l.putTimingReport(ctx, prefixStep, report)
+	return timings, err
+}
+
+func (l *LocalPipeline) putTimingReport(ctx context.Context, prefixStep string, report model.TimingReport) {
+	if l.storage == nil {
+		return
+	}
+	if err := PutTimingReport(ctx, l.storage, prefixStep, report); err != nil {
+		common.Logger(ctx).Warn(common.PrefixWarning(err.Error()))
+	}
+}
+
+// runWrapper runs the command, the returned wrapper must be closed to report the outcome to the backend.
+func (l *LocalPipeline) runWrapper(ctx context.Context, prefixStep string, command model.ActionCommand, step model.Step, sourceAuths map[string]model.SourceAuth) (*wrapper.Wrapper, []byte, error) {
 	flags := common.Wrapper{
 		Step:          step.Name,
 		Command:       string(command),
@@ -122,22 +172,25 @@ func (l *LocalPipeline) executeWrapper(prefixStep string, command model.ActionCo
 	var stdoutBuf bytes.Buffer
 	writers := []io.Writer{&stdoutBuf}
 	if l.pipeline.PrintLogs {
-		writers = append(writers, log.Writer())
+		writers = append(writers, common.LogOutput())
 	}
 	file := l.getLogFileWriter(prefixStep, command)
 	if file != nil {
-		defer func(file *os.File) {
+		defer func(file *redact.Writer) {
 			_ = file.Close()
 		}(file)
 		writers = append(writers, file)
 	}
 	stdout := io.MultiWriter(writers...)
-	wrap, err := wrapper.NewWrapper(l.ctx, flags, l.wrapper, env, stdout)
+	wrap, err := wrapper.NewWrapper(ctx, flags, l.wrapper, env, stdout)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize wrapper: %w", err)
+		return nil, nil, fmt.Errorf("failed to initialize wrapper: %w", err)
 	}
-	err = wrap.Provision() // Provision results have to be before stdoutBuf.Bytes()
-	return stdoutBuf.Bytes(), err
+	wrap.SetDecrypt(func(folder string) error {
+		return DecryptStepFiles(ctx, folder)
+	})
+	err = wrap.Run() // Run results have to be before stdoutBuf.Bytes()
+	return wrap, stdoutBuf.Bytes(), err
 }
 
 func (l *LocalPipeline) getEnv(prefixStep string, command model.ActionCommand, step model.Step, sourceAuths map[string]model.SourceAuth) []string {
@@ -179,7 +232,7 @@ func (l *LocalPipeline) getEnv(prefixStep string, command model.ActionCommand, s
 	return env
 }
 
-func (l *LocalPipeline) getLogFileWriter(prefix string, command model.ActionCommand) *os.File {
+func (l *LocalPipeline) getLogFileWriter(prefix string, command model.ActionCommand) *redact.Writer {
 	if l.pipeline.LogsPath == "" {
 		return nil
 	}
@@ -187,13 +240,13 @@ func (l *LocalPipeline) getLogFileWriter(prefix string, command model.ActionComm
 	fileName = strings.ReplaceAll(fileName, "-", "_")
 	file, err := os.OpenFile(filepath.Join(l.pipeline.LogsPath, fileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
-		slog.Warn(common.PrefixWarning(fmt.Sprintf("Failed to open log file: %v", err)))
+		common.Logger(l.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to open log file: %v", err)))
 		return nil
 	}
-	return file
+	return redact.NewWriter(file)
 }
 
-func (l *LocalPipeline) getApproval(pipelineName string, step model.Step, autoApprove bool, output []byte, approve model.ManualApprove) (bool, error) {
+func (l *LocalPipeline) getApproval(ctx context.Context, pipelineName string, step model.Step, autoApprove bool, output []byte, approve model.ManualApprove, waitApproval func() (<-chan wrapper.ApprovalDecision, func())) (bool, error) {
 	if output == nil {
 		return false, fmt.Errorf("no output from execution")
 	}
@@ -201,25 +254,27 @@ func (l *LocalPipeline) getApproval(pipelineName string, step model.Step, autoAp
 	if err != nil {
 		return false, err
 	}
+	metrics.PlanChanges(step.Name, *pipeChanges)
+	l.manager.PlanChanges(pipelineName, step.Name, *pipeChanges)
 	if step.Approve == model.ApproveReject || approve == model.ManualApproveReject {
 		return false, fmt.Errorf("stopped because step approve type is 'reject'")
 	}
 	if pipeChanges.NoChanges {
-		log.Printf("No changes detected for %s, skipping apply", pipelineName)
+		common.Logger(ctx).Info(fmt.Sprintf("No changes detected for %s, skipping apply", pipelineName))
 		return false, nil
 	}
 	if util.ShouldApprovePipeline(*pipeChanges, step.Approve, autoApprove, approve) {
-		log.Printf("Approved %s\n", pipelineName)
+		common.Logger(ctx).Info(fmt.Sprintf("Approved %s", pipelineName))
 		return true, nil
 	}
-	return l.getManualApproval(pipelineName, step.Name, pipeChanges)
+	return l.getManualApproval(ctx, pipelineName, step.Name, pipeChanges, waitApproval)
 }
 
 func getPipelineChanges(pipelineName string, stepType model.StepType, output []byte) (*model.PipelineChanges, error) {
 	var logParser func(string, string) (*model.PipelineChanges, error)
 	switch stepType {
 	case model.StepTypeTerraform:
-		logParser = terraform.ParseLogChanges
+		logParser = terraform.NewLogChangesParser()
 	case model.StepTypeArgoCD:
 		logParser = argocd.ParseLogChanges
 	}
@@ -238,25 +293,39 @@ func getPipelineChanges(pipelineName string, stepType model.StepType, output []b
 	return nil, fmt.Errorf("couldn't find plan output from logs for %s", pipelineName)
 }
 
-func (l *LocalPipeline) getManualApproval(pipelineName, step string, changes *model.PipelineChanges) (bool, error) {
+// getManualApproval accepts backend decisions only after the approval is requested, so an early decision can't
+// approve the changes.
+func (l *LocalPipeline) getManualApproval(ctx context.Context, pipelineName, step string, changes *model.PipelineChanges, waitApproval func() (<-chan wrapper.ApprovalDecision, func())) (bool, error) {
+	metrics.ApprovalPending(step, true)
+	defer metrics.ApprovalPending(step, false)
 	l.inputLock.Lock()
-
-	var logBuffer bytes.Buffer
-	originalLogOutput := log.Writer()
-	log.SetOutput(&logBuffer)
-	defer func() {
-		log.SetOutput(originalLogOutput)
-		log.Println(logBuffer.String())
-		l.inputLock.Unlock()
-	}()
-	time.Sleep(1 * time.Second) // Wait for output to be redirected
+	defer l.inputLock.Unlock()
+	defer common.PauseLogs()()
+	approvals, stopApprovals := waitApproval()
+	defer stopApprovals()
 	l.manager.ManualApproval(pipelineName, step, *changes, "")
 
 	fmt.Printf("Pipeline %s changes: %d to change, %d to destroy. Approve changes? (yes/no)", pipelineName,
 		changes.Changed, changes.Destroyed)
-	err := util.AskForConfirmation()
-	if err != nil {
-		return false, fmt.Errorf("manual approval failed: %v", err)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	confirmed := make(chan error, 1)
+	go func() {
+		confirmed <- util.AskForConfirmationContext(ctx)
+	}()
+	select {
+	case err := <-confirmed:
+		if err != nil {
+			return false, fmt.Errorf("manual approval failed: %v", err)
+		}
+		return true, nil
+	case decision := <-approvals:
+		fmt.Println()
+		if !decision.Approved {
+			return false, fmt.Errorf("manual approval failed: rejected from the wrapper backend: %s", decision.Reason)
+		}
+		common.Logger(ctx).Info(fmt.Sprintf("Approved %s from the wrapper backend", pipelineName))
+		l.manager.Approval(pipelineName, step, decision.By)
+		return true, nil
 	}
-	return true, nil
 }
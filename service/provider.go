@@ -2,7 +2,7 @@ package service
 
 import (
 	"context"
-	"log"
+	"fmt"
 	"strings"
 
 	"github.com/entigolabs/entigo-infralib-agent/aws"
@@ -16,9 +16,10 @@ func GetCloudProvider(ctx context.Context, flags *common.Flags) (model.CloudProv
 	if err != nil {
 		return nil, err
 	}
+	ctx = common.WithLogAttrs(ctx, common.PrefixAttr, strings.ToLower(prefix))
 	pipelineFlags := ProcessPipelineFlags(flags.Pipeline)
 	if flags.GCloud.ProjectId != "" {
-		log.Println("Using GCloud with project ID: ", flags.GCloud.ProjectId)
+		common.Logger(ctx).Info(fmt.Sprintf("Using GCloud with project ID: %s", flags.GCloud.ProjectId))
 		return gcloud.NewGCloud(ctx, strings.ToLower(prefix), flags.GCloud, pipelineFlags, flags.SkipBucketCreationDelay)
 	}
 	return aws.NewAWS(ctx, strings.ToLower(prefix), flags.AWS, pipelineFlags, flags.SkipBucketCreationDelay)
@@ -26,12 +27,32 @@ func GetCloudProvider(ctx context.Context, flags *common.Flags) (model.CloudProv
 
 func GetResourceProvider(ctx context.Context, flags *common.Flags) (model.ResourceProvider, error) {
 	if flags.GCloud.ProjectId != "" {
-		log.Println("Using GCloud with project ID: ", flags.GCloud.ProjectId)
+		common.Logger(ctx).Info(fmt.Sprintf("Using GCloud with project ID: %s", flags.GCloud.ProjectId))
 		return gcloud.NewGCloudProvider(ctx, flags.GCloud)
 	}
 	return aws.NewAWSProvider(ctx, flags.AWS)
 }
 
+// GetRemoteResourceProvider creates a provider for reading resources of another prefix. Missing remote fields default
+// to the agent flags.
+func GetRemoteResourceProvider(ctx context.Context, flags *common.Flags, providerType model.ProviderType, remote model.ConfigRemote) (model.ResourceProvider, error) {
+	if providerType == model.GCLOUD {
+		gCloud := flags.GCloud
+		if remote.GCloudProject != "" {
+			gCloud.ProjectId = remote.GCloudProject
+		}
+		if remote.GCloudLocation != "" {
+			gCloud.Location = remote.GCloudLocation
+		}
+		return gcloud.NewGCloudProvider(ctx, gCloud)
+	}
+	roleArn := flags.AWS.RoleArn
+	if remote.AWSRole != "" {
+		roleArn = remote.AWSRole
+	}
+	return aws.NewAWSRemoteProvider(ctx, roleArn, remote.AWSRegion)
+}
+
 func ProcessPipelineFlags(pipeline common.Pipeline) common.Pipeline {
 	if pipeline.TerraformCache.Value != nil {
 		return pipeline
@@ -45,3 +66,18 @@ func ProcessPipelineFlags(pipeline common.Pipeline) common.Pipeline {
 	pipeline.TerraformCache.Value = &enable
 	return pipeline
 }
+
+// GetPipelineBucket returns the bucket of the pipeline that runs the wrapper, nil when the bucket isn't set.
+func GetPipelineBucket(ctx context.Context, flags common.Wrapper) (model.Bucket, error) {
+	if flags.Bucket == "" {
+		return nil, nil
+	}
+	if flags.GCloudProject != "" {
+		return gcloud.NewStorage(ctx, nil, flags.GCloudProject, "", flags.Bucket)
+	}
+	awsConfig, err := aws.GetAWSConfig(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return aws.NewS3(ctx, awsConfig, flags.Bucket), nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+// remoteOutputs reads terraform outputs of other agent prefixes. Buckets and outputs are cached for the whole run.
+type remoteOutputs struct {
+	ctx          context.Context
+	flags        *common.Flags
+	providerType model.ProviderType
+	remotes      map[string]model.ConfigRemote
+	buckets      map[string]model.Bucket
+	cache        paramCache
+	lock         sync.Mutex
+}
+
+func newRemoteOutputs(ctx context.Context, flags *common.Flags, providerType model.ProviderType, remotes []model.ConfigRemote) *remoteOutputs {
+	remoteMap := make(map[string]model.ConfigRemote, len(remotes))
+	for _, remote := range remotes {
+		remoteMap[strings.ToLower(remote.Prefix)] = remote
+	}
+	return &remoteOutputs{
+		ctx:          ctx,
+		flags:        flags,
+		providerType: providerType,
+		remotes:      remoteMap,
+		buckets:      make(map[string]model.Bucket),
+		cache:        make(paramCache),
+	}
+}
+
+// getParameter returns the output value for a remote.<prefix>.<step>.<module>.<output> key.
+func (r *remoteOutputs) getParameter(replaceKey string) (string, error) {
+	parts := strings.Split(replaceKey, ".")
+	if len(parts) != 5 {
+		return "", fmt.Errorf("failed to parse remote key %s, got %d split parts instead of 5", replaceKey, len(parts))
+	}
+	prefix := strings.ToLower(parts[1])
+	outputs, err := r.getOutputs(prefix, parts[2])
+	if err != nil {
+		return "", fmt.Errorf("failed to get outputs for remote key %s: %w", replaceKey, err)
+	}
+	match := parameterIndexRegex.FindStringSubmatch(parts[4])
+	key := fmt.Sprintf("%s__%s", parts[3], strings.ReplaceAll(match[1], "/", "_"))
+	output, found := outputs[key]
+	if !found {
+		return "", fmt.Errorf("output %s not found in prefix %s step %s for key %s", key, prefix, parts[2], replaceKey)
+	}
+	return getOutputValue(output, replaceKey, match)
+}
+
+func (r *remoteOutputs) getOutputs(prefix, stepName string) (map[string]model.TFOutput, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	bucket, err := r.getBucket(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return getStepOutputs(r.ctx, bucket, prefix, stepName, r.cache)
+}
+
+func (r *remoteOutputs) getBucket(prefix string) (model.Bucket, error) {
+	if bucket, found := r.buckets[prefix]; found {
+		return bucket, nil
+	}
+	provider, err := GetRemoteResourceProvider(r.ctx, r.flags, r.providerType, r.remotes[prefix])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider for remote prefix %s: %w", prefix, err)
+	}
+	bucket, err := provider.GetBucket(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket for remote prefix %s: %w", prefix, err)
+	}
+	exists, err := bucket.BucketExists(r.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket for remote prefix %s: %w", prefix, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("bucket for remote prefix %s not found", prefix)
+	}
+	common.Logger(r.ctx).Info(fmt.Sprintf("Reading outputs from remote prefix %s", prefix))
+	r.buckets[prefix] = bucket
+	return bucket, nil
+}
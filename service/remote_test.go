@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+type memoryBucket struct {
+	model.Bucket
+	files map[string][]byte
+}
+
+func (b *memoryBucket) PutFile(_ context.Context, file string, content []byte) error {
+	b.files[file] = content
+	return nil
+}
+
+func (b *memoryBucket) BucketExists(_ context.Context) (bool, error) {
+	return b.files != nil, nil
+}
+
+func (b *memoryBucket) DeleteFile(_ context.Context, file string) error {
+	delete(b.files, file)
+	return nil
+}
+
+func (b *memoryBucket) GetFile(_ context.Context, file string) ([]byte, error) {
+	return b.files[file], nil
+}
+
+func (b *memoryBucket) ListFolderFiles(_ context.Context, folder string) ([]string, error) {
+	var files []string
+	for file := range b.files {
+		if strings.HasPrefix(file, folder+"/") {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func TestRemoteOutputsGetParameter(t *testing.T) {
+	bucket := &memoryBucket{files: map[string][]byte{
+		"net-main/terraform-output.json": []byte(`{
+			"vpc__vpc_id": {"sensitive": false, "type": "string", "value": "vpc-1"},
+			"vpc__private_subnets": {"sensitive": false, "type": ["list", "string"], "value": ["subnet-a", "subnet-b"]}
+		}`),
+	}}
+	remotes := newRemoteOutputs(t.Context(), nil, model.AWS, []model.ConfigRemote{{Prefix: "Net"}})
+	remotes.buckets["net"] = bucket
+	tests := []struct {
+		name     string
+		key      string
+		expected string
+		err      string
+	}{
+		{name: "output", key: "remote.net.main.vpc.vpc_id", expected: "vpc-1"},
+		{name: "prefix case", key: "remote.NET.main.vpc.vpc_id", expected: "vpc-1"},
+		{name: "list", key: "remote.net.main.vpc.private_subnets", expected: `"subnet-a","subnet-b"`},
+		{name: "list index", key: "remote.net.main.vpc.private_subnets[1]", expected: "subnet-b"},
+		{name: "list range", key: "remote.net.main.vpc.private_subnets[0-1]", expected: `"subnet-a","subnet-b"`},
+		{name: "missing output", key: "remote.net.main.vpc.vpc_cidr", err: "output vpc__vpc_cidr not found"},
+		{name: "missing step", key: "remote.net.apps.vpc.vpc_id", err: "output vpc__vpc_id not found"},
+		{name: "invalid key", key: "remote.net.main.vpc", err: "got 4 split parts instead of 5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := remotes.getParameter(test.key)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to get parameter: %v", err)
+			}
+			if value != test.expected {
+				t.Fatalf("expected value %q, got %q", test.expected, value)
+			}
+		})
+	}
+	delete(bucket.files, "net-main/terraform-output.json")
+	if value, err := remotes.getParameter("remote.net.main.vpc.vpc_id"); err != nil || value != "vpc-1" {
+		t.Fatalf("expected cached outputs for the run, got %q, %v", value, err)
+	}
+}
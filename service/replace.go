@@ -1,19 +1,21 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
 	ssmTypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
 	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/redact"
 	"github.com/entigolabs/entigo-infralib-agent/util"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
@@ -30,6 +32,7 @@ type keyType struct {
 type delayedKeyType struct {
 	keyType
 	ReplaceTag string
+	Functions  tagFunctions
 }
 
 const (
@@ -49,13 +52,13 @@ func (u *updater) replaceConfigStepValues(step model.Step, index int) (model.Ste
 	cache := make(paramCache)
 	modifiedStepYaml, delayedKeyTypes, err := u.replaceStringValues(step, string(stepYaml), index, cache)
 	if err != nil {
-		log.Printf("Failed to replace tags in step %s", step.Name)
+		common.Logger(u.ctx).Info(fmt.Sprintf("Failed to replace tags in step %s", step.Name))
 		return step, err
 	}
 	var modifiedStep model.Step
 	err = yaml.Unmarshal([]byte(modifiedStepYaml), &modifiedStep)
 	if err != nil {
-		slog.Debug(fmt.Sprintf("broken step yaml %s:\n%s", step.Name, modifiedStepYaml))
+		common.Logger(u.ctx).Debug(fmt.Sprintf("broken step yaml %s:\n%s", step.Name, modifiedStepYaml))
 		return step, fmt.Errorf("failed to unmarshal modified step %s yaml, error: %w", step.Name, err)
 	}
 	moduleChecksums, err := calculateModuleChecksums(modifiedStep)
@@ -64,12 +67,12 @@ func (u *updater) replaceConfigStepValues(step model.Step, index int) (model.Ste
 	}
 	modifiedStepYaml, err = u.replaceDelayedStringValues(step, modifiedStepYaml, index, cache, delayedKeyTypes)
 	if err != nil {
-		log.Printf("Failed to replace delayed tags in step %s", step.Name)
+		common.Logger(u.ctx).Info(fmt.Sprintf("Failed to replace delayed tags in step %s", step.Name))
 		return step, err
 	}
 	err = yaml.Unmarshal([]byte(modifiedStepYaml), &modifiedStep)
 	if err != nil {
-		slog.Debug(fmt.Sprintf("broken step yaml %s:\n%s", step.Name, modifiedStepYaml))
+		common.Logger(u.ctx).Debug(fmt.Sprintf("broken step yaml %s:\n%s", step.Name, modifiedStepYaml))
 		return step, fmt.Errorf("failed to unmarshal modified step %s yaml, error: %w", step.Name, err)
 	}
 	for i, module := range modifiedStep.Modules {
@@ -107,12 +110,14 @@ func calculateModuleChecksums(step model.Step) (map[string][]byte, error) {
 
 func (u *updater) replaceConfigStepFileValues(step model.Step, modifiedStep *model.Step, index int, cache paramCache) error {
 	for _, file := range step.Files {
-		if !strings.HasSuffix(file.Name, ".tf") && !strings.HasSuffix(file.Name, ".yaml") &&
-			!strings.HasSuffix(file.Name, ".yml") && !strings.HasSuffix(file.Name, ".hcl") {
+		// Encrypted files are decrypted by the pipeline, tags are not replaced in them
+		if file.Encrypted || (!strings.HasSuffix(file.Name, ".tf") && !strings.HasSuffix(file.Name, ".yaml") &&
+			!strings.HasSuffix(file.Name, ".yml") && !strings.HasSuffix(file.Name, ".hcl")) {
 			modifiedStep.Files = append(modifiedStep.Files, model.File{
-				Name:     strings.TrimPrefix(file.Name, fmt.Sprintf(IncludeFormat, step.Name)+"/"),
-				Content:  file.Content,
-				Checksum: util.CalculateHash(file.Content),
+				Name:      strings.TrimPrefix(file.Name, fmt.Sprintf(IncludeFormat, step.Name)+"/"),
+				Content:   file.Content,
+				Checksum:  util.CalculateHash(file.Content),
+				Encrypted: file.Encrypted,
 			})
 			continue
 		}
@@ -170,7 +175,7 @@ func (u *updater) replaceStringValues(step model.Step, content string, index int
 			content = strings.Replace(content, replaceTag, strings.Trim(replaceKey, "`"), 1)
 			continue
 		}
-		keyTypes, err := parseReplaceTag(match)
+		keyTypes, functions, err := parseReplaceTag(match)
 		if err != nil {
 			return "", nil, err
 		}
@@ -181,7 +186,8 @@ func (u *updater) replaceStringValues(step model.Step, content string, index int
 				break
 			}
 			if keyType.ReplaceType == string(model.ReplaceTypeAgent) {
-				delayedKeyTypes = append(delayedKeyTypes, delayedKeyType{keyType: keyType, ReplaceTag: replaceTag})
+				delayedKeyTypes = append(delayedKeyTypes, delayedKeyType{keyType: keyType, ReplaceTag: replaceTag,
+					Functions: functions})
 				replacement = skipReplace
 				break
 			}
@@ -196,6 +202,14 @@ func (u *updater) replaceStringValues(step model.Step, content string, index int
 		if replacement == skipReplace {
 			continue
 		}
+		if len(functions) > 0 && strings.HasPrefix(replacement, "module.") {
+			return "", nil, fmt.Errorf("functions can't be applied to output %s of the same step in tag %s",
+				replacement, replaceTag)
+		}
+		replacement, err = functions.apply(replacement)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to apply functions in tag %s: %w", replaceTag, err)
+		}
 		if strings.Contains(replacement, "\n") {
 			replacement, err = getMultilineReplacement(replaceTag, content, replacement)
 			if err != nil {
@@ -235,6 +249,10 @@ func (u *updater) replaceDelayedStringValues(step model.Step, content string, in
 		if err != nil {
 			return "", err
 		}
+		replacement, err = key.Functions.apply(replacement)
+		if err != nil {
+			return "", fmt.Errorf("failed to apply functions in tag %s: %w", key.ReplaceTag, err)
+		}
 		content = strings.Replace(content, key.ReplaceTag, replacement, 1)
 	}
 	return content, nil
@@ -271,7 +289,7 @@ func (u *updater) replaceMetadataValues(step model.Step, module model.Module, in
 			continue
 		}
 		for _, match := range matches {
-			keyTypes, err := parseReplaceTag(match)
+			keyTypes, functions, err := parseReplaceTag(match)
 			if err != nil {
 				return nil, err
 			}
@@ -289,6 +307,10 @@ func (u *updater) replaceMetadataValues(step model.Step, module model.Module, in
 					break
 				}
 			}
+			replacement, err = functions.apply(replacement)
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply functions in tag %s: %w", match[0], err)
+			}
 			replacement = strings.Trim(strings.Trim(replacement, `"`), "\n")
 			value = strings.Replace(value, match[0], replacement, 1)
 		}
@@ -313,7 +335,7 @@ func (u *updater) getReplacementValue(step model.Step, index int, replaceKey, re
 	case string(model.ReplaceTypeOutputOptional):
 		return u.getModuleParameter(step, replaceKey, cache, true)
 	case string(model.ReplaceTypeOutputCustom), string(model.ReplaceTypeGCSMCustom), string(model.ReplaceTypeSSMCustom):
-		return getSSMCustomParameter(u.resources.GetSSM(), replaceKey)
+		return getSSMCustomParameter(u.ctx, u.resources.GetSSM(), replaceKey)
 	case string(model.ReplaceTypeTOutput):
 		return u.getTypedModuleParameter(step, replaceKey, cache, false)
 	case string(model.ReplaceTypeTOutputOptional):
@@ -332,11 +354,68 @@ func (u *updater) getReplacementValue(step model.Step, index int, replaceKey, re
 		return getTypedStepModuleName(step, replaceKey)
 	case string(model.ReplaceTypeModule):
 		return "", nil // Ignore this replace
+	case string(model.ReplaceTypeVault):
+		return u.getSecretValue(u.vault, string(model.ReplaceTypeVault), replaceKey, replaceKey[strings.Index(replaceKey, ".")+1:])
+	case string(model.ReplaceTypeSecret):
+		backend, ref, _ := strings.Cut(replaceKey[strings.Index(replaceKey, ".")+1:], ".")
+		return u.getSecretValue(u.secrets[backend], backend, replaceKey, ref)
+	case string(model.ReplaceTypeRemote):
+		return u.remotes.getParameter(replaceKey)
 	default:
 		return "", fmt.Errorf("unknown replace type in tag '%s'", replaceType)
 	}
 }
 
+// getSecretValue resolves a <path>.<key> reference from a secret backend. Secrets are cached for the whole run.
+func (u *updater) getSecretValue(resolver model.SecretResolver, backend, replaceKey, ref string) (string, error) {
+	if resolver == nil {
+		return "", fmt.Errorf("secret backend %s is not configured for key %s", backend, replaceKey)
+	}
+	end := len(ref)
+	if bracket := strings.Index(ref, "["); bracket != -1 {
+		end = bracket
+	}
+	split := strings.LastIndex(ref[:end], ".")
+	if split <= 0 || split == len(ref)-1 {
+		return "", fmt.Errorf("failed to parse secret key %s, expected <path>.<key>", replaceKey)
+	}
+	path := ref[:split]
+	match := parameterIndexRegex.FindStringSubmatch(ref[split+1:])
+	secretValues, err := u.getSecret(resolver, backend, path)
+	if err != nil {
+		return "", err
+	}
+	output, found := secretValues[match[1]]
+	if !found {
+		return "", fmt.Errorf("key %s not found in secret %s for key %s", match[1], path, replaceKey)
+	}
+	value, err := getOutputValue(output, replaceKey, match)
+	if err != nil {
+		return "", err
+	}
+	redact.AddValues(value)
+	return value, nil
+}
+
+func (u *updater) getSecret(resolver model.SecretResolver, backend, path string) (map[string]model.TFOutput, error) {
+	u.secretLock.Lock()
+	defer u.secretLock.Unlock()
+	cacheKey := fmt.Sprintf("%s:%s", backend, path)
+	if secretValues, found := u.secretCache[cacheKey]; found {
+		return secretValues, nil
+	}
+	data, err := resolver.GetSecret(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret %s from %s: %w", path, backend, err)
+	}
+	secretValues := make(map[string]model.TFOutput, len(data))
+	for key, value := range data {
+		secretValues[key] = model.TFOutput{Sensitive: true, Value: value}
+	}
+	u.secretCache[cacheKey] = secretValues
+	return secretValues, nil
+}
+
 func (u *updater) getReplacementConfigValue(configKey string) (string, error) {
 	if configKey == "prefix" {
 		return u.resources.GetCloudPrefix(), nil
@@ -387,13 +466,13 @@ func (u *updater) getModuleParameter(step model.Step, replaceKey string, cache p
 	return u.getParameter(match, replaceKey, step, *foundStep, *module, cache, optional)
 }
 
-func getSSMCustomParameter(ssm model.SSM, replaceKey string) (string, error) {
+func getSSMCustomParameter(ctx context.Context, ssm model.SSM, replaceKey string) (string, error) {
 	parts := strings.Split(replaceKey, ".")
 	if len(parts) != 2 {
 		return "", fmt.Errorf("failed to parse ssm custom parameter key %s, got %d split parts instead of 2", replaceKey, len(parts))
 	}
 	match := parameterIndexRegex.FindStringSubmatch(parts[1])
-	return getSSMParameterValue(ssm, match, replaceKey, match[1])
+	return getSSMParameterValue(ctx, ssm, match, replaceKey, match[1])
 }
 
 func (u *updater) getTypedModuleParameter(step model.Step, replaceKey string, cache paramCache, optional bool) (string, error) {
@@ -431,14 +510,14 @@ func (u *updater) getParameter(match []string, replaceKey string, step, foundSte
 		if found {
 			return getOutputValue(output, replaceKey, match)
 		}
-		slog.Debug(fmt.Sprintf("step %s key %s not found in tf output", foundStep.Name, key))
+		common.Logger(u.ctx).Debug(fmt.Sprintf("step %s key %s not found in tf output", foundStep.Name, key))
 	}
 	parameterName := fmt.Sprintf("%s/%s-%s-%s/%s", ssmPrefix, u.resources.GetCloudPrefix(), foundStep.Name, module.Name, match[1])
 	prefix, found := module.Inputs["prefix"]
 	if found {
 		parameterName = fmt.Sprintf("%s/%s/%s", ssmPrefix, prefix, match[1])
 	}
-	value, err := getSSMParameterValue(u.resources.GetSSM(), match, replaceKey, parameterName)
+	value, err := getSSMParameterValue(u.ctx, u.resources.GetSSM(), match, replaceKey, parameterName)
 	if err != nil {
 		var parameterError *model.ParameterNotFoundError
 		if optional && errors.As(err, &parameterError) {
@@ -494,12 +573,42 @@ func getOutputMapValue(v map[string]interface{}, output model.TFOutput, replaceK
 }
 
 func (u *updater) getModuleOutputs(step model.Step, cache paramCache) (map[string]model.TFOutput, error) {
-	filePath := fmt.Sprintf("%s-%s/%s", u.resources.GetCloudPrefix(), step.Name, terraformOutput)
+	return getStepOutputs(u.ctx, u.resources.GetBucket(), u.resources.GetCloudPrefix(), step.Name, cache)
+}
+
+// GetStepOutputs returns the terraform outputs of a step, keys are in the <module>__<output> format.
+func GetStepOutputs(ctx context.Context, bucket model.Bucket, prefix, stepName string) (map[string]model.TFOutput, error) {
+	return getStepOutputs(ctx, bucket, prefix, stepName, make(paramCache))
+}
+
+// GetStepOutput returns the output and its value for a <step>.<module>.<key> key, the key supports list indexes.
+func GetStepOutput(ctx context.Context, bucket model.Bucket, prefix, outputKey string) (model.TFOutput, string, error) {
+	parts := strings.Split(outputKey, ".")
+	if len(parts) != 3 {
+		return model.TFOutput{}, "", fmt.Errorf("failed to parse output key %s, got %d split parts instead of 3",
+			outputKey, len(parts))
+	}
+	outputs, err := GetStepOutputs(ctx, bucket, prefix, parts[0])
+	if err != nil {
+		return model.TFOutput{}, "", err
+	}
+	match := parameterIndexRegex.FindStringSubmatch(parts[2])
+	key := fmt.Sprintf("%s__%s", parts[1], strings.ReplaceAll(match[1], "/", "_"))
+	output, found := outputs[key]
+	if !found {
+		return model.TFOutput{}, "", model.NewNotFoundError(fmt.Sprintf("output %s in step %s", key, parts[0]))
+	}
+	value, err := getOutputValue(output, outputKey, match)
+	return output, value, err
+}
+
+func getStepOutputs(ctx context.Context, bucket model.Bucket, prefix, stepName string, cache paramCache) (map[string]model.TFOutput, error) {
+	filePath := fmt.Sprintf("%s-%s/%s", prefix, stepName, terraformOutput)
 	outputs, found := cache[filePath]
 	if found {
 		return outputs, nil
 	}
-	file, err := u.resources.GetBucket().GetFile(filePath)
+	file, err := bucket.GetFile(ctx, filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -516,11 +625,14 @@ func (u *updater) getModuleOutputs(step model.Step, cache paramCache) (map[strin
 	return outputs, nil
 }
 
-func getSSMParameterValue(ssm model.SSM, match []string, replaceKey string, parameterName string) (string, error) {
-	parameter, err := ssm.GetParameter(parameterName)
+func getSSMParameterValue(ctx context.Context, ssm model.SSM, match []string, replaceKey string, parameterName string) (string, error) {
+	parameter, err := ssm.GetParameter(ctx, parameterName)
 	if err != nil {
 		return "", err
 	}
+	if parameter.Type == string(ssmTypes.ParameterTypeSecureString) {
+		redact.AddValues(strings.Split(*parameter.Value, ",")...)
+	}
 	if match[2] == "" {
 		return *parameter.Value, nil
 	}
@@ -649,7 +761,7 @@ func (u *updater) getModuleSelfOutputValue(step model.Step, module model.Module,
 	key := fmt.Sprintf("%s__%s", module.Name, strings.ReplaceAll(match[1], "/", "_"))
 	output, found := outputs[key]
 	if !found {
-		slog.Warn(fmt.Sprintf("step %s key %s not found in tf output", step.Name, key))
+		common.Logger(u.ctx).Warn(fmt.Sprintf("step %s key %s not found in tf output", step.Name, key))
 		return "", nil
 	}
 	return getOutputValue(output, replaceKey, match)
@@ -723,17 +835,21 @@ func getModuleType(module model.Module) string {
 	return moduleSource[strings.Index(module.Source, "/")+1:]
 }
 
-func replaceConfigValues(ssm model.SSM, prefix string, config model.Config) (model.Config, error) {
+func replaceConfigValues(ctx context.Context, ssm model.SSM, prefix string, config model.Config) (model.Config, error) {
 	if ssm == nil {
 		return config, nil
 	}
 	steps := config.Steps
 	config.Steps = nil
+	templates := detachWebhookTemplates(&config)
 	var err error
-	config, err = replaceConfigRootValues(ssm, prefix, config)
+	config, err = replaceConfigRootValues(ctx, ssm, prefix, config)
 	if err != nil {
 		return config, err
 	}
+	for index, webhookTemplates := range templates {
+		config.Notifications[index].Webhook.Templates = webhookTemplates
+	}
 	steps, err = replaceConfigStepsValues(prefix, steps, config)
 	if err != nil {
 		return config, err
@@ -742,7 +858,23 @@ func replaceConfigValues(ssm model.SSM, prefix string, config model.Config) (mod
 	return config, nil
 }
 
-func replaceConfigRootValues(ssm model.SSM, prefix string, config model.Config) (model.Config, error) {
+// detachWebhookTemplates removes webhook templates from the config as their go template syntax clashes with replace tags.
+func detachWebhookTemplates(config *model.Config) map[int]map[model.MessageType]string {
+	templates := make(map[int]map[model.MessageType]string)
+	config.Notifications = slices.Clone(config.Notifications)
+	for index, notifier := range config.Notifications {
+		if notifier.Webhook == nil || len(notifier.Webhook.Templates) == 0 {
+			continue
+		}
+		webhook := *notifier.Webhook
+		templates[index] = webhook.Templates
+		webhook.Templates = nil
+		config.Notifications[index].Webhook = &webhook
+	}
+	return templates
+}
+
+func replaceConfigRootValues(ctx context.Context, ssm model.SSM, prefix string, config model.Config) (model.Config, error) {
 	configYaml, err := yaml.Marshal(config)
 	if err != nil {
 		return config, err
@@ -755,7 +887,7 @@ func replaceConfigRootValues(ssm model.SSM, prefix string, config model.Config)
 	if err != nil {
 		return config, fmt.Errorf("failed to replace tags in config root, error: %v", err)
 	}
-	modifiedConfigYaml, err = replaceConfigCustomTags(ssm, modifiedConfigYaml, matches)
+	modifiedConfigYaml, err = replaceConfigCustomTags(ctx, ssm, modifiedConfigYaml, matches)
 	if err != nil {
 		return config, fmt.Errorf("failed to replace custom output tags in config root, error: %v", err)
 	}
@@ -793,7 +925,7 @@ func replaceConfigTags(prefix string, config model.Config, content string, match
 		if hasSamePrefixSuffix(replaceKey, "`") {
 			continue
 		}
-		keyTypes, err := parseReplaceTag(match)
+		keyTypes, functions, err := parseReplaceTag(match)
 		if err != nil {
 			return "", err
 		}
@@ -808,27 +940,30 @@ func replaceConfigTags(prefix string, config model.Config, content string, match
 			continue
 		}
 		configKey := replaceKey[strings.Index(replaceKey, ".")+1:]
-		if configKey == "prefix" {
-			content = strings.Replace(content, replaceTag, prefix, 1)
-			continue
+		configValue := prefix
+		if configKey != "prefix" {
+			configValue, err = util.GetValueFromStruct(configKey, config)
+			if err != nil {
+				return "", fmt.Errorf("failed to get config value %s: %s", configKey, err)
+			}
 		}
-		configValue, err := util.GetValueFromStruct(configKey, config)
+		configValue, err = functions.apply(configValue)
 		if err != nil {
-			return "", fmt.Errorf("failed to get config value %s: %s", configKey, err)
+			return "", fmt.Errorf("failed to apply functions in tag %s: %w", replaceTag, err)
 		}
 		content = strings.Replace(content, replaceTag, configValue, 1)
 	}
 	return content, nil
 }
 
-func replaceConfigCustomTags(ssm model.SSM, content string, matches [][]string) (string, error) {
+func replaceConfigCustomTags(ctx context.Context, ssm model.SSM, content string, matches [][]string) (string, error) {
 	for _, match := range matches {
 		replaceTag := match[0]
 		replaceKey := match[1]
 		if hasSamePrefixSuffix(replaceKey, "`") {
 			continue
 		}
-		keyTypes, err := parseReplaceTag(match)
+		keyTypes, functions, err := parseReplaceTag(match)
 		if err != nil {
 			return "", err
 		}
@@ -843,10 +978,14 @@ func replaceConfigCustomTags(ssm model.SSM, content string, matches [][]string)
 		if replaceKey == "" {
 			continue
 		}
-		parameter, err := getSSMCustomParameter(ssm, replaceKey)
+		parameter, err := getSSMCustomParameter(ctx, ssm, replaceKey)
 		if err != nil {
 			return "", err
 		}
+		parameter, err = functions.apply(parameter)
+		if err != nil {
+			return "", fmt.Errorf("failed to apply functions in tag %s: %w", replaceTag, err)
+		}
 		content = strings.Replace(content, replaceTag, parameter, 1)
 	}
 	return content, nil
@@ -885,7 +1024,7 @@ func replaceModuleInputsValues(module model.Module, content string, matches [][]
 		if hasSamePrefixSuffix(replaceKey, "`") {
 			continue
 		}
-		keyTypes, err := parseReplaceTag(match)
+		keyTypes, functions, err := parseReplaceTag(match)
 		if err != nil {
 			return content, err
 		}
@@ -903,6 +1042,10 @@ func replaceModuleInputsValues(module model.Module, content string, matches [][]
 		if err != nil {
 			return content, err
 		}
+		replacement, err = functions.apply(replacement)
+		if err != nil {
+			return content, fmt.Errorf("failed to apply functions in tag %s: %w", replaceTag, err)
+		}
 		if replacement == "" {
 			continue
 		}
@@ -933,24 +1076,53 @@ func getReplacementModuleValue(replaceKey string, module model.Module) (string,
 	}
 }
 
-func parseReplaceTag(match []string) ([]keyType, error) {
+// parseReplaceTag returns the fallback keys of a replace tag and the functions that are applied to the replacement.
+// Segments starting with . or " are keys, segments starting with a function name are functions.
+func parseReplaceTag(match []string) ([]keyType, tagFunctions, error) {
 	if len(match) != 2 {
-		return nil, fmt.Errorf("failed to parse replace tag match %s", match[0])
+		return nil, nil, fmt.Errorf("failed to parse replace tag match %s", match[0])
+	}
+	replaceTags, err := splitQuoted(match[1], '|')
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid replace tag %s: %w", match[0], err)
 	}
-	replaceTags := strings.Split(match[1], "|")
 	var keyTypes []keyType
+	var functions tagFunctions
 	for _, tag := range replaceTags {
-		replaceKey := strings.TrimLeft(strings.Trim(tag, " "), ".")
+		tag = strings.Trim(tag, " ")
+		if isTagFunction(tag) {
+			function, err := parseTagFunction(tag, match[0])
+			if err != nil {
+				return nil, nil, err
+			}
+			functions = append(functions, function)
+			continue
+		}
+		if len(functions) > 0 {
+			return nil, nil, fmt.Errorf("replace tag %s key %s must be before functions", match[0], tag)
+		}
+		replaceKey := strings.TrimLeft(tag, ".")
 		if strings.HasPrefix(replaceKey, `"`) {
 			keyTypes = append(keyTypes, keyType{ReplaceKey: replaceKey, ReplaceType: ""})
 			continue
 		}
 		splitIndex := strings.Index(replaceKey, ".")
 		if splitIndex == -1 || len(replaceKey) <= splitIndex {
-			return nil, fmt.Errorf("invalid replace tag format: %s", match[0])
+			return nil, nil, fmt.Errorf("invalid replace tag format: %s", match[0])
 		}
 		replaceType := strings.ToLower(replaceKey[:strings.Index(replaceKey, ".")])
 		keyTypes = append(keyTypes, keyType{ReplaceKey: replaceKey, ReplaceType: replaceType})
 	}
-	return keyTypes, nil
+	if len(keyTypes) == 0 {
+		return nil, nil, fmt.Errorf("replace tag %s has no keys", match[0])
+	}
+	return keyTypes, functions, nil
+}
+
+func isTagFunction(tag string) bool {
+	if tag == "" || strings.HasPrefix(tag, ".") || strings.HasPrefix(tag, `"`) {
+		return false
+	}
+	name, _, _ := strings.Cut(tag, " ")
+	return !strings.Contains(name, ".")
 }
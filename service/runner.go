@@ -7,9 +7,13 @@ import (
 	"time"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/metrics"
 	"github.com/entigolabs/entigo-infralib-agent/model"
 	"github.com/entigolabs/entigo-infralib-agent/notify"
+	"github.com/entigolabs/entigo-infralib-agent/report"
+	"github.com/entigolabs/entigo-infralib-agent/tracing"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const terminationNotifyTimeout = 10 * time.Second
@@ -24,9 +28,13 @@ type Runner struct {
 	manager      model.NotificationManager
 	campaignId   uuid.UUID
 	finalize     sync.Once
+	report       *report.Collector
 }
 
 func NewRunner(ctx context.Context, command common.Command, flags *common.Flags) (*Runner, error) {
+	campaignId := uuid.New()
+	tracing.SetCampaignId(ctx, campaignId.String())
+	ctx = common.WithLogAttrs(ctx, common.CampaignIdAttr, campaignId.String())
 	provider, err := GetCloudProvider(ctx, flags)
 	if err != nil {
 		return nil, err
@@ -35,12 +43,17 @@ func NewRunner(ctx context.Context, command common.Command, flags *common.Flags)
 	if err != nil {
 		return nil, err
 	}
-	config, err := GetRootConfig(resources.GetSSM(), resources.GetCloudPrefix(), flags.Config, resources.GetBucket())
+	config, err := GetRootConfig(ctx, resources.GetSSM(), resources.GetCloudPrefix(), flags.Config, resources.GetBucket())
 	if err != nil {
 		return nil, err
 	}
-	campaignId := uuid.New()
-	manager, err := notify.NewNotificationManager(ctx, config.Notifications, campaignId)
+	ctx = common.WithLogAttrs(ctx, common.PrefixAttr, resources.GetCloudPrefix())
+	collector := report.NewCollector(flags.Report)
+	var internal []model.Notifier
+	if collector != nil {
+		internal = append(internal, collector)
+	}
+	manager, err := notify.NewNotificationManager(ctx, config.Notifications, campaignId, resources.GetBucket(), internal...)
 	if err != nil {
 		return nil, err
 	}
@@ -53,13 +66,20 @@ func NewRunner(ctx context.Context, command common.Command, flags *common.Flags)
 		rootConfig:   config,
 		manager:      manager,
 		campaignId:   campaignId,
+		report:       collector,
 	}, nil
 }
 
-func (r *Runner) Run() error {
+func (r *Runner) Run() (err error) {
+	ctx, span := tracing.Start(r.ctx, "Runner.Run", attribute.String("command", string(r.command)))
+	defer tracing.End(span, &err)
+	defer r.writeReport()
 	r.manager.Modules(r.minResources, r.command, r.rootConfig)
 	defer r.notifyTerminationIfCanceled()
 	r.manager.Campaign(r.ctx, model.CampaignStatusStarted, r.minResources, r.command, nil)
+	if err := r.manager.Flush(ctx); err != nil {
+		common.Logger(r.ctx).Warn(common.PrefixWarning(fmt.Sprintf("failed to flush notification outbox: %v", err)))
+	}
 
 	resources, err := r.provider.SetupResources(r.manager, r.rootConfig)
 	if err != nil {
@@ -73,7 +93,7 @@ func (r *Runner) Run() error {
 	if err != nil {
 		return r.notifyError(fmt.Errorf("failed to set up encryption: %s", err))
 	}
-	updater, err := NewUpdater(r.ctx, r.flags, resources, r.manager, r.command, r.campaignId)
+	updater, err := NewUpdater(ctx, r.flags, resources, r.manager, r.command, r.campaignId)
 	if err != nil {
 		return r.notifyError(err)
 	}
@@ -87,7 +107,7 @@ func (r *Runner) Run() error {
 			status = model.CampaignStatusSkipped
 		}
 		r.manager.Campaign(r.ctx, status, r.minResources, r.command, nil)
-
+		metrics.CampaignFinished(r.command, status)
 	})
 	return nil
 }
@@ -96,10 +116,11 @@ func (r *Runner) notifyTerminationIfCanceled() {
 	if r.ctx.Err() == nil {
 		return
 	}
-	if !r.manager.HasNotifier(model.MessageTypeFailure) {
-		return
-	}
 	r.finalize.Do(func() {
+		metrics.CampaignFinished(r.command, model.CampaignStatusTerminated)
+		if !r.manager.HasNotifier(model.MessageTypeFailure) {
+			return
+		}
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), terminationNotifyTimeout)
 		defer cancel()
 		r.manager.Campaign(shutdownCtx, model.CampaignStatusTerminated, r.minResources, r.command,
@@ -107,6 +128,15 @@ func (r *Runner) notifyTerminationIfCanceled() {
 	})
 }
 
+func (r *Runner) writeReport() {
+	if r.report == nil {
+		return
+	}
+	if err := r.report.Write(); err != nil {
+		common.Logger(r.ctx).Warn(common.PrefixWarning(err.Error()))
+	}
+}
+
 func (r *Runner) updateAgentJob(resources model.Resources) error {
 	if r.flags.Pipeline.Type == string(common.PipelineTypeLocal) {
 		return nil
@@ -122,6 +152,7 @@ func (r *Runner) notifyError(err error) error {
 	}
 	r.finalize.Do(func() {
 		r.manager.Campaign(r.ctx, model.CampaignStatusFailure, r.minResources, r.command, err)
+		metrics.CampaignFinished(r.command, model.CampaignStatusFailure)
 	})
 	return err
 }
@@ -130,7 +161,7 @@ func (r *Runner) setupEncryption(resources model.Resources) error {
 	if resources.GetProviderType() != model.AWS {
 		return nil // TODO Remove when GCP encryption is implemented
 	}
-	moduleName, outputs, err := GetEncryptionOutputs(r.rootConfig, resources.GetCloudPrefix(), resources.GetBucket())
+	moduleName, outputs, err := GetEncryptionOutputs(r.ctx, r.rootConfig, resources.GetCloudPrefix(), resources.GetBucket())
 	if err != nil {
 		return fmt.Errorf("failed to get outputs for %s: %v", moduleName, err)
 	}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+const (
+	timingFolder     = "timings/%s"
+	timingReport     = timingFolder + "/%s.json"
+	timingTimeFormat = "20060102T150405Z"
+)
+
+// PutTimingReport stores the timing report of a step apply in the bucket.
+func PutTimingReport(ctx context.Context, bucket model.Bucket, prefixStep string, report model.TimingReport) error {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal timing report for %s: %v", prefixStep, err)
+	}
+	file := fmt.Sprintf(timingReport, prefixStep, report.Started.UTC().Format(timingTimeFormat))
+	if err = bucket.PutFile(ctx, file, content); err != nil {
+		return fmt.Errorf("failed to store timing report %s: %v", file, err)
+	}
+	return nil
+}
+
+// getTimingReport returns the latest timing report of the step that was started after since, nil if there's none.
+func getTimingReport(ctx context.Context, bucket model.Bucket, prefixStep string, since time.Time) (*model.TimingReport, error) {
+	files, err := bucket.ListFolderFiles(ctx, fmt.Sprintf(timingFolder, prefixStep))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list timing reports for %s: %v", prefixStep, err)
+	}
+	oldest := since.UTC().Truncate(time.Second).Format(timingTimeFormat)
+	files = slices.DeleteFunc(files, func(file string) bool {
+		return strings.TrimSuffix(path.Base(file), ".json") < oldest
+	})
+	if len(files) == 0 {
+		return nil, nil
+	}
+	file := slices.MaxFunc(files, func(a, b string) int {
+		return strings.Compare(path.Base(a), path.Base(b))
+	})
+	content, err := bucket.GetFile(ctx, file)
+	if err != nil || content == nil {
+		return nil, err
+	}
+	var report model.TimingReport
+	if err = json.Unmarshal(content, &report); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal timing report %s: %v", file, err)
+	}
+	model.SortTimings(report.Resources)
+	return &report, nil
+}
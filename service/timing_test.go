@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+func TestGetTimingReport(t *testing.T) {
+	bucket := &memoryBucket{files: make(map[string][]byte)}
+	started := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	reports := []model.TimingReport{
+		{Step: "old", Started: started.Add(-time.Hour)},
+		{Step: "previous", Started: started.Add(time.Minute)},
+		{Step: "latest", Started: started.Add(2 * time.Minute), Resources: []model.ResourceTiming{
+			{Address: "aws_vpc.fast", Duration: time.Second},
+			{Address: "aws_vpc.slow", Duration: time.Minute},
+		}},
+	}
+	for _, report := range reports {
+		if err := PutTimingReport(context.Background(), bucket, "dev-net", report); err != nil {
+			t.Fatalf("failed to put report: %v", err)
+		}
+	}
+	_ = PutTimingReport(context.Background(), bucket, "dev-net-other", model.TimingReport{Step: "other", Started: started.Add(time.Hour)})
+
+	report, err := getTimingReport(context.Background(), bucket, "dev-net", started)
+	if err != nil {
+		t.Fatalf("failed to get report: %v", err)
+	}
+	if report == nil || report.Step != "latest" {
+		t.Fatalf("expected the latest report, got %+v", report)
+	}
+	if report.Resources[0].Address != "aws_vpc.slow" {
+		t.Fatalf("expected resources sorted from slowest, got %+v", report.Resources)
+	}
+
+	report, err = getTimingReport(context.Background(), bucket, "dev-net", started.Add(time.Hour))
+	if err != nil || report != nil {
+		t.Fatalf("expected no report after the start, got %+v, %v", report, err)
+	}
+}
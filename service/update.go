@@ -3,11 +3,13 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -17,18 +19,26 @@ import (
 	"github.com/entigolabs/entigo-infralib-agent/argocd"
 	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/git"
+	"github.com/entigolabs/entigo-infralib-agent/metrics"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/redact"
+	"github.com/entigolabs/entigo-infralib-agent/secret"
+	"github.com/entigolabs/entigo-infralib-agent/sops"
 	"github.com/entigolabs/entigo-infralib-agent/terraform"
+	"github.com/entigolabs/entigo-infralib-agent/tracing"
 	"github.com/entigolabs/entigo-infralib-agent/util"
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/hcl/v2/hclwrite"
+	"go.opentelemetry.io/otel/attribute"
 	"gopkg.in/yaml.v3"
 )
 
 const (
 	stateFile = "state.yaml"
 	ssmPrefix = "/entigo-infralib"
+	// Secrets and pipeline environment variables are limited to 64KB.
+	maxRedactValuesSize = 60 * 1024
 )
 
 type Updater interface {
@@ -52,14 +62,20 @@ type updater struct {
 	moduleSources map[string]model.SourceKey
 	sources       map[model.SourceKey]*model.Source
 	firstRunDone  map[string]bool
+	secrets       map[string]model.SecretResolver
+	vault         model.SecretResolver
+	secretCache   paramCache
+	secretLock    sync.Mutex
+	remotes       *remoteOutputs
+	redactValues  *redactValues
 }
 
 func NewUpdater(ctx context.Context, flags *common.Flags, resources model.Resources, manager model.NotificationManager, command common.Command, campaignId uuid.UUID) (Updater, error) {
-	config, err := GetFullConfig(resources.GetSSM(), resources.GetCloudPrefix(), flags.Config, resources.GetBucket())
+	config, err := GetFullConfig(ctx, resources.GetSSM(), resources.GetCloudPrefix(), flags.Config, resources.GetBucket())
 	if err != nil {
 		return nil, err
 	}
-	state, err := getLatestState(resources.GetBucket())
+	state, err := getLatestState(ctx, resources.GetBucket())
 	if err != nil {
 		return nil, err
 	}
@@ -67,11 +83,14 @@ func NewUpdater(ctx context.Context, flags *common.Flags, resources model.Resour
 		return nil, fmt.Errorf("failed to validate config: %v", err)
 	}
 	ProcessConfig(&config, resources.GetProviderType())
+	if err = addRedactions(config); err != nil {
+		return nil, err
+	}
 	steps, err := getRunnableSteps(config, flags.Steps)
 	if err != nil {
 		return nil, err
 	}
-	sources, moduleSources, err := createSources(ctx, steps, config, state, resources.GetSSM())
+	sources, moduleSources, err := createSources(ctx, steps, config, state, resources.GetSSM(), flags.Cache.Dir)
 	if err != nil {
 		return nil, err
 	}
@@ -79,15 +98,33 @@ func NewUpdater(ctx context.Context, flags *common.Flags, resources model.Resour
 	if err != nil {
 		return nil, err
 	}
+	secrets, vault, err := createSecretResolvers(ctx, config)
+	if err != nil {
+		return nil, err
+	}
 	pipeline := ProcessPipelineFlags(flags.Pipeline)
+	var redactSecret *redactValues
 	if pipeline.Type != string(common.PipelineTypeLocal) {
-		wrapperConfigured, err := upsertWrapperConfig(config.Notifications, resources.GetCloudPrefix(), resources.GetSSM())
+		wrapperConfigured, err := upsertWrapperConfig(ctx, config, resources.GetCloudPrefix(), resources.GetSSM())
 		if err != nil {
 			return nil, err
 		}
 		if wrapperConfigured {
 			resources.GetPipeline().SetCampaignId(campaignId.String())
 		}
+		ageKeyConfigured, err := upsertSopsAgeKey(ctx, config, resources.GetCloudPrefix(), resources.GetSSM())
+		if err != nil {
+			return nil, err
+		}
+		resources.GetPipeline().SetSopsAgeKey(ageKeyConfigured)
+		redactSecret, err = newRedactValues(resources.GetCloudPrefix(), resources.GetSSM())
+		if err != nil {
+			return nil, err
+		}
+		if err = redactSecret.update(ctx); err != nil {
+			return nil, err
+		}
+		resources.GetPipeline().SetRedactValues(true)
 	}
 	return &updater{
 		ctx:           ctx,
@@ -105,11 +142,16 @@ func NewUpdater(ctx context.Context, flags *common.Flags, resources model.Resour
 		sources:       sources,
 		firstRunDone:  make(map[string]bool),
 		cmd:           command,
+		secrets:       secrets,
+		vault:         vault,
+		secretCache:   make(paramCache),
+		remotes:       newRemoteOutputs(ctx, flags, resources.GetProviderType(), config.Remotes),
+		redactValues:  redactSecret,
 	}, nil
 }
 
-func getLatestState(bucket model.Bucket) (*model.State, error) {
-	file, err := bucket.GetFile(stateFile)
+func getLatestState(ctx context.Context, bucket model.Bucket) (*model.State, error) {
+	file, err := bucket.GetFile(ctx, stateFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get state file: %v", err)
 	}
@@ -145,15 +187,27 @@ func getRunnableSteps(config model.Config, stepsFlag []string) ([]model.Step, er
 	return runnableSteps, nil
 }
 
-func createSources(ctx context.Context, steps []model.Step, config model.Config, state *model.State, ssm model.SSM) (map[model.SourceKey]*model.Source, map[string]model.SourceKey, error) {
+func createSources(ctx context.Context, steps []model.Step, config model.Config, state *model.State, ssm model.SSM, cacheDir string) (_ map[model.SourceKey]*model.Source, _ map[string]model.SourceKey, err error) {
+	spanCtx, span := tracing.Start(ctx, "createSources", attribute.Int("sources", len(config.Sources)))
+	defer tracing.End(span, &err)
+	var cache *git.Cache
+	if cacheDir != "" {
+		cache, err = git.NewCache(ctx, cacheDir)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 	sources := make(map[model.SourceKey]*model.Source)
 	for _, source := range config.Sources {
-		storage, stableVersion, err := getSourceStorage(ctx, source, config.Certs)
+		// Storage keeps the ctx for later git operations, so it doesn't get the ended span.
+		_, sourceSpan := tracing.Start(spanCtx, "getSourceStorage", attribute.String("source", source.URL))
+		storage, stableVersion, err := getSourceStorage(ctx, source, config.Certs, cache)
+		tracing.End(sourceSpan, &err)
 		if err != nil {
 			return nil, nil, err
 		}
 		if source.Username != "" {
-			if err := upsertSourceCredentials(source, ssm); err != nil {
+			if err := upsertSourceCredentials(ctx, source, ssm); err != nil {
 				return nil, nil, err
 			}
 		}
@@ -177,10 +231,19 @@ func createSources(ctx context.Context, steps []model.Step, config model.Config,
 		return nil, nil, err
 	}
 	err = addSourceReleases(steps, config.Sources, state, sources)
-	return sources, moduleSources, err
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, source := range sources {
+		if sourceClient, ok := source.Storage.(*git.SourceClient); ok {
+			source.Verifications = sourceClient.GetVerifications()
+			source.SkipVersions = sourceClient.GetSkipVersions()
+		}
+	}
+	return sources, moduleSources, nil
 }
 
-func getSourceStorage(ctx context.Context, source model.ConfigSource, certs []model.File) (model.Storage, *version.Version, error) {
+func getSourceStorage(ctx context.Context, source model.ConfigSource, certs []model.File, cache *git.Cache) (model.Storage, *version.Version, error) {
 	if util.IsLocalSource(source.URL) {
 		return git.NewLocalPath(source.URL), nil, nil
 	}
@@ -188,7 +251,7 @@ func getSourceStorage(ctx context.Context, source model.ConfigSource, certs []mo
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get CABundle for source %s: %v", source.CAFile, err)
 	}
-	sourceClient, err := git.NewSourceClient(ctx, source, CABundle)
+	sourceClient, err := git.NewSourceClient(ctx, source, CABundle, cache)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create source client %s: %v", source.URL, err)
 	}
@@ -207,17 +270,18 @@ func getCABundle(file string, certs []model.File) ([]byte, error) {
 		return nil, nil
 	}
 	for _, cert := range certs {
-		if filepath.Base(cert.Name) == file {
-			return cert.Content, nil
+		name, content := cert.Plain()
+		if filepath.Base(name) == file {
+			return content, nil
 		}
 	}
 	return nil, fmt.Errorf("CA file %s not found", file)
 }
 
-func upsertWrapperConfig(notifiers []model.ConfigNotification, prefix string, ssm model.SSM) (bool, error) {
-	notifierApi := getWrapperConfig(notifiers)
+func upsertWrapperConfig(ctx context.Context, config model.Config, prefix string, ssm model.SSM) (bool, error) {
+	notifierApi := getWrapperConfig(config)
 	if notifierApi == nil {
-		if err := ssm.DeleteSecret(model.WrapperConfigSecretName(prefix)); err != nil {
+		if err := ssm.DeleteSecret(ctx, model.WrapperConfigSecretName(prefix)); err != nil {
 			slog.Warn("failed to delete wrapper config secret", "error", err)
 		}
 		return false, nil
@@ -226,36 +290,102 @@ func upsertWrapperConfig(notifiers []model.ConfigNotification, prefix string, ss
 	if err != nil {
 		return false, fmt.Errorf("failed to marshal wrapper notifier config: %v", err)
 	}
-	if err = ssm.PutSecret(model.WrapperConfigSecretName(prefix), string(notifierYaml)); err != nil {
+	if err = ssm.PutSecret(ctx, model.WrapperConfigSecretName(prefix), string(notifierYaml)); err != nil {
 		return false, fmt.Errorf("failed to upsert wrapper config secret: %v", err)
 	}
 	return true, nil
 }
 
-func getWrapperConfig(notifiers []model.ConfigNotification) *model.NotificationApi {
-	for _, notifier := range notifiers {
+// upsertSopsAgeKey stores the dedicated pipeline age identity for decrypting step files and CA certificates. Agent
+// identities are never stored, without a pipeline identity the pipelines can only use KMS keys.
+func upsertSopsAgeKey(ctx context.Context, config model.Config, prefix string, ssm model.SSM) (bool, error) {
+	if config.PipelineAgeKey == "" {
+		if hasEncryptedStepFiles(config) {
+			common.Logger(ctx).Warn(common.PrefixWarning("Encrypted step files are decrypted in the pipelines only " +
+				"with KMS keys, set pipeline_age_key for age keys"))
+		}
+		return false, nil
+	}
+	if err := sops.ValidateAgeKey(config.PipelineAgeKey); err != nil {
+		return false, fmt.Errorf("failed to parse pipeline_age_key: %v", err)
+	}
+	redact.AddValues(config.PipelineAgeKey)
+	if err := ssm.PutSecret(ctx, model.SopsAgeKeySecretName(prefix), config.PipelineAgeKey); err != nil {
+		return false, fmt.Errorf("failed to upsert sops age key secret: %v", err)
+	}
+	return true, nil
+}
+
+// redactValues stores salted hashes of the values that are masked in the agent logs for the wrappers of cloud
+// pipelines, so the values aren't copied into the pipelines. Secret values are resolved while the steps are
+// processed, so the secret is updated before each step pipeline starts.
+type redactValues struct {
+	lock   sync.Mutex
+	prefix string
+	ssm    model.SSM
+	salt   []byte
+	stored []byte
+}
+
+func newRedactValues(prefix string, ssm model.SSM) (*redactValues, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate redact salt: %v", err)
+	}
+	return &redactValues{prefix: prefix, ssm: ssm, salt: salt}, nil
+}
+
+func (r *redactValues) update(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	content, err := json.Marshal(redact.GetHashes(r.salt))
+	if err != nil {
+		return fmt.Errorf("failed to marshal redact hashes: %v", err)
+	}
+	if r.stored != nil && bytes.Equal(r.stored, content) {
+		return nil
+	}
+	if len(content) > maxRedactValuesSize {
+		common.Logger(ctx).Warn(common.PrefixWarning(fmt.Sprintf("redact hashes exceed %d bytes, new values are "+
+			"not masked in the step pipelines", maxRedactValuesSize)))
+		return nil
+	}
+	if err = r.ssm.PutSecret(ctx, model.RedactValuesSecretName(r.prefix), string(content)); err != nil {
+		return fmt.Errorf("failed to upsert redact values secret: %v", err)
+	}
+	r.stored = content
+	return nil
+}
+
+func getWrapperConfig(config model.Config) *model.NotificationApi {
+	for _, notifier := range config.Notifications {
 		if notifier.Api == nil {
 			continue
 		}
 		if notifier.Api.WrapperURL == "" {
 			continue
 		}
-		return notifier.Api
+		api := *notifier.Api
+		api.RedactPatterns = config.RedactPatterns
+		return &api
 	}
 	return nil
 }
 
-func upsertSourceCredentials(source model.ConfigSource, ssm model.SSM) error {
+func upsertSourceCredentials(ctx context.Context, source model.ConfigSource, ssm model.SSM) error {
 	hash := util.HashCode(source.URL)
-	err := ssm.PutSecret(fmt.Sprintf(model.GitSourceFormat, hash), source.URL)
+	err := ssm.PutSecret(ctx, fmt.Sprintf(model.GitSourceFormat, hash), source.URL)
 	if err != nil {
 		return fmt.Errorf("failed to upsert secret %s: %v", fmt.Sprintf(model.GitSourceFormat, hash), err)
 	}
-	err = ssm.PutSecret(fmt.Sprintf(model.GitUsernameFormat, hash), source.Username)
+	err = ssm.PutSecret(ctx, fmt.Sprintf(model.GitUsernameFormat, hash), source.Username)
 	if err != nil {
 		return fmt.Errorf("failed to upsert secret %s: %v", fmt.Sprintf(model.GitUsernameFormat, hash), err)
 	}
-	err = ssm.PutSecret(fmt.Sprintf(model.GitPasswordFormat, hash), source.Password)
+	err = ssm.PutSecret(ctx, fmt.Sprintf(model.GitPasswordFormat, hash), source.Password)
 	if err != nil {
 		return fmt.Errorf("failed to upsert secret %s: %v", fmt.Sprintf(model.GitPasswordFormat, hash), err)
 	}
@@ -386,7 +516,7 @@ func addSourceReleases(steps []model.Step, configSources []model.ConfigSource, s
 		}
 		source.Version = upperVersion
 		if len(source.Modules) == 0 {
-			log.Printf("No modules found for Source %s\n", configSource.URL)
+			slog.Info(fmt.Sprintf("No modules found for Source %s", configSource.URL))
 		}
 		newestVersion, releases, err := getSourceReleases(steps, source, state)
 		if err != nil {
@@ -417,6 +547,43 @@ func createDestinations(ctx context.Context, config model.Config) (map[string]mo
 	return dests, nil
 }
 
+func createSecretResolvers(ctx context.Context, config model.Config) (map[string]model.SecretResolver, model.SecretResolver, error) {
+	resolvers := make(map[string]model.SecretResolver)
+	var vault model.SecretResolver
+	for _, configSecret := range config.Secrets {
+		var resolver model.SecretResolver
+		if configSecret.Vault != nil {
+			CABundle, err := getCABundle(configSecret.Vault.CAFile, config.Certs)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to get CABundle for secret backend %s: %v", configSecret.Name, err)
+			}
+			client, err := secret.NewVaultClient(ctx, configSecret.Name, *configSecret.Vault, CABundle)
+			if err != nil {
+				return nil, nil, fmt.Errorf("secret backend %s failed to create vault client: %v", configSecret.Name, err)
+			}
+			if vault == nil {
+				vault = client
+			}
+			resolver = client
+		} else if configSecret.File != nil {
+			client, err := secret.NewFileClient(*configSecret.File)
+			if err != nil {
+				return nil, nil, fmt.Errorf("secret backend %s failed to create file client: %v", configSecret.Name, err)
+			}
+			resolver = client
+		}
+		resolvers[configSecret.Name] = resolver
+	}
+	if vault == nil && os.Getenv(secret.VaultAddressEnv) != "" {
+		client, err := secret.NewVaultClient(ctx, string(model.ReplaceTypeVault), model.Vault{}, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create vault client from environment: %v", err)
+		}
+		vault = client
+	}
+	return resolvers, vault, nil
+}
+
 func getLocalPipeline(ctx context.Context, resources model.Resources, pipeline common.Pipeline, gcloudFlags common.GCloud, manager model.NotificationManager, config model.Config, campaignId string) *LocalPipeline {
 	if pipeline.Type == string(common.PipelineTypeLocal) {
 		return NewLocalPipeline(ctx, resources, pipeline, gcloudFlags, manager, config, campaignId)
@@ -425,6 +592,7 @@ func getLocalPipeline(ctx context.Context, resources model.Resources, pipeline c
 }
 
 func (u *updater) Process() (bool, error) {
+	defer u.recordReleasesBehind()
 	index := 0
 	mostReleases := 1
 	u.manager.Sources(u.sources)
@@ -432,7 +600,7 @@ func (u *updater) Process() (bool, error) {
 		index = 1
 		mostReleases = u.getMostReleases()
 		if mostReleases < 2 {
-			log.Println("No updates found")
+			common.Logger(u.ctx).Info("No updates found")
 			return false, nil
 		}
 	}
@@ -450,11 +618,56 @@ func (u *updater) Process() (bool, error) {
 	return true, nil
 }
 
-func (u *updater) setPipelineIndex(index int) {
-	pipelineIndex := index
+func (u *updater) recordReleasesBehind() {
+	u.stateLock.Lock()
+	defer u.stateLock.Unlock()
+	for _, step := range u.steps {
+		stepState := GetStepState(u.state, step.Name)
+		for _, module := range step.Modules {
+			if util.IsClientModule(module) {
+				continue
+			}
+			source := u.getModuleSource(module.Source)
+			moduleState := GetModuleState(stepState, module.Name)
+			if source == nil || source.ForcedVersion != "" || moduleState == nil {
+				continue
+			}
+			moduleVersion := moduleState.Version
+			if moduleState.AppliedVersion != nil {
+				moduleVersion = *moduleState.AppliedVersion
+			}
+			current, err := version.NewVersion(moduleVersion)
+			if err != nil {
+				continue
+			}
+			behind := 0
+			for _, release := range source.Releases {
+				if release.GreaterThan(current) {
+					behind++
+				}
+			}
+			metrics.ReleasesBehind(step.Name, module.Name, source.URL, behind)
+		}
+	}
+}
+
+func (u *updater) stepLogger(step model.Step) *slog.Logger {
+	return common.Logger(u.ctx).With(common.StepAttr, step.Name)
+}
+
+func (u *updater) moduleLogger(step model.Step, module model.Module) *slog.Logger {
+	return u.stepLogger(step).With(common.ModuleAttr, module.Name)
+}
+
+func (u *updater) getPipelineIndex(index int) int {
 	if u.cmd == common.RunCommand {
-		pipelineIndex = 1
+		return 1
 	}
+	return index
+}
+
+func (u *updater) setPipelineIndex(index int) {
+	pipelineIndex := u.getPipelineIndex(index)
 	u.manager.SetCurrentPipelineIndex(pipelineIndex)
 	if u.resources.GetPipeline() != nil {
 		u.resources.GetPipeline().SetPipelineIndex(pipelineIndex)
@@ -464,11 +677,14 @@ func (u *updater) setPipelineIndex(index int) {
 	}
 }
 
-func (u *updater) processRelease(index int) error {
+func (u *updater) processRelease(index int) (err error) {
+	ctx, span := tracing.Start(u.ctx, "updater.processRelease", attribute.Int("release.index", index))
+	defer tracing.End(span, &err)
+	ctx = common.WithLogAttrs(ctx, common.PipelineIndexAttr, u.getPipelineIndex(index))
 	u.logReleases(index)
 	u.updateState()
 	if u.cmd == common.UpdateCommand {
-		if err := u.updateChecksums(index); err != nil {
+		if err = u.updateChecksums(index); err != nil {
 			return err
 		}
 	}
@@ -477,13 +693,13 @@ func (u *updater) processRelease(index int) error {
 	var failedSteps []string
 	retrySteps := make([]model.Step, 0)
 	for _, step := range u.steps {
-		retry, err := u.processStep(index, step, wg, errChan)
+		retry, err := u.processStep(ctx, index, step, wg, errChan)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				wg.Wait()
 				return err
 			}
-			slog.Error(common.PrefixError(err))
+			common.Logger(ctx).Error(common.PrefixError(err))
 			failedSteps = append(failedSteps, step.Name)
 			break
 		}
@@ -497,7 +713,7 @@ func (u *updater) processRelease(index int) error {
 		return u.ctx.Err()
 	}
 	time.Sleep(1 * time.Second)
-	err := u.putStateFileOrDie()
+	err = u.putStateFileOrDie()
 	if err != nil {
 		return err
 	}
@@ -507,7 +723,7 @@ func (u *updater) processRelease(index int) error {
 	if len(failedSteps) > 0 {
 		return fmt.Errorf("failed to apply steps %s", strings.Join(failedSteps, ", "))
 	}
-	err = u.retrySteps(index, retrySteps, wg)
+	err = u.retrySteps(ctx, index, retrySteps, wg)
 	if err != nil {
 		return err
 	}
@@ -523,7 +739,7 @@ func (u *updater) getMostReleases() int {
 	mostReleases := 0
 	for _, source := range u.sources {
 		if source.ForcedVersion != "" {
-			slog.Warn(common.PrefixWarning(fmt.Sprintf("Source %s has forced version %s", source.URL,
+			common.Logger(u.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Source %s has forced version %s", source.URL,
 				source.ForcedVersion)))
 		}
 		if len(source.Releases) > mostReleases {
@@ -545,7 +761,7 @@ func (u *updater) logReleases(index int) {
 			sourceReleases = append(sourceReleases, fmt.Sprintf("%s %s", source.URL, release.Original()))
 		}
 	}
-	log.Printf("Applying releases: %s", strings.Join(sourceReleases, ", "))
+	common.Logger(u.ctx).Info(fmt.Sprintf("Applying releases: %s", strings.Join(sourceReleases, ", ")))
 }
 
 func (u *updater) getNotifySourceVersions(index int) []model.SourceVersion {
@@ -581,11 +797,15 @@ func (u *updater) updateState() {
 		createState(u.config, u.state)
 		return
 	}
-	removeUnusedSteps(u.resources.GetCloudPrefix(), u.config, u.state, u.resources.GetBucket())
+	removeUnusedSteps(u.ctx, u.resources.GetCloudPrefix(), u.config, u.state, u.resources.GetBucket())
 	addNewSteps(u.config, u.state)
 }
 
-func (u *updater) processStep(index int, step model.Step, wg *model.SafeCounter, errChan chan<- string) (bool, error) {
+func (u *updater) processStep(ctx context.Context, index int, step model.Step, wg *model.SafeCounter, errChan chan<- string) (_ bool, err error) {
+	ctx, span := tracing.Start(ctx, "updater.processStep", attribute.String("step", step.Name),
+		attribute.Int("release.index", index))
+	defer tracing.End(span, &err)
+	ctx = common.WithLogAttrs(ctx, common.StepAttr, step.Name)
 	stepState, err := u.getStepState(step)
 	if err != nil {
 		return false, err
@@ -606,26 +826,26 @@ func (u *updater) processStep(index int, step model.Step, wg *model.SafeCounter,
 		u.postCallback(model.ApplyStatusFailure, *stepState, err)
 		var parameterError *model.ParameterNotFoundError
 		if wg.HasCount() && errors.As(err, &parameterError) {
-			slog.Warn(common.PrefixWarning(err.Error()))
-			log.Printf("Step %s will be retried if others succeed\n", step.Name)
+			common.Logger(ctx).Warn(common.PrefixWarning(err.Error()))
+			common.Logger(ctx).Info(fmt.Sprintf("Step %s will be retried if others succeed", step.Name))
 			return true, nil
 		}
 		return false, err
 	}
 	if !u.firstRunDone[step.Name] {
-		err = u.updateCertFiles(step.Name)
+		err = u.updateCertFiles(ctx, step.Name)
 		if err != nil {
 			u.postCallback(model.ApplyStatusFailure, *stepState, err)
 			return false, err
 		}
 	}
-	executePipelines, providers, files, err := u.updateStepFiles(step, moduleVersions, index)
+	executePipelines, providers, files, err := u.updateStepFiles(ctx, step, moduleVersions, index)
 	if err != nil {
 		u.postCallback(model.ApplyStatusFailure, *stepState, err)
 		return false, err
 	}
 	u.updateStepChecksums(step, files)
-	err = u.applyRelease(!u.firstRunDone[step.Name], executePipelines, step, stepState, index, providers, wg, errChan, files)
+	err = u.applyRelease(ctx, !u.firstRunDone[step.Name], executePipelines, step, stepState, index, providers, wg, errChan, files)
 	if err != nil {
 		return false, err
 	}
@@ -633,16 +853,16 @@ func (u *updater) processStep(index int, step model.Step, wg *model.SafeCounter,
 	return false, nil
 }
 
-func (u *updater) retrySteps(index int, retrySteps []model.Step, wg *model.SafeCounter) error {
+func (u *updater) retrySteps(ctx context.Context, index int, retrySteps []model.Step, wg *model.SafeCounter) error {
 	if len(retrySteps) == 0 {
 		return nil
 	}
 	u.pipelineFlags.AllowParallel = false
 	for _, step := range retrySteps {
-		log.Printf("Retrying step %s\n", step.Name)
-		_, err := u.processStep(index, step, wg, nil)
+		common.Logger(ctx).Info(fmt.Sprintf("Retrying step %s", step.Name))
+		_, err := u.processStep(ctx, index, step, wg, nil)
 		if err != nil {
-			slog.Error(common.PrefixError(err))
+			common.Logger(ctx).Error(common.PrefixError(err))
 			return fmt.Errorf("failed to apply step %s", step.Name)
 		}
 	}
@@ -660,39 +880,39 @@ func (u *updater) updateDestinationsApplyFiles(step model.Step, files map[string
 func (u *updater) updateDestinationsFiles(step model.Step, branch string, files map[string]model.File) {
 	folder := fmt.Sprintf("steps/%s-%s", u.resources.GetCloudPrefix(), step.Name)
 	for name, destination := range u.destinations {
-		log.Printf("Step %s updating %s files for destination %s\n", step.Name, branch, name)
+		u.stepLogger(step).Info(fmt.Sprintf("Step %s updating %s files for destination %s", step.Name, branch, name))
 		err := destination.UpdateFiles(branch, folder, files)
 		if err != nil {
-			slog.Warn(common.PrefixWarning(fmt.Sprintf("Step %s failed to update %s files for destination %s: %s",
+			u.stepLogger(step).Warn(common.PrefixWarning(fmt.Sprintf("Step %s failed to update %s files for destination %s: %s",
 				step.Name, branch, name, err)))
 			continue
 		}
 	}
 }
 
-func (u *updater) applyRelease(firstRun bool, executePipelines bool, step model.Step, stepState *model.StateStep, index int, providers map[model.SourceKey]model.Set[string], wg *model.SafeCounter, errChan chan<- string, files map[string]model.File) error {
+func (u *updater) applyRelease(ctx context.Context, firstRun bool, executePipelines bool, step model.Step, stepState *model.StateStep, index int, providers map[model.SourceKey]model.Set[string], wg *model.SafeCounter, errChan chan<- string, files map[string]model.File) error {
 	if !executePipelines && !firstRun {
-		log.Printf("Skipping step %s because all applied module versions are newer or older than current releases\n", step.Name)
-		u.postCallBackWithMetadata(stepState, step, model.ApplyStatusSkipped, index)
+		common.Logger(ctx).Info(fmt.Sprintf("Skipping step %s because all applied module versions are newer or older than current releases", step.Name))
+		u.postCallBackWithMetadata(stepState, step, model.ApplyStatusSkipped, index, nil)
 		return nil
 	}
 	u.updateDestinationsPlanFiles(step, files)
 	if !firstRun {
 		if !u.hasChanged(step, providers) {
-			log.Printf("Skipping step %s\n", step.Name)
-			return u.putAppliedStateFile(stepState, step, model.ApplyStatusSkipped, index)
+			common.Logger(ctx).Info(fmt.Sprintf("Skipping step %s", step.Name))
+			return u.putAppliedStateFile(stepState, step, model.ApplyStatusSkipped, index, nil)
 		}
-		return u.executePipeline(firstRun, step, stepState, index, files)
+		return u.executePipeline(ctx, firstRun, step, stepState, index, files)
 	}
 	if !u.pipelineFlags.AllowParallel || !u.appliedVersionMatchesRelease(step, *stepState, index) {
-		return u.executePipeline(firstRun, step, stepState, index, files)
+		return u.executePipeline(ctx, firstRun, step, stepState, index, files)
 	}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		err := u.executePipeline(firstRun, step, stepState, index, files)
+		err := u.executePipeline(ctx, firstRun, step, stepState, index, files)
 		if err != nil {
-			slog.Error(common.PrefixError(err))
+			common.Logger(ctx).Error(common.PrefixError(err))
 			errChan <- step.Name
 		}
 	}()
@@ -702,22 +922,22 @@ func (u *updater) applyRelease(firstRun bool, executePipelines bool, step model.
 func (u *updater) hasChanged(step model.Step, providers map[model.SourceKey]model.Set[string]) bool {
 	changed := u.getChangedProviders(providers)
 	if len(changed) > 0 {
-		log.Printf("Step %s providers have changed: %s\n", step.Name, strings.Join(changed, ", "))
+		u.stepLogger(step).Info(fmt.Sprintf("Step %s providers have changed: %s", step.Name, strings.Join(changed, ", ")))
 		return true
 	}
 	changed = u.getChangedModules(step)
 	if len(changed) > 0 {
-		log.Printf("Step %s modules have changed: %s\n", step.Name, strings.Join(changed, ", "))
+		u.stepLogger(step).Info(fmt.Sprintf("Step %s modules have changed: %s", step.Name, strings.Join(changed, ", ")))
 		return true
 	}
 	changed = u.getChangedStepModules(step)
 	if len(changed) > 0 {
-		log.Printf("Step %s module inputs have changed: %s\n", step.Name, strings.Join(changed, ", "))
+		u.stepLogger(step).Info(fmt.Sprintf("Step %s module inputs have changed: %s", step.Name, strings.Join(changed, ", ")))
 		return true
 	}
 	changed = u.getChangedStepFiles(step)
 	if len(changed) > 0 {
-		log.Printf("Step %s files have changed: %s\n", step.Name, strings.Join(changed, ", "))
+		u.stepLogger(step).Info(fmt.Sprintf("Step %s files have changed: %s", step.Name, strings.Join(changed, ", ")))
 		return true
 	}
 	return false
@@ -734,18 +954,18 @@ func (u *updater) getChangedProviders(repoProviders map[model.SourceKey]model.Se
 			providerKey := fmt.Sprintf("providers/%s.tf", provider)
 			previousChecksum, ok := providerSource.PreviousChecksums[providerKey]
 			if !ok {
-				slog.Debug(fmt.Sprintf("Provider %s not found in previous checksums", provider))
+				common.Logger(u.ctx).Debug(fmt.Sprintf("Provider %s not found in previous checksums", provider))
 				changed = append(changed, provider)
 				continue
 			}
 			currentChecksum, ok := providerSource.CurrentChecksums[providerKey]
 			if !ok {
-				slog.Debug(fmt.Sprintf("Provider %s not found in current checksums", provider))
+				common.Logger(u.ctx).Debug(fmt.Sprintf("Provider %s not found in current checksums", provider))
 				changed = append(changed, provider)
 				continue
 			}
 			if !bytes.Equal(previousChecksum, currentChecksum) {
-				slog.Debug(fmt.Sprintf("Provider %s has changed, previous %s, current %s", provider,
+				common.Logger(u.ctx).Debug(fmt.Sprintf("Provider %s has changed, previous %s, current %s", provider,
 					hex.EncodeToString(previousChecksum), hex.EncodeToString(currentChecksum)))
 				changed = append(changed, provider)
 			}
@@ -765,7 +985,7 @@ func (u *updater) getChangedModules(step model.Step) []string {
 		}
 		moduleSource := u.getModuleSource(module.Source)
 		if moduleSource.PreviousChecksums == nil || moduleSource.CurrentChecksums == nil {
-			slog.Debug(fmt.Sprintf("Module %s source is missing checksums", module.Name))
+			u.moduleLogger(step, module).Debug(fmt.Sprintf("Module %s source is missing checksums", module.Name))
 			changed = append(changed, module.Name)
 			continue
 		}
@@ -776,18 +996,18 @@ func (u *updater) getChangedModules(step model.Step) []string {
 		moduleKey := fmt.Sprintf("modules/%s", source)
 		previousChecksum, ok := moduleSource.PreviousChecksums[moduleKey]
 		if !ok {
-			slog.Debug(fmt.Sprintf("Module %s not found in previous checksums", module.Name))
+			u.moduleLogger(step, module).Debug(fmt.Sprintf("Module %s not found in previous checksums", module.Name))
 			changed = append(changed, module.Name)
 			continue
 		}
 		currentChecksum, ok := moduleSource.CurrentChecksums[moduleKey]
 		if !ok {
-			slog.Debug(fmt.Sprintf("Module %s not found in current checksums", module.Name))
+			u.moduleLogger(step, module).Debug(fmt.Sprintf("Module %s not found in current checksums", module.Name))
 			changed = append(changed, module.Name)
 			continue
 		}
 		if !bytes.Equal(previousChecksum, currentChecksum) {
-			slog.Debug(fmt.Sprintf("Module %s has changed, previous %s, current %s", module.Name,
+			u.moduleLogger(step, module).Debug(fmt.Sprintf("Module %s has changed, previous %s, current %s", module.Name,
 				hex.EncodeToString(previousChecksum), hex.EncodeToString(currentChecksum)))
 			changed = append(changed, module.Name)
 		}
@@ -802,7 +1022,7 @@ func (u *updater) getChangedStepModules(step model.Step) []string {
 	}
 	previousChecksums, exists := u.stepChecksums.PreviousChecksums[step.Name]
 	if !exists {
-		slog.Debug(fmt.Sprintf("Step %s is missing previous checksums", step.Name))
+		u.stepLogger(step).Debug(fmt.Sprintf("Step %s is missing previous checksums", step.Name))
 		for _, module := range step.Modules {
 			changed = append(changed, module.Name)
 		}
@@ -810,7 +1030,7 @@ func (u *updater) getChangedStepModules(step model.Step) []string {
 	}
 	currentChecksums, exists := u.stepChecksums.CurrentChecksums[step.Name]
 	if !exists {
-		slog.Debug(fmt.Sprintf("Step %s is missing current checksums", step.Name))
+		u.stepLogger(step).Debug(fmt.Sprintf("Step %s is missing current checksums", step.Name))
 		for _, module := range step.Modules {
 			changed = append(changed, module.Name)
 		}
@@ -824,7 +1044,7 @@ func (u *updater) getChangedStepModules(step model.Step) []string {
 		current := currentChecksums.ModuleChecksums[module.Name]
 		if !bytes.Equal(previous, current) {
 			changed = append(changed, module.Name)
-			slog.Debug(fmt.Sprintf("Module %s inputs have changed, previous %s, current %s", module.Name,
+			u.moduleLogger(step, module).Debug(fmt.Sprintf("Module %s inputs have changed, previous %s, current %s", module.Name,
 				hex.EncodeToString(previous), hex.EncodeToString(current)))
 
 		}
@@ -839,7 +1059,7 @@ func (u *updater) getChangedStepFiles(step model.Step) []string {
 	}
 	previousChecksums, exists := u.stepChecksums.PreviousChecksums[step.Name]
 	if !exists {
-		slog.Debug(fmt.Sprintf("Step %s is missing previous checksums", step.Name))
+		u.stepLogger(step).Debug(fmt.Sprintf("Step %s is missing previous checksums", step.Name))
 		for _, file := range step.Files {
 			changed = append(changed, file.Name)
 		}
@@ -847,7 +1067,7 @@ func (u *updater) getChangedStepFiles(step model.Step) []string {
 	}
 	currentChecksums, exists := u.stepChecksums.CurrentChecksums[step.Name]
 	if !exists {
-		slog.Debug(fmt.Sprintf("Step %s is missing current checksums", step.Name))
+		u.stepLogger(step).Debug(fmt.Sprintf("Step %s is missing current checksums", step.Name))
 		for _, file := range step.Files {
 			changed = append(changed, file.Name)
 		}
@@ -857,7 +1077,7 @@ func (u *updater) getChangedStepFiles(step model.Step) []string {
 		previous := previousChecksums.FileChecksums[name]
 		if !bytes.Equal(previous, file) {
 			changed = append(changed, name)
-			slog.Debug(fmt.Sprintf("File %s has changed, previous %s, current %s", name, string(previous),
+			u.stepLogger(step).Debug(fmt.Sprintf("File %s has changed, previous %s, current %s", name, string(previous),
 				string(file)))
 		}
 	}
@@ -885,29 +1105,52 @@ func (u *updater) appliedVersionMatchesRelease(step model.Step, stepState model.
 	return true
 }
 
-func (u *updater) executePipeline(firstRun bool, step model.Step, stepState *model.StateStep, index int, files map[string]model.File) error {
-	log.Printf("Applying release for step %s\n", step.Name)
+func (u *updater) executePipeline(ctx context.Context, firstRun bool, step model.Step, stepState *model.StateStep, index int, files map[string]model.File) (err error) {
+	ctx, span := tracing.Start(ctx, "updater.executePipeline", attribute.String("step", step.Name))
+	defer tracing.End(span, &err)
+	common.Logger(ctx).Info(fmt.Sprintf("Applying release for step %s", step.Name))
 	autoApprove := getAutoApprove(*stepState)
-	var err error
+	started := time.Now()
+	var timings []model.ResourceTiming
 	if u.pipelineFlags.Type == string(common.PipelineTypeLocal) {
-		err = u.localPipeline.executeLocalPipeline(step, autoApprove, u.getStepAuthSources(step), u.getManualApproval(step))
-	} else if firstRun {
-		err = u.createExecuteStepPipelines(step, autoApprove, index)
+		timings, err = u.localPipeline.executeLocalPipeline(ctx, step, autoApprove, u.getStepAuthSources(step), u.getManualApproval(step))
 	} else {
-		err = u.executeStepPipelines(step, autoApprove, index)
+		if firstRun {
+			err = u.createExecuteStepPipelines(ctx, step, autoApprove, index)
+		} else {
+			err = u.executeStepPipelines(ctx, step, autoApprove, index)
+		}
+		timings = u.getPipelineTimings(ctx, step, started)
 	}
+	slowest := model.SlowestResources(timings, model.SlowestResourcesCount)
 	if err != nil {
-		u.postCallback(model.ApplyStatusFailure, *stepState, err)
+		metrics.StepFinished(step.Name, model.ApplyStatusFailure, time.Since(started))
+		u.postCallbackWithStep(model.ApplyStatusFailure, *stepState, nil, slowest, err)
 		return err
 	}
-	log.Printf("release applied successfully for step %s\n", step.Name)
-	err = u.putAppliedStateFile(stepState, step, model.ApplyStatusSuccess, index)
+	metrics.StepFinished(step.Name, model.ApplyStatusSuccess, time.Since(started))
+	common.Logger(ctx).Info(fmt.Sprintf("release applied successfully for step %s", step.Name))
+	err = u.putAppliedStateFile(stepState, step, model.ApplyStatusSuccess, index, slowest)
 	if err == nil {
 		u.updateDestinationsApplyFiles(step, files)
 	}
 	return err
 }
 
+// getPipelineTimings returns the resource timings that the wrapper stored for the cloud pipeline apply.
+func (u *updater) getPipelineTimings(ctx context.Context, step model.Step, started time.Time) []model.ResourceTiming {
+	prefixStep := fmt.Sprintf("%s-%s", u.resources.GetCloudPrefix(), step.Name)
+	report, err := getTimingReport(ctx, u.resources.GetBucket(), prefixStep, started)
+	if err != nil {
+		common.Logger(ctx).Warn(common.PrefixWarning(err.Error()))
+		return nil
+	}
+	if report == nil {
+		return nil
+	}
+	return report.Resources
+}
+
 func (u *updater) getStepState(step model.Step) (*model.StateStep, error) {
 	stepState := GetStepState(u.state, step.Name)
 	if stepState == nil {
@@ -916,21 +1159,21 @@ func (u *updater) getStepState(step model.Step) (*model.StateStep, error) {
 	return stepState, nil
 }
 
-func (u *updater) updateCertFiles(stepName string) error {
+func (u *updater) updateCertFiles(ctx context.Context, stepName string) error {
 	folder := fmt.Sprintf("steps/%s-%s", u.resources.GetCloudPrefix(), stepName)
 	if len(u.config.Certs) == 0 {
-		return removeFolder(u.resources.GetBucket(), fmt.Sprintf("%s/%s", folder, certsFolder))
+		return removeFolder(ctx, u.resources.GetBucket(), fmt.Sprintf("%s/%s", folder, certsFolder))
 	}
 	allFiles := model.NewSet[string]()
 	for _, file := range u.config.Certs {
 		filePath := fmt.Sprintf("%s/%s", folder, file.Name)
-		err := u.resources.GetBucket().PutFile(filePath, file.Content)
+		err := u.resources.GetBucket().PutFile(ctx, filePath, file.Content)
 		if err != nil {
 			return err
 		}
 		allFiles.Add(filePath)
 	}
-	bucketFiles, err := u.resources.GetBucket().ListFolderFiles(fmt.Sprintf("%s/%s", folder, certsFolder))
+	bucketFiles, err := u.resources.GetBucket().ListFolderFiles(ctx, fmt.Sprintf("%s/%s", folder, certsFolder))
 	if err != nil {
 		return fmt.Errorf("failed to list folder allFiles: %w", err)
 	}
@@ -938,7 +1181,7 @@ func (u *updater) updateCertFiles(stepName string) error {
 		if allFiles.Contains(bucketFile) {
 			continue
 		}
-		err = u.resources.GetBucket().DeleteFile(bucketFile)
+		err = u.resources.GetBucket().DeleteFile(ctx, bucketFile)
 		if err != nil {
 			return fmt.Errorf("failed to delete file %s: %w", bucketFile, err)
 		}
@@ -972,21 +1215,21 @@ func (u *updater) updateStepChecksums(step model.Step, files map[string]model.Fi
 	u.stepChecksums.CurrentChecksums[step.Name] = stepChecksum
 }
 
-func (u *updater) updateStepFiles(step model.Step, moduleVersions map[string]model.ModuleVersion, index int) (bool, map[model.SourceKey]model.Set[string], map[string]model.File, error) {
+func (u *updater) updateStepFiles(ctx context.Context, step model.Step, moduleVersions map[string]model.ModuleVersion, index int) (bool, map[model.SourceKey]model.Set[string], map[string]model.File, error) {
 	switch step.Type {
 	case model.StepTypeTerraform:
-		return u.updateTerraformFiles(step, moduleVersions, index)
+		return u.updateTerraformFiles(ctx, step, moduleVersions, index)
 	case model.StepTypeArgoCD:
-		execute, files, err := u.updateArgoCDFiles(step, moduleVersions)
+		execute, files, err := u.updateArgoCDFiles(ctx, step, moduleVersions)
 		return execute, nil, files, err
 	default:
 		return false, nil, nil, fmt.Errorf("step type %s not supported", step.Type)
 	}
 }
 
-func (u *updater) createExecuteStepPipelines(step model.Step, autoApprove bool, index int) error {
+func (u *updater) createExecuteStepPipelines(ctx context.Context, step model.Step, autoApprove bool, index int) error {
 	bucket := u.resources.GetBucket()
-	repoMetadata, err := bucket.GetRepoMetadata()
+	repoMetadata, err := bucket.GetRepoMetadata(ctx)
 	if err != nil {
 		return err
 	}
@@ -996,12 +1239,15 @@ func (u *updater) createExecuteStepPipelines(step model.Step, autoApprove bool,
 	vpcConfig := u.getVpcConfig(step)
 	imageVersion, imageSource := u.getBaseImage(step, index)
 	sources := u.getStepAuthSources(step)
+	if err = u.redactValues.update(ctx); err != nil {
+		return err
+	}
 	err = u.resources.GetBuilder().CreateProject(stepName, repoMetadata.URL, stepName, step, imageVersion, imageSource,
 		vpcConfig, sources)
 	if err != nil {
 		return fmt.Errorf("failed to create CodeBuild project: %w", err)
 	}
-	return u.createExecutePipelines(stepName, stepName, step, autoApprove, bucket, sources)
+	return u.createExecutePipelines(ctx, stepName, stepName, step, autoApprove, bucket, sources)
 }
 
 func (u *updater) getVpcConfig(step model.Step) *model.VpcConfig {
@@ -1018,12 +1264,12 @@ func (u *updater) getVpcConfig(step model.Step) *model.VpcConfig {
 	}
 }
 
-func (u *updater) createExecutePipelines(projectName string, stepName string, step model.Step, autoApprove bool, bucket model.Bucket, authSources map[string]model.SourceAuth) error {
-	executionId, err := u.resources.GetPipeline().CreatePipeline(projectName, stepName, step, bucket, authSources)
+func (u *updater) createExecutePipelines(ctx context.Context, projectName string, stepName string, step model.Step, autoApprove bool, bucket model.Bucket, authSources map[string]model.SourceAuth) error {
+	executionId, err := u.resources.GetPipeline().CreatePipeline(ctx, projectName, stepName, step, bucket, authSources)
 	if err != nil {
 		return fmt.Errorf("failed to create pipeline %s: %w", projectName, err)
 	}
-	err = u.resources.GetPipeline().WaitPipelineExecution(projectName, projectName, executionId, autoApprove, step, u.getManualApproval(step))
+	err = u.resources.GetPipeline().WaitPipelineExecution(ctx, projectName, projectName, executionId, autoApprove, step, u.getManualApproval(step))
 	if err != nil {
 		return fmt.Errorf("failed to wait for pipeline %s execution: %w", projectName, err)
 	}
@@ -1065,12 +1311,12 @@ func (u *updater) getStepAuthSources(step model.Step) map[string]model.SourceAut
 	return authSources
 }
 
-func (u *updater) executeStepPipelines(step model.Step, autoApprove bool, index int) error {
+func (u *updater) executeStepPipelines(ctx context.Context, step model.Step, autoApprove bool, index int) error {
 	stepName := fmt.Sprintf("%s-%s", u.resources.GetCloudPrefix(), step.Name)
 	vpcConfig := u.getVpcConfig(step)
 	imageVersion, imageSource := u.getBaseImage(step, index)
 	bucket := u.resources.GetBucket()
-	repoMetadata, err := bucket.GetRepoMetadata()
+	repoMetadata, err := bucket.GetRepoMetadata(ctx)
 	if err != nil {
 		return err
 	}
@@ -1084,11 +1330,14 @@ func (u *updater) executeStepPipelines(step model.Step, autoApprove bool, index
 	if err != nil {
 		return err
 	}
-	executionId, err := u.resources.GetPipeline().StartPipelineExecution(stepName, stepName, step, repoMetadata.Name)
+	if err = u.redactValues.update(ctx); err != nil {
+		return err
+	}
+	executionId, err := u.resources.GetPipeline().StartPipelineExecution(ctx, stepName, stepName, step, repoMetadata.Name)
 	if err != nil {
 		return fmt.Errorf("failed to start pipeline %s execution: %w", stepName, err)
 	}
-	return u.resources.GetPipeline().WaitPipelineExecution(stepName, stepName, executionId, autoApprove, step, u.getManualApproval(step))
+	return u.resources.GetPipeline().WaitPipelineExecution(ctx, stepName, stepName, executionId, autoApprove, step, u.getManualApproval(step))
 }
 
 func getAutoApprove(state model.StateStep) bool {
@@ -1102,20 +1351,25 @@ func getAutoApprove(state model.StateStep) bool {
 
 func getSourceReleases(steps []model.Step, source *model.Source, state *model.State) (*version.Version, []*version.Version, error) {
 	sourceClient := source.Storage.(*git.SourceClient)
-	oldestVersion, err := getOldestVersion(steps, source, state)
+	oldestVersion, applied, err := getOldestVersion(steps, source, state)
 	if err != nil {
 		return nil, nil, err
 	}
 	if oldestVersion == StableVersion || oldestVersion == source.StableVersion.Original() {
 		latestRelease := source.StableVersion
-		log.Printf("Latest release for %s is %s\n", source.URL, latestRelease.Original())
+		slog.Info(fmt.Sprintf("Latest release for %s is %s", source.URL, latestRelease.Original()))
 		return latestRelease, []*version.Version{latestRelease}, nil
 	}
-	oldestRelease, err := sourceClient.GetRelease(getFormattedVersionString(oldestVersion))
+	var oldestRelease *version.Version
+	if applied {
+		oldestRelease, err = sourceClient.GetAppliedRelease(getFormattedVersionString(oldestVersion))
+	} else {
+		oldestRelease, err = sourceClient.GetRelease(getFormattedVersionString(oldestVersion))
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get oldest release %s: %w", oldestVersion, err)
 	}
-	log.Printf("Oldest module version for %s is %s\n", source.URL, oldestRelease.Original())
+	slog.Info(fmt.Sprintf("Oldest module version for %s is %s", source.URL, oldestRelease.Original()))
 
 	newestVersion, err := getNewestVersion(steps, source)
 	if err != nil {
@@ -1127,7 +1381,7 @@ func getSourceReleases(steps []model.Step, source *model.Source, state *model.St
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to get newest release %s: %w", oldestVersion, err)
 		}
-		log.Printf("Newest module version for %s is %s\n", source.URL, newestRelease.Original())
+		slog.Info(fmt.Sprintf("Newest module version for %s is %s", source.URL, newestRelease.Original()))
 	}
 
 	releases, err := sourceClient.GetReleases(oldestRelease, newestRelease)
@@ -1137,8 +1391,10 @@ func getSourceReleases(steps []model.Step, source *model.Source, state *model.St
 	return releases[len(releases)-1], releases, nil
 }
 
-func getOldestVersion(steps []model.Step, source *model.Source, state *model.State) (string, error) {
+// getOldestVersion also returns whether the oldest version is already applied by any of the modules
+func getOldestVersion(steps []model.Step, source *model.Source, state *model.State) (string, bool, error) {
 	oldestVersion := source.Version.Original()
+	appliedVersions := model.NewSet[string]()
 	var err error
 	for _, step := range steps {
 		stepState := GetStepState(state, step.Name)
@@ -1148,7 +1404,7 @@ func getOldestVersion(steps []model.Step, source *model.Source, state *model.Sta
 			}
 			oldestVersion, err = getOlderVersion(oldestVersion, module.Version)
 			if err != nil {
-				return "", err
+				return "", false, err
 			}
 			if stepState == nil {
 				continue
@@ -1163,16 +1419,17 @@ func getOldestVersion(steps []model.Step, source *model.Source, state *model.Sta
 			moduleStateVersion := ""
 			if moduleState.AppliedVersion != nil {
 				moduleStateVersion = *moduleState.AppliedVersion
+				appliedVersions.Add(moduleStateVersion)
 			} else if moduleState.Version != "" {
 				moduleStateVersion = moduleState.Version
 			}
 			oldestVersion, err = getOlderVersion(oldestVersion, moduleStateVersion)
 			if err != nil {
-				return "", err
+				return "", false, err
 			}
 		}
 	}
-	return oldestVersion, nil
+	return oldestVersion, appliedVersions.Contains(oldestVersion), nil
 }
 
 func getOlderVersion(oldestVersion string, compareVersion string) (string, error) {
@@ -1212,15 +1469,30 @@ func getNewestVersion(steps []model.Step, source *model.Source) (string, error)
 			if moduleVersion == "" {
 				moduleVersion = source.Version.Original()
 			}
+			if isModuleVersionSkipped(module, source, moduleVersion) {
+				slog.Warn(common.PrefixWarning(fmt.Sprintf("Module %s version %s is skipped", module.Name, moduleVersion)))
+				continue
+			}
 			newestVersion, err = getNewerVersion(newestVersion, moduleVersion)
 			if err != nil {
 				return "", err
 			}
 		}
 	}
+	if newestVersion == "" && source.Version != nil {
+		return source.Version.Original(), nil
+	}
 	return newestVersion, nil
 }
 
+func isModuleVersionSkipped(module model.Module, source *model.Source, moduleVersion string) bool {
+	moduleSemver, err := version.NewVersion(moduleVersion)
+	if err != nil {
+		return false
+	}
+	return util.IsVersionSkipped(module.SkipVersions, moduleSemver) || util.IsVersionSkipped(source.SkipVersions, moduleSemver)
+}
+
 func getNewerVersion(newestVersion string, moduleVersion string) (string, error) {
 	if newestVersion == "" {
 		return moduleVersion, nil
@@ -1240,19 +1512,19 @@ func getNewerVersion(newestVersion string, moduleVersion string) (string, error)
 	}
 }
 
-func (u *updater) updateTerraformFiles(step model.Step, moduleVersions map[string]model.ModuleVersion, index int) (bool, map[model.SourceKey]model.Set[string], map[string]model.File, error) {
+func (u *updater) updateTerraformFiles(ctx context.Context, step model.Step, moduleVersions map[string]model.ModuleVersion, index int) (bool, map[model.SourceKey]model.Set[string], map[string]model.File, error) {
 	files := make(map[string]model.File)
-	mainPath, mainFile, err := u.createBackendConf(fmt.Sprintf("%s-%s", u.resources.GetCloudPrefix(), step.Name), u.resources.GetBucket())
+	mainPath, mainFile, err := u.createBackendConf(ctx, fmt.Sprintf("%s-%s", u.resources.GetCloudPrefix(), step.Name), u.resources.GetBucket())
 	if err != nil {
 		return false, nil, nil, err
 	}
 	files[mainPath] = model.File{Content: mainFile}
-	changed, mainPath, mainBytes, err := u.createTerraformMain(step, moduleVersions)
+	changed, mainPath, mainBytes, err := u.createTerraformMain(ctx, step, moduleVersions)
 	if err != nil {
 		return false, nil, nil, err
 	}
 	files[mainPath] = model.File{Content: mainBytes}
-	err = u.updateIncludedStepFiles(step, ReservedTFFiles, model.NewSet(terraformCache, certsFolder), files)
+	err = u.updateIncludedStepFiles(ctx, step, ReservedTFFiles, model.NewSet(terraformCache, certsFolder), files)
 	if err != nil {
 		return false, nil, nil, err
 	}
@@ -1278,7 +1550,7 @@ func (u *updater) updateTerraformFiles(step model.Step, moduleVersions map[strin
 	}
 	providerFile := fmt.Sprintf("steps/%s-%s/provider.tf", u.resources.GetCloudPrefix(), step.Name)
 	files[providerFile] = model.File{Content: []byte(modifiedProvider), Checksum: providerChecksum}
-	err = u.resources.GetBucket().PutFile(providerFile, []byte(modifiedProvider))
+	err = u.resources.GetBucket().PutFile(ctx, providerFile, []byte(modifiedProvider))
 	return changed || len(step.Files) > 0, providers, files, err
 }
 
@@ -1318,7 +1590,7 @@ func (u *updater) getSourceVersions(step model.Step, moduleVersions map[string]m
 	return sourceVersions, nil
 }
 
-func (u *updater) updateArgoCDFiles(step model.Step, moduleVersions map[string]model.ModuleVersion) (bool, map[string]model.File, error) {
+func (u *updater) updateArgoCDFiles(ctx context.Context, step model.Step, moduleVersions map[string]model.ModuleVersion) (bool, map[string]model.File, error) {
 	executePipeline := false
 	files := make(map[string]model.File)
 	for _, module := range step.Modules {
@@ -1340,13 +1612,13 @@ func (u *updater) updateArgoCDFiles(step model.Step, moduleVersions map[string]m
 			return false, nil, err
 		}
 		executePipeline = true
-		filePath, file, err := u.createArgoCDApp(module, step, moduleVersion.Version, inputBytes)
+		filePath, file, err := u.createArgoCDApp(ctx, module, step, moduleVersion.Version, inputBytes)
 		if err != nil {
 			return false, nil, err
 		}
 		files[filePath] = model.File{Content: file}
 	}
-	err := u.updateIncludedStepFiles(step, ReservedAppsFiles, model.NewSet(certsFolder), files)
+	err := u.updateIncludedStepFiles(ctx, step, ReservedAppsFiles, model.NewSet(certsFolder), files)
 	return executePipeline, files, err
 }
 
@@ -1361,7 +1633,7 @@ func getModuleInputBytes(inputs map[string]interface{}) ([]byte, error) {
 	return inputBytes, nil
 }
 
-func (u *updater) createBackendConf(path string, bucket model.Bucket) (string, []byte, error) {
+func (u *updater) createBackendConf(ctx context.Context, path string, bucket model.Bucket) (string, []byte, error) {
 	key := fmt.Sprintf("%s/terraform.tfstate", path)
 	backendConfig := u.resources.GetBackendConfigVars(key)
 	confBytes, err := util.CreateKeyValuePairs(backendConfig, "", "")
@@ -1369,7 +1641,7 @@ func (u *updater) createBackendConf(path string, bucket model.Bucket) (string, [
 		return "", nil, fmt.Errorf("failed to convert backend config values: %w", err)
 	}
 	filePath := fmt.Sprintf("steps/%s/backend.conf", path)
-	return filePath, confBytes, bucket.PutFile(filePath, confBytes)
+	return filePath, confBytes, bucket.PutFile(ctx, filePath, confBytes)
 }
 
 func (u *updater) putStateFileOrDie() error {
@@ -1378,8 +1650,8 @@ func (u *updater) putStateFileOrDie() error {
 		if !errors.Is(err, context.Canceled) {
 			state, _ := yaml.Marshal(u.state)
 			if state != nil {
-				log.Println(string(state))
-				log.Println("Update the state file manually to avoid reapplying steps")
+				common.Logger(u.ctx).Info(string(state))
+				common.Logger(u.ctx).Info("Update the state file manually to avoid reapplying steps")
 			}
 		}
 		return fmt.Errorf("failed to put state file: %w", err)
@@ -1392,10 +1664,10 @@ func (u *updater) putStateFile() error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
-	return u.resources.GetBucket().PutFile(stateFile, stateBytes)
+	return u.resources.GetBucket().PutFile(u.ctx, stateFile, stateBytes)
 }
 
-func (u *updater) putAppliedStateFile(stepState *model.StateStep, step model.Step, status model.ApplyStatus, index int) error {
+func (u *updater) putAppliedStateFile(stepState *model.StateStep, step model.Step, status model.ApplyStatus, index int, slowest []model.ResourceTiming) error {
 	u.stateLock.Lock()
 	defer u.stateLock.Unlock()
 
@@ -1406,32 +1678,32 @@ func (u *updater) putAppliedStateFile(stepState *model.StateStep, step model.Ste
 	if u.manager == nil || !u.manager.HasNotifier(model.MessageTypeProgress) {
 		return u.putStateFile()
 	}
-	u.postCallBackWithMetadata(stepState, step, status, index)
+	u.postCallBackWithMetadata(stepState, step, status, index, slowest)
 	return u.putStateFile()
 }
 
-func (u *updater) postCallBackWithMetadata(stepState *model.StateStep, step model.Step, status model.ApplyStatus, index int) {
+func (u *updater) postCallBackWithMetadata(stepState *model.StateStep, step model.Step, status model.ApplyStatus, index int, slowest []model.ResourceTiming) {
 	modifiedStep, err := u.replaceStepMetadataValues(step, index)
 	if err == nil {
-		u.postCallbackWithStep(status, *stepState, &modifiedStep, nil)
+		u.postCallbackWithStep(status, *stepState, &modifiedStep, slowest, nil)
 	} else {
-		slog.Error(common.PrefixError(fmt.Errorf("error replacing step %s metadata values: %v", step.Name, err)))
+		u.stepLogger(step).Error(common.PrefixError(fmt.Errorf("error replacing step %s metadata values: %v", step.Name, err)))
 	}
 }
 
 func (u *updater) postCallback(status model.ApplyStatus, stepState model.StateStep, err error) {
-	u.postCallbackWithStep(status, stepState, nil, err)
+	u.postCallbackWithStep(status, stepState, nil, nil, err)
 }
 
-func (u *updater) postCallbackWithStep(status model.ApplyStatus, stepState model.StateStep, step *model.Step, err error) {
+func (u *updater) postCallbackWithStep(status model.ApplyStatus, stepState model.StateStep, step *model.Step, slowest []model.ResourceTiming, err error) {
 	if u.manager == nil || !u.manager.HasNotifier(model.MessageTypeProgress) {
 		return
 	}
-	log.Printf("Notifying step %s status '%s'", stepState.Name, status)
-	u.manager.StepState(status, stepState, step, err)
+	common.Logger(u.ctx).Info(fmt.Sprintf("Notifying step %s status '%s'", stepState.Name, status))
+	u.manager.StepState(status, stepState, step, slowest, err)
 }
 
-func (u *updater) createTerraformMain(step model.Step, moduleVersions map[string]model.ModuleVersion) (bool, string, []byte, error) {
+func (u *updater) createTerraformMain(ctx context.Context, step model.Step, moduleVersions map[string]model.ModuleVersion) (bool, string, []byte, error) {
 	file := hclwrite.NewEmptyFile()
 	body := file.Body()
 	changed := false
@@ -1451,11 +1723,11 @@ func (u *updater) createTerraformMain(step model.Step, moduleVersions map[string
 	}
 	filePath := fmt.Sprintf("steps/%s-%s/main.tf", u.resources.GetCloudPrefix(), step.Name)
 	fileBytes := file.Bytes()
-	err := u.resources.GetBucket().PutFile(filePath, fileBytes)
+	err := u.resources.GetBucket().PutFile(ctx, filePath, fileBytes)
 	return changed, filePath, fileBytes, err
 }
 
-func (u *updater) createArgoCDApp(module model.Module, step model.Step, moduleVersion string, values []byte) (string, []byte, error) {
+func (u *updater) createArgoCDApp(ctx context.Context, module model.Module, step model.Step, moduleVersion string, values []byte) (string, []byte, error) {
 	moduleSource := u.getModuleSource(module.Source)
 	appBytes, err := argocd.GetApplicationFile(moduleSource.Storage, module, moduleSource.URL, moduleVersion, values,
 		u.resources.GetProviderType())
@@ -1463,7 +1735,7 @@ func (u *updater) createArgoCDApp(module model.Module, step model.Step, moduleVe
 		return "", nil, fmt.Errorf("failed to create application file: %w", err)
 	}
 	filePath := fmt.Sprintf("steps/%s-%s/%s.yaml", u.resources.GetCloudPrefix(), step.Name, module.Name)
-	return filePath, appBytes, u.resources.GetBucket().PutFile(filePath, appBytes)
+	return filePath, appBytes, u.resources.GetBucket().PutFile(ctx, filePath, appBytes)
 }
 
 func (u *updater) updateModuleVersions(step model.Step, stepState *model.StateStep, index int) (map[string]model.ModuleVersion, error) {
@@ -1531,6 +1803,16 @@ func (u *updater) getModuleVersion(module model.Module, stepState *model.StateSt
 		return getFormattedVersion(moduleSemver), false, nil
 	}
 	releaseTag := moduleSource.Releases[index]
+	if len(module.SkipVersions) > 0 || len(moduleSource.SkipVersions) > 0 {
+		releaseTag = getAllowedRelease(module, moduleSource, moduleSemver, index)
+		if releaseTag == nil {
+			if moduleState.AppliedVersion != nil && moduleState.Source == moduleSource.URL {
+				return *moduleState.AppliedVersion, false, nil
+			}
+			return "", false, fmt.Errorf("module %s has no allowed releases, versions up to %s are skipped",
+				module.Name, getFormattedVersion(moduleSemver))
+		}
+	}
 	if moduleState.AppliedVersion == nil || moduleState.Source != moduleSource.URL {
 		moduleState.Source = moduleSource.URL
 		moduleState.AppliedVersion = nil
@@ -1557,6 +1839,28 @@ func (u *updater) getModuleVersion(module model.Module, stepState *model.StateSt
 	return getFormattedVersion(releaseTag), true, nil
 }
 
+// getAllowedRelease returns the newest release up to the index that isn't skipped for the module or the source.
+// GetReleases keeps the oldest release even when the source skips it, so the source skip list is checked here too.
+// If the module version itself is skipped, then the release must be older than the module version.
+func getAllowedRelease(module model.Module, source *model.Source, moduleSemver *version.Version, index int) *version.Version {
+	var maxVersion *version.Version
+	if util.IsVersionSkipped(module.SkipVersions, moduleSemver) || util.IsVersionSkipped(source.SkipVersions, moduleSemver) {
+		maxVersion = moduleSemver
+	}
+	for i := index; i >= 0; i-- {
+		release := source.Releases[i]
+		if maxVersion != nil && !release.LessThan(maxVersion) {
+			continue
+		}
+		if util.IsVersionSkipped(module.SkipVersions, release) || util.IsVersionSkipped(source.SkipVersions, release) {
+			slog.Debug(fmt.Sprintf("Skipping release %s for module %s", release.Original(), module.Name))
+			continue
+		}
+		return release
+	}
+	return nil
+}
+
 func getStepAutoApprove(approve model.Approve) bool {
 	if approve == model.ApproveNever || approve == model.ApproveForce {
 		return true
@@ -1660,19 +1964,19 @@ func (u *updater) updateChecksums(index int) error {
 	return nil
 }
 
-func (u *updater) updateIncludedStepFiles(step model.Step, reservedFiles, excludedFolders model.Set[string], includedFiles map[string]model.File) error {
+func (u *updater) updateIncludedStepFiles(ctx context.Context, step model.Step, reservedFiles, excludedFolders model.Set[string], includedFiles map[string]model.File) error {
 	files := model.Set[string]{}
 	folder := fmt.Sprintf("steps/%s-%s", u.resources.GetCloudPrefix(), step.Name)
 	for _, file := range step.Files {
 		target := fmt.Sprintf("%s/%s", folder, file.Name)
-		err := u.resources.GetBucket().PutFile(target, file.Content)
+		err := u.resources.GetBucket().PutFile(ctx, target, file.Content)
 		if err != nil {
 			return err
 		}
 		files.Add(target)
 		includedFiles[target] = model.File{Content: file.Content}
 	}
-	folderFiles, err := u.resources.GetBucket().ListFolderFilesWithExclude(folder, excludedFolders)
+	folderFiles, err := u.resources.GetBucket().ListFolderFilesWithExclude(ctx, folder, excludedFolders)
 	if err != nil {
 		return err
 	}
@@ -1682,7 +1986,7 @@ func (u *updater) updateIncludedStepFiles(step model.Step, reservedFiles, exclud
 			continue
 		}
 		if !files.Contains(file) {
-			err = u.resources.GetBucket().DeleteFile(file)
+			err = u.resources.GetBucket().DeleteFile(ctx, file)
 			if err != nil {
 				return err
 			}
@@ -1776,7 +2080,7 @@ func (u *updater) getModuleMetadata(module model.Module, moduleSource string, so
 	if err != nil {
 		var fileError model.NotFoundError
 		if errors.As(err, &fileError) {
-			slog.Debug(fmt.Sprintf("Module %s agent file not found", module.Name))
+			common.Logger(u.ctx).Debug(fmt.Sprintf("Module %s agent file not found", module.Name))
 			return nil, nil
 		}
 		return nil, fmt.Errorf("failed to get module file %s: %w", filePath, err)
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/hashicorp/go-version"
+)
+
+func TestGetAllowedRelease(t *testing.T) {
+	var releases []*version.Version
+	for _, release := range []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0"} {
+		releases = append(releases, version.Must(version.NewVersion(release)))
+	}
+	tests := []struct {
+		name          string
+		moduleSkip    []string
+		sourceSkip    []string
+		moduleVersion string
+		expected      string
+	}{
+		{name: "no skips", expected: "v1.3.0"},
+		{name: "module skip", moduleSkip: []string{"v1.3.0"}, expected: "v1.2.0"},
+		{name: "source skip", sourceSkip: []string{"v1.3.0", "1.2.0"}, expected: "v1.1.0"},
+		{name: "module and source skip", moduleSkip: []string{"v1.3.0"}, sourceSkip: []string{"v1.2.0"}, expected: "v1.1.0"},
+		{name: "skipped module version", moduleSkip: []string{"v1.2.0"}, moduleVersion: "v1.2.0", expected: "v1.1.0"},
+		{name: "skipped source version", sourceSkip: []string{"v1.2.0"}, moduleVersion: "v1.2.0", expected: "v1.1.0"},
+		{name: "all skipped", moduleSkip: []string{"v1.0.0", "v1.1.0"}, sourceSkip: []string{"v1.2.0", "v1.3.0"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			module := model.Module{Name: "vpc", SkipVersions: test.moduleSkip}
+			source := &model.Source{Releases: releases, SkipVersions: test.sourceSkip}
+			var moduleSemver *version.Version
+			if test.moduleVersion != "" {
+				moduleSemver = version.Must(version.NewVersion(test.moduleVersion))
+			}
+			release := getAllowedRelease(module, source, moduleSemver, len(releases)-1)
+			if test.expected == "" {
+				if release != nil {
+					t.Fatalf("expected no release, got %s", release.Original())
+				}
+				return
+			}
+			if release == nil || release.Original() != test.expected {
+				t.Fatalf("expected release %s, got %v", test.expected, release)
+			}
+		})
+	}
+}
+
+func TestGetNewestVersion(t *testing.T) {
+	source := &model.Source{
+		Version:      version.Must(version.NewVersion("v1.3.0")),
+		Modules:      model.NewSet("aws/vpc", "aws/eks"),
+		SkipVersions: []string{"v1.3.0"},
+	}
+	tests := []struct {
+		name     string
+		modules  []model.Module
+		expected string
+	}{
+		{
+			name: "newest module",
+			modules: []model.Module{{Name: "vpc", Source: "aws/vpc", Version: "v1.1.0"},
+				{Name: "eks", Source: "aws/eks", Version: "v1.2.0"}},
+			expected: "v1.2.0",
+		},
+		{
+			name: "module skip",
+			modules: []model.Module{{Name: "vpc", Source: "aws/vpc", Version: "v1.1.0"},
+				{Name: "eks", Source: "aws/eks", Version: "v1.2.0", SkipVersions: []string{"v1.2.0"}}},
+			expected: "v1.1.0",
+		},
+		{
+			name: "source skip",
+			modules: []model.Module{{Name: "vpc", Source: "aws/vpc", Version: "v1.1.0"},
+				{Name: "eks", Source: "aws/eks"}},
+			expected: "v1.1.0",
+		},
+		{
+			name:     "other source",
+			modules:  []model.Module{{Name: "vpc", Source: "google/vpc", Version: "v1.2.0"}},
+			expected: "v1.3.0",
+		},
+		{
+			name: "stable",
+			modules: []model.Module{{Name: "vpc", Source: "aws/vpc", Version: "v1.1.0"},
+				{Name: "eks", Source: "aws/eks", Version: StableVersion}},
+			expected: StableVersion,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			newestVersion, err := getNewestVersion([]model.Step{{Name: "net", Modules: test.modules}}, source)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if newestVersion != test.expected {
+				t.Fatalf("expected version %s, got %s", test.expected, newestVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package sops
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DecryptFolder decrypts the encrypted files of the folder in place and returns their decrypted paths. Age encrypted
+// files are replaced by files without the .age extension. Hidden folders, like .terraform, are skipped.
+func (d *Decryptor) DecryptFolder(folder string) ([]string, error) {
+	var decrypted []string
+	err := filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == folder && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if entry.IsDir() {
+			if path != folder && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		name, err := d.decryptFile(path)
+		if err != nil || name == "" {
+			return err
+		}
+		decrypted = append(decrypted, name)
+		return nil
+	})
+	return decrypted, err
+}
+
+func (d *Decryptor) decryptFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !IsEncrypted(content) {
+		return "", nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	name, plaintext, err := d.Decrypt(path, content)
+	if err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(name, plaintext, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("failed to write decrypted file %s: %w", name, err)
+	}
+	if name != path {
+		if err = os.Remove(path); err != nil {
+			return "", fmt.Errorf("failed to remove encrypted file %s: %w", path, err)
+		}
+	}
+	return name, nil
+}
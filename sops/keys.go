@@ -0,0 +1,110 @@
+package sops
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+const (
+	AgeKeyEnv     = "SOPS_AGE_KEY"
+	AgeKeyFileEnv = "SOPS_AGE_KEY_FILE"
+
+	ageHeader      = "age-encryption.org/v1\n"
+	defaultAgeKeys = "sops/age/keys.txt"
+)
+
+type ageKeys struct {
+	once       sync.Once
+	identities []age.Identity
+	err        error
+}
+
+// getIdentities reads age identities from SOPS_AGE_KEY, SOPS_AGE_KEY_FILE and the default sops keys file, same as
+// SOPS does for age recipients.
+func (k *ageKeys) getIdentities() ([]age.Identity, error) {
+	k.once.Do(func() {
+		var identities []age.Identity
+		if key := os.Getenv(AgeKeyEnv); key != "" {
+			parsed, err := age.ParseIdentities(strings.NewReader(key))
+			if err != nil {
+				k.err = fmt.Errorf("failed to parse %s: %w", AgeKeyEnv, err)
+				return
+			}
+			identities = append(identities, parsed...)
+		}
+		keyFile := os.Getenv(AgeKeyFileEnv)
+		if keyFile == "" {
+			if configDir, err := os.UserConfigDir(); err == nil {
+				keyFile = filepath.Join(configDir, defaultAgeKeys)
+			}
+		}
+		if keyFile != "" {
+			parsed, err := readIdentitiesFile(keyFile)
+			if err != nil {
+				k.err = err
+				return
+			}
+			identities = append(identities, parsed...)
+		}
+		k.identities = identities
+	})
+	return k.identities, k.err
+}
+
+func readIdentitiesFile(keyFile string) ([]age.Identity, error) {
+	content, err := os.ReadFile(keyFile)
+	if errors.Is(err, os.ErrNotExist) && os.Getenv(AgeKeyFileEnv) == "" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read age key file %s: %w", keyFile, err)
+	}
+	identities, err := age.ParseIdentities(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age key file %s: %w", keyFile, err)
+	}
+	slog.Debug(fmt.Sprintf("Loaded %d age identities from %s", len(identities), keyFile))
+	return identities, nil
+}
+
+// ValidateAgeKey checks that the key contains age identities in the SOPS_AGE_KEY format.
+func ValidateAgeKey(key string) error {
+	if _, err := age.ParseIdentities(strings.NewReader(key)); err != nil {
+		return fmt.Errorf("invalid age key: %w", err)
+	}
+	return nil
+}
+
+func isAgeEncrypted(content []byte) bool {
+	return bytes.HasPrefix(content, []byte(ageHeader)) ||
+		bytes.HasPrefix(bytes.TrimSpace(content), []byte(armor.Header))
+}
+
+func (d *Decryptor) decryptAge(content []byte) ([]byte, error) {
+	identities, err := d.keys.getIdentities()
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("no age identities found, set %s or %s", AgeKeyEnv, AgeKeyFileEnv)
+	}
+	var reader io.Reader = bytes.NewReader(content)
+	if !bytes.HasPrefix(content, []byte(ageHeader)) {
+		reader = armor.NewReader(bytes.NewReader(bytes.TrimSpace(content)))
+	}
+	decrypted, err := age.Decrypt(reader, identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(decrypted)
+}
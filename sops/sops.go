@@ -0,0 +1,65 @@
+package sops
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/getsops/sops/v3/decrypt"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	AgeExtension = ".age"
+
+	metadataKey     = "sops"
+	encryptedPrefix = "ENC[AES256_GCM,"
+)
+
+// Decryptor decrypts SOPS encrypted yaml, json and binary files with the upstream SOPS library and age encrypted
+// files. SOPS uses its own key sources: age identities, the gpg keyring and the cloud KMS keys from the SOPS metadata.
+type Decryptor struct {
+	keys *ageKeys
+}
+
+func NewDecryptor() *Decryptor {
+	return &Decryptor{keys: &ageKeys{}}
+}
+
+// IsEncrypted reports whether the content is age encrypted or a SOPS encrypted yaml/json document.
+func IsEncrypted(content []byte) bool {
+	return isAgeEncrypted(content) || isSopsEncrypted(content)
+}
+
+func isSopsEncrypted(content []byte) bool {
+	if !bytes.Contains(content, []byte(metadataKey)) || !bytes.Contains(content, []byte(encryptedPrefix)) {
+		return false
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return false
+	}
+	metadata, ok := document[metadataKey].(map[string]interface{})
+	return ok && metadata["mac"] != nil && metadata["version"] != nil
+}
+
+// Decrypt returns the decrypted file name and content. Age encrypted files lose the .age extension. Files that are
+// not yaml or json are decrypted as SOPS binary files.
+func (d *Decryptor) Decrypt(name string, content []byte) (string, []byte, error) {
+	if isAgeEncrypted(content) {
+		plaintext, err := d.decryptAge(content)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decrypt age file %s: %w", name, err)
+		}
+		return strings.TrimSuffix(name, AgeExtension), plaintext, nil
+	}
+	if !isSopsEncrypted(content) {
+		return "", nil, fmt.Errorf("file %s is not encrypted, sops metadata not found", name)
+	}
+	plaintext, err := decrypt.DataWithFormat(content, formats.FormatForPath(name))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decrypt sops file %s: %w", name, err)
+	}
+	return name, plaintext, nil
+}
@@ -0,0 +1,228 @@
+package sops
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Test files are encrypted with the sops 3.13.3 binary.
+const testAgeKey2 = "AGE-SECRET-KEY-15R03UNSW2NZ3GKWP7REQNPAGPPY2F6PS37DDH7CRD6G6E8R2YXCQHC8ENK"
+
+func setAgeKeys(t *testing.T, keyFile string) {
+	t.Setenv(AgeKeyEnv, "")
+	t.Setenv(AgeKeyFileEnv, keyFile)
+	t.Setenv("SOPS_GPG_EXEC", filepath.Join(t.TempDir(), "gpg"))
+}
+
+func readTestFile(t *testing.T, name string) []byte {
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return content
+}
+
+func assertSameValues(t *testing.T, expected, actual []byte) {
+	var want, got interface{}
+	if err := yaml.Unmarshal(expected, &want); err != nil {
+		t.Fatalf("failed to unmarshal expected content: %v", err)
+	}
+	if err := yaml.Unmarshal(actual, &got); err != nil {
+		t.Fatalf("failed to unmarshal decrypted content: %v\n%s", err, actual)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("decrypted values differ, expected %v, got %v", want, got)
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	setAgeKeys(t, filepath.Join("testdata", "keys.txt"))
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+		binary   bool
+	}{
+		{name: "age yaml", file: "age.enc.yaml", expected: "secrets.yaml"},
+		{name: "age json", file: "age.enc.json", expected: "secrets.json"},
+		{name: "encrypted regex with mac only encrypted", file: "regex.enc.yaml", expected: "regex.yaml"},
+		{name: "binary", file: "ca.sops.pem", expected: "ca.pem", binary: true},
+		{name: "age file", file: "ca.pem.age", expected: "ca.pem", binary: true},
+		{name: "single key group", file: "group.enc.json", expected: "secrets.json"},
+	}
+	decryptor := NewDecryptor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := readTestFile(t, tt.file)
+			if !IsEncrypted(content) {
+				t.Fatalf("file %s is not recognized as encrypted", tt.file)
+			}
+			name, plaintext, err := decryptor.Decrypt(tt.file, content)
+			if err != nil {
+				t.Fatalf("failed to decrypt %s: %v", tt.file, err)
+			}
+			if name != strings.TrimSuffix(tt.file, AgeExtension) {
+				t.Errorf("unexpected decrypted name %s", name)
+			}
+			expected := readTestFile(t, tt.expected)
+			if tt.binary {
+				if !bytes.Equal(expected, plaintext) {
+					t.Fatalf("expected %q, got %q", expected, plaintext)
+				}
+				return
+			}
+			assertSameValues(t, expected, plaintext)
+		})
+	}
+}
+
+func TestDecryptKeepsComments(t *testing.T) {
+	setAgeKeys(t, filepath.Join("testdata", "keys.txt"))
+	_, plaintext, err := NewDecryptor().Decrypt("age.enc.yaml",
+		readTestFile(t, "age.enc.yaml"))
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	for _, comment := range []string{"# database settings", "# inline comment"} {
+		if !bytes.Contains(plaintext, []byte(comment)) {
+			t.Errorf("comment %q is missing from\n%s", comment, plaintext)
+		}
+	}
+}
+
+func TestDecryptPGP(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not found")
+	}
+	home := t.TempDir()
+	t.Setenv("GNUPGHOME", home)
+	cmd := exec.Command("gpg", "--batch", "--import", filepath.Join("testdata", "pgp.asc"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("failed to import pgp key: %v\n%s", err, output)
+	}
+	t.Cleanup(func() {
+		_ = exec.Command("gpgconf", "--kill", "all").Run()
+	})
+	// Only the second age key is available, the age key of the file can't be used
+	keyFile := filepath.Join(t.TempDir(), "keys.txt")
+	if err := os.WriteFile(keyFile, []byte(testAgeKey2), 0600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+	setAgeKeys(t, keyFile)
+	t.Setenv("SOPS_GPG_EXEC", "")
+	_, plaintext, err := NewDecryptor().Decrypt("pgp.enc.yaml", readTestFile(t, "pgp.enc.yaml"))
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	assertSameValues(t, readTestFile(t, "secrets.yaml"), plaintext)
+}
+
+func TestDecryptErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		keyFile string
+		err     string
+	}{
+		{name: "modified unencrypted value", file: "mac-mismatch.enc.yaml", keyFile: "keys.txt", err: "Failed to verify data integrity"},
+		{name: "key not available", file: "age.enc.yaml", keyFile: "missing.txt", err: "failed to decrypt sops file"},
+		{name: "age key not available", file: "ca.pem.age", keyFile: "missing.txt", err: "failed to read age key file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setAgeKeys(t, filepath.Join("testdata", tt.keyFile))
+			_, _, err := NewDecryptor().Decrypt(tt.file, readTestFile(t, tt.file))
+			if err == nil {
+				t.Fatalf("expected error containing %q", tt.err)
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestUnencryptedSuffix(t *testing.T) {
+	content := readTestFile(t, "age.enc.yaml")
+	if !bytes.Contains(content, []byte("token_unencrypted: visible")) {
+		t.Fatal("value with the unencrypted suffix is encrypted in the test file")
+	}
+	setAgeKeys(t, filepath.Join("testdata", "keys.txt"))
+	_, plaintext, err := NewDecryptor().Decrypt("age.enc.yaml", content)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	var values map[string]interface{}
+	if err = yaml.Unmarshal(plaintext, &values); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if values["token_unencrypted"] != "visible" {
+		t.Fatalf("expected unencrypted value visible, got %v", values["token_unencrypted"])
+	}
+}
+
+func TestDecryptFolder(t *testing.T) {
+	setAgeKeys(t, filepath.Join("testdata", "keys.txt"))
+	folder := t.TempDir()
+	files := map[string][]byte{
+		"ca-certificates/ca.pem.age": readTestFile(t, "ca.pem.age"),
+		"secrets.yaml":               readTestFile(t, "age.enc.yaml"),
+		"plain.tf":                   []byte("locals {}\n"),
+		".terraform/cached.yaml":     readTestFile(t, "age.enc.yaml"),
+	}
+	for name, content := range files {
+		path := filepath.Join(folder, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatalf("failed to create folder: %v", err)
+		}
+		if err := os.WriteFile(path, content, 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	decrypted, err := NewDecryptor().DecryptFolder(folder)
+	if err != nil {
+		t.Fatalf("failed to decrypt folder: %v", err)
+	}
+	if len(decrypted) != 2 {
+		t.Fatalf("expected 2 decrypted files, got %v", decrypted)
+	}
+	cert, err := os.ReadFile(filepath.Join(folder, "ca-certificates", "ca.pem"))
+	if err != nil {
+		t.Fatalf("decrypted certificate not found: %v", err)
+	}
+	if !bytes.Equal(cert, readTestFile(t, "ca.pem")) {
+		t.Fatalf("unexpected certificate %q", cert)
+	}
+	if _, err = os.Stat(filepath.Join(folder, "ca-certificates", "ca.pem.age")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("encrypted certificate was not removed: %v", err)
+	}
+	secrets, err := os.ReadFile(filepath.Join(folder, "secrets.yaml"))
+	if err != nil {
+		t.Fatalf("failed to read secrets: %v", err)
+	}
+	assertSameValues(t, readTestFile(t, "secrets.yaml"), secrets)
+	cached, err := os.ReadFile(filepath.Join(folder, ".terraform", "cached.yaml"))
+	if err != nil || !IsEncrypted(cached) {
+		t.Fatalf("hidden folder files must not be decrypted: %v", err)
+	}
+	if _, err = NewDecryptor().DecryptFolder(filepath.Join(folder, "missing")); err != nil {
+		t.Fatalf("missing folder must be skipped: %v", err)
+	}
+}
+
+func TestValidateAgeKey(t *testing.T) {
+	if err := ValidateAgeKey(testAgeKey2); err != nil {
+		t.Fatalf("expected valid age key, got %v", err)
+	}
+	if err := ValidateAgeKey("not a key"); err == nil {
+		t.Fatal("expected invalid age key error")
+	}
+}
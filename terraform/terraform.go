@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 	"maps"
 	"path/filepath"
@@ -32,6 +31,8 @@ const (
 )
 
 var planRegex = regexp.MustCompile(`Plan: (?:(?P<import>\d+) to import, )?(?P<add>\d+) to add, (?P<change>\d+) to change, (?P<destroy>\d+) to destroy`)
+var resourceChangeRegex = regexp.MustCompile(`#\s+(module\.([^.\[\s]+)\S*)\s+(will be created|will be updated in-place|will be destroyed|must be replaced|will be imported)`)
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
 
 type Terraform interface {
 	GetTerraformProvider(step model.Step, moduleVersions map[string]model.ModuleVersion, sourceVersions map[model.SourceKey]string) ([]byte, map[model.SourceKey]model.Set[string], error)
@@ -530,11 +531,11 @@ func ParseLogChanges(pipelineName, message string) (*model.PipelineChanges, erro
 	tfChanges := model.PipelineChanges{}
 	if strings.HasPrefix(message, "No changes. Your infrastructure matches the configuration.") ||
 		strings.HasPrefix(message, "No changes. No objects need to be destroyed.") {
-		log.Printf("Pipeline %s: %s", pipelineName, message)
+		slog.Info(fmt.Sprintf("Pipeline %s: %s", pipelineName, message))
 		tfChanges.NoChanges = true
 		return &tfChanges, nil
 	} else if strings.HasPrefix(message, "You can apply this plan to save these new output values") {
-		log.Printf("Pipeline %s: %s", pipelineName, message)
+		slog.Info(fmt.Sprintf("Pipeline %s: %s", pipelineName, message))
 		return &tfChanges, nil
 	}
 
@@ -544,3 +545,59 @@ func ParseLogChanges(pipelineName, message string) (*model.PipelineChanges, erro
 	}
 	return util.GetChangesFromMatches(pipelineName, message, matches, planRegex.SubexpNames())
 }
+
+// NewLogChangesParser returns a log parser that also collects per module changes from the resource change lines
+// preceding the plan summary. Resources are counted once, so the same logs can be parsed again.
+func NewLogChangesParser() func(string, string) (*model.PipelineChanges, error) {
+	modules := make(map[string]*model.ModuleChanges)
+	resources := model.NewSet[string]()
+	var order []string
+	return func(pipelineName, message string) (*model.PipelineChanges, error) {
+		if address, name, action, found := parseResourceChange(message); found {
+			if resources.Contains(address) {
+				return nil, nil
+			}
+			resources.Add(address)
+			module, exists := modules[name]
+			if !exists {
+				module = &model.ModuleChanges{Name: name}
+				modules[name] = module
+				order = append(order, name)
+			}
+			addModuleChange(module, action)
+			return nil, nil
+		}
+		changes, err := ParseLogChanges(pipelineName, message)
+		if err != nil || changes == nil || changes.NoChanges {
+			return changes, err
+		}
+		for _, name := range order {
+			changes.Modules = append(changes.Modules, *modules[name])
+		}
+		return changes, nil
+	}
+}
+
+func parseResourceChange(message string) (string, string, string, bool) {
+	matches := resourceChangeRegex.FindStringSubmatch(ansiRegex.ReplaceAllString(message, ""))
+	if matches == nil {
+		return "", "", "", false
+	}
+	return matches[1], matches[2], matches[3], true
+}
+
+func addModuleChange(module *model.ModuleChanges, action string) {
+	switch action {
+	case "will be created":
+		module.Added++
+	case "will be updated in-place":
+		module.Changed++
+	case "will be destroyed":
+		module.Destroyed++
+	case "must be replaced":
+		module.Added++
+		module.Destroyed++
+	case "will be imported":
+		module.Imported++
+	}
+}
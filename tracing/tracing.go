@@ -0,0 +1,149 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	serviceName = "entigo-infralib-agent"
+	tracerName  = "github.com/entigolabs/entigo-infralib-agent"
+
+	traceParentHeader = "traceparent"
+
+	CampaignIdKey = attribute.Key("campaign.id")
+)
+
+var campaignId atomic.Value
+
+// Init sets up the OTLP trace exporter when an OTLP endpoint is configured with the standard OTEL_EXPORTER_OTLP_*
+// environment variables. Without an endpoint spans are not recorded. The returned function flushes the spans.
+func Init(ctx context.Context) (func(context.Context), error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{},
+		propagation.Baggage{}))
+	if !enabled() {
+		return func(context.Context) {}, nil
+	}
+	exporter, err := newExporter(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %v", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(common.GetVersion().Version)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %v", err)
+	}
+	// Environment attributes, like OTEL_SERVICE_NAME, take precedence over the agent defaults.
+	res, err = resource.Merge(res, resource.Environment())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSpanProcessor(campaignProcessor{}),
+	)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) {
+		if err := provider.Shutdown(ctx); err != nil {
+			slog.Warn(common.PrefixWarning(fmt.Sprintf("failed to flush traces: %v", err)))
+		}
+	}, nil
+}
+
+func enabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") ||
+		strings.EqualFold(os.Getenv("OTEL_TRACES_EXPORTER"), "none") {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+func newExporter(ctx context.Context) (sdktrace.SpanExporter, error) {
+	protocol := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL")
+	if protocol == "" {
+		protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	switch protocol {
+	case "grpc":
+		return otlptracegrpc.New(ctx)
+	case "", "http/protobuf":
+		return otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %s", protocol)
+	}
+}
+
+// SetCampaignId adds the campaign id attribute to the span in ctx and to all spans started afterwards.
+func SetCampaignId(ctx context.Context, id string) {
+	campaignId.Store(id)
+	trace.SpanFromContext(ctx).SetAttributes(CampaignIdKey.String(id))
+}
+
+// TraceParent returns the W3C traceparent of the span in ctx for passing the trace context to pipelines, empty
+// when the span isn't recorded.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get(traceParentHeader)
+}
+
+// FromEnv returns ctx with the remote span of the TRACEPARENT env variable, so commands started by pipelines
+// continue the trace of the agent. Invalid values are ignored.
+func FromEnv(ctx context.Context) context.Context {
+	traceParent := os.Getenv(model.TraceParentEnv)
+	if traceParent == "" {
+		return ctx
+	}
+	return propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{traceParentHeader: traceParent})
+}
+
+// Start starts a span that is a child of the span in ctx.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End records the error pointed to by err and ends the span, meant to be deferred with a named error result.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil && !errors.Is(*err, context.Canceled) {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+type campaignProcessor struct{}
+
+func (campaignProcessor) OnStart(_ context.Context, span sdktrace.ReadWriteSpan) {
+	if id, ok := campaignId.Load().(string); ok && id != "" {
+		span.SetAttributes(CampaignIdKey.String(id))
+	}
+}
+
+func (campaignProcessor) OnEnd(sdktrace.ReadOnlySpan) {}
+
+func (campaignProcessor) Shutdown(context.Context) error {
+	return nil
+}
+
+func (campaignProcessor) ForceFlush(context.Context) error {
+	return nil
+}
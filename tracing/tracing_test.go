@@ -0,0 +1,32 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+func TestTraceParentFromEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceParent string
+		expected    string
+	}{
+		{
+			name:        "trace parent",
+			traceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expected:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+		{name: "pipeline default", traceParent: model.CampaignSentinelNone},
+		{name: "empty"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(model.TraceParentEnv, test.traceParent)
+			if traceParent := TraceParent(FromEnv(context.Background())); traceParent != test.expected {
+				t.Fatalf("expected trace parent %q, got %q", test.expected, traceParent)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	diffContext = 3
+	missingFile = "/dev/null"
+)
+
+// UnifiedDiff returns the unified diff of the file contents, nil content is treated as a missing file.
+func UnifiedDiff(name string, from, to []byte) (string, error) {
+	fromFile, toFile := "a/"+name, "b/"+name
+	if from == nil {
+		fromFile = missingFile
+	}
+	if to == nil {
+		toFile = missingFile
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(from),
+		B:        splitLines(to),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  diffContext,
+	})
+}
+
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	last := len(lines) - 1
+	if lines[last] == "" {
+		return lines[:last]
+	}
+	lines[last] += "\n"
+	return lines
+}
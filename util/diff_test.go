@@ -0,0 +1,45 @@
+package util
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     []byte
+		to       []byte
+		expected string
+	}{
+		{
+			name:     "modified",
+			from:     []byte("prefix: dev\nsteps: []\n"),
+			to:       []byte("prefix: prod\nsteps: []\n"),
+			expected: "--- a/config.yaml\n+++ b/config.yaml\n@@ -1,2 +1,2 @@\n-prefix: dev\n+prefix: prod\n steps: []\n",
+		},
+		{
+			name:     "added",
+			to:       []byte("prefix: dev"),
+			expected: "--- /dev/null\n+++ b/config.yaml\n@@ -0,0 +1 @@\n+prefix: dev\n",
+		},
+		{
+			name:     "deleted",
+			from:     []byte("prefix: dev\n"),
+			expected: "--- a/config.yaml\n+++ /dev/null\n@@ -1 +0,0 @@\n-prefix: dev\n",
+		},
+		{
+			name: "equal",
+			from: []byte("prefix: dev\n"),
+			to:   []byte("prefix: dev\n"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff, err := UnifiedDiff("config.yaml", test.from, test.to)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff != test.expected {
+				t.Fatalf("expected diff:\n%s\ngot:\n%s", test.expected, diff)
+			}
+		})
+	}
+}
@@ -5,12 +5,12 @@ import (
 	"bufio"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"hash/fnv"
 	"io"
-	"log"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -19,6 +19,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
@@ -256,11 +257,39 @@ func DelayBucketCreation(bucket string, skipDelay bool) {
 }
 
 func AskForConfirmation() error {
-	reader := bufio.NewReader(os.Stdin)
+	return AskForConfirmationContext(context.Background())
+}
+
+// stdinLines reads stdin in a single goroutine, so an abandoned confirmation doesn't keep a reader that would
+// consume the answer to the next one.
+var stdinLines = sync.OnceValue(func() <-chan string {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- line
+		}
+	}()
+	return lines
+})
+
+// AskForConfirmationContext waits for a yes or no answer from stdin until the context is cancelled.
+func AskForConfirmationContext(ctx context.Context) error {
 	for {
-		response, err := reader.ReadString('\n')
-		if err != nil {
-			return fmt.Errorf("failed to read input: %w", err)
+		var response string
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-stdinLines():
+			if !ok {
+				return fmt.Errorf("failed to read input: %w", io.EOF)
+			}
+			response = line
 		}
 		response = strings.ToLower(strings.TrimSpace(response))
 		switch response {
@@ -395,7 +424,7 @@ func ShouldApprovePipeline(changes model.PipelineChanges, approve model.Approve,
 }
 
 func GetChangesFromMatches(pipelineName, message string, matches, subExpNames []string) (*model.PipelineChanges, error) {
-	log.Printf("Pipeline %s: %s", pipelineName, message)
+	slog.Info(fmt.Sprintf("Pipeline %s: %s", pipelineName, message))
 	result := make(map[string]string)
 	noChanges := true
 	for i, name := range subExpNames {
@@ -459,6 +488,20 @@ func IsVersionOlder(currentVersion, oldVersion string) bool {
 	return semver.LessThan(oldVer)
 }
 
+// IsVersionSkipped checks if the version equals any of the skipped semantic versions
+func IsVersionSkipped(skipVersions []string, release *version.Version) bool {
+	if release == nil {
+		return false
+	}
+	for _, skipVersion := range skipVersions {
+		skipSemver, err := version.NewVersion(skipVersion)
+		if err == nil && skipSemver.Equal(release) {
+			return true
+		}
+	}
+	return false
+}
+
 func DeepCopyYAML(src map[string]interface{}) (map[string]interface{}, error) {
 	content, err := yaml.Marshal(src)
 	if err != nil {
@@ -6,13 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log/slog"
 	"net/url"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
 	"github.com/entigolabs/entigo-infralib-agent/model"
 	"github.com/entigolabs/entigo-infralib-agent/util"
@@ -25,7 +25,6 @@ const (
 	pingInterval     = 4 * time.Minute
 	initialBackoff   = 1 * time.Second
 	maxBackoff       = 30 * time.Second
-	logBufferSize    = 4096
 	maxBatchSize     = 1024
 	windDownTimeout  = 5 * time.Second
 	handshakeTimeout = 10 * time.Second
@@ -47,16 +46,27 @@ type backendClient struct {
 
 	handshake HandshakeData
 
-	logs        chan string
+	// spool holds log lines on disk until the server acknowledges them. Lines
+	// are replayed from the server's resume offset after a reconnect, so neither
+	// a backend outage nor a full channel drops them.
+	spool    *spool
+	spoolDir string
+	// pingOffsets holds the sent spool offset of each unanswered ping of the current stream.
+	pingOffsets []uint64
+	pingLock    sync.Mutex
 	planSummary chan *v1alpha1.PlanSummary
 	done        chan struct{}
 	finished    chan error
 
-	// pendingLogs / pendingPlan hold the batch consumed from its channel but
-	// not yet successfully Sent. Set before Send, cleared after success; on a
-	// broken stream they survive into the next epoch and get replayed on the
-	// freshly-opened stream so reconnect doesn't drop them.
-	pendingLogs []string
+	cancellations chan string
+	// approvals is set only while an approval wait is active.
+	approvals    chan ApprovalDecision
+	approvalLock sync.Mutex
+
+	// pendingPlan holds the summary consumed from its channel but not yet
+	// successfully Sent. Set before Send, cleared after success; on a broken
+	// stream it survives into the next epoch and gets replayed on the
+	// freshly-opened stream so reconnect doesn't drop it.
 	pendingPlan *v1alpha1.PlanSummary
 
 	droppedLogs uint64
@@ -67,15 +77,16 @@ type backendClient struct {
 	execErr  error
 }
 
-func newBackendClient(api *model.NotificationApi, insecureGRPC bool) (*backendClient, error) {
+func newBackendClient(ctx context.Context, api *model.NotificationApi, insecureGRPC bool, spoolDir string) (*backendClient, error) {
 	host, pathPrefix, err := parseTarget(api.WrapperURL)
 	if err != nil {
 		return nil, err
 	}
-	// Internal ctx is detached from the caller's ctx so that SIGINT cancelling
-	// the wrapper's ctx doesn't tear down the gRPC stream mid-flight — Disconnect
-	// still needs a live stream to deliver ExecutionComplete.
-	internalCtx, cancel := context.WithCancel(context.Background())
+	// Internal ctx is detached from the caller's ctx cancellation so that SIGINT
+	// cancelling the wrapper's ctx doesn't tear down the gRPC stream mid-flight —
+	// Disconnect still needs a live stream to deliver ExecutionComplete. The
+	// caller's trace span is kept and propagated to the backend.
+	internalCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 	ts, err := util.GetTokenSource(internalCtx, api.OAuth)
 	if err != nil {
 		cancel()
@@ -84,6 +95,7 @@ func newBackendClient(api *model.NotificationApi, insecureGRPC bool) (*backendCl
 
 	interceptors := []grpc.StreamClientInterceptor{
 		NewAuthInterceptor(ts, api.Headers).StreamClient(),
+		traceInterceptor(),
 	}
 	if pathPrefix != "" {
 		interceptors = append(interceptors, pathPrefixInterceptor(pathPrefix))
@@ -104,23 +116,32 @@ func newBackendClient(api *model.NotificationApi, insecureGRPC bool) (*backendCl
 	}
 
 	return &backendClient{
-		ctx:         internalCtx,
-		cancel:      cancel,
-		client:      v1alpha1.NewWrapperServiceClient(conn),
-		conn:        conn,
-		pingTime:    pingInterval,
-		pingTicker:  time.NewTicker(pingInterval),
-		logs:        make(chan string, logBufferSize),
-		planSummary: make(chan *v1alpha1.PlanSummary, 1),
-		done:        make(chan struct{}),
-		finished:    make(chan error, 1),
+		ctx:           internalCtx,
+		cancel:        cancel,
+		client:        v1alpha1.NewWrapperServiceClient(conn),
+		conn:          conn,
+		pingTime:      pingInterval,
+		pingTicker:    time.NewTicker(pingInterval),
+		spoolDir:      spoolDir,
+		planSummary:   make(chan *v1alpha1.PlanSummary, 1),
+		done:          make(chan struct{}),
+		finished:      make(chan error, 1),
+		cancellations: make(chan string, 1),
 	}, nil
 }
 
 func (g *backendClient) Connect(h HandshakeData) error {
 	g.handshake = h
+	spool, err := openSpool(g.spoolDir, h)
+	if err != nil {
+		g.cancel()
+		_ = g.conn.Close()
+		return err
+	}
+	g.spool = spool
 	stream, err := g.openStream()
 	if err != nil {
+		_ = g.spool.close(false)
 		g.cancel()
 		_ = g.conn.Close()
 		return err
@@ -130,9 +151,12 @@ func (g *backendClient) Connect(h HandshakeData) error {
 }
 
 func (g *backendClient) SendLog(line string) error {
-	select {
-	case g.logs <- line:
-	default:
+	added, err := g.spool.append(line)
+	if err != nil {
+		atomic.AddUint64(&g.droppedLogs, 1)
+		return err
+	}
+	if !added {
 		atomic.AddUint64(&g.droppedLogs, 1)
 	}
 	return nil
@@ -143,14 +167,14 @@ func (g *backendClient) SendPlan(summary *v1alpha1.PlanSummary) error {
 	select {
 	case g.planSummary <- summary:
 	default:
-		slog.Warn("wrapper plan summary buffer full, dropping")
+		common.Logger(g.ctx).Warn("wrapper plan summary buffer full, dropping")
 	}
 	return nil
 }
 
 func (g *backendClient) Disconnect(ctx context.Context, exitCode int, execErr error) error {
 	if dropped := atomic.LoadUint64(&g.droppedLogs); dropped > 0 {
-		slog.Warn("wrapper dropped log lines due to backpressure", "count", dropped)
+		common.Logger(ctx).Warn("wrapper dropped log lines, spool is full", "count", dropped)
 	}
 	g.exitCode = exitCode
 	g.execErr = execErr
@@ -159,12 +183,45 @@ func (g *backendClient) Disconnect(ctx context.Context, exitCode int, execErr er
 		g.cancel()
 		_ = g.conn.Close()
 	}()
+	var err error
 	select {
-	case err := <-g.finished:
-		return err
+	case err = <-g.finished:
 	case <-ctx.Done():
-		return ctx.Err()
+		err = ctx.Err()
+	}
+	if spoolErr := g.spool.close(err == nil); spoolErr != nil {
+		common.Logger(ctx).Warn("wrapper spool not removed", "err", spoolErr)
 	}
+	return err
+}
+
+func (g *backendClient) Cancellations() <-chan string {
+	return g.cancellations
+}
+
+func (g *backendClient) WaitApproval() (<-chan ApprovalDecision, func()) {
+	approvals := make(chan ApprovalDecision, 1)
+	g.approvalLock.Lock()
+	g.approvals = approvals
+	g.approvalLock.Unlock()
+	return approvals, func() {
+		g.approvalLock.Lock()
+		defer g.approvalLock.Unlock()
+		if g.approvals == approvals {
+			g.approvals = nil
+		}
+	}
+}
+
+// offerApproval delivers the decision to the active approval wait, decisions received before the wait are dropped.
+func (g *backendClient) offerApproval(decision ApprovalDecision) {
+	g.approvalLock.Lock()
+	defer g.approvalLock.Unlock()
+	if g.approvals == nil {
+		common.Logger(g.ctx).Warn("Ignoring plan decision received while no approval is pending", "by", decision.By)
+		return
+	}
+	offer(g.approvals, decision)
 }
 
 func (g *backendClient) supervise(initialStream wrapperStream) {
@@ -184,13 +241,13 @@ func (g *backendClient) supervise(initialStream wrapperStream) {
 			select {
 			case <-recvDone:
 			case <-time.After(windDownTimeout):
-				slog.Warn("wrapper recv goroutine wind-down timed out")
+				common.Logger(g.ctx).Warn("wrapper recv goroutine wind-down timed out")
 			}
 			g.finished <- err
 			return
 		}
 
-		slog.Warn("wrapper stream broken, reconnecting", "err", epochErr)
+		common.Logger(g.ctx).Warn("wrapper stream broken, reconnecting", "err", epochErr)
 		<-recvDone
 
 		newStream, ok := g.reconnect()
@@ -202,12 +259,15 @@ func (g *backendClient) supervise(initialStream wrapperStream) {
 	}
 }
 
+// reconnect keeps retrying after Disconnect has been signalled, so spooled
+// lines still get delivered if the backend returns before Disconnect's ctx
+// expires and the internal ctx is cancelled.
 func (g *backendClient) reconnect() (wrapperStream, bool) {
 	backoffDur := initialBackoff
 	for {
 		select {
-		case <-g.done:
-			g.finished <- nil
+		case <-g.ctx.Done():
+			g.finished <- g.ctx.Err()
 			return nil, false
 		case <-time.After(backoffDur):
 		}
@@ -215,7 +275,7 @@ func (g *backendClient) reconnect() (wrapperStream, bool) {
 
 		stream, err := g.openStream()
 		if err != nil {
-			slog.Error("wrapper reconnect failed", "err", err)
+			common.Logger(g.ctx).Error("wrapper reconnect failed", "err", err)
 			continue
 		}
 		return stream, true
@@ -228,8 +288,8 @@ func (g *backendClient) runEpoch(stream wrapperStream, recvErrCh <-chan error) e
 	}
 	for {
 		select {
-		case line := <-g.logs:
-			if err := g.sendLogBatch(stream, g.drainLogBatch(line)); err != nil {
+		case <-g.spool.notify:
+			if err := g.sendSpooled(stream); err != nil {
 				return err
 			}
 		case summary := <-g.planSummary:
@@ -237,8 +297,8 @@ func (g *backendClient) runEpoch(stream wrapperStream, recvErrCh <-chan error) e
 				return err
 			}
 		case <-g.pingTicker.C:
-			slog.Debug("Sending ping request to server")
-			if err := stream.Send(pingRequest); err != nil {
+			common.Logger(g.ctx).Debug("Sending ping request to server")
+			if err := g.sendPing(stream); err != nil {
 				return err
 			}
 		case err := <-recvErrCh:
@@ -254,50 +314,91 @@ func (g *backendClient) runEpoch(stream wrapperStream, recvErrCh <-chan error) e
 // server before ExecutionComplete" — important for the plan summary, which
 // would otherwise race with the disconnect signal in select.
 func (g *backendClient) drainPending(stream wrapperStream) error {
+	if err := g.sendSpooled(stream); err != nil {
+		return err
+	}
+	select {
+	case summary := <-g.planSummary:
+		return g.sendPlanSummary(stream, summary)
+	default:
+		return nil
+	}
+}
+
+// sendSpooled sends all unsent spool lines in batches of up to maxBatchSize
+// lines to bound message size. Lines are marked as sent only after a
+// successful Send, a broken stream resumes from the server's offset.
+func (g *backendClient) sendSpooled(stream wrapperStream) error {
 	for {
-		select {
-		case line := <-g.logs:
-			if err := g.sendLogBatch(stream, g.drainLogBatch(line)); err != nil {
-				return err
-			}
-		case summary := <-g.planSummary:
-			if err := g.sendPlanSummary(stream, summary); err != nil {
-				return err
+		offset, lines, err := g.spool.readBatch(maxBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to read spool: %v", err)
+		}
+		if len(lines) == 0 {
+			if g.spool.awaitsAck() {
+				return g.sendPing(stream)
 			}
-		default:
 			return nil
 		}
+		if err = g.sendLogBatch(stream, offset, lines); err != nil {
+			return err
+		}
+		g.spool.advance(offset + uint64(len(lines)))
+		if err = g.sendStructuredEvents(stream, offset, lines); err != nil {
+			return err
+		}
 	}
 }
 
-// drainLogBatch pulls everything currently sitting in g.logs into a batch
-// starting with the line that already woke the select. Caps at maxBatchSize
-// to bound message size; if more remain, the next select iteration picks them
-// up.
-func (g *backendClient) drainLogBatch(first string) []string {
-	batch := make([]string, 0, maxBatchSize)
-	batch = append(batch, first)
-	for len(batch) < maxBatchSize {
-		select {
-		case more := <-g.logs:
-			batch = append(batch, more)
-		default:
-			return batch
+// sendStructuredEvents sends the terraform -json events parsed from the batch. Events are best effort, the
+// events of a batch that was stored before the stream broke are not replayed.
+func (g *backendClient) sendStructuredEvents(stream wrapperStream, offset uint64, lines []string) error {
+	for i, line := range lines {
+		event := parseStructuredEvent(line, offset+uint64(i))
+		if event == nil {
+			continue
+		}
+		if err := stream.Send(&v1alpha1.StreamLogsRequest{
+			Payload: &v1alpha1.StreamLogsRequest_StructuredEvent{StructuredEvent: event},
+		}); err != nil {
+			return err
 		}
 	}
-	return batch
+	return nil
 }
 
-func (g *backendClient) sendLogBatch(stream wrapperStream, lines []string) error {
-	g.pendingLogs = lines
+// sendPing records the sent spool offset before the ping. The server answers pings in order after storing the
+// preceding batches, so the answer acknowledges the lines sent before the ping and their spool segments can be
+// removed.
+func (g *backendClient) sendPing(stream wrapperStream) error {
+	g.pingLock.Lock()
+	g.pingOffsets = append(g.pingOffsets, g.spool.sent())
+	g.pingLock.Unlock()
+	return stream.Send(pingRequest)
+}
+
+func (g *backendClient) ackPing() {
+	g.pingLock.Lock()
+	if len(g.pingOffsets) == 0 {
+		g.pingLock.Unlock()
+		return
+	}
+	offset := g.pingOffsets[0]
+	g.pingOffsets = g.pingOffsets[1:]
+	g.pingLock.Unlock()
+	if err := g.spool.ack(offset); err != nil {
+		common.Logger(g.ctx).Warn("failed to remove acknowledged spool segments", "err", err)
+	}
+}
+
+func (g *backendClient) sendLogBatch(stream wrapperStream, offset uint64, lines []string) error {
 	if err := stream.Send(&v1alpha1.StreamLogsRequest{
 		Payload: &v1alpha1.StreamLogsRequest_LogBatch{
-			LogBatch: &v1alpha1.LogBatch{Lines: lines},
+			LogBatch: &v1alpha1.LogBatch{Lines: lines, Offset: &offset},
 		},
 	}); err != nil {
 		return err
 	}
-	g.pendingLogs = nil
 	g.pingTicker.Reset(g.pingTime)
 	return nil
 }
@@ -317,10 +418,8 @@ func (g *backendClient) sendPlanSummary(stream wrapperStream, summary *v1alpha1.
 }
 
 func (g *backendClient) flushPending(stream wrapperStream) error {
-	if len(g.pendingLogs) > 0 {
-		if err := g.sendLogBatch(stream, g.pendingLogs); err != nil {
-			return err
-		}
+	if err := g.sendSpooled(stream); err != nil {
+		return err
 	}
 	if g.pendingPlan != nil {
 		if err := g.sendPlanSummary(stream, g.pendingPlan); err != nil {
@@ -336,23 +435,60 @@ func (g *backendClient) runReceive(stream wrapperStream, recvErrCh chan<- error,
 		resp, err := stream.Recv()
 		if err != nil {
 			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-				slog.Info("Stream listener stopped due to context cancellation.")
+				common.Logger(g.ctx).Info("Stream listener stopped due to context cancellation.")
 			} else if errors.Is(err, io.EOF) {
-				slog.Info("Server closed the stream gracefully.")
+				common.Logger(g.ctx).Info("Server closed the stream gracefully.")
 			} else {
-				slog.Error("Stream listener stopped with an unexpected error", "error", err)
+				common.Logger(g.ctx).Error("Stream listener stopped with an unexpected error", "error", err)
 			}
 			recvErrCh <- err
 			return
 		}
-		if c := resp.GetComplete(); c != nil {
-			slog.Debug("Server reported stream complete", "total_received", c.GetTotalReceived())
+		g.handleResponse(resp)
+	}
+}
+
+func (g *backendClient) handleResponse(resp *v1alpha1.StreamLogsResponse) {
+	switch payload := resp.GetPayload().(type) {
+	case *v1alpha1.StreamLogsResponse_Ping:
+		g.ackPing()
+	case *v1alpha1.StreamLogsResponse_Complete:
+		common.Logger(g.ctx).Debug("Server reported stream complete", "total_received", payload.Complete.GetTotalReceived())
+	case *v1alpha1.StreamLogsResponse_CancelExecution:
+		common.Logger(g.ctx).Warn("Server requested execution cancel", "reason", payload.CancelExecution.GetReason())
+		offer(g.cancellations, payload.CancelExecution.GetReason())
+	case *v1alpha1.StreamLogsResponse_ApprovePlan:
+		common.Logger(g.ctx).Info("Server approved plan", "approved_by", payload.ApprovePlan.GetApprovedBy())
+		g.offerApproval(ApprovalDecision{Approved: true, By: payload.ApprovePlan.GetApprovedBy()})
+	case *v1alpha1.StreamLogsResponse_RejectPlan:
+		common.Logger(g.ctx).Info("Server rejected plan", "rejected_by", payload.RejectPlan.GetRejectedBy(),
+			"reason", payload.RejectPlan.GetReason())
+		g.offerApproval(ApprovalDecision{By: payload.RejectPlan.GetRejectedBy(),
+			Reason: payload.RejectPlan.GetReason()})
+	case *v1alpha1.StreamLogsResponse_SetLogLevel:
+		level := modelLogLevel(payload.SetLogLevel.GetLevel())
+		if level == "" {
+			common.Logger(g.ctx).Warn("Server requested unsupported log level", "level", payload.SetLogLevel.GetLevel())
+			return
 		}
+		if err := common.ChooseLogger(string(level)); err != nil {
+			common.Logger(g.ctx).Warn("failed to change log level", "err", err)
+			return
+		}
+		common.Logger(g.ctx).Info("Server changed log level", "level", level)
+	}
+}
+
+// offer delivers the value without blocking the receive loop, dropping it when a value is already pending.
+func offer[T any](ch chan T, value T) {
+	select {
+	case ch <- value:
+	default:
 	}
 }
 
 func (g *backendClient) openStream() (wrapperStream, error) {
-	slog.Info("Opening wrapper log stream...")
+	common.Logger(g.ctx).Info("Opening wrapper log stream...")
 	streamCtx, streamCancel := context.WithCancel(g.ctx)
 	stream, err := g.client.StreamLogs(streamCtx)
 	if err != nil {
@@ -364,6 +500,10 @@ func (g *backendClient) openStream() (wrapperStream, error) {
 	// returns instead of blocking forever. Stopped on success below.
 	handshakeTimer := time.AfterFunc(handshakeTimeout, streamCancel)
 	defer handshakeTimer.Stop()
+	// Pings of a broken stream are never answered.
+	g.pingLock.Lock()
+	g.pingOffsets = nil
+	g.pingLock.Unlock()
 
 	hs := &v1alpha1.StreamLogsRequest{
 		Payload: &v1alpha1.StreamLogsRequest_Handshake{
@@ -385,11 +525,19 @@ func (g *backendClient) openStream() (wrapperStream, error) {
 		streamCancel()
 		return nil, fmt.Errorf("failed to read handshake ack: %v", err)
 	}
-	if resp.GetHandshakeAck() == nil {
+	ack := resp.GetHandshakeAck()
+	if ack == nil {
 		streamCancel()
 		return nil, fmt.Errorf("expected handshake ack, got %T", resp.GetPayload())
 	}
-	slog.Info("Successfully connected to backend.")
+	// Servers without offset tracking leave the resume point unset, the spool
+	// then continues from the last successfully sent batch.
+	if ack.ResumeOffset != nil {
+		if err = g.spool.resume(ack.GetResumeOffset()); err != nil {
+			common.Logger(g.ctx).Warn("failed to remove acknowledged spool segments", "err", err)
+		}
+	}
+	common.Logger(g.ctx).Info("Successfully connected to backend.")
 	return stream, nil
 }
 
@@ -0,0 +1,62 @@
+package wrapper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
+)
+
+func approveResponse(by string) *v1alpha1.StreamLogsResponse {
+	return &v1alpha1.StreamLogsResponse{Payload: &v1alpha1.StreamLogsResponse_ApprovePlan{
+		ApprovePlan: &v1alpha1.ApprovePlan{ApprovedBy: by}}}
+}
+
+func TestEarlyApprovalIsIgnored(t *testing.T) {
+	client := &backendClient{ctx: context.Background()}
+	client.handleResponse(approveResponse("early"))
+
+	approvals, stop := client.WaitApproval()
+	defer stop()
+	select {
+	case decision := <-approvals:
+		t.Fatalf("approval received before the wait must be ignored, got %+v", decision)
+	default:
+	}
+
+	client.handleResponse(&v1alpha1.StreamLogsResponse{Payload: &v1alpha1.StreamLogsResponse_RejectPlan{
+		RejectPlan: &v1alpha1.RejectPlan{RejectedBy: "reviewer", Reason: "too many changes"}}})
+	client.handleResponse(approveResponse("late"))
+	select {
+	case decision := <-approvals:
+		if decision.Approved || decision.By != "reviewer" || decision.Reason != "too many changes" {
+			t.Fatalf("expected the first decision during the wait, got %+v", decision)
+		}
+	default:
+		t.Fatal("decision received during the wait was not delivered")
+	}
+}
+
+func TestApprovalAfterWaitIsIgnored(t *testing.T) {
+	client := &backendClient{ctx: context.Background()}
+	first, stop := client.WaitApproval()
+	stop()
+	client.handleResponse(approveResponse("stale"))
+	select {
+	case decision := <-first:
+		t.Fatalf("approval received after the wait must be ignored, got %+v", decision)
+	default:
+	}
+
+	second, stop := client.WaitApproval()
+	defer stop()
+	select {
+	case decision := <-second:
+		t.Fatalf("approval of a previous wait must not be delivered, got %+v", decision)
+	default:
+	}
+	client.handleResponse(approveResponse("approver"))
+	if decision := <-second; !decision.Approved || decision.By != "approver" {
+		t.Fatalf("unexpected decision %+v", decision)
+	}
+}
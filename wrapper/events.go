@@ -0,0 +1,163 @@
+package wrapper
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// tfEvent is a terraform machine-readable UI event, emitted one per line with the -json flag.
+type tfEvent struct {
+	Message    string           `json:"@message"`
+	Timestamp  time.Time        `json:"@timestamp"`
+	Type       string           `json:"type"`
+	Hook       *tfHook          `json:"hook"`
+	Change     *tfHook          `json:"change"`
+	Changes    *tfChangeSummary `json:"changes"`
+	Diagnostic *tfDiagnostic    `json:"diagnostic"`
+}
+
+type tfHook struct {
+	Resource struct {
+		Addr string `json:"addr"`
+	} `json:"resource"`
+	Action         string `json:"action"`
+	ElapsedSeconds int64  `json:"elapsed_seconds"`
+	IdKey          string `json:"id_key"`
+	IdValue        string `json:"id_value"`
+}
+
+type tfChangeSummary struct {
+	Add       int32  `json:"add"`
+	Change    int32  `json:"change"`
+	Import    int32  `json:"import"`
+	Remove    int32  `json:"remove"`
+	Operation string `json:"operation"`
+}
+
+type tfDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Address  string `json:"address"`
+	Range    *struct {
+		Filename string `json:"filename"`
+		Start    tfPos  `json:"start"`
+		End      tfPos  `json:"end"`
+	} `json:"range"`
+}
+
+type tfPos struct {
+	Line   int32 `json:"line"`
+	Column int32 `json:"column"`
+	Byte   int32 `json:"byte"`
+}
+
+var structuredEventTypes = map[string]v1alpha1.StructuredEventType{
+	"planned_change": v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_PLANNED_CHANGE,
+	"apply_start":    v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_APPLY_START,
+	"apply_progress": v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_APPLY_PROGRESS,
+	"apply_complete": v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_APPLY_COMPLETE,
+	"apply_errored":  v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_APPLY_ERRORED,
+	"diagnostic":     v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_DIAGNOSTIC,
+	"change_summary": v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_CHANGE_SUMMARY,
+}
+
+var resourceActions = map[string]v1alpha1.ResourceAction{
+	"noop":    v1alpha1.ResourceAction_RESOURCE_ACTION_NOOP,
+	"create":  v1alpha1.ResourceAction_RESOURCE_ACTION_CREATE,
+	"read":    v1alpha1.ResourceAction_RESOURCE_ACTION_READ,
+	"update":  v1alpha1.ResourceAction_RESOURCE_ACTION_UPDATE,
+	"replace": v1alpha1.ResourceAction_RESOURCE_ACTION_REPLACE,
+	"delete":  v1alpha1.ResourceAction_RESOURCE_ACTION_DELETE,
+	"move":    v1alpha1.ResourceAction_RESOURCE_ACTION_MOVE,
+	"import":  v1alpha1.ResourceAction_RESOURCE_ACTION_IMPORT,
+	"remove":  v1alpha1.ResourceAction_RESOURCE_ACTION_FORGET,
+}
+
+var diagnosticSeverities = map[string]v1alpha1.DiagnosticSeverity{
+	"error":   v1alpha1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_ERROR,
+	"warning": v1alpha1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING,
+}
+
+// parseStructuredEvent returns nil for plain output lines and for event types without a typed message.
+func parseStructuredEvent(line string, offset uint64) *v1alpha1.StructuredEvent {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") || !strings.Contains(line, `"@message"`) {
+		return nil
+	}
+	var event tfEvent
+	if err := json.Unmarshal([]byte(line), &event); err != nil {
+		return nil
+	}
+	eventType, found := structuredEventTypes[event.Type]
+	if !found {
+		return nil
+	}
+	structured := &v1alpha1.StructuredEvent{
+		Type:    eventType,
+		Offset:  offset,
+		Message: event.Message,
+	}
+	if !event.Timestamp.IsZero() {
+		structured.Timestamp = timestamppb.New(event.Timestamp)
+	}
+	switch {
+	case event.Hook != nil:
+		structured.Event = &v1alpha1.StructuredEvent_Resource{Resource: toResourceEvent(eventType, event.Hook)}
+	case event.Change != nil:
+		structured.Event = &v1alpha1.StructuredEvent_Resource{Resource: toResourceEvent(eventType, event.Change)}
+	case event.Changes != nil:
+		structured.Event = &v1alpha1.StructuredEvent_ChangeSummary{ChangeSummary: &v1alpha1.ChangeSummary{
+			Added:     event.Changes.Add,
+			Changed:   event.Changes.Change,
+			Imported:  event.Changes.Import,
+			Destroyed: event.Changes.Remove,
+			Operation: event.Changes.Operation,
+		}}
+	case event.Diagnostic != nil:
+		structured.Event = &v1alpha1.StructuredEvent_Diagnostic{Diagnostic: toDiagnostic(event.Diagnostic)}
+	default:
+		return nil
+	}
+	return structured
+}
+
+func toResourceEvent(eventType v1alpha1.StructuredEventType, hook *tfHook) *v1alpha1.ResourceEvent {
+	resource := &v1alpha1.ResourceEvent{
+		Address: hook.Resource.Addr,
+		Action:  resourceActions[hook.Action],
+		IdKey:   hook.IdKey,
+		IdValue: hook.IdValue,
+	}
+	if eventType != v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_PLANNED_CHANGE &&
+		eventType != v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_APPLY_START {
+		resource.Elapsed = durationpb.New(time.Duration(hook.ElapsedSeconds) * time.Second)
+	}
+	return resource
+}
+
+func toDiagnostic(diagnostic *tfDiagnostic) *v1alpha1.Diagnostic {
+	converted := &v1alpha1.Diagnostic{
+		Severity: diagnosticSeverities[diagnostic.Severity],
+		Summary:  diagnostic.Summary,
+		Detail:   diagnostic.Detail,
+		Address:  diagnostic.Address,
+	}
+	if diagnostic.Range != nil {
+		converted.Range = &v1alpha1.SourceRange{
+			Filename: diagnostic.Range.Filename,
+			Start:    toSourcePos(diagnostic.Range.Start),
+			End:      toSourcePos(diagnostic.Range.End),
+		}
+	}
+	return converted
+}
+
+func toSourcePos(pos tfPos) *v1alpha1.SourcePos {
+	return &v1alpha1.SourcePos{Line: pos.Line, Column: pos.Column, Byte: pos.Byte}
+}
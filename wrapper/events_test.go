@@ -0,0 +1,135 @@
+package wrapper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestParseStructuredEvent(t *testing.T) {
+	timestamp := timestamppb.New(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name     string
+		line     string
+		expected *v1alpha1.StructuredEvent
+	}{
+		{name: "plain line", line: "Terraform will perform the following actions:"},
+		{name: "invalid json", line: `{"@message": "broken"`},
+		{name: "json without message", line: `{"type": "apply_start"}`},
+		{name: "unknown type", line: `{"@message": "Terraform 1.9.0", "type": "version"}`},
+		{name: "type without body", line: `{"@message": "Apply started", "type": "apply_start"}`},
+		{
+			name: "planned change",
+			line: `{"@message": "aws_vpc.main: Plan to create", "@timestamp": "2026-10-01T12:00:00Z", ` +
+				`"type": "planned_change", "change": {"resource": {"addr": "aws_vpc.main"}, "action": "create"}}`,
+			expected: &v1alpha1.StructuredEvent{
+				Type:      v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_PLANNED_CHANGE,
+				Offset:    7,
+				Message:   "aws_vpc.main: Plan to create",
+				Timestamp: timestamp,
+				Event: &v1alpha1.StructuredEvent_Resource{Resource: &v1alpha1.ResourceEvent{
+					Address: "aws_vpc.main",
+					Action:  v1alpha1.ResourceAction_RESOURCE_ACTION_CREATE,
+				}},
+			},
+		},
+		{
+			name: "apply complete",
+			line: `  {"@message": "aws_vpc.main: Creation complete", "type": "apply_complete", "hook": {"resource": ` +
+				`{"addr": "aws_vpc.main"}, "action": "create", "elapsed_seconds": 12, "id_key": "id", ` +
+				`"id_value": "vpc-1"}}  `,
+			expected: &v1alpha1.StructuredEvent{
+				Type:    v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_APPLY_COMPLETE,
+				Offset:  7,
+				Message: "aws_vpc.main: Creation complete",
+				Event: &v1alpha1.StructuredEvent_Resource{Resource: &v1alpha1.ResourceEvent{
+					Address: "aws_vpc.main",
+					Action:  v1alpha1.ResourceAction_RESOURCE_ACTION_CREATE,
+					IdKey:   "id",
+					IdValue: "vpc-1",
+					Elapsed: durationpb.New(12 * time.Second),
+				}},
+			},
+		},
+		{
+			name: "forget action",
+			line: `{"@message": "aws_vpc.old: Plan to forget", "type": "planned_change", "change": {"resource": ` +
+				`{"addr": "aws_vpc.old"}, "action": "remove"}}`,
+			expected: &v1alpha1.StructuredEvent{
+				Type:    v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_PLANNED_CHANGE,
+				Offset:  7,
+				Message: "aws_vpc.old: Plan to forget",
+				Event: &v1alpha1.StructuredEvent_Resource{Resource: &v1alpha1.ResourceEvent{
+					Address: "aws_vpc.old",
+					Action:  v1alpha1.ResourceAction_RESOURCE_ACTION_FORGET,
+				}},
+			},
+		},
+		{
+			name: "change summary",
+			line: `{"@message": "Plan: 1 to add", "type": "change_summary", "changes": {"add": 1, "change": 2, ` +
+				`"import": 3, "remove": 4, "operation": "plan"}}`,
+			expected: &v1alpha1.StructuredEvent{
+				Type:    v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_CHANGE_SUMMARY,
+				Offset:  7,
+				Message: "Plan: 1 to add",
+				Event: &v1alpha1.StructuredEvent_ChangeSummary{ChangeSummary: &v1alpha1.ChangeSummary{
+					Added:     1,
+					Changed:   2,
+					Imported:  3,
+					Destroyed: 4,
+					Operation: "plan",
+				}},
+			},
+		},
+		{
+			name: "diagnostic",
+			line: `{"@message": "Error: Invalid reference", "type": "diagnostic", "diagnostic": {"severity": ` +
+				`"error", "summary": "Invalid reference", "detail": "Unknown variable", "address": "aws_vpc.main", ` +
+				`"range": {"filename": "main.tf", "start": {"line": 1, "column": 2, "byte": 3}, ` +
+				`"end": {"line": 4, "column": 5, "byte": 6}}}}`,
+			expected: &v1alpha1.StructuredEvent{
+				Type:    v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_DIAGNOSTIC,
+				Offset:  7,
+				Message: "Error: Invalid reference",
+				Event: &v1alpha1.StructuredEvent_Diagnostic{Diagnostic: &v1alpha1.Diagnostic{
+					Severity: v1alpha1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_ERROR,
+					Summary:  "Invalid reference",
+					Detail:   "Unknown variable",
+					Address:  "aws_vpc.main",
+					Range: &v1alpha1.SourceRange{
+						Filename: "main.tf",
+						Start:    &v1alpha1.SourcePos{Line: 1, Column: 2, Byte: 3},
+						End:      &v1alpha1.SourcePos{Line: 4, Column: 5, Byte: 6},
+					},
+				}},
+			},
+		},
+		{
+			name: "warning without range",
+			line: `{"@message": "Warning: Deprecated", "type": "diagnostic", "diagnostic": {"severity": "warning", ` +
+				`"summary": "Deprecated"}}`,
+			expected: &v1alpha1.StructuredEvent{
+				Type:    v1alpha1.StructuredEventType_STRUCTURED_EVENT_TYPE_DIAGNOSTIC,
+				Offset:  7,
+				Message: "Warning: Deprecated",
+				Event: &v1alpha1.StructuredEvent_Diagnostic{Diagnostic: &v1alpha1.Diagnostic{
+					Severity: v1alpha1.DiagnosticSeverity_DIAGNOSTIC_SEVERITY_WARNING,
+					Summary:  "Deprecated",
+				}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			event := parseStructuredEvent(test.line, 7)
+			if !proto.Equal(event, test.expected) {
+				t.Fatalf("expected event %v, got %v", test.expected, event)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package wrapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+// httpHandler serves stored executions:
+//
+//	GET /campaigns/{campaign}                                                    list of execution statuses
+//	GET /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}        execution status
+//	GET /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/logs   plain text logs
+//	GET /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/plan   plan summary json
+//	GET /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/events structured events as json lines
+//
+// and sends control messages to running executions, with an optional json body:
+//
+//	POST /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/cancel    {"reason": ""}
+//	POST /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/approve   {"by": ""}
+//	POST /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/reject    {"by": "", "reason": ""}
+//	POST /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/log-level {"level": "debug | info | warn | error"}
+func (s *Server) httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET /campaigns/{campaign}", s.auth(s.handleListExecutions))
+	mux.HandleFunc("GET /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}", s.auth(s.handleStatus))
+	mux.HandleFunc("GET /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/logs", s.auth(s.handleLogs))
+	mux.HandleFunc("GET /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/plan", s.auth(s.handlePlan))
+	mux.HandleFunc("GET /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/events", s.auth(s.handleEvents))
+	mux.HandleFunc("POST /campaigns/{campaign}/pipelines/{pipeline}/steps/{step}/{command}/{control}", s.auth(s.handleControl))
+	return mux
+}
+
+func (s *Server) auth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" && !s.validToken(r.Header.Get("Authorization")) {
+			http.Error(w, "invalid or missing token", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) handleListExecutions(w http.ResponseWriter, r *http.Request) {
+	campaignId := r.PathValue("campaign")
+	if !namePattern.MatchString(campaignId) {
+		http.Error(w, "invalid campaign id", http.StatusBadRequest)
+		return
+	}
+	keys, err := s.store.ListExecutions(r.Context(), campaignId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	statuses := make([]ExecutionStatus, 0, len(keys))
+	for _, key := range keys {
+		executionStatus, err := s.getStatus(r.Context(), key)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if executionStatus != nil {
+			statuses = append(statuses, *executionStatus)
+		}
+	}
+	writeJSON(w, statuses)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	key, ok := getExecutionKey(w, r)
+	if !ok {
+		return
+	}
+	executionStatus, err := s.getStatus(r.Context(), key)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if executionStatus == nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, executionStatus)
+}
+
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	key, ok := getExecutionKey(w, r)
+	if !ok {
+		return
+	}
+	logs, err := s.store.GetLogs(r.Context(), key)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if logs == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(logs)
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	key, ok := getExecutionKey(w, r)
+	if !ok {
+		return
+	}
+	events, err := s.store.GetEvents(r.Context(), key)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if events == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	_, _ = w.Write(events)
+}
+
+func (s *Server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	key, ok := getExecutionKey(w, r)
+	if !ok {
+		return
+	}
+	plan, err := s.store.GetFile(r.Context(), key, planFile)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if plan == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(plan)
+}
+
+type controlRequest struct {
+	Reason string `json:"reason"`
+	By     string `json:"by"`
+	Level  string `json:"level"`
+}
+
+func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
+	key, ok := getExecutionKey(w, r)
+	if !ok {
+		return
+	}
+	var request controlRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&request); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	resp, err := getControlResponse(r.PathValue("control"), request)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sent, err := s.sendControl(key, resp)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !sent {
+		http.Error(w, "execution has no open stream", http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func getControlResponse(control string, request controlRequest) (*v1alpha1.StreamLogsResponse, error) {
+	switch control {
+	case "cancel":
+		return &v1alpha1.StreamLogsResponse{Payload: &v1alpha1.StreamLogsResponse_CancelExecution{
+			CancelExecution: &v1alpha1.CancelExecution{Reason: request.Reason},
+		}}, nil
+	case "approve":
+		return &v1alpha1.StreamLogsResponse{Payload: &v1alpha1.StreamLogsResponse_ApprovePlan{
+			ApprovePlan: &v1alpha1.ApprovePlan{ApprovedBy: request.By},
+		}}, nil
+	case "reject":
+		return &v1alpha1.StreamLogsResponse{Payload: &v1alpha1.StreamLogsResponse_RejectPlan{
+			RejectPlan: &v1alpha1.RejectPlan{RejectedBy: request.By, Reason: request.Reason},
+		}}, nil
+	case "log-level":
+		level := protoLogLevel(common.LogLevel(request.Level))
+		if level == v1alpha1.LogLevel_LOG_LEVEL_UNSPECIFIED {
+			return nil, fmt.Errorf("unsupported log level %q", request.Level)
+		}
+		return &v1alpha1.StreamLogsResponse{Payload: &v1alpha1.StreamLogsResponse_SetLogLevel{
+			SetLogLevel: &v1alpha1.SetLogLevel{Level: level},
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported control %q", control)
+	}
+}
+
+func getExecutionKey(w http.ResponseWriter, r *http.Request) (ExecutionKey, bool) {
+	pipelineIndex, validIndex := parseStoredPipelineIndex(r.PathValue("pipeline"))
+	key := ExecutionKey{
+		CampaignId:    r.PathValue("campaign"),
+		PipelineIndex: pipelineIndex,
+		Step:          r.PathValue("step"),
+		Command:       model.ActionCommand(r.PathValue("command")),
+	}
+	if !namePattern.MatchString(key.CampaignId) || !validIndex || !namePattern.MatchString(key.Step) ||
+		protoCommand(key.Command) == v1alpha1.Command_COMMAND_UNSPECIFIED {
+		http.Error(w, "invalid campaign, pipeline, step or command", http.StatusBadRequest)
+		return ExecutionKey{}, false
+	}
+	return key, true
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		slog.Warn("failed to write wrapper http response", "err", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	slog.Error("wrapper http request failed", "err", err)
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
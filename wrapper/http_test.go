@@ -0,0 +1,136 @@
+package wrapper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	handler := NewServer(store, "token").httpHandler()
+	const execution = "/campaigns/campaign/pipelines/0/steps/net/apply"
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		body          string
+		status        int
+	}{
+		{name: "health without token", method: http.MethodGet, path: "/healthz", status: http.StatusOK},
+		{name: "missing token", method: http.MethodGet, path: "/campaigns/campaign", status: http.StatusUnauthorized},
+		{
+			name: "invalid token", method: http.MethodGet, path: "/campaigns/campaign", authorization: "Bearer other",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "valid token", method: http.MethodGet, path: "/campaigns/campaign", authorization: "Bearer token",
+			status: http.StatusOK,
+		},
+		{
+			name: "invalid campaign", method: http.MethodGet, path: "/campaigns/_campaign", authorization: "Bearer token",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "invalid command", method: http.MethodGet, path: "/campaigns/campaign/pipelines/0/steps/net/deploy",
+			authorization: "Bearer token", status: http.StatusBadRequest,
+		},
+		{
+			name: "missing status", method: http.MethodGet, path: execution, authorization: "Bearer token",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "missing plan", method: http.MethodGet, path: execution + "/plan", authorization: "Bearer token",
+			status: http.StatusNotFound,
+		},
+		{
+			name: "control without token", method: http.MethodPost, path: execution + "/cancel",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown control", method: http.MethodPost, path: execution + "/pause", authorization: "Bearer token",
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "invalid control body", method: http.MethodPost, path: execution + "/approve",
+			authorization: "Bearer token", body: "{", status: http.StatusBadRequest,
+		},
+		{
+			name: "invalid log level", method: http.MethodPost, path: execution + "/log-level",
+			authorization: "Bearer token", body: `{"level": "trace"}`, status: http.StatusBadRequest,
+		},
+		{
+			name: "control without stream", method: http.MethodPost, path: execution + "/approve",
+			authorization: "Bearer token", body: `{"by": "jane"}`, status: http.StatusConflict,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			if test.authorization != "" {
+				request.Header.Set("Authorization", test.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != test.status {
+				t.Fatalf("expected status %d, got %d: %s", test.status, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetControlResponse(t *testing.T) {
+	tests := []struct {
+		control  string
+		request  controlRequest
+		expected *v1alpha1.StreamLogsResponse
+	}{
+		{
+			control: "cancel",
+			request: controlRequest{Reason: "stop"},
+			expected: &v1alpha1.StreamLogsResponse{Payload: &v1alpha1.StreamLogsResponse_CancelExecution{
+				CancelExecution: &v1alpha1.CancelExecution{Reason: "stop"},
+			}},
+		},
+		{
+			control: "approve",
+			request: controlRequest{By: "jane"},
+			expected: &v1alpha1.StreamLogsResponse{Payload: &v1alpha1.StreamLogsResponse_ApprovePlan{
+				ApprovePlan: &v1alpha1.ApprovePlan{ApprovedBy: "jane"},
+			}},
+		},
+		{
+			control: "reject",
+			request: controlRequest{By: "jane", Reason: "destroys the vpc"},
+			expected: &v1alpha1.StreamLogsResponse{Payload: &v1alpha1.StreamLogsResponse_RejectPlan{
+				RejectPlan: &v1alpha1.RejectPlan{RejectedBy: "jane", Reason: "destroys the vpc"},
+			}},
+		},
+		{
+			control: "log-level",
+			request: controlRequest{Level: "debug"},
+			expected: &v1alpha1.StreamLogsResponse{Payload: &v1alpha1.StreamLogsResponse_SetLogLevel{
+				SetLogLevel: &v1alpha1.SetLogLevel{Level: v1alpha1.LogLevel_LOG_LEVEL_DEBUG},
+			}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.control, func(t *testing.T) {
+			resp, err := getControlResponse(test.control, test.request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !proto.Equal(resp, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, resp)
+			}
+		})
+	}
+}
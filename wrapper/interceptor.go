@@ -2,8 +2,10 @@ package wrapper
 
 import (
 	"context"
-	"log/slog"
 
+	"github.com/entigolabs/entigo-infralib-agent/common"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 	"golang.org/x/oauth2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -33,7 +35,7 @@ func (i *AuthInterceptor) applyMetadata(ctx context.Context) context.Context {
 	if i.tokenSource != nil {
 		token, err := i.tokenSource.Token()
 		if err != nil {
-			slog.Error("failed to get token for stream", "error", err)
+			common.Logger(ctx).Error("failed to get token for stream", "error", err)
 		} else {
 			kv = append(kv, "authorization", "Bearer "+token.AccessToken)
 		}
@@ -46,3 +48,15 @@ func (i *AuthInterceptor) applyMetadata(ctx context.Context) context.Context {
 	}
 	return metadata.AppendToOutgoingContext(ctx, kv...)
 }
+
+// traceInterceptor propagates the trace context of the stream ctx in the stream metadata.
+func traceInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		carrier := propagation.MapCarrier{}
+		otel.GetTextMapPropagator().Inject(ctx, carrier)
+		for key, value := range carrier {
+			ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
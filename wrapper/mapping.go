@@ -1,8 +1,11 @@
 package wrapper
 
 import (
+	"github.com/entigolabs/entigo-infralib-agent/common"
 	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // Returns "" for unrecognized commands — callers treat that as a hard error.
@@ -52,3 +55,87 @@ func protoStepType(t model.StepType) v1alpha1.StepType {
 		return v1alpha1.StepType_STEP_TYPE_UNSPECIFIED
 	}
 }
+
+// Returns "" for unspecified commands.
+func modelCommand(cmd v1alpha1.Command) model.ActionCommand {
+	switch cmd {
+	case v1alpha1.Command_COMMAND_PLAN:
+		return model.PlanCommand
+	case v1alpha1.Command_COMMAND_APPLY:
+		return model.ApplyCommand
+	case v1alpha1.Command_COMMAND_PLAN_DESTROY:
+		return model.PlanDestroyCommand
+	case v1alpha1.Command_COMMAND_APPLY_DESTROY:
+		return model.ApplyDestroyCommand
+	case v1alpha1.Command_COMMAND_ARGOCD_PLAN:
+		return model.ArgoCDPlanCommand
+	case v1alpha1.Command_COMMAND_ARGOCD_APPLY:
+		return model.ArgoCDApplyCommand
+	case v1alpha1.Command_COMMAND_ARGOCD_PLAN_DESTROY:
+		return model.ArgoCDPlanDestroyCommand
+	case v1alpha1.Command_COMMAND_ARGOCD_APPLY_DESTROY:
+		return model.ArgoCDApplyDestroyCommand
+	default:
+		return ""
+	}
+}
+
+func modelStepType(t v1alpha1.StepType) model.StepType {
+	switch t {
+	case v1alpha1.StepType_STEP_TYPE_TERRAFORM:
+		return model.StepTypeTerraform
+	case v1alpha1.StepType_STEP_TYPE_ARGOCD_APPS:
+		return model.StepTypeArgoCD
+	default:
+		return ""
+	}
+}
+
+// Returns "" for unspecified levels.
+func modelLogLevel(level v1alpha1.LogLevel) common.LogLevel {
+	switch level {
+	case v1alpha1.LogLevel_LOG_LEVEL_DEBUG:
+		return common.DebugLogLevel
+	case v1alpha1.LogLevel_LOG_LEVEL_INFO:
+		return common.ProdLogLevel
+	case v1alpha1.LogLevel_LOG_LEVEL_WARN:
+		return common.WarnLogLevel
+	case v1alpha1.LogLevel_LOG_LEVEL_ERROR:
+		return common.ErrorLogLevel
+	default:
+		return ""
+	}
+}
+
+func protoLogLevel(level common.LogLevel) v1alpha1.LogLevel {
+	switch level {
+	case common.DebugLogLevel:
+		return v1alpha1.LogLevel_LOG_LEVEL_DEBUG
+	case common.ProdLogLevel:
+		return v1alpha1.LogLevel_LOG_LEVEL_INFO
+	case common.WarnLogLevel:
+		return v1alpha1.LogLevel_LOG_LEVEL_WARN
+	case common.ErrorLogLevel:
+		return v1alpha1.LogLevel_LOG_LEVEL_ERROR
+	default:
+		return v1alpha1.LogLevel_LOG_LEVEL_UNSPECIFIED
+	}
+}
+
+func protoResourceTimings(timings []model.ResourceTiming) []*v1alpha1.ResourceTiming {
+	converted := make([]*v1alpha1.ResourceTiming, 0, len(timings))
+	for _, timing := range timings {
+		resource := &v1alpha1.ResourceTiming{
+			Address:   timing.Address,
+			Action:    resourceActions[timing.Action],
+			Started:   timestamppb.New(timing.Started),
+			Duration:  durationpb.New(timing.Duration),
+			Completed: timing.Completed,
+		}
+		if !timing.Finished.IsZero() {
+			resource.Finished = timestamppb.New(timing.Finished)
+		}
+		converted = append(converted, resource)
+	}
+	return converted
+}
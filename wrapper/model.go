@@ -15,13 +15,21 @@ type HandshakeData struct {
 	PipelineIndex int32
 }
 
+// ApprovalDecision is a plan approval or rejection received from the backend.
+type ApprovalDecision struct {
+	Approved bool
+	By       string
+	Reason   string
+}
+
 // BackendClient forwards wrapper logs to the portal backend over gRPC.
 type BackendClient interface {
 	// Connect opens the stream, sends the handshake, and starts the supervisor
 	// that owns reconnect logic. Must be called exactly once before SendLog.
 	Connect(h HandshakeData) error
 	// SendLog forwards a single raw stdout line. Safe to call concurrently
-	// from multiple goroutines; non-blocking (drops on buffer overflow).
+	// from multiple goroutines; writes to the disk spool without waiting for
+	// the stream (drops when the spool is full).
 	SendLog(line string) error
 	// SendPlan delivers the summary of a successful plan run. One-shot, called
 	// at most once after the entrypoint exits and before Disconnect.
@@ -31,4 +39,11 @@ type BackendClient interface {
 	// supervisor to wind down before giving up. Releases the underlying
 	// connection regardless of outcome.
 	Disconnect(ctx context.Context, exitCode int, execErr error) error
+	// Cancellations delivers the reasons of cancel requests received from the
+	// server. Buffered, requests received while one is pending are dropped.
+	Cancellations() <-chan string
+	// WaitApproval starts accepting plan approval decisions from the server
+	// until the returned stop func is called. Decisions received while no
+	// wait is active are dropped. Buffered, the first pending decision wins.
+	WaitApproval() (<-chan ApprovalDecision, func())
 }
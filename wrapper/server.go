@@ -0,0 +1,430 @@
+package wrapper
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const serverShutdownTimeout = 10 * time.Second
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+var pingResponse = &v1alpha1.StreamLogsResponse{
+	Payload: &v1alpha1.StreamLogsResponse_Ping{Ping: &v1alpha1.Ping{}},
+}
+
+type ServerConfig struct {
+	GRPCAddress string
+	HTTPAddress string
+	TLSCert     string
+	TLSKey      string
+}
+
+// Server is a reference implementation of the WrapperService. Received logs, plan summaries and execution
+// statuses are stored per campaign, pipeline, step and command and can be read back over HTTP.
+type Server struct {
+	v1alpha1.UnimplementedWrapperServiceServer
+	store    Store
+	token    string
+	lock     sync.Mutex
+	received map[ExecutionKey]uint64
+	streams  map[ExecutionKey]*serverStream
+}
+
+type logStream = grpc.BidiStreamingServer[v1alpha1.StreamLogsRequest, v1alpha1.StreamLogsResponse]
+
+// serverStream serializes sends, as control messages are sent from HTTP handlers while the stream handler
+// replies to the wrapper.
+type serverStream struct {
+	lock   sync.Mutex
+	stream logStream
+}
+
+func (s *serverStream) send(resp *v1alpha1.StreamLogsResponse) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.stream.Send(resp)
+}
+
+// ExecutionStatus is stored when the stream handshake is accepted and updated when the execution completes.
+type ExecutionStatus struct {
+	CampaignId    string     `json:"campaign_id"`
+	Step          string     `json:"step"`
+	Command       string     `json:"command"`
+	StepType      string     `json:"step_type"`
+	PipelineIndex int32      `json:"pipeline_index"`
+	Started       time.Time  `json:"started"`
+	Completed     *time.Time `json:"completed,omitempty"`
+	ExitCode      *int32     `json:"exit_code,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	TotalReceived uint64     `json:"total_received"`
+}
+
+func NewServer(store Store, token string) *Server {
+	return &Server{
+		store:    store,
+		token:    token,
+		received: make(map[ExecutionKey]uint64),
+		streams:  make(map[ExecutionKey]*serverStream),
+	}
+}
+
+// Serve runs the gRPC and HTTP servers until the context is cancelled or one of the servers fails.
+func (s *Server) Serve(ctx context.Context, config ServerConfig) error {
+	grpcServer, err := s.newGRPCServer(config)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", config.GRPCAddress)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", config.GRPCAddress, err)
+	}
+	errs := make(chan error, 2)
+	go func() {
+		slog.Info("Wrapper gRPC server listening", "address", listener.Addr().String())
+		errs <- grpcServer.Serve(listener)
+	}()
+	var httpServer *http.Server
+	if config.HTTPAddress != "" {
+		httpServer = &http.Server{Addr: config.HTTPAddress, Handler: s.httpHandler(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			slog.Info("Wrapper HTTP server listening", "address", config.HTTPAddress)
+			var err error
+			if config.TLSCert != "" {
+				err = httpServer.ListenAndServeTLS(config.TLSCert, config.TLSKey)
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}()
+	}
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+	s.shutdown(grpcServer, httpServer)
+	return err
+}
+
+func (s *Server) newGRPCServer(config ServerConfig) (*grpc.Server, error) {
+	options := []grpc.ServerOption{grpc.StreamInterceptor(s.authInterceptor())}
+	if config.TLSCert != "" {
+		creds, err := credentials.NewServerTLSFromFile(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls credentials: %v", err)
+		}
+		options = append(options, grpc.Creds(creds))
+	}
+	grpcServer := grpc.NewServer(options...)
+	v1alpha1.RegisterWrapperServiceServer(grpcServer, s)
+	return grpcServer, nil
+}
+
+func (s *Server) shutdown(grpcServer *grpc.Server, httpServer *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			slog.Warn("wrapper HTTP server shutdown failed", "err", err)
+		}
+	}
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+}
+
+func (s *Server) authInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if s.token != "" {
+			md, _ := metadata.FromIncomingContext(ss.Context())
+			values := md.Get("authorization")
+			if len(values) == 0 || !s.validToken(values[0]) {
+				return status.Error(codes.Unauthenticated, "invalid or missing token")
+			}
+		}
+		return handler(srv, ss)
+	}
+}
+
+func (s *Server) validToken(authorization string) bool {
+	return subtle.ConstantTimeCompare([]byte(authorization), []byte("Bearer "+s.token)) == 1
+}
+
+func (s *Server) StreamLogs(stream logStream) error {
+	ctx := stream.Context()
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	handshake := req.GetHandshake()
+	if handshake == nil {
+		return status.Error(codes.InvalidArgument, "first message must be a handshake")
+	}
+	key, err := validateHandshake(handshake)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	resumeOffset, err := s.startExecution(ctx, key, handshake)
+	if err != nil {
+		return status.Errorf(codes.Internal, "failed to store execution: %v", err)
+	}
+	if err = stream.Send(&v1alpha1.StreamLogsResponse{
+		Payload: &v1alpha1.StreamLogsResponse_HandshakeAck{
+			HandshakeAck: &v1alpha1.HandshakeAck{ResumeOffset: &resumeOffset},
+		},
+	}); err != nil {
+		return err
+	}
+	slog.Info("Wrapper stream opened", "campaign", key.CampaignId, "pipeline", key.PipelineIndex, "step", key.Step,
+		"command", key.Command, "resume_offset", resumeOffset)
+	active := &serverStream{stream: stream}
+	s.setStream(key, active)
+	defer s.removeStream(key, active)
+	// Events are buffered until the next batch or the end of the stream, so they are stored in chunks.
+	var events []string
+	defer func() {
+		if len(events) == 0 {
+			return
+		}
+		if err := s.store.AppendEvents(ctx, key, events); err != nil {
+			slog.Error("failed to store wrapper events", "campaign", key.CampaignId, "step", key.Step, "err", err)
+		}
+	}()
+	for {
+		req, err = stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch payload := req.GetPayload().(type) {
+		case *v1alpha1.StreamLogsRequest_LogBatch:
+			if len(events) > 0 {
+				if err = s.store.AppendEvents(ctx, key, events); err != nil {
+					return status.Errorf(codes.Internal, "failed to store events: %v", err)
+				}
+				events = nil
+			}
+			if err = s.appendLogs(ctx, key, payload.LogBatch); err != nil {
+				return status.Errorf(codes.Internal, "failed to store logs: %v", err)
+			}
+		case *v1alpha1.StreamLogsRequest_StructuredEvent:
+			event, err := protojson.Marshal(payload.StructuredEvent)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid structured event: %v", err)
+			}
+			events = append(events, string(event))
+		case *v1alpha1.StreamLogsRequest_PlanSummary:
+			if err = s.putPlan(ctx, key, payload.PlanSummary); err != nil {
+				return status.Errorf(codes.Internal, "failed to store plan summary: %v", err)
+			}
+		case *v1alpha1.StreamLogsRequest_Ping:
+			if err = active.send(pingResponse); err != nil {
+				return err
+			}
+		case *v1alpha1.StreamLogsRequest_Complete:
+			total, err := s.completeExecution(ctx, key, payload.Complete)
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to store execution: %v", err)
+			}
+			slog.Info("Wrapper execution completed", "campaign", key.CampaignId, "step", key.Step,
+				"command", key.Command, "exit_code", payload.Complete.GetExitCode(), "total_received", total)
+			return active.send(&v1alpha1.StreamLogsResponse{
+				Payload: &v1alpha1.StreamLogsResponse_Complete{
+					Complete: &v1alpha1.StreamComplete{TotalReceived: total},
+				},
+			})
+		case *v1alpha1.StreamLogsRequest_Handshake:
+			return status.Error(codes.InvalidArgument, "handshake already received")
+		default:
+			return status.Errorf(codes.InvalidArgument, "unsupported payload %T", payload)
+		}
+	}
+}
+
+// setStream replaces the stream of a reconnected execution, control messages go to the latest stream.
+func (s *Server) setStream(key ExecutionKey, stream *serverStream) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.streams[key] = stream
+}
+
+func (s *Server) removeStream(key ExecutionKey, stream *serverStream) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.streams[key] == stream {
+		delete(s.streams, key)
+	}
+}
+
+// sendControl sends a control message to the open stream of the execution. Returns false if the execution
+// has no open stream.
+func (s *Server) sendControl(key ExecutionKey, resp *v1alpha1.StreamLogsResponse) (bool, error) {
+	s.lock.Lock()
+	stream, found := s.streams[key]
+	s.lock.Unlock()
+	if !found {
+		return false, nil
+	}
+	return true, stream.send(resp)
+}
+
+func validateHandshake(handshake *v1alpha1.Handshake) (ExecutionKey, error) {
+	if !namePattern.MatchString(handshake.GetCampaignId()) {
+		return ExecutionKey{}, fmt.Errorf("invalid campaign id %q", handshake.GetCampaignId())
+	}
+	if !namePattern.MatchString(handshake.GetStep()) {
+		return ExecutionKey{}, fmt.Errorf("invalid step %q", handshake.GetStep())
+	}
+	command := modelCommand(handshake.GetCommand())
+	if command == "" {
+		return ExecutionKey{}, fmt.Errorf("unsupported command %s", handshake.GetCommand())
+	}
+	stepType := modelStepType(handshake.GetStepType())
+	if stepType == "" {
+		return ExecutionKey{}, fmt.Errorf("unsupported step type %s", handshake.GetStepType())
+	}
+	if getStepType(command) != stepType {
+		return ExecutionKey{}, fmt.Errorf("command %s doesn't match step type %s", command, stepType)
+	}
+	if handshake.GetPipelineIndex() < 0 {
+		return ExecutionKey{}, fmt.Errorf("invalid pipeline index %d", handshake.GetPipelineIndex())
+	}
+	return ExecutionKey{CampaignId: handshake.GetCampaignId(), PipelineIndex: handshake.GetPipelineIndex(),
+		Step: handshake.GetStep(), Command: command}, nil
+}
+
+// startExecution stores the execution status and returns the offset of the next expected log line.
+// A reconnected stream keeps the existing status.
+func (s *Server) startExecution(ctx context.Context, key ExecutionKey, handshake *v1alpha1.Handshake) (uint64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, found := s.received[key]; !found {
+		logs, err := s.store.GetLogs(ctx, key)
+		if err != nil {
+			return 0, err
+		}
+		s.received[key] = uint64(bytes.Count(logs, []byte("\n")))
+	}
+	resumeOffset := s.received[key]
+	existing, err := s.getStatus(ctx, key)
+	if err != nil || existing != nil {
+		return resumeOffset, err
+	}
+	return resumeOffset, s.putStatus(ctx, key, ExecutionStatus{
+		CampaignId:    key.CampaignId,
+		Step:          key.Step,
+		Command:       string(key.Command),
+		StepType:      string(modelStepType(handshake.GetStepType())),
+		PipelineIndex: key.PipelineIndex,
+		Started:       time.Now(),
+	})
+}
+
+// appendLogs stores the batch, lines before the next expected offset were already stored and are dropped.
+// Batches without an offset are appended as is.
+func (s *Server) appendLogs(ctx context.Context, key ExecutionKey, batch *v1alpha1.LogBatch) error {
+	lines := batch.GetLines()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	next := s.received[key]
+	if batch.Offset != nil {
+		offset := batch.GetOffset()
+		if offset < next {
+			lines = lines[min(next-offset, uint64(len(lines))):]
+		} else if offset > next {
+			slog.Warn("wrapper log batch skips lines", "campaign", key.CampaignId, "step", key.Step,
+				"expected_offset", next, "offset", offset)
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if err := s.store.AppendLogs(ctx, key, lines); err != nil {
+		return err
+	}
+	if batch.Offset != nil {
+		s.received[key] = max(next, batch.GetOffset()) + uint64(len(lines))
+	} else {
+		s.received[key] = next + uint64(len(lines))
+	}
+	return nil
+}
+
+func (s *Server) putPlan(ctx context.Context, key ExecutionKey, summary *v1alpha1.PlanSummary) error {
+	content, err := protojson.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	return s.store.PutFile(ctx, key, planFile, content)
+}
+
+func (s *Server) completeExecution(ctx context.Context, key ExecutionKey, complete *v1alpha1.ExecutionComplete) (uint64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	total := s.received[key]
+	executionStatus, err := s.getStatus(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if executionStatus == nil {
+		executionStatus = &ExecutionStatus{CampaignId: key.CampaignId, Step: key.Step, Command: string(key.Command),
+			PipelineIndex: key.PipelineIndex}
+	}
+	completed := time.Now()
+	exitCode := complete.GetExitCode()
+	executionStatus.Completed = &completed
+	executionStatus.ExitCode = &exitCode
+	executionStatus.Error = complete.GetError()
+	executionStatus.TotalReceived = total
+	return total, s.putStatus(ctx, key, *executionStatus)
+}
+
+func (s *Server) getStatus(ctx context.Context, key ExecutionKey) (*ExecutionStatus, error) {
+	content, err := s.store.GetFile(ctx, key, executionFile)
+	if err != nil || content == nil {
+		return nil, err
+	}
+	var executionStatus ExecutionStatus
+	if err = json.Unmarshal(content, &executionStatus); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal execution status: %v", err)
+	}
+	return &executionStatus, nil
+}
+
+func (s *Server) putStatus(ctx context.Context, key ExecutionKey, executionStatus ExecutionStatus) error {
+	content, err := json.Marshal(executionStatus)
+	if err != nil {
+		return err
+	}
+	return s.store.PutFile(ctx, key, executionFile, content)
+}
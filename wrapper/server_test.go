@@ -0,0 +1,137 @@
+package wrapper
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+func newTestHandshake(pipelineIndex int32) *v1alpha1.Handshake {
+	return &v1alpha1.Handshake{
+		CampaignId:    "campaign",
+		Step:          "net",
+		Command:       v1alpha1.Command_COMMAND_APPLY,
+		StepType:      v1alpha1.StepType_STEP_TYPE_TERRAFORM,
+		PipelineIndex: pipelineIndex,
+	}
+}
+
+func TestServerExecutionsPerPipeline(t *testing.T) {
+	store, err := NewDiskStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	server := NewServer(store, "token")
+	ctx := context.Background()
+	tests := []struct {
+		name         string
+		pipeline     int32
+		lines        []string
+		resumeOffset uint64
+	}{
+		{name: "first release", pipeline: 0, lines: []string{"a", "b", "c"}},
+		{name: "second release", pipeline: 1, lines: []string{"d"}},
+		{name: "first release reconnect", pipeline: 0, resumeOffset: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handshake := newTestHandshake(test.pipeline)
+			key, err := validateHandshake(handshake)
+			if err != nil {
+				t.Fatalf("invalid handshake: %v", err)
+			}
+			resumeOffset, err := server.startExecution(ctx, key, handshake)
+			if err != nil {
+				t.Fatalf("failed to start execution: %v", err)
+			}
+			if resumeOffset != test.resumeOffset {
+				t.Fatalf("expected resume offset %d, got %d", test.resumeOffset, resumeOffset)
+			}
+			offset := uint64(0)
+			if err = server.appendLogs(ctx, key, &v1alpha1.LogBatch{Lines: test.lines, Offset: &offset}); err != nil {
+				t.Fatalf("failed to append logs: %v", err)
+			}
+		})
+	}
+	handler := server.httpHandler()
+	routes := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{path: "/campaigns/campaign/pipelines/0/steps/net/apply/logs", status: http.StatusOK, body: "a\nb\nc\n"},
+		{path: "/campaigns/campaign/pipelines/1/steps/net/apply/logs", status: http.StatusOK, body: "d\n"},
+		{path: "/campaigns/campaign/pipelines/2/steps/net/apply/logs", status: http.StatusNotFound},
+		{path: "/campaigns/campaign/pipelines/-1/steps/net/apply/logs", status: http.StatusBadRequest},
+	}
+	for _, route := range routes {
+		request := httptest.NewRequest(http.MethodGet, route.path, nil)
+		request.Header.Set("Authorization", "Bearer token")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != route.status {
+			t.Fatalf("%s: expected status %d, got %d", route.path, route.status, recorder.Code)
+		}
+		if route.body != "" && recorder.Body.String() != route.body {
+			t.Fatalf("%s: expected body %q, got %q", route.path, route.body, recorder.Body.String())
+		}
+	}
+	request := httptest.NewRequest(http.MethodGet, "/campaigns/campaign", nil)
+	request.Header.Set("Authorization", "Bearer token")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	var statuses []ExecutionStatus
+	if err = json.Unmarshal(recorder.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("failed to unmarshal statuses: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].PipelineIndex != 0 || statuses[1].PipelineIndex != 1 {
+		t.Fatalf("expected executions of pipelines 0 and 1, got %+v", statuses)
+	}
+}
+
+type memoryBucket struct {
+	model.Bucket
+	files map[string][]byte
+}
+
+func (b *memoryBucket) PutFile(_ context.Context, file string, content []byte) error {
+	b.files[file] = content
+	return nil
+}
+
+func (b *memoryBucket) GetFile(_ context.Context, file string) ([]byte, error) {
+	return b.files[file], nil
+}
+
+func (b *memoryBucket) ListFolderFiles(_ context.Context, folder string) ([]string, error) {
+	var files []string
+	for file := range b.files {
+		if strings.HasPrefix(file, folder+"/") {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func TestBucketStoreListExecutions(t *testing.T) {
+	bucket := &memoryBucket{files: map[string][]byte{
+		"wrapper/campaign/1/net/apply/execution.json":    []byte("{}"),
+		"wrapper/campaign/1/net/apply/logs/00000001.txt": []byte("a\n"),
+		"wrapper/campaign/net/apply/execution.json":      []byte("{}"),
+		"wrapper/campaign/x/net/apply/execution.json":    []byte("{}"),
+	}}
+	keys, err := NewBucketStore(bucket).ListExecutions(context.Background(), "campaign")
+	if err != nil {
+		t.Fatalf("failed to list executions: %v", err)
+	}
+	expected := ExecutionKey{CampaignId: "campaign", PipelineIndex: 1, Step: "net", Command: model.ApplyCommand}
+	if len(keys) != 1 || keys[0] != expected {
+		t.Fatalf("expected %+v, got %+v", expected, keys)
+	}
+}
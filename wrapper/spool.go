@@ -0,0 +1,305 @@
+package wrapper
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	spoolFolder      = "ei-agent-wrapper"
+	maxSpoolSize     = 64 * 1024 * 1024
+	spoolSegmentSize = 8 * 1024 * 1024
+	spoolExtension   = ".log"
+)
+
+// spool holds the log lines of a single campaign step on disk in rotated segments. Lines are numbered from 0 in
+// the order they were written, so sending can resume from the offset acknowledged by the server after a
+// reconnect. Segments are removed once all their lines are acknowledged, only undelivered lines count towards
+// the size limit. Existing segments are continued, which lets a restarted wrapper on the same host replay lines
+// that were never delivered.
+type spool struct {
+	lock         sync.Mutex
+	dir          string
+	name         string
+	segments     []*spoolSegment
+	size         int64
+	maxSize      int64
+	segmentSize  int64
+	next         uint64
+	acked        uint64
+	ackRequested uint64
+	notify       chan struct{}
+}
+
+type spoolSegment struct {
+	file      *os.File
+	first     uint64
+	positions []int64
+	size      int64
+}
+
+func (s *spoolSegment) end() uint64 {
+	return s.first + uint64(len(s.positions))
+}
+
+func openSpool(dir string, h HandshakeData) (*spool, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), spoolFolder)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create spool dir %s: %v", dir, err)
+	}
+	name := url.PathEscape(fmt.Sprintf("%s-%d-%s-%s", h.CampaignId, h.PipelineIndex, h.Step,
+		strings.ToLower(strings.TrimPrefix(h.Command.String(), "COMMAND_"))))
+	s := &spool{dir: dir, name: name, maxSize: maxSpoolSize, segmentSize: spoolSegmentSize,
+		notify: make(chan struct{}, 1)}
+	if err := s.load(); err != nil {
+		s.closeSegments()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load indexes the lines of existing segments, a new segment is created when none exist.
+func (s *spool) load() error {
+	files, err := filepath.Glob(filepath.Join(s.dir, s.name+".*"+spoolExtension))
+	if err != nil {
+		return err
+	}
+	var firsts []uint64
+	for _, file := range files {
+		offset := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), s.name+"."), spoolExtension)
+		first, err := strconv.ParseUint(offset, 10, 64)
+		if err == nil {
+			firsts = append(firsts, first)
+		}
+	}
+	slices.Sort(firsts)
+	for _, first := range firsts {
+		if len(s.segments) > 0 {
+			first = max(first, s.segments[len(s.segments)-1].end())
+		}
+		segment, err := s.openSegment(first)
+		if err != nil {
+			return err
+		}
+		s.segments = append(s.segments, segment)
+		s.size += segment.size
+	}
+	if len(s.segments) == 0 {
+		segment, err := s.openSegment(0)
+		if err != nil {
+			return err
+		}
+		s.segments = append(s.segments, segment)
+	}
+	s.next = s.segments[0].first
+	s.acked = s.next
+	s.ackRequested = s.next
+	return nil
+}
+
+func (s *spool) segmentPath(first uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s.%012d%s", s.name, first, spoolExtension))
+}
+
+func (s *spool) openSegment(first uint64) (*spoolSegment, error) {
+	file, err := os.OpenFile(s.segmentPath(first), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool segment: %v", err)
+	}
+	segment := &spoolSegment{file: file, first: first}
+	if err = segment.load(); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to read spool segment %s: %v", file.Name(), err)
+	}
+	return segment, nil
+}
+
+// load indexes the lines of the segment file.
+func (s *spoolSegment) load() error {
+	reader := bufio.NewReader(s.file)
+	for {
+		line, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			if line != "" {
+				// Partially written line from an interrupted run, terminate it so new lines start cleanly.
+				if _, err = s.file.WriteString("\n"); err != nil {
+					return err
+				}
+				s.positions = append(s.positions, s.size)
+				s.size += int64(len(line)) + 1
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.positions = append(s.positions, s.size)
+		s.size += int64(len(line))
+	}
+}
+
+func (s *spool) active() *spoolSegment {
+	return s.segments[len(s.segments)-1]
+}
+
+func (s *spool) total() uint64 {
+	return s.active().end()
+}
+
+// append writes the line to the active segment and wakes up the sender. The active segment is rotated when it's
+// full. Returns false if the undelivered lines reach the size limit.
+func (s *spool) append(line string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	length := int64(len(line)) + 1
+	if s.size+length > s.maxSize {
+		return false, nil
+	}
+	segment := s.active()
+	if len(segment.positions) > 0 && segment.size+length > s.segmentSize {
+		rotated, err := s.openSegment(segment.end())
+		if err != nil {
+			return false, err
+		}
+		s.segments = append(s.segments, rotated)
+		segment = rotated
+	}
+	if _, err := segment.file.WriteString(line + "\n"); err != nil {
+		return false, err
+	}
+	segment.positions = append(segment.positions, segment.size)
+	segment.size += length
+	s.size += length
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return true, nil
+}
+
+// readBatch returns up to limit unsent lines of a single segment and the offset of the first line.
+func (s *spool) readBatch(limit int) (uint64, []string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	index := slices.IndexFunc(s.segments, func(segment *spoolSegment) bool {
+		return s.next < segment.end()
+	})
+	if index == -1 {
+		return s.next, nil, nil
+	}
+	segment := s.segments[index]
+	start := s.next - segment.first
+	end := min(start+uint64(limit), uint64(len(segment.positions)))
+	endPosition := segment.size
+	if end < uint64(len(segment.positions)) {
+		endPosition = segment.positions[end]
+	}
+	startPosition := segment.positions[start]
+	buffer := make([]byte, endPosition-startPosition)
+	if _, err := segment.file.ReadAt(buffer, startPosition); err != nil {
+		return s.next, nil, err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(buffer), "\n"), "\n")
+	return s.next, lines, nil
+}
+
+// advance marks the lines up to the offset as sent.
+func (s *spool) advance(offset uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.next = max(s.next, offset)
+}
+
+// resume rewinds or forwards sending to the offset acknowledged by the server. Lines of removed segments were
+// already acknowledged, so sending doesn't rewind before the oldest segment.
+func (s *spool) resume(offset uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.next = min(max(offset, s.segments[0].first), s.total())
+	s.ackRequested = s.next
+	return s.acknowledge(offset)
+}
+
+// sent returns the offset of the next unsent line.
+func (s *spool) sent() uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.next
+}
+
+// awaitsAck reports whether rotated segments are fully sent and can be removed after the server acknowledges
+// them. Returns true once for each sent offset.
+func (s *spool) awaitsAck() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, segment := range s.segments[:len(s.segments)-1] {
+		if segment.end() <= s.next && segment.end() > s.ackRequested {
+			s.ackRequested = s.next
+			return true
+		}
+	}
+	return false
+}
+
+// ack removes the rotated segments with all lines before the offset.
+func (s *spool) ack(offset uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.acknowledge(offset)
+}
+
+func (s *spool) acknowledge(offset uint64) error {
+	s.acked = max(s.acked, min(offset, s.next))
+	var errs []error
+	for len(s.segments) > 1 && s.segments[0].end() <= s.acked {
+		segment := s.segments[0]
+		errs = append(errs, segment.file.Close(), os.Remove(segment.file.Name()))
+		s.size -= segment.size
+		s.segments = s.segments[1:]
+	}
+	return errors.Join(errs...)
+}
+
+func (s *spool) hasUnsent() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.next < s.total()
+}
+
+func (s *spool) closeSegments() {
+	for _, segment := range s.segments {
+		_ = segment.file.Close()
+	}
+}
+
+// close closes the segments and removes them if all lines were delivered.
+func (s *spool) close(delivered bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var errs []error
+	for _, segment := range s.segments {
+		errs = append(errs, segment.file.Close())
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if delivered && s.next >= s.total() {
+		for _, segment := range s.segments {
+			errs = append(errs, os.Remove(segment.file.Name()))
+		}
+		return errors.Join(errs...)
+	}
+	return fmt.Errorf("spool segments %s kept with %d undelivered lines", s.segmentPath(s.segments[0].first),
+		s.total()-s.next)
+}
@@ -0,0 +1,191 @@
+package wrapper
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
+)
+
+func newTestSpool(t *testing.T, dir string, pipelineIndex int32) *spool {
+	s, err := openSpool(dir, HandshakeData{CampaignId: "campaign", Step: "net", Command: v1alpha1.Command_COMMAND_APPLY,
+		PipelineIndex: pipelineIndex})
+	if err != nil {
+		t.Fatalf("failed to open spool: %v", err)
+	}
+	s.segmentSize = 4
+	s.maxSize = 8
+	return s
+}
+
+func appendLines(t *testing.T, s *spool, lines ...string) []bool {
+	var added []bool
+	for _, line := range lines {
+		ok, err := s.append(line)
+		if err != nil {
+			t.Fatalf("failed to append line: %v", err)
+		}
+		added = append(added, ok)
+	}
+	return added
+}
+
+func readAll(t *testing.T, s *spool) (uint64, []string) {
+	first := s.sent()
+	var lines []string
+	for {
+		offset, batch, err := s.readBatch(10)
+		if err != nil {
+			t.Fatalf("failed to read batch: %v", err)
+		}
+		if len(batch) == 0 {
+			return first, lines
+		}
+		lines = append(lines, batch...)
+		s.advance(offset + uint64(len(batch)))
+	}
+}
+
+func getSegmentFiles(t *testing.T, dir string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+spoolExtension))
+	if err != nil {
+		t.Fatalf("failed to list segments: %v", err)
+	}
+	for i, file := range files {
+		files[i] = filepath.Base(file)
+	}
+	return files
+}
+
+func TestSpoolSegmentPerPipeline(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestSpool(t, dir, 0)
+	second := newTestSpool(t, dir, 1)
+	appendLines(t, first, "a")
+	appendLines(t, second, "b")
+	expected := []string{"campaign-0-net-apply.000000000000.log", "campaign-1-net-apply.000000000000.log"}
+	if files := getSegmentFiles(t, dir); !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected segments %v, got %v", expected, files)
+	}
+	if _, lines := readAll(t, second); !reflect.DeepEqual(lines, []string{"b"}) {
+		t.Fatalf("expected only the second pipeline lines, got %v", lines)
+	}
+}
+
+func TestSpoolAcknowledge(t *testing.T) {
+	tests := []struct {
+		name     string
+		ack      uint64
+		added    []bool
+		segments []string
+	}{
+		{
+			name:     "no ack",
+			added:    []bool{true, true, true, true, false},
+			segments: []string{"000000000000", "000000000002"},
+		},
+		{
+			name:     "partial segment ack",
+			ack:      1,
+			added:    []bool{true, true, true, true, false},
+			segments: []string{"000000000000", "000000000002"},
+		},
+		{
+			name:     "segment ack",
+			ack:      2,
+			added:    []bool{true, true, true, true, true},
+			segments: []string{"000000000002", "000000000004"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := newTestSpool(t, dir, 0)
+			added := appendLines(t, s, "a", "b", "c", "d")
+			readAll(t, s)
+			if !s.awaitsAck() {
+				t.Fatalf("expected sent segment to await ack")
+			}
+			if s.awaitsAck() {
+				t.Fatalf("expected ack to be requested once")
+			}
+			if err := s.ack(test.ack); err != nil {
+				t.Fatalf("failed to ack: %v", err)
+			}
+			added = append(added, appendLines(t, s, "e")...)
+			if !reflect.DeepEqual(added, test.added) {
+				t.Fatalf("expected added %v, got %v", test.added, added)
+			}
+			var segments []string
+			for _, file := range getSegmentFiles(t, dir) {
+				segments = append(segments, strings.TrimSuffix(strings.TrimPrefix(file, s.name+"."), spoolExtension))
+			}
+			if !reflect.DeepEqual(segments, test.segments) {
+				t.Fatalf("expected segments %v, got %v", test.segments, segments)
+			}
+		})
+	}
+}
+
+func TestSpoolReloadAndResume(t *testing.T) {
+	tests := []struct {
+		name   string
+		resume *uint64
+		offset uint64
+		lines  []string
+	}{
+		{name: "replay all", offset: 2, lines: []string{"c", "d", "e"}},
+		{name: "resume from server", resume: ptr(uint64(3)), offset: 3, lines: []string{"d", "e"}},
+		{name: "resume before removed segment", resume: ptr(uint64(0)), offset: 2, lines: []string{"c", "d", "e"}},
+		{name: "resume after end", resume: ptr(uint64(9)), offset: 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s := newTestSpool(t, dir, 0)
+			appendLines(t, s, "a", "b", "c", "d")
+			readAll(t, s)
+			if err := s.ack(2); err != nil {
+				t.Fatalf("failed to ack: %v", err)
+			}
+			// Interrupted run, the partially written line is terminated on reload.
+			file, err := os.OpenFile(s.segmentPath(2), os.O_APPEND|os.O_WRONLY, 0600)
+			if err != nil {
+				t.Fatalf("failed to open segment: %v", err)
+			}
+			_, _ = file.WriteString("e")
+			_ = file.Close()
+			if err = s.close(false); err == nil {
+				t.Fatalf("expected undelivered spool to be kept")
+			}
+
+			reloaded := newTestSpool(t, dir, 0)
+			if test.resume != nil {
+				if err = reloaded.resume(*test.resume); err != nil {
+					t.Fatalf("failed to resume: %v", err)
+				}
+			}
+			offset, lines := readAll(t, reloaded)
+			if offset != test.offset || !reflect.DeepEqual(lines, test.lines) {
+				t.Fatalf("expected lines %v from %d, got %v from %d", test.lines, test.offset, lines, offset)
+			}
+			appendLines(t, reloaded, "f")
+			if offset, lines = readAll(t, reloaded); offset != 5 || !reflect.DeepEqual(lines, []string{"f"}) {
+				t.Fatalf("expected new line at offset 5, got %v from %d", lines, offset)
+			}
+			if err = reloaded.close(true); err != nil {
+				t.Fatalf("failed to close spool: %v", err)
+			}
+			if files := getSegmentFiles(t, dir); len(files) != 0 {
+				t.Fatalf("expected delivered spool to be removed, got %v", files)
+			}
+		})
+	}
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,261 @@
+package wrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+const (
+	logsFile      = "logs.txt"
+	eventsFile    = "events.jsonl"
+	planFile      = "plan.json"
+	executionFile = "execution.json"
+	bucketFolder  = "wrapper"
+	bucketLogs    = "logs"
+	bucketEvents  = "events"
+)
+
+// ExecutionKey identifies a single wrapper execution stored by the server. A campaign runs the same step and
+// command once per release pipeline.
+type ExecutionKey struct {
+	CampaignId    string
+	PipelineIndex int32
+	Step          string
+	Command       model.ActionCommand
+}
+
+func (k ExecutionKey) path() string {
+	return path.Join(k.CampaignId, strconv.Itoa(int(k.PipelineIndex)), k.Step, string(k.Command))
+}
+
+func parseStoredPipelineIndex(value string) (int32, bool) {
+	index, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || index < 0 {
+		return 0, false
+	}
+	return int32(index), true
+}
+
+// Store persists log batches, structured events, plan summaries and execution statuses received by the wrapper
+// server. Missing files are returned as nil without an error.
+type Store interface {
+	AppendLogs(ctx context.Context, key ExecutionKey, lines []string) error
+	GetLogs(ctx context.Context, key ExecutionKey) ([]byte, error)
+	// AppendEvents appends json encoded structured events, one per line.
+	AppendEvents(ctx context.Context, key ExecutionKey, events []string) error
+	GetEvents(ctx context.Context, key ExecutionKey) ([]byte, error)
+	PutFile(ctx context.Context, key ExecutionKey, name string, content []byte) error
+	GetFile(ctx context.Context, key ExecutionKey, name string) ([]byte, error)
+	ListExecutions(ctx context.Context, campaignId string) ([]ExecutionKey, error)
+}
+
+type diskStore struct {
+	dir  string
+	lock sync.Mutex
+}
+
+func NewDiskStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create storage dir %s: %v", dir, err)
+	}
+	return &diskStore{dir: dir}, nil
+}
+
+func (d *diskStore) AppendLogs(_ context.Context, key ExecutionKey, lines []string) error {
+	return d.appendLines(key, logsFile, lines)
+}
+
+func (d *diskStore) GetLogs(ctx context.Context, key ExecutionKey) ([]byte, error) {
+	return d.GetFile(ctx, key, logsFile)
+}
+
+func (d *diskStore) AppendEvents(_ context.Context, key ExecutionKey, events []string) error {
+	return d.appendLines(key, eventsFile, events)
+}
+
+func (d *diskStore) GetEvents(ctx context.Context, key ExecutionKey) ([]byte, error) {
+	return d.GetFile(ctx, key, eventsFile)
+}
+
+func (d *diskStore) appendLines(key ExecutionKey, name string, lines []string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	folder := filepath.Join(d.dir, filepath.FromSlash(key.path()))
+	if err := os.MkdirAll(folder, 0750); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(folder, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(joinLines(lines))
+	return errors.Join(err, file.Close())
+}
+
+func (d *diskStore) PutFile(_ context.Context, key ExecutionKey, name string, content []byte) error {
+	folder := filepath.Join(d.dir, filepath.FromSlash(key.path()))
+	if err := os.MkdirAll(folder, 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(folder, name), content, 0640)
+}
+
+func (d *diskStore) GetFile(_ context.Context, key ExecutionKey, name string) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(d.dir, filepath.FromSlash(key.path()), name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return content, err
+}
+
+func (d *diskStore) ListExecutions(_ context.Context, campaignId string) ([]ExecutionKey, error) {
+	files, err := fs.Glob(os.DirFS(filepath.Join(d.dir, campaignId)), path.Join("*", "*", "*", executionFile))
+	if err != nil {
+		return nil, err
+	}
+	var keys []ExecutionKey
+	for _, file := range files {
+		key, ok := getStoredExecutionKey(campaignId, strings.Split(file, "/"))
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+type chunkKey struct {
+	execution ExecutionKey
+	folder    string
+}
+
+// bucketStore stores each log and event batch as a separate object, as buckets don't support appending.
+type bucketStore struct {
+	bucket model.Bucket
+	lock   sync.Mutex
+	chunks map[chunkKey]int
+}
+
+func NewBucketStore(bucket model.Bucket) Store {
+	return &bucketStore{bucket: bucket, chunks: make(map[chunkKey]int)}
+}
+
+func (b *bucketStore) AppendLogs(ctx context.Context, key ExecutionKey, lines []string) error {
+	return b.appendChunk(ctx, key, bucketLogs, lines)
+}
+
+func (b *bucketStore) GetLogs(ctx context.Context, key ExecutionKey) ([]byte, error) {
+	return b.getChunks(ctx, key, bucketLogs)
+}
+
+func (b *bucketStore) AppendEvents(ctx context.Context, key ExecutionKey, events []string) error {
+	return b.appendChunk(ctx, key, bucketEvents, events)
+}
+
+func (b *bucketStore) GetEvents(ctx context.Context, key ExecutionKey) ([]byte, error) {
+	return b.getChunks(ctx, key, bucketEvents)
+}
+
+func (b *bucketStore) appendChunk(ctx context.Context, key ExecutionKey, folder string, lines []string) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ck := chunkKey{execution: key, folder: folder}
+	chunk, found := b.chunks[ck]
+	if !found {
+		files, err := b.listChunks(ctx, key, folder)
+		if err != nil {
+			return err
+		}
+		chunk = len(files)
+	}
+	chunk++
+	file := path.Join(b.folder(key), folder, fmt.Sprintf("%08d.txt", chunk))
+	if err := b.bucket.PutFile(ctx, file, []byte(joinLines(lines))); err != nil {
+		return err
+	}
+	b.chunks[ck] = chunk
+	return nil
+}
+
+func (b *bucketStore) getChunks(ctx context.Context, key ExecutionKey, folder string) ([]byte, error) {
+	files, err := b.listChunks(ctx, key, folder)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	var logs []byte
+	for _, file := range files {
+		content, err := b.bucket.GetFile(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, content...)
+	}
+	return logs, nil
+}
+
+func (b *bucketStore) PutFile(ctx context.Context, key ExecutionKey, name string, content []byte) error {
+	return b.bucket.PutFile(ctx, path.Join(b.folder(key), name), content)
+}
+
+func (b *bucketStore) GetFile(ctx context.Context, key ExecutionKey, name string) ([]byte, error) {
+	return b.bucket.GetFile(ctx, path.Join(b.folder(key), name))
+}
+
+func (b *bucketStore) ListExecutions(ctx context.Context, campaignId string) ([]ExecutionKey, error) {
+	campaignFolder := path.Join(bucketFolder, campaignId)
+	files, err := b.bucket.ListFolderFiles(ctx, campaignFolder)
+	if err != nil {
+		return nil, err
+	}
+	var keys []ExecutionKey
+	for _, file := range files {
+		key, ok := getStoredExecutionKey(campaignId, strings.Split(strings.TrimPrefix(file, campaignFolder+"/"), "/"))
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// getStoredExecutionKey parses the {pipeline}/{step}/{command}/execution.json path parts of a stored execution.
+func getStoredExecutionKey(campaignId string, parts []string) (ExecutionKey, bool) {
+	if len(parts) != 4 || parts[3] != executionFile {
+		return ExecutionKey{}, false
+	}
+	pipelineIndex, ok := parseStoredPipelineIndex(parts[0])
+	if !ok {
+		return ExecutionKey{}, false
+	}
+	return ExecutionKey{CampaignId: campaignId, PipelineIndex: pipelineIndex, Step: parts[1],
+		Command: model.ActionCommand(parts[2])}, true
+}
+
+func (b *bucketStore) listChunks(ctx context.Context, key ExecutionKey, folder string) ([]string, error) {
+	files, err := b.bucket.ListFolderFiles(ctx, path.Join(b.folder(key), folder))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+func (b *bucketStore) folder(key ExecutionKey) string {
+	return path.Join(bucketFolder, key.path())
+}
+
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,122 @@
+package wrapper
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+)
+
+var ansiRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+var applyStartRegex = regexp.MustCompile(`^(\S+): (Creating|Modifying|Destroying|Reading)\.\.\.`)
+var applyCompleteRegex = regexp.MustCompile(`^(\S+): (Creation|Modifications|Destruction|Read) complete after (\S+)`)
+
+var humanActions = map[string]string{
+	"Creating":      "create",
+	"Creation":      "create",
+	"Modifying":     "update",
+	"Modifications": "update",
+	"Destroying":    "delete",
+	"Destruction":   "delete",
+	"Reading":       "read",
+	"Read":          "read",
+}
+
+type timingKey struct {
+	address string
+	action  string
+}
+
+// timingRecorder collects resource timings from both the human-readable and the -json apply output.
+type timingRecorder struct {
+	now     func() time.Time
+	started time.Time
+	order   []timingKey
+	timings map[timingKey]*model.ResourceTiming
+}
+
+func newTimingRecorder() *timingRecorder {
+	return &timingRecorder{
+		now:     time.Now,
+		started: time.Now(),
+		timings: make(map[timingKey]*model.ResourceTiming),
+	}
+}
+
+func (r *timingRecorder) record(line string) {
+	line = strings.TrimSpace(ansiRegex.ReplaceAllString(line, ""))
+	if strings.HasPrefix(line, "{") {
+		r.recordEvent(line)
+		return
+	}
+	if matches := applyStartRegex.FindStringSubmatch(line); matches != nil {
+		r.start(matches[1], humanActions[matches[2]], r.now())
+		return
+	}
+	if matches := applyCompleteRegex.FindStringSubmatch(line); matches != nil {
+		elapsed, err := time.ParseDuration(matches[3])
+		if err != nil {
+			return
+		}
+		r.finish(matches[1], humanActions[matches[2]], r.now(), elapsed, true)
+	}
+}
+
+func (r *timingRecorder) recordEvent(line string) {
+	var event tfEvent
+	if err := json.Unmarshal([]byte(line), &event); err != nil || event.Hook == nil {
+		return
+	}
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = r.now()
+	}
+	elapsed := time.Duration(event.Hook.ElapsedSeconds) * time.Second
+	switch event.Type {
+	case "apply_start":
+		r.start(event.Hook.Resource.Addr, event.Hook.Action, timestamp)
+	case "apply_complete":
+		r.finish(event.Hook.Resource.Addr, event.Hook.Action, timestamp, elapsed, true)
+	case "apply_errored":
+		r.finish(event.Hook.Resource.Addr, event.Hook.Action, timestamp, elapsed, false)
+	}
+}
+
+func (r *timingRecorder) start(address, action string, started time.Time) {
+	key := timingKey{address: address, action: action}
+	if _, found := r.timings[key]; found {
+		return
+	}
+	r.order = append(r.order, key)
+	r.timings[key] = &model.ResourceTiming{Address: address, Action: action, Started: started}
+}
+
+func (r *timingRecorder) finish(address, action string, finished time.Time, elapsed time.Duration, completed bool) {
+	key := timingKey{address: address, action: action}
+	timing, found := r.timings[key]
+	if !found {
+		r.start(address, action, finished.Add(-elapsed))
+		timing = r.timings[key]
+	}
+	timing.Finished = finished
+	timing.Completed = completed
+	// Terraform reports whole seconds, the measured time is more precise when the start was seen.
+	timing.Duration = max(elapsed, finished.Sub(timing.Started))
+}
+
+// resources returns the timings sorted from slowest to fastest. Unfinished resources are timed until now.
+func (r *timingRecorder) resources() []model.ResourceTiming {
+	now := r.now()
+	timings := make([]model.ResourceTiming, 0, len(r.order))
+	for _, key := range r.order {
+		timing := *r.timings[key]
+		if timing.Finished.IsZero() {
+			timing.Duration = now.Sub(timing.Started)
+		}
+		timings = append(timings, timing)
+	}
+	model.SortTimings(timings)
+	return timings
+}
@@ -3,25 +3,37 @@ package wrapper
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path"
 	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/entigolabs/entigo-infralib-agent/common"
+	"github.com/entigolabs/entigo-infralib-agent/gen/wrapper/v1alpha1"
 	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/redact"
 )
 
-const tfPlan = "steps/%s/%s-plan.json"
+const (
+	tfPlan     = "steps/%s/%s-plan.json"
+	stepFolder = "steps/%s"
+)
+
+const (
+	disconnectTimeout = 10 * time.Second
+	terminateTimeout  = time.Minute
+)
 
-const disconnectTimeout = 10 * time.Second
+const gitPasswordEnvPrefix = "GIT_AUTH_PASSWORD_"
 
 type Wrapper struct {
 	ctx           context.Context
@@ -37,6 +49,11 @@ type Wrapper struct {
 	entrypoint    string
 	env           []string
 	stdout        io.Writer
+	timings       *timingRecorder
+	decrypt       func(folder string) error
+	storeTimings  func(report model.TimingReport) error
+	exitCode      int
+	runErr        error
 }
 
 func NewWrapper(ctx context.Context, flags common.Wrapper, config *model.NotificationApi, env []string, stdout io.Writer) (*Wrapper, error) {
@@ -46,11 +63,16 @@ func NewWrapper(ctx context.Context, flags common.Wrapper, config *model.Notific
 		campaignId = ""
 	}
 	pipelineIndex := parsePipelineIndex(flags.PipelineIndex)
+	addRedactions(config, env)
+	var timings *timingRecorder
+	if command == model.ApplyCommand || command == model.ApplyDestroyCommand {
+		timings = newTimingRecorder()
+	}
 	// Provisioning must not depend on the backend — fall back to transparent
 	// mode on any init failure.
-	client, err := getBackendClient(config, campaignId, flags.Insecure)
+	client, err := getBackendClient(ctx, config, campaignId, flags.Insecure, flags.SpoolDir)
 	if err != nil {
-		slog.Error("wrapper backend init failed, running entrypoint without log forwarding", "err", err)
+		common.Logger(ctx).Error("wrapper backend init failed, running entrypoint without log forwarding", "err", err)
 		client = nil
 	}
 	return &Wrapper{
@@ -67,6 +89,7 @@ func NewWrapper(ctx context.Context, flags common.Wrapper, config *model.Notific
 		env:           env,
 		stdout:        stdout,
 		stepType:      getStepType(command),
+		timings:       timings,
 	}, nil
 }
 
@@ -83,45 +106,138 @@ func parsePipelineIndex(raw string) int32 {
 	return int32(v)
 }
 
-func getBackendClient(config *model.NotificationApi, campaignId string, insecure bool) (BackendClient, error) {
+// addRedactions registers the source passwords, the SOPS age key and the hashes of the agent secret values from the
+// entrypoint environment and the configured patterns, as the wrapper runs in a separate process in cloud pipelines.
+func addRedactions(config *model.NotificationApi, env []string) {
+	for _, variable := range env {
+		name, value, found := strings.Cut(variable, "=")
+		if !found {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(name, gitPasswordEnvPrefix), name == model.SopsAgeKeyEnv:
+			redact.AddValues(value)
+		case name == model.RedactValuesEnv:
+			var hashes redact.Hashes
+			if err := json.Unmarshal([]byte(value), &hashes); err != nil {
+				slog.Warn("wrapper redact values ignored", "err", err)
+				continue
+			}
+			if err := redact.AddHashes(hashes); err != nil {
+				slog.Warn("wrapper redact values ignored", "err", err)
+			}
+		}
+	}
+	if config == nil || len(config.RedactPatterns) == 0 {
+		return
+	}
+	if err := redact.AddPatterns(config.RedactPatterns...); err != nil {
+		slog.Warn("wrapper redact patterns ignored", "err", err)
+	}
+}
+
+func getBackendClient(ctx context.Context, config *model.NotificationApi, campaignId string, insecure bool, spoolDir string) (BackendClient, error) {
 	if config == nil || config.WrapperURL == "" {
 		return nil, nil
 	}
 	if campaignId == "" {
-		slog.Warn("wrapper config supplied but CAMPAIGN_ID is empty, running transparently")
+		common.Logger(ctx).Warn("wrapper config supplied but CAMPAIGN_ID is empty, running transparently")
 		return nil, nil
 	}
-	return newBackendClient(config, insecure)
+	return newBackendClient(ctx, config, insecure, spoolDir)
 }
 
 func (w *Wrapper) Provision() error {
+	err := w.Run()
+	w.Close()
+	return err
+}
+
+// Run connects to the backend and runs the entrypoint. The backend stream stays open until Close, so approval
+// decisions can still be received after a plan.
+func (w *Wrapper) Run() error {
 	w.connectBackend()
 
-	exitCode, runErr := w.runEntrypoint()
-	if w.client == nil {
-		return runErr
+	if err := w.decryptStepFiles(); err != nil {
+		w.exitCode, w.runErr = -1, err
+		return err
 	}
-
-	if w.command == model.PlanCommand && exitCode == 0 {
+	started := time.Now().UTC()
+	w.exitCode, w.runErr = w.runEntrypoint()
+	if w.client != nil && w.command == model.PlanCommand && w.exitCode == 0 {
 		w.sendPlan()
 	}
+	if w.client != nil && w.timings != nil {
+		w.sendTimings()
+	}
+	if w.storeTimings != nil && w.timings != nil {
+		w.storeTimingReport(started)
+	}
+	return w.runErr
+}
+
+// SetDecrypt sets the function that decrypts the encrypted step files in the workspace before the entrypoint runs.
+// Step files and CA certificates are stored encrypted in the bucket.
+func (w *Wrapper) SetDecrypt(decrypt func(folder string) error) {
+	w.decrypt = decrypt
+}
+
+// SetTimingReport sets the function that stores the timing report of an apply, so the agent can report the slowest
+// resources of cloud pipelines.
+func (w *Wrapper) SetTimingReport(store func(report model.TimingReport) error) {
+	w.storeTimings = store
+}
+
+func (w *Wrapper) decryptStepFiles() error {
+	if w.decrypt == nil {
+		return nil
+	}
+	if w.prefixStep == "" {
+		common.Logger(w.ctx).Warn("TF_VAR_prefix flag not set, can't find the step files to decrypt")
+		return nil
+	}
+	return w.decrypt(path.Join(w.getPlanPath(), fmt.Sprintf(stepFolder, w.prefixStep)))
+}
+
+// Timings returns the resource timings of a terraform apply sorted from slowest to fastest, nil for other commands.
+func (w *Wrapper) Timings() []model.ResourceTiming {
+	if w.timings == nil {
+		return nil
+	}
+	return w.timings.resources()
+}
+
+// Close reports the outcome of Run to the backend and closes the stream.
+func (w *Wrapper) Close() {
+	if w.client == nil {
+		return
+	}
 	// w.ctx has no deadline of its own — always cap Disconnect so a hung
-	// backend can't block Provision indefinitely.
+	// backend can't block Close indefinitely.
 	base := w.ctx
 	if base.Err() != nil {
 		base = context.Background()
 	}
 	disconnectCtx, cancel := context.WithTimeout(base, disconnectTimeout)
 	defer cancel()
-	if err := w.client.Disconnect(disconnectCtx, exitCode, runErr); err != nil {
-		slog.Warn("wrapper backend Disconnect failed", "err", err)
+	if err := w.client.Disconnect(disconnectCtx, w.exitCode, w.runErr); err != nil {
+		common.Logger(w.ctx).Warn("wrapper backend Disconnect failed", "err", err)
+	}
+	w.client = nil
+}
+
+// WaitApproval returns plan approval decisions from the backend until stop is called, the channel is nil when there's
+// no backend connection.
+func (w *Wrapper) WaitApproval() (<-chan ApprovalDecision, func()) {
+	if w.client == nil {
+		return nil, func() {}
 	}
-	return runErr
+	return w.client.WaitApproval()
 }
 
 func (w *Wrapper) connectBackend() {
 	if w.client == nil || w.stepType == "" {
-		slog.Debug("Wrapper client has not been initialized, either empty wrapper config or stepType from command flag")
+		common.Logger(w.ctx).Debug("Wrapper client has not been initialized, either empty wrapper config or stepType from command flag")
 		return
 	}
 	err := w.client.Connect(HandshakeData{
@@ -132,7 +248,7 @@ func (w *Wrapper) connectBackend() {
 		PipelineIndex: w.pipelineIndex,
 	})
 	if err != nil {
-		slog.Error("wrapper backend connection failed, running entrypoint without log forwarding", "err", err)
+		common.Logger(w.ctx).Error("wrapper backend connection failed, running entrypoint without log forwarding", "err", err)
 		w.client = nil
 	}
 }
@@ -140,17 +256,31 @@ func (w *Wrapper) connectBackend() {
 func (w *Wrapper) runEntrypoint() (int, error) {
 	cmd := exec.CommandContext(w.ctx, w.entrypoint)
 	cmd.Env = w.env
+	// Own process group, so terraform and its providers receive the termination signal together.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = terminateTimeout
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return -1, fmt.Errorf("failed to create stdout pipe: %v", err)
 	}
-	cmd.Stderr = log.Writer()
+	stderr := redact.NewWriter(os.Stderr)
+	defer func() {
+		_ = stderr.Flush()
+	}()
+	cmd.Stderr = stderr
 
 	if err := cmd.Start(); err != nil {
 		return -1, fmt.Errorf("failed to start %s: %v", w.entrypoint, err)
 	}
 
+	stopWatch := make(chan struct{})
+	defer close(stopWatch)
+	go w.watchCancel(cmd.Process.Pid, stopWatch)
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -175,36 +305,84 @@ func (w *Wrapper) runEntrypoint() (int, error) {
 	return exitCode, waitErr
 }
 
+// watchCancel terminates the entrypoint process group when the backend requests a cancel.
+func (w *Wrapper) watchCancel(pid int, stop <-chan struct{}) {
+	if w.client == nil {
+		return
+	}
+	select {
+	case reason := <-w.client.Cancellations():
+		common.Logger(w.ctx).Warn("Terminating entrypoint on backend request", "reason", reason)
+		if err := syscall.Kill(-pid, syscall.SIGTERM); err != nil {
+			common.Logger(w.ctx).Error("failed to terminate entrypoint", "err", err)
+		}
+	case <-stop:
+	}
+}
+
 func (w *Wrapper) streamStdout(r io.Reader) {
 	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := redact.Line(scanner.Text())
+		if w.timings != nil {
+			w.timings.record(line)
+		}
 		_, _ = fmt.Fprintln(w.stdout, line)
 		if w.client != nil {
 			if err := w.client.SendLog(line); err != nil {
-				slog.Warn("wrapper backend SendLog failed", "err", err)
+				common.Logger(w.ctx).Warn("wrapper backend SendLog failed", "err", err)
 			}
 		}
 	}
 	if err := scanner.Err(); err != nil {
-		slog.Error("wrapper stdout scanner failed", "err", err)
+		common.Logger(w.ctx).Error("wrapper stdout scanner failed", "err", err)
 	}
 }
 
 func (w *Wrapper) sendPlan() {
 	if w.prefixStep == "" {
-		slog.Warn("TF_VAR_prefix flag not set, can't find the plan")
+		common.Logger(w.ctx).Warn("TF_VAR_prefix flag not set, can't find the plan")
 		return
 	}
 	planFile := path.Join(w.getPlanPath(), fmt.Sprintf(tfPlan, w.prefixStep, w.prefixStep))
 	summary, err := readPlanSummary(planFile)
 	if err != nil {
-		slog.Warn("wrapper plan summary unavailable", "err", err)
+		common.Logger(w.ctx).Warn("wrapper plan summary unavailable", "err", err)
+		return
+	}
+	if err := w.client.SendPlan(summary); err != nil {
+		common.Logger(w.ctx).Warn("wrapper backend SendPlan failed", "err", err)
+	}
+}
+
+// sendTimings sends the slowest resources of the apply, also when it failed.
+func (w *Wrapper) sendTimings() {
+	slowest := model.SlowestResources(w.Timings(), model.SlowestResourcesCount)
+	if len(slowest) == 0 {
 		return
 	}
+	summary := &v1alpha1.PlanSummary{SlowestResources: protoResourceTimings(slowest)}
 	if err := w.client.SendPlan(summary); err != nil {
-		slog.Warn("wrapper backend SendPlan failed", "err", err)
+		common.Logger(w.ctx).Warn("wrapper backend SendPlan failed", "err", err)
+	}
+}
+
+// storeTimingReport stores the timings of the apply, also when it failed.
+func (w *Wrapper) storeTimingReport(started time.Time) {
+	timings := w.Timings()
+	if len(timings) == 0 {
+		return
+	}
+	report := model.TimingReport{
+		Step:      w.step,
+		Command:   w.command,
+		Started:   started,
+		Finished:  time.Now().UTC(),
+		Resources: timings,
+	}
+	if err := w.storeTimings(report); err != nil {
+		common.Logger(w.ctx).Warn(common.PrefixWarning(fmt.Sprintf("Failed to store timing report: %v", err)))
 	}
 }
 
@@ -0,0 +1,53 @@
+package wrapper
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/entigolabs/entigo-infralib-agent/model"
+	"github.com/entigolabs/entigo-infralib-agent/redact"
+)
+
+func TestAddRedactions(t *testing.T) {
+	// Hashes of the values the agent registered while resolving secret replacement tags
+	agent := redact.Hashes{Salt: []byte("salt"), Values: make(map[int][]string)}
+	for _, value := range []string{"vault-db-password", "secure-ssm-value"} {
+		sum := sha256.Sum256([]byte("salt" + value))
+		agent.Values[len(value)] = append(agent.Values[len(value)], base64.RawStdEncoding.EncodeToString(sum[:16]))
+	}
+	values, err := json.Marshal(agent)
+	if err != nil {
+		t.Fatalf("failed to marshal values: %v", err)
+	}
+	if strings.Contains(string(values), "vault-db-password") {
+		t.Fatal("redact hashes must not contain the values")
+	}
+	env := []string{
+		"PATH=/usr/bin",
+		"INFRALIB_BUCKET=bucket-name",
+		gitPasswordEnvPrefix + "abc=git-token-value",
+		model.SopsAgeKeyEnv + "=AGE-SECRET-KEY-1TESTKEY",
+		model.RedactValuesEnv + "=" + string(values),
+	}
+	addRedactions(&model.NotificationApi{RedactPatterns: []string{`ghp_[a-z]+`}}, env)
+
+	line := "db vault-db-password ssm secure-ssm-value git git-token-value age AGE-SECRET-KEY-1TESTKEY token ghp_abc " +
+		"bucket bucket-name path /usr/bin"
+	expected := "db *** ssm *** git *** age *** token *** bucket bucket-name path /usr/bin"
+	if redacted := redact.Line(line); redacted != expected {
+		t.Fatalf("expected %q, got %q", expected, redacted)
+	}
+}
+
+func TestAddRedactionsInvalidValues(t *testing.T) {
+	addRedactions(nil, []string{model.RedactValuesEnv + "=not-json-value"})
+	if redacted := redact.Line("not-json-value"); redacted != "not-json-value" {
+		t.Fatalf("invalid redact values must be ignored, got %q", redacted)
+	}
+	if strings.Contains(strings.Join(redact.Values(), ","), "not-json-value") {
+		t.Fatal("invalid redact values must not be registered")
+	}
+}